@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/ystyle/cangjie-mem/internal/store"
+	"github.com/ystyle/cangjie-mem/pkg/db"
+	"github.com/ystyle/cangjie-mem/pkg/embed"
+	"github.com/ystyle/cangjie-mem/pkg/markdown"
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// runIngestLibrary 执行 ingest-library 子命令：把库源码目录导入为库级记忆
+//
+//	cangjie-mem ingest-library --name tang --path ./tang
+func runIngestLibrary(args []string) {
+	flags := flag.NewFlagSet("ingest-library", flag.ExitOnError)
+	name := flags.String("name", "", "库名（必需）")
+	path := flags.String("path", "", "库根目录（必需）")
+	libraryVersion := flags.String("library-version", "", "适用的库版本范围（可选，如 ^1.2）")
+	headingDepth := flags.Int("heading-depth", markdown.DefaultDepth, "Markdown 按几级以内的标题切分")
+	dbPath := flags.String("db", "", "数据库文件路径（默认 ~/.cangjie-mem/memory.db）")
+	flags.Parse(args)
+
+	if envDB := getEnvOrDefault("CANGJIE_DB_PATH", *dbPath); envDB != "" {
+		dbPath = &envDB
+	}
+	if *name == "" || *path == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	database, err := db.New(db.Config{Path: *dbPath})
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	st := store.New(database)
+	st.SetEmbedder(embed.NewHashEmbedder(embed.DefaultDimensions))
+
+	result, err := st.IngestLibrary(types.IngestRequest{
+		Name:           *name,
+		Path:           *path,
+		LibraryVersion: *libraryVersion,
+		HeadingDepth:   *headingDepth,
+	})
+	if err != nil {
+		log.Fatalf("Failed to ingest library: %v", err)
+	}
+
+	fmt.Printf("✓ 已导入库 %s：%d 个文件，%d 个文档章节，%d 个符号\n", result.Library, result.Files, result.Sections, result.Symbols)
+	fmt.Printf("  新增 %d 条，更新 %d 条，未变化 %d 条，移入回收站 %d 条记忆\n", result.Added, result.Updated, result.Unchanged, result.Removed)
+}
@@ -8,9 +8,11 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	mcpserver "github.com/mark3labs/mcp-go/server"
 	"github.com/ystyle/cangjie-mem/internal/api"
+	"github.com/ystyle/cangjie-mem/internal/store"
 	"github.com/ystyle/cangjie-mem/pkg/mcp"
 	"github.com/ystyle/cangjie-mem/pkg/version"
 	"github.com/ystyle/cangjie-mem"
@@ -33,6 +35,21 @@ func getEnvBool(key string, defaultValue bool) bool {
 }
 
 func main() {
+	// 子命令
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "ingest-library":
+			runIngestLibrary(os.Args[2:])
+			return
+		case "import-markdown":
+			runImportMarkdown(os.Args[2:])
+			return
+		case "pack":
+			runPack(os.Args[2:])
+			return
+		}
+	}
+
 	// 命令行参数
 	dbPath := flag.String("db", "", "数据库文件路径（默认 ~/.cangjie-mem/memory.db）")
 	showVersion := flag.Bool("version", false, "显示版本信息")
@@ -48,6 +65,25 @@ func main() {
 	enableAPI := flag.Bool("api", false, "启用 REST API（默认 false）")
 	enableUI := flag.Bool("ui", false, "启用 Web UI（默认 false）")
 
+	// 回收站保留时长
+	trashRetention := flag.Duration("trash-retention", store.DefaultTrashRetention, "回收站保留时长，超过后自动彻底删除（默认 720h，0 表示永久保留）")
+
+	// 检索置信度权重
+	scoringWeights := flag.String("scoring-weights", "", "检索置信度权重（如 relevance=0.45,level=0.15,source=0.1,access=0.05,project=0.25，未指定的使用默认值）")
+
+	// 项目依赖
+	projectDeps := flag.Bool("project-deps", false, "检索时读取 project_context 下的 cjpm.toml，库级记忆只返回项目依赖的库（默认 false）")
+
+	// 知识包仓库
+	registries := flag.String("registries", "", "知识包仓库目录或 URL，逗号分隔（REST API 只能从这些仓库安装、升级知识包）")
+
+	// 源码根目录
+	sourceRoots := flag.String("source-roots", "", "源码根目录，逗号分隔（REST API 只能导入这些目录下的库源码和符号）")
+
+	// 知识包签名
+	keyringDir := flag.String("keyring", "", "受信任的签名公钥目录（每个 .pub 文件一个公钥）")
+	requireSignature := flag.Bool("require-signature", false, "导入知识包时要求密钥环中公钥的有效签名（默认 false）")
+
 	flag.Parse()
 
 	// 环境变量覆盖（优先级高于命令行参数）
@@ -75,6 +111,32 @@ func main() {
 	if envUI := getEnvBool("CANGJIE_UI_ENABLED", *enableUI); envUI {
 		enableUI = &envUI
 	}
+	if envRetention := os.Getenv("CANGJIE_TRASH_RETENTION"); envRetention != "" {
+		retention, err := time.ParseDuration(envRetention)
+		if err != nil {
+			log.Fatalf("Invalid CANGJIE_TRASH_RETENTION: %v", err)
+		}
+		trashRetention = &retention
+	}
+
+	if envWeights := getEnvOrDefault("CANGJIE_SCORING_WEIGHTS", *scoringWeights); envWeights != "" {
+		scoringWeights = &envWeights
+	}
+	if envDeps := getEnvBool("CANGJIE_PROJECT_DEPS", *projectDeps); envDeps != *projectDeps {
+		projectDeps = &envDeps
+	}
+	if envRegistries := getEnvOrDefault("CANGJIE_REGISTRIES", *registries); envRegistries != "" {
+		registries = &envRegistries
+	}
+	if envSourceRoots := getEnvOrDefault("CANGJIE_SOURCE_ROOTS", *sourceRoots); envSourceRoots != "" {
+		sourceRoots = &envSourceRoots
+	}
+	if envKeyring := getEnvOrDefault("CANGJIE_KEYRING", *keyringDir); envKeyring != "" {
+		keyringDir = &envKeyring
+	}
+	if envRequire := getEnvBool("CANGJIE_REQUIRE_SIGNATURE", *requireSignature); envRequire {
+		requireSignature = &envRequire
+	}
 
 	if *showVersion {
 		fmt.Printf("cangjie-mem %s\n", version.Version)
@@ -85,8 +147,23 @@ func main() {
 
 	// 创建 MCP 服务器
 	cfg := mcp.Config{
-		DBPath:    *dbPath,
-		HTTPToken: *httpToken,
+		DBPath:         *dbPath,
+		HTTPToken:      *httpToken,
+		TrashRetention: *trashRetention,
+
+		ProjectDependencies: *projectDeps,
+		Registries:          strings.Split(*registries, ","),
+		SourceRoots:         strings.Split(*sourceRoots, ","),
+
+		KeyringDir:       *keyringDir,
+		RequireSignature: *requireSignature,
+	}
+	if *scoringWeights != "" {
+		weights, err := store.ParseScoringWeights(*scoringWeights)
+		if err != nil {
+			log.Fatalf("Invalid scoring weights: %v", err)
+		}
+		cfg.ScoringWeights = &weights
 	}
 
 	server, err := mcp.New(cfg)
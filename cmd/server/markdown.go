@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/ystyle/cangjie-mem/internal/store"
+	"github.com/ystyle/cangjie-mem/pkg/db"
+	"github.com/ystyle/cangjie-mem/pkg/embed"
+	"github.com/ystyle/cangjie-mem/pkg/markdown"
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// runImportMarkdown 执行 import-markdown 子命令：把 Markdown 文档按标题切分后导入
+// 整个导入在一个事务中执行，有失败时全部回滚；指定 -preview 时试运行，只输出每个章节的导入结果
+//
+//	cangjie-mem import-markdown --file cj_syntax.md --heading-depth 2
+func runImportMarkdown(args []string) {
+	flags := flag.NewFlagSet("import-markdown", flag.ExitOnError)
+	file := flags.String("file", "", "Markdown 文件路径（必需）")
+	title := flags.String("title", "", "文档标题（可选，作为每条记忆标题的前缀）")
+	level := flags.String("level", string(types.LevelLanguage), "记忆层级（language/library/project）")
+	libraryName := flags.String("library", "", "库名（library 层级必需）")
+	libraryVersion := flags.String("library-version", "", "适用的库版本范围（可选）")
+	pattern := flags.String("project-path-pattern", "", "项目路径模式（project 层级必需）")
+	headingDepth := flags.Int("heading-depth", markdown.DefaultDepth, "按几级以内的标题切分（1~6）")
+	strategy := flags.String("strategy", string(types.ConflictOverwrite), "与已有同名记忆冲突时的处理策略（overwrite/skip/keep_both/merge）")
+	previewOnly := flags.Bool("preview", false, "试运行：只输出导入结果，不写入")
+	dbPath := flags.String("db", "", "数据库文件路径（默认 ~/.cangjie-mem/memory.db）")
+	flags.Parse(args)
+
+	if envDB := getEnvOrDefault("CANGJIE_DB_PATH", *dbPath); envDB != "" {
+		dbPath = &envDB
+	}
+	if *file == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+	content, err := os.ReadFile(*file)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", *file, err)
+	}
+
+	database, err := db.New(db.Config{Path: *dbPath})
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	st := store.New(database)
+	st.SetEmbedder(embed.NewHashEmbedder(embed.DefaultDimensions))
+
+	memories, err := st.SplitMarkdown(types.MarkdownImportRequest{
+		Content:            string(content),
+		Title:              *title,
+		Level:              types.KnowledgeLevel(*level),
+		LibraryName:        *libraryName,
+		LibraryVersion:     *libraryVersion,
+		ProjectPathPattern: *pattern,
+		HeadingDepth:       *headingDepth,
+	})
+	if err != nil {
+		log.Fatalf("Failed to split markdown: %v", err)
+	}
+
+	// 预览时试运行导入，结果与实际导入一致
+	result, err := st.ImportMemories(types.NewPackageMemories(memories), types.ImportOptions{
+		DryRun:   *previewOnly,
+		Strategy: types.ConflictStrategy(*strategy),
+	})
+	if err != nil {
+		log.Fatalf("Failed to import memories: %v", err)
+	}
+	for _, item := range result.Items {
+		if *previewOnly || item.Action == types.ImportFailed {
+			printImportItem(item)
+		}
+	}
+	if result.Failed > 0 {
+		log.Fatalf("导入失败：%d 条记忆有错误，已全部回滚", result.Failed)
+	}
+	if *previewOnly {
+		fmt.Printf("共 %d 个章节：将新增 %d 条，更新 %d 条，跳过 %d 条\n", result.Total, result.Added, result.Updated, result.Skipped)
+		return
+	}
+	fmt.Printf("✓ 导入完成：新增 %d 条，更新 %d 条，跳过 %d 条\n", result.Added, result.Updated, result.Skipped)
+}
+
+// printImportItem 输出单条记忆的导入结果
+func printImportItem(item types.ImportItemResult) {
+	if item.Reason != "" {
+		fmt.Printf("  - [%s] %s（%s）\n", item.Action, item.Title, item.Reason)
+		return
+	}
+	fmt.Printf("  - [%s] %s\n", item.Action, item.Title)
+}
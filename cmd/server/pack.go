@@ -0,0 +1,373 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ystyle/cangjie-mem/internal/store"
+	"github.com/ystyle/cangjie-mem/pkg/db"
+	"github.com/ystyle/cangjie-mem/pkg/embed"
+	"github.com/ystyle/cangjie-mem/pkg/registry"
+	"github.com/ystyle/cangjie-mem/pkg/signing"
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// runPack 执行 pack 子命令：知识包签名和从仓库安装、升级、卸载
+//
+//	cangjie-mem pack keygen --name alice
+//	cangjie-mem pack sign --key ~/.cangjie-mem/keys/alice.key --in tang.json
+//	cangjie-mem pack verify --in tang.json --keyring ./trusted-keys
+//	cangjie-mem pack index --dir ./registry
+//	cangjie-mem pack install --registry ./registry --name tang
+//	cangjie-mem pack upgrade --name tang --preview
+//	cangjie-mem pack uninstall --name tang
+//	cangjie-mem pack list [--registry ./registry]
+func runPack(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: cangjie-mem pack <keygen|sign|verify|index|install|upgrade|uninstall|list> [flags]")
+		os.Exit(2)
+	}
+	switch args[0] {
+	case "keygen":
+		runPackKeygen(args[1:])
+	case "sign":
+		runPackSign(args[1:])
+	case "verify":
+		runPackVerify(args[1:])
+	case "index":
+		runPackIndex(args[1:])
+	case "install":
+		runPackInstall(args[1:], false)
+	case "upgrade":
+		runPackInstall(args[1:], true)
+	case "uninstall":
+		runPackUninstall(args[1:])
+	case "list":
+		runPackList(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown pack command: %s\n", args[0])
+		os.Exit(2)
+	}
+}
+
+// defaultKeyDir 默认密钥目录（~/.cangjie-mem/keys）
+func defaultKeyDir() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".cangjie-mem", "keys")
+}
+
+// runPackKeygen 生成签名密钥对
+func runPackKeygen(args []string) {
+	flags := flag.NewFlagSet("pack keygen", flag.ExitOnError)
+	name := flags.String("name", "", "密钥名称（必需，生成 <name>.key 和 <name>.pub）")
+	dir := flags.String("dir", defaultKeyDir(), "密钥目录")
+	flags.Parse(args)
+
+	if *name == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+	pub, err := signing.GenerateKey(*dir, *name)
+	if err != nil {
+		log.Fatalf("Failed to generate key: %v", err)
+	}
+
+	fmt.Printf("✓ 已生成密钥 %s\n", signing.KeyID(pub))
+	fmt.Printf("  私钥: %s（请妥善保管）\n", filepath.Join(*dir, *name+signing.PrivateKeyExt))
+	fmt.Printf("  公钥: %s（放入导入方的密钥环目录）\n", filepath.Join(*dir, *name+signing.PublicKeyExt))
+}
+
+// runPackSign 签名知识包（1.0 知识包先升级为当前格式）
+func runPackSign(args []string) {
+	flags := flag.NewFlagSet("pack sign", flag.ExitOnError)
+	keyPath := flags.String("key", "", "私钥文件（必需）")
+	signer := flags.String("signer", "", "签名者名称（默认为私钥文件名）")
+	in := flags.String("in", "", "知识包文件（必需）")
+	out := flags.String("out", "", "输出文件（默认覆盖输入文件）")
+	flags.Parse(args)
+
+	if *keyPath == "" || *in == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if *signer == "" {
+		*signer = strings.TrimSuffix(filepath.Base(*keyPath), filepath.Ext(*keyPath))
+	}
+	if *out == "" {
+		out = in
+	}
+
+	key, err := signing.ReadPrivateKey(*keyPath)
+	if err != nil {
+		log.Fatalf("Failed to read key: %v", err)
+	}
+	pkg := readPackage(*in)
+	if err := signing.Sign(&pkg, key, *signer, time.Now()); err != nil {
+		log.Fatalf("Failed to sign package: %v", err)
+	}
+
+	data, err := json.MarshalIndent(pkg, "", "  ")
+	if err != nil {
+		log.Fatalf("Failed to encode package: %v", err)
+	}
+	if err := os.WriteFile(*out, append(data, '\n'), 0644); err != nil {
+		log.Fatalf("Failed to write %s: %v", *out, err)
+	}
+	fmt.Printf("✓ 已签名知识包 %s（%d 条记忆）: %s\n", pkg.Package.Name, len(pkg.Memories), *out)
+	fmt.Printf("  签名者: %s（%s）\n", pkg.Signature.Signer, pkg.Signature.KeyID)
+}
+
+// runPackVerify 验证知识包签名，指定密钥环时还要求签名公钥受信任
+func runPackVerify(args []string) {
+	flags := flag.NewFlagSet("pack verify", flag.ExitOnError)
+	in := flags.String("in", "", "知识包文件（必需）")
+	keyringDir := flags.String("keyring", "", "受信任的签名公钥目录（可选）")
+	flags.Parse(args)
+
+	if envKeyring := getEnvOrDefault("CANGJIE_KEYRING", *keyringDir); envKeyring != "" {
+		keyringDir = &envKeyring
+	}
+	if *in == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	var keyring *signing.Keyring
+	if *keyringDir != "" {
+		var err error
+		if keyring, err = signing.LoadKeyring(*keyringDir); err != nil {
+			log.Fatalf("Failed to load keyring: %v", err)
+		}
+	}
+	pkg := readPackage(*in)
+	signer, err := keyring.Verify(&pkg)
+	if err != nil {
+		log.Fatalf("✗ %v", err)
+	}
+
+	fmt.Printf("✓ 签名有效: %s（%s），签名于 %s\n", signer.Signer, signer.KeyID, signer.SignedAt.Local().Format("2006-01-02 15:04:05"))
+	switch {
+	case signer.Trusted:
+		fmt.Printf("  受信任的公钥: %s\n", signer.Name)
+	case keyring != nil:
+		log.Fatalf("✗ 签名公钥不在密钥环 %s 中", *keyringDir)
+	default:
+		fmt.Println("  未指定密钥环，未检查公钥是否受信任")
+	}
+}
+
+// readPackage 读取知识包文件
+func readPackage(path string) types.KnowledgePackage {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", path, err)
+	}
+	var pkg types.KnowledgePackage
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		log.Fatalf("Invalid package %s: %v", path, err)
+	}
+	return pkg
+}
+
+// runPackIndex 扫描目录下的知识包文件生成仓库索引 index.json（目录可直接作为仓库，或由静态文件服务器发布）
+func runPackIndex(args []string) {
+	flags := flag.NewFlagSet("pack index", flag.ExitOnError)
+	dir := flags.String("dir", "", "仓库目录（必需）")
+	flags.Parse(args)
+
+	if *dir == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+	index, err := registry.BuildIndex(*dir)
+	if err != nil {
+		log.Fatalf("Failed to build registry index: %v", err)
+	}
+	for _, entry := range index.Packages {
+		fmt.Printf("  - %s@%s（%s）\n", entry.Name, entry.Version, entry.Path)
+	}
+	fmt.Printf("✓ 已生成 %s：%d 个包版本\n", filepath.Join(*dir, registry.IndexFile), len(index.Packages))
+}
+
+// runPackInstall 从仓库安装或升级知识包，指定 -preview 时试运行，只输出与已安装版本的差异
+func runPackInstall(args []string, upgrade bool) {
+	command := "pack install"
+	if upgrade {
+		command = "pack upgrade"
+	}
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	name := flags.String("name", "", "包名（必需）")
+	source := flags.String("registry", "", "仓库目录或 URL（安装时必需，升级时默认为安装来源）")
+	version := flags.String("version", "", "版本（默认为仓库中的最新版本）")
+	strategy := flags.String("strategy", string(types.ConflictKeepBoth), "与不属于该包的已有记忆冲突时的处理策略（overwrite/skip/keep_both/merge，属于其他包的记忆总是都保留）")
+	previewOnly := flags.Bool("preview", false, "试运行：只输出差异和导入结果，不写入")
+	st, closeStore := packStore(flags, args)
+	defer closeStore()
+
+	if *name == "" || (!upgrade && *source == "") {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if *source == "" {
+		installed, err := st.GetPackage(*name)
+		if err != nil {
+			log.Fatalf("Failed to upgrade package: %v", err)
+		}
+		*source = installed.Registry
+	}
+
+	req := types.PackageInstallRequest{
+		Registry: *source,
+		Name:     *name,
+		Version:  *version,
+		ImportOptions: types.ImportOptions{
+			DryRun:   *previewOnly,
+			Strategy: types.ConflictStrategy(*strategy),
+		},
+	}
+	install := st.InstallPackage
+	if upgrade {
+		install = st.UpgradePackage
+	}
+	result, err := install(req)
+	if err != nil {
+		log.Fatalf("Failed to %s package: %v", strings.TrimPrefix(command, "pack "), err)
+	}
+
+	if result.Signer != nil {
+		fmt.Printf("签名者: %s（%s，受信任: %t）\n", result.Signer.Signer, result.Signer.KeyID, result.Signer.Trusted)
+	}
+	printPackageDiff(result.Diff)
+	for _, item := range result.Import.Items {
+		if item.Action == types.ImportFailed {
+			printImportItem(item)
+		}
+	}
+	if result.Import.Failed > 0 {
+		log.Fatalf("安装失败：%d 条记忆有错误，已全部回滚", result.Import.Failed)
+	}
+	if *previewOnly {
+		fmt.Printf("试运行：将新增 %d 条，更新 %d 条，跳过 %d 条，移入回收站 %d 条，恢复 %d 条\n",
+			result.Import.Added, result.Import.Updated, result.Import.Skipped, result.Removed, result.Restored)
+		return
+	}
+	fmt.Printf("✓ 已安装 %s@%s：新增 %d 条，更新 %d 条，跳过 %d 条，移入回收站 %d 条，恢复 %d 条\n", result.Package.Name, result.Package.Version,
+		result.Import.Added, result.Import.Updated, result.Import.Skipped, result.Removed, result.Restored)
+}
+
+// printPackageDiff 输出与已安装版本的差异
+func printPackageDiff(diff types.PackageDiff) {
+	if diff.FromVersion != "" {
+		fmt.Printf("%s → %s\n", diff.FromVersion, diff.ToVersion)
+	}
+	for _, item := range diff.Added {
+		fmt.Printf("  + %s\n", item.Title)
+	}
+	for _, item := range diff.Changed {
+		fields := make([]string, len(item.Diffs))
+		for i, d := range item.Diffs {
+			fields[i] = d.Field
+		}
+		fmt.Printf("  ~ %s（%s）\n", item.Title, strings.Join(fields, ", "))
+	}
+	for _, item := range diff.Removed {
+		fmt.Printf("  - %s\n", item.Title)
+	}
+	for _, item := range diff.Conflicts {
+		owner := ""
+		if item.Package != "" {
+			owner = "，属于 " + item.Package
+		}
+		fmt.Printf("  ! %s（已有记忆 #%d%s）\n", item.Title, item.ID, owner)
+	}
+	fmt.Printf("新增 %d 条，修改 %d 条，删除 %d 条，冲突 %d 条，未变化 %d 条\n",
+		len(diff.Added), len(diff.Changed), len(diff.Removed), len(diff.Conflicts), diff.Unchanged)
+}
+
+// runPackUninstall 卸载知识包，包新增的记忆移入回收站，包覆盖过的已有记忆恢复为覆盖前的版本
+func runPackUninstall(args []string) {
+	flags := flag.NewFlagSet("pack uninstall", flag.ExitOnError)
+	name := flags.String("name", "", "包名（必需）")
+	st, closeStore := packStore(flags, args)
+	defer closeStore()
+
+	if *name == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+	result, err := st.UninstallPackage(*name)
+	if err != nil {
+		log.Fatalf("Failed to uninstall package: %v", err)
+	}
+	fmt.Printf("✓ 已卸载 %s：%d 条记忆移入回收站，%d 条恢复为安装前版本\n", result.Name, result.Removed, result.Restored)
+}
+
+// runPackList 列出已安装的知识包，指定 -registry 时列出仓库中可安装的包
+func runPackList(args []string) {
+	flags := flag.NewFlagSet("pack list", flag.ExitOnError)
+	source := flags.String("registry", "", "仓库目录或 URL（可选）")
+	st, closeStore := packStore(flags, args)
+	defer closeStore()
+
+	if *source != "" {
+		index, err := st.RegistryIndex(*source)
+		if err != nil {
+			log.Fatalf("Failed to read registry: %v", err)
+		}
+		for _, entry := range index.Packages {
+			fmt.Printf("%s@%s\t%s\n", entry.Name, entry.Version, entry.Description)
+		}
+		return
+	}
+
+	packages, err := st.ListPackages()
+	if err != nil {
+		log.Fatalf("Failed to list packages: %v", err)
+	}
+	for _, p := range packages {
+		fmt.Printf("%s@%s\t%d 条记忆\t%s\n", p.Name, p.Version, len(p.MemoryIDs), p.Registry)
+	}
+}
+
+// packStore 解析公共参数（数据库、密钥环）并打开 Store
+func packStore(flags *flag.FlagSet, args []string) (*store.Store, func()) {
+	dbPath := flags.String("db", "", "数据库文件路径（默认 ~/.cangjie-mem/memory.db）")
+	keyringDir := flags.String("keyring", "", "受信任的签名公钥目录（可选）")
+	requireSignature := flags.Bool("require-signature", false, "要求密钥环中公钥的有效签名")
+	flags.Parse(args)
+
+	if envDB := getEnvOrDefault("CANGJIE_DB_PATH", *dbPath); envDB != "" {
+		dbPath = &envDB
+	}
+	if envKeyring := getEnvOrDefault("CANGJIE_KEYRING", *keyringDir); envKeyring != "" {
+		keyringDir = &envKeyring
+	}
+	if envRequire := getEnvBool("CANGJIE_REQUIRE_SIGNATURE", *requireSignature); envRequire {
+		requireSignature = &envRequire
+	}
+	if *requireSignature && *keyringDir == "" {
+		log.Fatalf("A keyring is required when signatures are required")
+	}
+
+	database, err := db.New(db.Config{Path: *dbPath})
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	st := store.New(database)
+	st.SetEmbedder(embed.NewHashEmbedder(embed.DefaultDimensions))
+	if *keyringDir != "" {
+		keyring, err := signing.LoadKeyring(*keyringDir)
+		if err != nil {
+			database.Close()
+			log.Fatalf("Failed to load keyring: %v", err)
+		}
+		st.SetKeyring(keyring, *requireSignature)
+	}
+	return st, func() { database.Close() }
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// handleDiagnose 处理编译诊断（解析编译输出并查找修复记忆）
+func (s *Server) handleDiagnose(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// 解析请求体
+	var req types.DiagnoseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.sendError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	resp, err := s.store.Diagnose(req)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid") {
+			s.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to diagnose: %v", err))
+		return
+	}
+
+	s.sendJSON(w, http.StatusOK, resp)
+}
@@ -4,69 +4,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/ystyle/cangjie-mem/pkg/types"
 )
 
-// importPreviewStore 存储导入预览数据（生产环境应使用 Redis 或数据库）
-type importPreviewStore struct {
-	previews map[string]*importPreviewData
-}
-
-type importPreviewData struct {
-	Memories []types.StoreRequest
-	Expiry   time.Time
-}
-
-// 全局预览存储
-var importStore = &importPreviewStore{
-	previews: make(map[string]*importPreviewData),
-}
-
-// cleanupPreviews 定期清理过期的预览数据
-func init() {
-	go func() {
-		ticker := time.NewTicker(5 * time.Minute)
-		defer ticker.Stop()
-		for range ticker.C {
-			importStore.cleanup()
-		}
-	}()
-}
-
-func (s *importPreviewStore) save(memories []types.StoreRequest) string {
-	id := uuid.New().String()
-	s.previews[id] = &importPreviewData{
-		Memories: memories,
-		Expiry:   time.Now().Add(30 * time.Minute),
-	}
-	return id
-}
-
-func (s *importPreviewStore) get(id string) ([]types.StoreRequest, bool) {
-	data, ok := s.previews[id]
-	if !ok {
-		return nil, false
-	}
-	if time.Now().After(data.Expiry) {
-		delete(s.previews, id)
-		return nil, false
-	}
-	return data.Memories, true
-}
-
-func (s *importPreviewStore) cleanup() {
-	now := time.Now()
-	for id, data := range s.previews {
-		if now.After(data.Expiry) {
-			delete(s.previews, id)
-		}
-	}
-}
-
 // handleExport 处理导出
 func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -95,17 +41,13 @@ func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
 		pkgName = fmt.Sprintf("cangjie-mem-%s", req.Level)
 	}
 
-	pkg := types.KnowledgePackage{
-		Version: types.PackageFormatVersion,
-		Package: types.PackageInfo{
-			Name:        pkgName,
-			Description: req.Description,
-			Author:      req.Author,
-			Tags:        req.Tags,
-			Version:     time.Now().Format("2006.01.02.150405"),
-		},
-		Memories: memories,
-	}
+	pkg := types.NewKnowledgePackage(types.PackageInfo{
+		Name:        pkgName,
+		Description: req.Description,
+		Author:      req.Author,
+		Tags:        req.Tags,
+		Version:     time.Now().Format("2006.01.02.150405"),
+	}, memories)
 
 	// 设置响应头
 	w.Header().Set("Content-Type", "application/json")
@@ -129,8 +71,8 @@ func (s *Server) handleImport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 校验包格式版本
-	if err := types.CheckPackageVersion(pkg.Version); err != nil {
+	// 协商包格式版本（1.0 升级为当前格式，2.0 校验内容哈希和校验和）
+	if err := types.NegotiatePackage(&pkg); err != nil {
 		s.sendError(w, http.StatusBadRequest, fmt.Sprintf("Invalid package format: %v", err))
 		return
 	}
@@ -140,16 +82,59 @@ func (s *Server) handleImport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 预览导入
-	preview, err := s.store.PreviewImport(pkg.Memories)
+	// 验证签名，预览并保存导入会话
+	preview, err := s.store.CreateImportSession(pkg, types.ImportSourcePackage, uploader(r))
 	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid") {
+			s.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to preview import: %v", err))
 		return
 	}
 
-	// 保存预览数据到内存存储
-	importID := importStore.save(pkg.Memories)
-	preview.ImportID = importID
+	s.sendJSON(w, http.StatusOK, preview)
+}
+
+// handleImportMarkdown 处理 Markdown 文档导入（按标题切分并预览，确认走 /api/import/confirm）
+func (s *Server) handleImportMarkdown(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// 解析请求体
+	var req types.MarkdownImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.sendError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	// 切分文档
+	memories, err := s.store.SplitMarkdown(req)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid") {
+			s.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to split markdown: %v", err))
+		return
+	}
+
+	// 预览并保存导入会话
+	name := req.Title
+	if name == "" {
+		name = "markdown"
+	}
+	pkg := types.KnowledgePackage{
+		Package:  types.PackageInfo{Name: name},
+		Memories: types.NewPackageMemories(memories),
+	}
+	preview, err := s.store.CreateImportSession(pkg, types.ImportSourceMarkdown, uploader(r))
+	if err != nil {
+		s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to preview import: %v", err))
+		return
+	}
 
 	s.sendJSON(w, http.StatusOK, preview)
 }
@@ -168,20 +153,80 @@ func (s *Server) handleImportConfirm(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 获取预览数据
-	memories, ok := importStore.get(req.ImportID)
-	if !ok {
-		s.sendError(w, http.StatusNotFound, fmt.Sprintf("Import preview not found or expired: %s", req.ImportID))
+	// 执行导入（冲突按请求中的策略处理；试运行或有失败时回滚，结果中包含每条记忆的动作和原因）
+	result, err := s.store.ConfirmImport(req)
+	if err != nil {
+		s.sendImportError(w, err)
+		return
+	}
+
+	if !result.RolledBack {
+		log.Printf("✓ Import completed: %d added, %d updated, %d skipped", result.Added, result.Updated, result.Skipped)
+	} else if result.Failed > 0 {
+		log.Printf("✗ Import rolled back: %d of %d memories failed", result.Failed, result.Total)
+	}
+	s.sendJSON(w, http.StatusOK, result)
+}
+
+// handleImportHistory 处理导入历史列表
+func (s *Server) handleImportHistory(w http.ResponseWriter, r *http.Request) {
+	req := types.ImportHistoryRequest{Status: r.URL.Query().Get("status")}
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 0 {
+			s.sendError(w, http.StatusBadRequest, "Invalid limit parameter")
+			return
+		}
+		req.Limit = limit
+	}
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			s.sendError(w, http.StatusBadRequest, "Invalid offset parameter")
+			return
+		}
+		req.Offset = offset
+	}
+
+	resp, err := s.store.ImportHistory(req)
+	if err != nil {
+		s.sendImportError(w, err)
 		return
 	}
+	s.sendJSON(w, http.StatusOK, resp)
+}
 
-	// 执行导入
-	result, err := s.store.ImportMemories(memories)
+// handleImportSession 处理单个导入会话详情（包含逐条导入结果）
+func (s *Server) handleImportSession(w http.ResponseWriter, r *http.Request) {
+	session, err := s.store.GetImportSession(r.PathValue("id"))
 	if err != nil {
-		s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to import memories: %v", err))
+		s.sendImportError(w, err)
 		return
 	}
+	s.sendJSON(w, http.StatusOK, session)
+}
 
-	log.Printf("✓ Import completed: %d added, %d updated", result.Added, result.Updated)
-	s.sendJSON(w, http.StatusOK, result)
+// sendImportError 发送导入错误响应
+func (s *Server) sendImportError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		s.sendError(w, http.StatusNotFound, err.Error())
+	case strings.HasPrefix(err.Error(), "import session already"):
+		s.sendError(w, http.StatusConflict, err.Error())
+	case strings.HasPrefix(err.Error(), "invalid"):
+		s.sendError(w, http.StatusBadRequest, err.Error())
+	default:
+		s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to import memories: %v", err))
+	}
+}
+
+// uploader 请求的上传者：认证用户名，未启用认证时为客户端地址
+func uploader(r *http.Request) string {
+	if user, _, ok := r.BasicAuth(); ok && user != "" {
+		return user
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
 }
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// handleIngestLibrary 处理从服务器本地的库源码目录导入库级记忆
+func (s *Server) handleIngestLibrary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// 解析请求体
+	var req types.IngestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.sendError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	// 只允许导入服务端配置的源码根目录下的路径
+	path, err := s.store.ResolveSourcePath(req.Path)
+	if err != nil {
+		s.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	req.Path = path
+
+	result, err := s.store.IngestLibrary(req)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid") {
+			s.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to ingest library: %v", err))
+		return
+	}
+
+	s.sendJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// handleCreateLink 处理建立记忆关系
+func (s *Server) handleCreateLink(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// 解析请求体
+	var req types.LinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.sendError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	link, err := s.store.LinkMemories(req)
+	if err != nil {
+		s.sendLinkError(w, err)
+		return
+	}
+
+	s.sendJSON(w, http.StatusCreated, link)
+}
+
+// handleDeleteLink 处理删除记忆关系（?source_id=1&target_id=2&relation=depends_on，relation 可省略）
+func (s *Server) handleDeleteLink(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		s.sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	sourceID, err := strconv.ParseInt(query.Get("source_id"), 10, 64)
+	if err != nil {
+		s.sendError(w, http.StatusBadRequest, "Invalid source_id parameter")
+		return
+	}
+	targetID, err := strconv.ParseInt(query.Get("target_id"), 10, 64)
+	if err != nil {
+		s.sendError(w, http.StatusBadRequest, "Invalid target_id parameter")
+		return
+	}
+
+	resp, err := s.store.UnlinkMemories(types.LinkRequest{
+		SourceID: sourceID,
+		TargetID: targetID,
+		Relation: types.LinkRelation(query.Get("relation")),
+	})
+	if err != nil {
+		s.sendLinkError(w, err)
+		return
+	}
+
+	s.sendJSON(w, http.StatusOK, resp)
+}
+
+// handleMemoryGraph 处理关系图遍历（?depth=2&relation=depends_on&direction=outgoing，均可省略）
+func (s *Server) handleMemoryGraph(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// 解析 ID
+	id, err := parseID(r.URL.Path)
+	if err != nil {
+		s.sendError(w, http.StatusBadRequest, fmt.Sprintf("Invalid ID: %v", err))
+		return
+	}
+
+	req := types.GraphRequest{
+		ID:        id,
+		Relation:  types.LinkRelation(r.URL.Query().Get("relation")),
+		Direction: r.URL.Query().Get("direction"),
+	}
+	if depthStr := r.URL.Query().Get("depth"); depthStr != "" {
+		if req.Depth, err = strconv.Atoi(depthStr); err != nil || req.Depth <= 0 {
+			s.sendError(w, http.StatusBadRequest, "Invalid depth parameter")
+			return
+		}
+	}
+
+	resp, err := s.store.MemoryGraph(req)
+	if err != nil {
+		s.sendLinkError(w, err)
+		return
+	}
+
+	s.sendJSON(w, http.StatusOK, resp)
+}
+
+// sendLinkError 发送记忆关系错误响应
+func (s *Server) sendLinkError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.Contains(err.Error(), "already exists") || strings.Contains(err.Error(), "in trash"):
+		s.sendError(w, http.StatusConflict, err.Error())
+	case strings.Contains(err.Error(), "not found"):
+		s.sendError(w, http.StatusNotFound, err.Error())
+	case strings.Contains(err.Error(), "invalid"):
+		s.sendError(w, http.StatusBadRequest, err.Error())
+	default:
+		s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to process links: %v", err))
+	}
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// handleListPackages 处理已安装知识包列表
+func (s *Server) handleListPackages(w http.ResponseWriter, r *http.Request) {
+	packages, err := s.store.ListPackages()
+	if err != nil {
+		s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list packages: %v", err))
+		return
+	}
+	s.sendJSON(w, http.StatusOK, packages)
+}
+
+// handleGetPackage 处理已安装知识包详情
+func (s *Server) handleGetPackage(w http.ResponseWriter, r *http.Request) {
+	pkg, err := s.store.GetPackage(r.PathValue("name"))
+	if err != nil {
+		s.sendPackageError(w, err)
+		return
+	}
+	s.sendJSON(w, http.StatusOK, pkg)
+}
+
+// handleInstallPackage 处理从仓库安装知识包
+func (s *Server) handleInstallPackage(w http.ResponseWriter, r *http.Request) {
+	s.installPackage(w, r, false)
+}
+
+// handleUpgradePackage 处理从仓库升级知识包（试运行时只返回差异）
+func (s *Server) handleUpgradePackage(w http.ResponseWriter, r *http.Request) {
+	s.installPackage(w, r, true)
+}
+
+// installPackage 解析安装/升级请求并执行（请求不能指定仓库，只从服务端配置的仓库中安装）
+func (s *Server) installPackage(w http.ResponseWriter, r *http.Request, upgrade bool) {
+	var req types.PackageInstallRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.sendError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	install := s.store.InstallPackage
+	if upgrade {
+		install = s.store.UpgradePackage
+	}
+	result, err := install(req)
+	if err != nil {
+		s.sendPackageError(w, err)
+		return
+	}
+
+	if !result.Import.RolledBack {
+		log.Printf("✓ Package %s@%s installed: %d added, %d updated, %d removed",
+			result.Package.Name, result.Package.Version, result.Import.Added, result.Import.Updated, result.Removed)
+	}
+	s.sendJSON(w, http.StatusOK, result)
+}
+
+// handleUninstallPackage 处理卸载知识包（包拥有的记忆移入回收站）
+func (s *Server) handleUninstallPackage(w http.ResponseWriter, r *http.Request) {
+	result, err := s.store.UninstallPackage(r.PathValue("name"))
+	if err != nil {
+		s.sendPackageError(w, err)
+		return
+	}
+	s.sendJSON(w, http.StatusOK, result)
+}
+
+// handleRegistryIndex 处理仓库索引查询（只列出服务端配置的仓库，不接受客户端指定的仓库）
+func (s *Server) handleRegistryIndex(w http.ResponseWriter, r *http.Request) {
+	registries, err := s.store.ConfiguredRegistries()
+	if err != nil {
+		s.sendPackageError(w, err)
+		return
+	}
+	s.sendJSON(w, http.StatusOK, registries)
+}
+
+// sendPackageError 根据错误类型返回知识包相关错误的状态码
+func (s *Server) sendPackageError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		s.sendError(w, http.StatusNotFound, err.Error())
+	case strings.HasPrefix(err.Error(), "package already installed"):
+		s.sendError(w, http.StatusConflict, err.Error())
+	case strings.HasPrefix(err.Error(), "invalid"):
+		s.sendError(w, http.StatusBadRequest, err.Error())
+	case strings.HasPrefix(err.Error(), "failed to fetch"):
+		s.sendError(w, http.StatusBadGateway, err.Error())
+	default:
+		s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to process package: %v", err))
+	}
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// handleListRevisions 处理修订历史列表
+func (s *Server) handleListRevisions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// 解析 ID
+	id, err := parseID(r.URL.Path)
+	if err != nil {
+		s.sendError(w, http.StatusBadRequest, fmt.Sprintf("Invalid ID: %v", err))
+		return
+	}
+
+	resp, err := s.store.ListRevisions(id)
+	if err != nil {
+		s.sendRevisionError(w, err)
+		return
+	}
+
+	s.sendJSON(w, http.StatusOK, resp)
+}
+
+// handleDiffRevisions 处理修订版本差异（?from=1&to=2，均可省略）
+func (s *Server) handleDiffRevisions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// 解析 ID
+	id, err := parseID(r.URL.Path)
+	if err != nil {
+		s.sendError(w, http.StatusBadRequest, fmt.Sprintf("Invalid ID: %v", err))
+		return
+	}
+
+	var from, to int
+	if fromStr := r.URL.Query().Get("from"); fromStr != "" {
+		if from, err = strconv.Atoi(fromStr); err != nil || from <= 0 {
+			s.sendError(w, http.StatusBadRequest, "Invalid from parameter")
+			return
+		}
+	}
+	if toStr := r.URL.Query().Get("to"); toStr != "" {
+		if to, err = strconv.Atoi(toStr); err != nil || to <= 0 {
+			s.sendError(w, http.StatusBadRequest, "Invalid to parameter")
+			return
+		}
+	}
+
+	resp, err := s.store.DiffRevisions(id, from, to)
+	if err != nil {
+		s.sendRevisionError(w, err)
+		return
+	}
+
+	s.sendJSON(w, http.StatusOK, resp)
+}
+
+// handleRestoreRevision 处理回滚到指定修订版本
+func (s *Server) handleRestoreRevision(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// 解析 ID
+	id, err := parseID(r.URL.Path)
+	if err != nil {
+		s.sendError(w, http.StatusBadRequest, fmt.Sprintf("Invalid ID: %v", err))
+		return
+	}
+
+	revision, err := strconv.Atoi(r.PathValue("revision"))
+	if err != nil || revision <= 0 {
+		s.sendError(w, http.StatusBadRequest, "Invalid revision")
+		return
+	}
+
+	memory, err := s.store.RestoreRevision(id, revision, types.EditorAPI)
+	if err != nil {
+		s.sendRevisionError(w, err)
+		return
+	}
+
+	s.sendJSON(w, http.StatusOK, memory)
+}
+
+// sendRevisionError 发送修订历史错误响应
+func (s *Server) sendRevisionError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.Contains(err.Error(), "in trash"):
+		s.sendError(w, http.StatusConflict, err.Error())
+	case strings.Contains(err.Error(), "not found"):
+		s.sendError(w, http.StatusNotFound, err.Error())
+	case strings.Contains(err.Error(), "invalid") || strings.Contains(err.Error(), "required"):
+		s.sendError(w, http.StatusUnprocessableEntity, err.Error())
+	default:
+		s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to process revisions: %v", err))
+	}
+}
@@ -51,12 +51,39 @@ func (s *Server) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("POST /api/memories", s.auth(s.cors(s.handleCreateMemory)))
 	mux.HandleFunc("GET /api/memories/", s.auth(s.cors(s.handleMemoryDetail)))
 	mux.HandleFunc("PUT /api/memories/", s.auth(s.cors(s.handleUpdateMemory)))
+	mux.HandleFunc("PATCH /api/memories/", s.auth(s.cors(s.handleUpdateMemory)))
 	mux.HandleFunc("DELETE /api/memories/", s.auth(s.cors(s.handleDeleteMemory)))
 	mux.HandleFunc("POST /api/search", s.auth(s.cors(s.handleSearch)))
 	mux.HandleFunc("GET /api/categories", s.auth(s.cors(s.handleCategories)))
+	mux.HandleFunc("POST /api/diagnose", s.auth(s.cors(s.handleDiagnose)))
+	mux.HandleFunc("GET /api/symbols", s.auth(s.cors(s.handleListSymbols)))
+	mux.HandleFunc("POST /api/symbols/import", s.auth(s.cors(s.handleImportSymbols)))
+	mux.HandleFunc("POST /api/libraries/ingest", s.auth(s.cors(s.handleIngestLibrary)))
 	mux.HandleFunc("POST /api/export", s.auth(s.cors(s.handleExport)))
 	mux.HandleFunc("POST /api/import", s.auth(s.cors(s.handleImport)))
+	mux.HandleFunc("POST /api/import/markdown", s.auth(s.cors(s.handleImportMarkdown)))
 	mux.HandleFunc("POST /api/import/confirm", s.auth(s.cors(s.handleImportConfirm)))
+	mux.HandleFunc("GET /api/import/history", s.auth(s.cors(s.handleImportHistory)))
+	mux.HandleFunc("GET /api/import/history/{id}", s.auth(s.cors(s.handleImportSession)))
+	mux.HandleFunc("GET /api/packages", s.auth(s.cors(s.handleListPackages)))
+	mux.HandleFunc("GET /api/packages/{name}", s.auth(s.cors(s.handleGetPackage)))
+	mux.HandleFunc("DELETE /api/packages/{name}", s.auth(s.cors(s.handleUninstallPackage)))
+	mux.HandleFunc("POST /api/packages/install", s.auth(s.cors(s.handleInstallPackage)))
+	mux.HandleFunc("POST /api/packages/upgrade", s.auth(s.cors(s.handleUpgradePackage)))
+	mux.HandleFunc("GET /api/registry", s.auth(s.cors(s.handleRegistryIndex)))
+	mux.HandleFunc("GET /api/memories/{id}/revisions", s.auth(s.cors(s.handleListRevisions)))
+	mux.HandleFunc("GET /api/memories/{id}/revisions/diff", s.auth(s.cors(s.handleDiffRevisions)))
+	mux.HandleFunc("POST /api/memories/{id}/revisions/{revision}/restore", s.auth(s.cors(s.handleRestoreRevision)))
+	mux.HandleFunc("GET /api/trash", s.auth(s.cors(s.handleListTrash)))
+	mux.HandleFunc("DELETE /api/trash", s.auth(s.cors(s.handleEmptyTrash)))
+	mux.HandleFunc("POST /api/trash/{id}/restore", s.auth(s.cors(s.handleRestoreTrash)))
+	mux.HandleFunc("DELETE /api/trash/{id}", s.auth(s.cors(s.handlePurgeTrash)))
+	mux.HandleFunc("POST /api/links", s.auth(s.cors(s.handleCreateLink)))
+	mux.HandleFunc("DELETE /api/links", s.auth(s.cors(s.handleDeleteLink)))
+	mux.HandleFunc("GET /api/memories/{id}/graph", s.auth(s.cors(s.handleMemoryGraph)))
+	mux.HandleFunc("GET /api/suggestions", s.auth(s.cors(s.handleListSuggestions)))
+	mux.HandleFunc("POST /api/suggestions/{id}/approve", s.auth(s.cors(s.handleApproveSuggestion)))
+	mux.HandleFunc("POST /api/suggestions/{id}/reject", s.auth(s.cors(s.handleRejectSuggestion)))
 
 	log.Println("✓ REST API 端点已注册: /api/*")
 	if s.apiUser != "" && s.apiPass != "" {
@@ -124,7 +151,7 @@ func (s *Server) cors(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// 设置 CORS 头
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 		w.Header().Set("Access-Control-Expose-Headers", "WWW-Authenticate")
 
@@ -217,6 +244,19 @@ func parseID(path string) (int64, error) {
 	return id, nil
 }
 
+// parseTags 解析标签查询参数（支持 tags=a,b 和重复的 tags 参数）
+func parseTags(values []string) []string {
+	var tags []string
+	for _, v := range values {
+		for _, tag := range strings.Split(v, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
 // === API 处理器 ===
 
 // handleHealth 健康检查
@@ -235,10 +275,12 @@ func (s *Server) handleListMemories(w http.ResponseWriter, r *http.Request) {
 	req := types.ListRequest{
 		Level:              r.URL.Query().Get("level"),
 		LibraryName:        r.URL.Query().Get("library_name"),
+		LibraryVersion:     r.URL.Query().Get("library_version"),
 		ProjectPathPattern: r.URL.Query().Get("project_path_pattern"),
 		LanguageTag:        r.URL.Query().Get("language_tag"),
 		OrderBy:            r.URL.Query().Get("order_by"),
 		Brief:              r.URL.Query().Get("brief") == "true",
+		Tags:               parseTags(r.URL.Query()["tags"]),
 	}
 
 	// 解析 limit
@@ -267,6 +309,10 @@ func (s *Server) handleListMemories(w http.ResponseWriter, r *http.Request) {
 	// 调用 store
 	resp, err := s.store.ListMemories(req)
 	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid") {
+			s.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list memories: %v", err))
 		return
 	}
@@ -287,6 +333,7 @@ func (s *Server) handleCreateMemory(w http.ResponseWriter, r *http.Request) {
 		s.sendError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
 		return
 	}
+	req.Editor = types.EditorAPI
 
 	// 验证必填字段
 	if req.Level == "" || req.Title == "" || req.Content == "" {
@@ -313,6 +360,10 @@ func (s *Server) handleCreateMemory(w http.ResponseWriter, r *http.Request) {
 	// 存储记忆
 	resp, err := s.store.StoreMemory(req)
 	if err != nil {
+		if strings.Contains(err.Error(), "invalid library_version") {
+			s.sendError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
 		s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to create memory: %v", err))
 		return
 	}
@@ -364,32 +415,40 @@ func (s *Server) handleUpdateMemory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 解析请求体
-	var req types.StoreRequest
+	// 解析请求体（未提供的字段为 nil，保持原值）
+	var req types.UpdateRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		s.sendError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
 		return
 	}
+	req.ID = id
+	req.Editor = types.EditorAPI
 
 	// 验证必填字段（PUT 需要所有字段，PATCH 可以部分更新）
 	if r.Method == http.MethodPut {
-		if req.Level == "" || req.Title == "" || req.Content == "" {
+		if req.Level == nil || *req.Level == "" || req.Title == nil || *req.Title == "" || req.Content == nil || *req.Content == "" {
 			s.sendError(w, http.StatusBadRequest, "Missing required fields: level, title, and content are required")
 			return
 		}
 	}
 
 	// 验证层级（如果提供）
-	if req.Level != "" && !req.Level.IsValid() {
-		s.sendError(w, http.StatusUnprocessableEntity, fmt.Sprintf("Invalid level: %s. Must be one of: language, project, library", req.Level))
+	if req.Level != nil && !req.Level.IsValid() {
+		s.sendError(w, http.StatusUnprocessableEntity, fmt.Sprintf("Invalid level: %s. Must be one of: language, project, library", *req.Level))
 		return
 	}
 
 	// 更新记忆
-	memory, err := s.store.UpdateMemory(id, req)
+	memory, err := s.store.UpdateMemory(req)
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			s.sendError(w, http.StatusNotFound, fmt.Sprintf("Memory not found: id=%d", id))
+		} else if strings.Contains(err.Error(), "no fields to update") {
+			s.sendError(w, http.StatusBadRequest, "No fields to update")
+		} else if strings.Contains(err.Error(), "in trash") {
+			s.sendError(w, http.StatusConflict, err.Error())
+		} else if strings.HasPrefix(err.Error(), "invalid") {
+			s.sendError(w, http.StatusUnprocessableEntity, err.Error())
 		} else {
 			s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to update memory: %v", err))
 		}
@@ -414,7 +473,7 @@ func (s *Server) handleDeleteMemory(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 删除记忆
-	_, err = s.store.DeleteMemory(types.DeleteRequest{ID: id})
+	_, err = s.store.DeleteMemory(types.DeleteRequest{ID: id, Editor: types.EditorAPI})
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			s.sendError(w, http.StatusNotFound, fmt.Sprintf("Memory not found: id=%d", id))
@@ -450,6 +509,11 @@ func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 	// 执行搜索
 	resp, err := s.store.RecallMemories(req)
 	if err != nil {
+		// 查询语法或参数错误
+		if strings.HasPrefix(err.Error(), "invalid") {
+			s.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to search memories: %v", err))
 		return
 	}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ystyle/cangjie-mem/internal/store"
+	"github.com/ystyle/cangjie-mem/pkg/db"
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// getTestServer 获取测试 API 服务器和路由
+func getTestServer(t *testing.T) (*Server, *http.ServeMux) {
+	t.Helper()
+
+	database, err := db.New(db.Config{Path: filepath.Join(t.TempDir(), "api.db")})
+	if err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	server := NewWithStore(store.New(database), nil)
+	server.apiUser, server.apiPass = "", ""
+	mux := http.NewServeMux()
+	server.RegisterRoutes(mux)
+	return server, mux
+}
+
+func TestHandleUpdateMemoryValidation(t *testing.T) {
+	server, mux := getTestServer(t)
+	resp, err := server.store.StoreMemory(types.StoreRequest{Level: types.LevelLanguage, Title: "泛型", Content: "仓颉的泛型"})
+	if err != nil {
+		t.Fatalf("StoreMemory() error = %v", err)
+	}
+	path := "/api/memories/" + strconv.FormatInt(resp.ID, 10)
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"project level without path", `{"level":"project"}`, http.StatusUnprocessableEntity},
+		{"empty title", `{"title":""}`, http.StatusUnprocessableEntity},
+		{"invalid source", `{"source":"unknown"}`, http.StatusUnprocessableEntity},
+		{"valid update", `{"content":"仓颉的泛型约束"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, path, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("PATCH %s %s = %d, want %d: %s", path, tt.body, rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleIngestLibrarySourceRoots(t *testing.T) {
+	server, mux := getTestServer(t)
+	root := t.TempDir()
+	server.store.SetSourceRoots([]string{root})
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"filesystem root", `{"name":"x","path":"/"}`, http.StatusBadRequest},
+		{"outside source roots", `{"name":"x","path":"` + t.TempDir() + `"}`, http.StatusBadRequest},
+		{"inside source root", `{"name":"x","path":"` + root + `"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/libraries/ingest", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("POST /api/libraries/ingest %s = %d, want %d: %s", tt.body, rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleImportSymbolsSourceRoots(t *testing.T) {
+	server, mux := getTestServer(t)
+	root := t.TempDir()
+	server.store.SetSourceRoots([]string{root})
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"filesystem root", `{"path":"/","create_memories":true}`, http.StatusBadRequest},
+		{"outside source roots", `{"path":"` + t.TempDir() + `","create_memories":true}`, http.StatusBadRequest},
+		{"inside source root", `{"path":"` + root + `"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/symbols/import", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("POST /api/symbols/import %s = %d, want %d: %s", tt.body, rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// handleListSuggestions 处理建议列表
+func (s *Server) handleListSuggestions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	req := types.SuggestionListRequest{
+		Status: r.URL.Query().Get("status"),
+	}
+
+	// 解析 limit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 0 {
+			s.sendError(w, http.StatusBadRequest, "Invalid limit parameter")
+			return
+		}
+		if limit > 100 {
+			limit = 100 // 最大限制
+		}
+		req.Limit = limit
+	}
+
+	// 解析 offset
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			s.sendError(w, http.StatusBadRequest, "Invalid offset parameter")
+			return
+		}
+		req.Offset = offset
+	}
+
+	resp, err := s.store.ListSuggestions(req)
+	if err != nil {
+		if strings.Contains(err.Error(), "invalid status") {
+			s.sendError(w, http.StatusBadRequest, err.Error())
+		} else {
+			s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list suggestions: %v", err))
+		}
+		return
+	}
+
+	s.sendJSON(w, http.StatusOK, resp)
+}
+
+// handleApproveSuggestion 处理批准建议（转为正式记忆）
+func (s *Server) handleApproveSuggestion(w http.ResponseWriter, r *http.Request) {
+	req, ok := s.parseReviewRequest(w, r)
+	if !ok {
+		return
+	}
+
+	suggestion, err := s.store.ApproveSuggestion(req)
+	if err != nil {
+		s.sendReviewError(w, req.ID, err)
+		return
+	}
+
+	s.sendJSON(w, http.StatusOK, suggestion)
+}
+
+// handleRejectSuggestion 处理拒绝建议
+func (s *Server) handleRejectSuggestion(w http.ResponseWriter, r *http.Request) {
+	req, ok := s.parseReviewRequest(w, r)
+	if !ok {
+		return
+	}
+
+	suggestion, err := s.store.RejectSuggestion(req)
+	if err != nil {
+		s.sendReviewError(w, req.ID, err)
+		return
+	}
+
+	s.sendJSON(w, http.StatusOK, suggestion)
+}
+
+// parseReviewRequest 解析审核请求（请求体可选）
+func (s *Server) parseReviewRequest(w http.ResponseWriter, r *http.Request) (types.SuggestionReviewRequest, bool) {
+	var req types.SuggestionReviewRequest
+
+	if r.Method != http.MethodPost {
+		s.sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return req, false
+	}
+
+	// 解析 ID
+	id, err := parseID(r.URL.Path)
+	if err != nil {
+		s.sendError(w, http.StatusBadRequest, fmt.Sprintf("Invalid ID: %v", err))
+		return req, false
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		s.sendError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return req, false
+	}
+	req.ID = id
+	req.Editor = types.EditorAPI
+
+	return req, true
+}
+
+// sendReviewError 发送审核错误响应
+func (s *Server) sendReviewError(w http.ResponseWriter, id int64, err error) {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		s.sendError(w, http.StatusNotFound, fmt.Sprintf("Suggestion not found: id=%d", id))
+	case strings.Contains(err.Error(), "already reviewed"):
+		s.sendError(w, http.StatusConflict, err.Error())
+	case strings.Contains(err.Error(), "required") || strings.Contains(err.Error(), "invalid"):
+		s.sendError(w, http.StatusUnprocessableEntity, err.Error())
+	default:
+		s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to review suggestion: %v", err))
+	}
+}
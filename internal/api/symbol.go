@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// handleListSymbols 处理符号查询（?name=ArrayList.append&prefix=true&kind=func&library_name=tang&limit=20）
+func (s *Server) handleListSymbols(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	req := types.SymbolRequest{
+		Name:        query.Get("name"),
+		Prefix:      query.Get("prefix") == "true",
+		Kind:        query.Get("kind"),
+		LibraryName: query.Get("library_name"),
+		LanguageTag: query.Get("language_tag"),
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			s.sendError(w, http.StatusBadRequest, "Invalid limit parameter")
+			return
+		}
+		req.Limit = n
+	}
+
+	resp, err := s.store.LookupSymbols(req)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid") {
+			s.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to lookup symbols: %v", err))
+		return
+	}
+
+	s.sendJSON(w, http.StatusOK, resp)
+}
+
+// handleImportSymbols 处理从服务器本地的 .cj 源码目录导入符号
+func (s *Server) handleImportSymbols(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// 解析请求体
+	var req types.SymbolImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.sendError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	// 只允许导入服务端配置的源码根目录下的路径
+	path, err := s.store.ResolveSourcePath(req.Path)
+	if err != nil {
+		s.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	req.Path = path
+
+	result, err := s.store.ImportSymbols(req)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid") {
+			s.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to import symbols: %v", err))
+		return
+	}
+
+	s.sendJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// handleListTrash 处理回收站列表
+func (s *Server) handleListTrash(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	req := types.TrashListRequest{
+		LanguageTag: r.URL.Query().Get("language_tag"),
+	}
+
+	// 解析 limit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 0 {
+			s.sendError(w, http.StatusBadRequest, "Invalid limit parameter")
+			return
+		}
+		if limit > 100 {
+			limit = 100 // 最大限制
+		}
+		req.Limit = limit
+	}
+
+	// 解析 offset
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			s.sendError(w, http.StatusBadRequest, "Invalid offset parameter")
+			return
+		}
+		req.Offset = offset
+	}
+
+	resp, err := s.store.ListTrash(req)
+	if err != nil {
+		s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list trash: %v", err))
+		return
+	}
+
+	s.sendJSON(w, http.StatusOK, resp)
+}
+
+// handleRestoreTrash 处理从回收站恢复记忆
+func (s *Server) handleRestoreTrash(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// 解析 ID
+	id, err := parseID(r.URL.Path)
+	if err != nil {
+		s.sendError(w, http.StatusBadRequest, fmt.Sprintf("Invalid ID: %v", err))
+		return
+	}
+
+	memory, err := s.store.RestoreMemory(id, types.EditorAPI)
+	if err != nil {
+		s.sendTrashError(w, err)
+		return
+	}
+
+	s.sendJSON(w, http.StatusOK, memory)
+}
+
+// handlePurgeTrash 处理彻底删除回收站中的单条记忆
+func (s *Server) handlePurgeTrash(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		s.sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// 解析 ID
+	id, err := parseID(r.URL.Path)
+	if err != nil {
+		s.sendError(w, http.StatusBadRequest, fmt.Sprintf("Invalid ID: %v", err))
+		return
+	}
+
+	if err := s.store.PurgeMemory(id); err != nil {
+		s.sendTrashError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleEmptyTrash 处理清空回收站
+func (s *Server) handleEmptyTrash(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		s.sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	resp, err := s.store.EmptyTrash()
+	if err != nil {
+		s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to empty trash: %v", err))
+		return
+	}
+
+	s.sendJSON(w, http.StatusOK, resp)
+}
+
+// sendTrashError 发送回收站操作错误响应
+func (s *Server) sendTrashError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.Contains(err.Error(), "not in trash"):
+		s.sendError(w, http.StatusConflict, err.Error())
+	case strings.Contains(err.Error(), "not found"):
+		s.sendError(w, http.StatusNotFound, err.Error())
+	case strings.Contains(err.Error(), "invalid"):
+		s.sendError(w, http.StatusBadRequest, err.Error())
+	default:
+		s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to process trash: %v", err))
+	}
+}
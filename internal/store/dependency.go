@@ -0,0 +1,42 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ystyle/cangjie-mem/pkg/cjpm"
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// SetProjectDependencies 设置是否读取项目 cjpm.toml 的依赖来限定库级检索范围
+// 默认关闭：project_context 由客户端传入，启用后服务器会读取客户端指定路径下的文件
+func (s *Store) SetProjectDependencies(enabled bool) {
+	s.projectDeps = enabled
+}
+
+// projectDependencies 读取项目目录下 cjpm.toml 的依赖名
+// 未启用、文件不可访问（如远程服务器上不存在该路径）或无法解析时返回 nil，不影响检索
+func (s *Store) projectDependencies(projectPath string) []string {
+	if !s.projectDeps || projectPath == "" {
+		return nil
+	}
+	manifest, err := cjpm.Load(projectPath)
+	if err != nil {
+		return nil
+	}
+	return manifest.DependencyNames()
+}
+
+// dependencyScope 确定检索时按项目依赖限定的库名：
+// 指定了库名或只检索语言级、项目级时不限定
+func (s *Store) dependencyScope(req types.RecallRequest, level types.KnowledgeLevel) []string {
+	if req.LibraryName != "" || (level != "" && level != types.LevelLibrary) {
+		return nil
+	}
+	return s.projectDependencies(req.ProjectContext)
+}
+
+// dependencyStrategy 检索策略中的依赖说明（如 _cjpm_deps(tang,json)）
+func dependencyStrategy(deps []string) string {
+	return fmt.Sprintf("_cjpm_deps(%s)", strings.Join(deps, ","))
+}
@@ -0,0 +1,194 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/ystyle/cangjie-mem/pkg/db"
+	"github.com/ystyle/cangjie-mem/pkg/diagnostic"
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// 每条诊断返回的修复记忆数
+const (
+	defaultDiagnosticFixes = 3
+	maxDiagnosticFixes     = 10
+)
+
+// Diagnose 解析编译输出，为每条诊断查找修复记忆
+// 依次尝试：签名完全一致 → 错误码一致 → 在 diagnostic 标签的记忆中全文检索
+func (s *Store) Diagnose(req types.DiagnoseRequest) (*types.DiagnoseResponse, error) {
+	if strings.TrimSpace(req.Output) == "" {
+		return nil, fmt.Errorf("invalid output: build output cannot be empty")
+	}
+	if req.LanguageTag == "" {
+		req.LanguageTag = "cangjie"
+	}
+	if req.MaxFixes <= 0 {
+		req.MaxFixes = defaultDiagnosticFixes
+	}
+	if req.MaxFixes > maxDiagnosticFixes {
+		req.MaxFixes = maxDiagnosticFixes
+	}
+
+	opts := db.RecallOptions{
+		LanguageTag: req.LanguageTag,
+		ProjectPath: req.ProjectContext,
+		Libraries:   s.projectDependencies(req.ProjectContext),
+		Limit:       req.MaxFixes,
+	}
+
+	resp := &types.DiagnoseResponse{Results: []types.DiagnosticResult{}}
+	cache := map[string][]types.DiagnosticFix{}
+	for _, d := range diagnostic.Parse(req.Output) {
+		if d.Severity != diagnostic.SeverityError && !req.IncludeWarnings {
+			continue
+		}
+
+		fixes, ok := cache[d.Signature]
+		if !ok {
+			var err error
+			fixes, err = s.diagnosticFixes(d, opts)
+			if err != nil {
+				return nil, err
+			}
+			cache[d.Signature] = fixes
+		}
+
+		resp.Results = append(resp.Results, types.DiagnosticResult{
+			Severity:  d.Severity,
+			Code:      d.Code,
+			Message:   d.Message,
+			Signature: d.Signature,
+			File:      d.File,
+			Line:      d.Line,
+			Column:    d.Column,
+			Fixes:     fixes,
+		})
+		if len(fixes) > 0 {
+			resp.Matched++
+		}
+	}
+	resp.Total = len(resp.Results)
+
+	return resp, nil
+}
+
+// diagnosticFixes 查找单条诊断的修复记忆
+func (s *Store) diagnosticFixes(d diagnostic.Diagnostic, opts db.RecallOptions) ([]types.DiagnosticFix, error) {
+	results, err := s.db.FindDiagnosticFixes(d.Signature, false, opts)
+	if err != nil {
+		return nil, err
+	}
+	match := types.DiagnosticMatchSignature
+
+	if len(results) == 0 && d.Code != "" {
+		results, err = s.db.FindDiagnosticFixes(diagnostic.CodePrefix(d.Code), true, opts)
+		if err != nil {
+			return nil, err
+		}
+		match = types.DiagnosticMatchCode
+	}
+
+	if len(results) == 0 {
+		results, err = s.searchDiagnostics(d.Signature, opts)
+		if err != nil {
+			return nil, err
+		}
+		match = types.DiagnosticMatchText
+	}
+
+	ids := make([]int64, len(results))
+	for i, r := range results {
+		ids[i] = r.ID
+	}
+	signatures, err := s.db.DiagnosticSignatures(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	fixes := make([]types.DiagnosticFix, 0, len(results))
+	for _, r := range results {
+		fixes = append(fixes, types.DiagnosticFix{
+			ID:                  r.ID,
+			Level:               r.Level,
+			Title:               r.Title,
+			Content:             r.Content,
+			Summary:             r.Summary,
+			LibraryName:         r.LibraryName,
+			DiagnosticSignature: signatures[r.ID],
+			Match:               match,
+		})
+		_ = s.db.UpdateAccessCount(r.ID)
+	}
+	return fixes, nil
+}
+
+// searchDiagnostics 用签名中的关键词在 diagnostic 标签的记忆中全文检索（没有结果时逐步放宽）
+func (s *Store) searchDiagnostics(signature string, opts db.RecallOptions) ([]types.RecallResult, error) {
+	keywords := diagnostic.Keywords(signature)
+	if len(keywords) == 0 {
+		return nil, nil
+	}
+	parsed, err := parseQuery(strings.Join(keywords, " "))
+	if err != nil {
+		return nil, nil
+	}
+
+	opts.Tags = []string{types.TagDiagnostic}
+	queries := []*parsedQuery{parsed}
+	for _, relaxed := range parsed.Relaxations() {
+		queries = append(queries, relaxed.Query)
+	}
+	for _, q := range queries {
+		results, err := s.db.Recall(q.MatchExpression(), opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search diagnostics: %w", err)
+		}
+		if len(results) > 0 {
+			return results, nil
+		}
+	}
+	return nil, nil
+}
+
+// prepareDiagnosticStore 规范化存储请求中的诊断签名，并添加 diagnostic 标签
+func prepareDiagnosticStore(req *types.StoreRequest) {
+	if req.DiagnosticSignature == "" {
+		return
+	}
+	req.DiagnosticSignature = diagnostic.ParseSignature(req.DiagnosticSignature)
+	if req.DiagnosticSignature != "" {
+		req.Tags = append(req.Tags, types.TagDiagnostic)
+	}
+}
+
+// prepareDiagnosticUpdate 规范化更新请求中的诊断签名，设置签名时保留原有标签并添加 diagnostic 标签
+func (s *Store) prepareDiagnosticUpdate(req *types.UpdateRequest) error {
+	if req.DiagnosticSignature == nil || *req.DiagnosticSignature == "" {
+		return nil
+	}
+	signature := diagnostic.ParseSignature(*req.DiagnosticSignature)
+	req.DiagnosticSignature = &signature
+	if signature == "" {
+		return nil
+	}
+
+	var tags []string
+	if req.Tags != nil {
+		tags = *req.Tags
+	} else {
+		existing, err := s.db.GetByID(req.ID)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("memory not found: id=%d", req.ID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get memory: %w", err)
+		}
+		tags = existing.Tags
+	}
+	tags = append(append([]string{}, tags...), types.TagDiagnostic)
+	req.Tags = &tags
+	return nil
+}
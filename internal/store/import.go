@@ -0,0 +1,112 @@
+package store
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// 导入会话有效期（超时未确认的预览不能再导入）
+const importSessionTTL = 30 * time.Minute
+
+// 导入历史分页
+const (
+	defaultImportHistoryLimit = 20
+	maxImportHistoryLimit     = 100
+)
+
+// CreateImportSession 预览知识包并保存为导入会话，返回的预览 ID 用于确认导入；
+// 知识包带签名时验证签名（签名无效时拒绝），预览中返回签名者
+func (s *Store) CreateImportSession(pkg types.KnowledgePackage, source, uploader string) (*types.ImportPreview, error) {
+	var signer *types.PackageSigner
+	if source == types.ImportSourcePackage {
+		var err error
+		if signer, err = s.verifySignature(&pkg); err != nil {
+			return nil, err
+		}
+	}
+
+	preview, err := s.PreviewImport(pkg.Memories)
+	if err != nil {
+		return nil, err
+	}
+	preview.Signer = signer
+
+	now := time.Now()
+	session := types.ImportSession{
+		ID:            uuid.New().String(),
+		Source:        source,
+		Uploader:      uploader,
+		FormatVersion: pkg.Version,
+		Package:       pkg.Package,
+		CreatedAt:     now,
+		ExpiresAt:     now.Add(importSessionTTL),
+	}
+	if err := s.db.CreateImportSession(session, pkg.Memories); err != nil {
+		return nil, err
+	}
+	preview.ImportID = session.ID
+	return preview, nil
+}
+
+// ConfirmImport 确认导入会话中的记忆（同一会话只能导入一次，试运行不受限制）
+func (s *Store) ConfirmImport(req types.ImportConfirmRequest) (*types.ImportResult, error) {
+	if req.ImportID == "" {
+		return nil, fmt.Errorf("invalid import_id: import_id cannot be empty")
+	}
+	session, err := s.db.GetImportSession(req.ImportID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateImportOptions(req.ImportOptions, session.Total); err != nil {
+		return nil, err
+	}
+
+	result, err := s.db.ConfirmImport(req.ImportID, req.ImportOptions)
+	if err == nil && !result.RolledBack {
+		s.syncEmbeddings(importedIDs(result)...)
+	}
+	return result, err
+}
+
+// GetImportSession 获取导入会话（包含逐条导入结果）
+func (s *Store) GetImportSession(id string) (*types.ImportSession, error) {
+	return s.db.GetImportSession(id)
+}
+
+// ImportHistory 列出导入历史
+func (s *Store) ImportHistory(req types.ImportHistoryRequest) (*types.ImportHistoryResponse, error) {
+	switch req.Status {
+	case "", types.ImportSessionPending, types.ImportSessionCompleted, types.ImportSessionFailed, types.ImportSessionExpired:
+	default:
+		return nil, fmt.Errorf("invalid status: %s", req.Status)
+	}
+	if req.Limit <= 0 {
+		req.Limit = defaultImportHistoryLimit
+	}
+	if req.Limit > maxImportHistoryLimit {
+		req.Limit = maxImportHistoryLimit
+	}
+	if req.Offset < 0 {
+		req.Offset = 0
+	}
+	return s.db.ListImportSessions(req)
+}
+
+// validateImportOptions 校验导入选项（冲突处理策略和序号）
+func validateImportOptions(opts types.ImportOptions, total int) error {
+	if !opts.Strategy.IsValid() {
+		return fmt.Errorf("invalid strategy: %s", opts.Strategy)
+	}
+	for _, r := range opts.Resolutions {
+		if !r.Strategy.IsValid() {
+			return fmt.Errorf("invalid strategy: %s", r.Strategy)
+		}
+		if r.Index < 0 || r.Index >= total {
+			return fmt.Errorf("invalid resolution: index %d out of range", r.Index)
+		}
+	}
+	return nil
+}
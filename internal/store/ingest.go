@@ -0,0 +1,265 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/ystyle/cangjie-mem/pkg/markdown"
+	"github.com/ystyle/cangjie-mem/pkg/semver"
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// 库文档目录
+const docsDir = "docs"
+
+// 导入章节生成的摘要最大长度（字符）
+const maxSummaryRunes = 200
+
+// upsertOutcome 写入记忆的结果
+type upsertOutcome int
+
+const (
+	upsertAdded upsertOutcome = iota
+	upsertUpdated
+	upsertUnchanged
+)
+
+// IngestLibrary 导入库源码目录：README 和 docs/ 下的 Markdown 按标题切分，.cj 源文件按顶层声明切分，
+// 每个章节/声明生成一条库级记忆并记录出处（文件路径、标题），重复导入时按出处更新原记忆，
+// 上游已删除或改名的章节、声明对应的记忆移入回收站；整个导入在一个事务中完成，任一文件失败时不写入任何记忆
+func (s *Store) IngestLibrary(req types.IngestRequest) (*types.IngestResult, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return nil, fmt.Errorf("invalid name: library name cannot be empty")
+	}
+	if strings.TrimSpace(req.Path) == "" {
+		return nil, fmt.Errorf("invalid path: library path cannot be empty")
+	}
+	if req.LanguageTag == "" {
+		req.LanguageTag = "cangjie"
+	}
+	if req.LibraryVersion != "" {
+		if _, err := semver.ParseRange(req.LibraryVersion); err != nil {
+			return nil, fmt.Errorf("invalid library_version: %w", err)
+		}
+	}
+	info, err := os.Stat(req.Path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("invalid path: %s is not a directory", req.Path)
+	}
+
+	docs, err := libraryDocs(req.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read library docs: %w", err)
+	}
+
+	result := &types.IngestResult{Library: req.Name, Files: len(docs)}
+	var written []int64 // 新增或更新的记忆，导入后生成向量
+	err = s.inTx(func(tx *Store) error {
+		seen := map[int64]bool{} // 本次导入写入或确认的记忆
+		record := func(id int64, outcome upsertOutcome) {
+			countOutcome(result, outcome)
+			seen[id] = true
+			if outcome != upsertUnchanged {
+				written = append(written, id)
+			}
+		}
+
+		for _, rel := range docs {
+			data, err := os.ReadFile(filepath.Join(req.Path, filepath.FromSlash(rel)))
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", rel, err)
+			}
+			titles := map[string]int{}
+			for _, section := range markdown.Split(string(data), req.HeadingDepth) {
+				// 同一文件中标题路径相同的章节加序号区分，否则会共用一个出处
+				title := section.Title()
+				titles[title]++
+				if n := titles[title]; n > 1 {
+					title = fmt.Sprintf("%s (%d)", title, n)
+				}
+				memory := sectionMemory(req, rel, title, section)
+				provenance := types.Provenance{File: rel, Section: title}
+				id, err := tx.db.FindByProvenance(req.LanguageTag, req.Name, provenance)
+				if err != nil {
+					return err
+				}
+				id, outcome, err := tx.upsertMemory(id, memory, provenance)
+				if err != nil {
+					return err
+				}
+				record(id, outcome)
+				result.Sections++
+			}
+		}
+
+		symbols, err := tx.importSymbols(types.SymbolImportRequest{
+			Path:           req.Path,
+			LibraryName:    req.Name,
+			LibraryVersion: req.LibraryVersion,
+			LanguageTag:    req.LanguageTag,
+			CreateMemories: true,
+		}, record)
+		if err != nil {
+			return err
+		}
+		result.Files += symbols.Files
+		result.Symbols = symbols.Symbols
+
+		// 上游已删除或改名的章节、声明：出处属于该库但本次没有写入的记忆移入回收站
+		ids, err := tx.db.ProvenanceIDs(req.LanguageTag, req.Name)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if seen[id] {
+				continue
+			}
+			if err := tx.db.Delete(id, types.EditorImport); err != nil {
+				return err
+			}
+			result.Removed++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.syncEmbeddings(written...)
+	return result, nil
+}
+
+// libraryDocs 列出库根目录下的 README*.md 和 docs/ 下的 Markdown 文件（相对路径，按路径排序）
+func libraryDocs(root string) ([]string, error) {
+	var docs []string
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && isMarkdown(name) && strings.HasPrefix(strings.ToLower(name), "readme") {
+			docs = append(docs, name)
+		}
+	}
+
+	dir := filepath.Join(root, docsDir)
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				if path != dir && strings.HasPrefix(entry.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !isMarkdown(entry.Name()) {
+				return nil
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			docs = append(docs, filepath.ToSlash(rel))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return docs, nil
+}
+
+// isMarkdown 判断是否为 Markdown 文件
+func isMarkdown(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	return ext == ".md" || ext == ".markdown"
+}
+
+// sectionMemory 由 Markdown 章节生成库级记忆：标题为文件名加标题路径，摘要为第一段
+func sectionMemory(req types.IngestRequest, file, sectionTitle string, section markdown.Section) types.StoreRequest {
+	title := file
+	if sectionTitle != "" {
+		title = file + ": " + sectionTitle
+	}
+	return types.StoreRequest{
+		Level:          types.LevelLibrary,
+		LanguageTag:    req.LanguageTag,
+		LibraryName:    req.Name,
+		LibraryVersion: req.LibraryVersion,
+		Title:          title,
+		Content:        section.Body,
+		Summary:        sectionSummary(section),
+		Source:         types.SourceManual,
+		Editor:         types.EditorImport,
+	}
+}
+
+// upsertMemory 写入导入生成的记忆并记录出处：id 为原记忆时更新（内容没变则跳过），
+// 原记忆不存在或在回收站中时新建
+func (s *Store) upsertMemory(id int64, memory types.StoreRequest, provenance types.Provenance) (int64, upsertOutcome, error) {
+	if id > 0 {
+		current, err := s.db.GetByID(id)
+		if err != nil && err != sql.ErrNoRows {
+			return 0, 0, fmt.Errorf("failed to get memory: %w", err)
+		}
+		if err == nil && current.DeletedAt == nil {
+			if sameMemory(current, memory) {
+				return id, upsertUnchanged, s.db.SetProvenance(id, memory.LanguageTag, memory.LibraryName, provenance)
+			}
+			if _, err := s.db.Patch(types.NewUpdateRequest(id, memory)); err != nil {
+				return 0, 0, fmt.Errorf("failed to update memory %q: %w", memory.Title, err)
+			}
+			return id, upsertUpdated, s.db.SetProvenance(id, memory.LanguageTag, memory.LibraryName, provenance)
+		}
+	}
+
+	resp, err := s.db.Store(memory)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to store memory %q: %w", memory.Title, err)
+	}
+	return resp.ID, upsertAdded, s.db.SetProvenance(resp.ID, memory.LanguageTag, memory.LibraryName, provenance)
+}
+
+// sameMemory 判断导入生成的记忆与现有记忆是否一致（标签为空时不比较）
+func sameMemory(current *types.Memory, memory types.StoreRequest) bool {
+	if current.Level != memory.Level || current.LibraryName != memory.LibraryName ||
+		current.LibraryVersion != memory.LibraryVersion || current.Title != memory.Title ||
+		current.Content != memory.Content || current.Summary != memory.Summary {
+		return false
+	}
+	return memory.Tags == nil || reflect.DeepEqual(current.Tags, memory.Tags)
+}
+
+// countOutcome 累计导入结果
+func countOutcome(result *types.IngestResult, outcome upsertOutcome) {
+	switch outcome {
+	case upsertAdded:
+		result.Added++
+	case upsertUpdated:
+		result.Updated++
+	default:
+		result.Unchanged++
+	}
+}
+
+// truncateRunes 按字符截断文本，超出时以省略号结尾
+func truncateRunes(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max-1]) + "…"
+}
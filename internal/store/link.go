@@ -0,0 +1,196 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// 关系图遍历深度
+const (
+	defaultGraphDepth = 1
+	maxGraphDepth     = 3
+)
+
+// LinkMemories 建立记忆关系
+func (s *Store) LinkMemories(req types.LinkRequest) (*types.Link, error) {
+	if err := validateLinkEnds(req); err != nil {
+		return nil, err
+	}
+	if !req.Relation.IsValid() {
+		return nil, fmt.Errorf("invalid relation: %s (must be one of: see_also, depends_on, supersedes, contradicts)", req.Relation)
+	}
+	return s.db.Link(req)
+}
+
+// UnlinkMemories 删除记忆关系
+func (s *Store) UnlinkMemories(req types.LinkRequest) (*types.UnlinkResponse, error) {
+	if err := validateLinkEnds(req); err != nil {
+		return nil, err
+	}
+	if req.Relation != "" && !req.Relation.IsValid() {
+		return nil, fmt.Errorf("invalid relation: %s", req.Relation)
+	}
+	removed, err := s.db.Unlink(req)
+	if err != nil {
+		return nil, err
+	}
+	return &types.UnlinkResponse{Removed: removed}, nil
+}
+
+// validateLinkEnds 验证关系两端
+func validateLinkEnds(req types.LinkRequest) error {
+	if req.SourceID <= 0 {
+		return fmt.Errorf("invalid source_id: %d", req.SourceID)
+	}
+	if req.TargetID <= 0 {
+		return fmt.Errorf("invalid target_id: %d", req.TargetID)
+	}
+	if req.SourceID == req.TargetID {
+		return fmt.Errorf("invalid link: a memory cannot link to itself")
+	}
+	return nil
+}
+
+// MemoryGraph 从指定记忆出发按关系遍历（广度优先）
+func (s *Store) MemoryGraph(req types.GraphRequest) (*types.GraphResponse, error) {
+	if req.ID <= 0 {
+		return nil, fmt.Errorf("invalid id: %d", req.ID)
+	}
+	if req.Depth <= 0 {
+		req.Depth = defaultGraphDepth
+	}
+	if req.Depth > maxGraphDepth {
+		req.Depth = maxGraphDepth
+	}
+	if req.Direction == "" {
+		req.Direction = types.LinkDirectionBoth
+	}
+	if !validDirection(req.Direction) {
+		return nil, fmt.Errorf("invalid direction: %s (must be one of: outgoing, incoming, both)", req.Direction)
+	}
+	if req.Relation != "" && !req.Relation.IsValid() {
+		return nil, fmt.Errorf("invalid relation: %s", req.Relation)
+	}
+
+	root, err := s.db.GraphNodes([]int64{req.ID})
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := root[req.ID]; !ok {
+		return nil, fmt.Errorf("memory not found: id=%d", req.ID)
+	}
+
+	depths := map[int64]int{req.ID: 0}
+	order := []int64{req.ID}
+	seenLinks := map[int64]bool{}
+	resp := &types.GraphResponse{Root: req.ID, Links: []types.Link{}}
+
+	frontier := []int64{req.ID}
+	for depth := 1; depth <= req.Depth && len(frontier) > 0; depth++ {
+		links, err := s.db.Neighbors(frontier, req.Relation)
+		if err != nil {
+			return nil, err
+		}
+
+		inFrontier := make(map[int64]bool, len(frontier))
+		for _, id := range frontier {
+			inFrontier[id] = true
+		}
+
+		var next []int64
+		for _, l := range links {
+			var other int64
+			switch {
+			case inFrontier[l.SourceID] && req.Direction != types.LinkDirectionIncoming:
+				other = l.TargetID
+			case inFrontier[l.TargetID] && req.Direction != types.LinkDirectionOutgoing:
+				other = l.SourceID
+			default:
+				continue
+			}
+
+			if !seenLinks[l.ID] {
+				seenLinks[l.ID] = true
+				resp.Links = append(resp.Links, l)
+			}
+			if _, ok := depths[other]; !ok {
+				depths[other] = depth
+				order = append(order, other)
+				next = append(next, other)
+			}
+		}
+		frontier = next
+	}
+
+	nodes, err := s.db.GraphNodes(order)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range order {
+		node := nodes[id]
+		node.Depth = depths[id]
+		resp.Nodes = append(resp.Nodes, node)
+	}
+
+	return resp, nil
+}
+
+// validDirection 验证遍历方向
+func validDirection(direction string) bool {
+	switch direction {
+	case types.LinkDirectionOutgoing, types.LinkDirectionIncoming, types.LinkDirectionBoth:
+		return true
+	default:
+		return false
+	}
+}
+
+// attachRelated 为检索结果附带一跳关联记忆
+func (s *Store) attachRelated(results []types.RecallResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(results))
+	index := make(map[int64]int, len(results))
+	for i, r := range results {
+		ids[i] = r.ID
+		index[r.ID] = i
+	}
+
+	links, err := s.db.Neighbors(ids, "")
+	if err != nil {
+		return err
+	}
+
+	var otherIDs []int64
+	for _, l := range links {
+		otherIDs = append(otherIDs, l.SourceID, l.TargetID)
+	}
+	nodes, err := s.db.GraphNodes(otherIDs)
+	if err != nil {
+		return err
+	}
+
+	add := func(resultID, otherID int64, l types.Link, direction string) {
+		i, ok := index[resultID]
+		node, found := nodes[otherID]
+		if !ok || !found {
+			return
+		}
+		results[i].Related = append(results[i].Related, types.RelatedMemory{
+			ID:        node.ID,
+			Level:     node.Level,
+			Title:     node.Title,
+			Summary:   node.Summary,
+			Relation:  l.Relation,
+			Direction: direction,
+		})
+	}
+	for _, l := range links {
+		add(l.SourceID, l.TargetID, l, types.LinkDirectionOutgoing)
+		add(l.TargetID, l.SourceID, l, types.LinkDirectionIncoming)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ystyle/cangjie-mem/pkg/markdown"
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// SplitMarkdown 把 Markdown 文档按标题切分为待导入的记忆（结果是确定的，同一文档总是得到相同的记忆）
+// 每个章节一条记忆：标题为标题路径（如 "泛型 > 泛型约束"），摘要为第一段，正文保留代码块和更深级别的标题；
+// 第一个标题之前的内容只在指定了文档标题时导入
+func (s *Store) SplitMarkdown(req types.MarkdownImportRequest) ([]types.StoreRequest, error) {
+	if strings.TrimSpace(req.Content) == "" {
+		return nil, fmt.Errorf("invalid content: markdown content cannot be empty")
+	}
+	if req.Level == "" {
+		req.Level = types.LevelLanguage
+	}
+	if !req.Level.IsValid() {
+		return nil, fmt.Errorf("invalid level: %s", req.Level)
+	}
+	if req.Level == types.LevelLibrary && req.LibraryName == "" {
+		return nil, fmt.Errorf("invalid library_name: library_name is required for library level")
+	}
+	if req.Level == types.LevelProject && req.ProjectPathPattern == "" {
+		return nil, fmt.Errorf("invalid project_path_pattern: project_path_pattern is required for project level")
+	}
+	if req.HeadingDepth < 0 || req.HeadingDepth > markdown.MaxDepth {
+		return nil, fmt.Errorf("invalid heading_depth: %d (expected 1-%d)", req.HeadingDepth, markdown.MaxDepth)
+	}
+	if req.LanguageTag == "" {
+		req.LanguageTag = "cangjie"
+	}
+
+	var memories []types.StoreRequest
+	seen := map[string]int{}
+	for _, section := range markdown.Split(req.Content, req.HeadingDepth) {
+		title := section.Title()
+		if req.Title != "" {
+			title = strings.Join(append([]string{req.Title}, section.Headings...), " > ")
+		}
+		if title == "" {
+			continue
+		}
+		// 同一文档中标题路径相同的章节加序号区分
+		seen[title]++
+		if n := seen[title]; n > 1 {
+			title = fmt.Sprintf("%s (%d)", title, n)
+		}
+
+		memories = append(memories, types.StoreRequest{
+			Level:              req.Level,
+			LanguageTag:        req.LanguageTag,
+			LibraryName:        req.LibraryName,
+			LibraryVersion:     req.LibraryVersion,
+			ProjectPathPattern: req.ProjectPathPattern,
+			Title:              title,
+			Content:            section.Body,
+			Summary:            sectionSummary(section),
+			Source:             types.SourceManual,
+			Tags:               req.Tags,
+		})
+	}
+	if len(memories) == 0 {
+		return nil, fmt.Errorf("invalid content: no sections found")
+	}
+	return memories, nil
+}
+
+// sectionSummary 章节摘要：正文第一段（过长时截断）
+func sectionSummary(section markdown.Section) string {
+	return truncateRunes(section.FirstParagraph(), maxSummaryRunes)
+}
@@ -0,0 +1,168 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ystyle/cangjie-mem/pkg/registry"
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// SetRegistries 设置服务端配置的知识包仓库（目录或 URL）
+// REST API 不接受客户端指定的仓库，未指定仓库的安装、升级和仓库索引查询只使用这些仓库
+func (s *Store) SetRegistries(sources []string) {
+	s.registries = nil
+	for _, source := range sources {
+		if source = strings.TrimSpace(source); source != "" {
+			s.registries = append(s.registries, source)
+		}
+	}
+}
+
+// InstallPackage 从仓库安装知识包（包已安装时返回错误，请使用 UpgradePackage），
+// 未指定仓库时从服务端配置的仓库中查找
+func (s *Store) InstallPackage(req types.PackageInstallRequest) (*types.PackageInstallResult, error) {
+	return s.installPackage(req, false)
+}
+
+// UpgradePackage 从仓库升级已安装的知识包（未指定仓库时从服务端配置的仓库中查找，优先使用安装来源），
+// 结果中包含与已安装版本的差异；试运行时只返回差异和导入结果
+func (s *Store) UpgradePackage(req types.PackageInstallRequest) (*types.PackageInstallResult, error) {
+	return s.installPackage(req, true)
+}
+
+// installPackage 下载并校验知识包（格式版本、校验和、签名），然后安装或升级
+func (s *Store) installPackage(req types.PackageInstallRequest, upgrade bool) (*types.PackageInstallResult, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return nil, fmt.Errorf("invalid name: package name cannot be empty")
+	}
+	if req.Registry == "" {
+		preferred := ""
+		if upgrade {
+			installed, err := s.db.GetPackage(req.Name)
+			if err != nil {
+				return nil, err
+			}
+			preferred = installed.Registry
+		}
+		source, err := s.findRegistry(req.Name, req.Version, preferred)
+		if err != nil {
+			return nil, err
+		}
+		req.Registry = source
+	}
+	reg, err := registry.Open(req.Registry)
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := reg.Fetch(req.Name, req.Version)
+	if err != nil {
+		return nil, err
+	}
+	if err := types.NegotiatePackage(pkg); err != nil {
+		return nil, fmt.Errorf("invalid package format: %w", err)
+	}
+	signer, err := s.verifySignature(pkg)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateImportOptions(req.ImportOptions, len(pkg.Memories)); err != nil {
+		return nil, err
+	}
+
+	signerName := ""
+	if signer != nil {
+		signerName = signer.Signer
+		if signer.Trusted {
+			signerName = signer.Name
+		}
+	}
+	result, err := s.db.InstallPackage(*pkg, reg.Source(), signerName, req.ImportOptions, upgrade)
+	if err != nil {
+		return nil, err
+	}
+	result.Signer = signer
+	if !result.Import.RolledBack {
+		s.syncEmbeddings(append(importedIDs(result.Import), result.RestoredIDs...)...)
+	}
+	return result, nil
+}
+
+// UninstallPackage 卸载知识包：包新增的记忆移入回收站，包覆盖过的已有记忆恢复为覆盖前的版本
+func (s *Store) UninstallPackage(name string) (*types.PackageUninstallResult, error) {
+	result, err := s.db.UninstallPackage(name)
+	if err != nil {
+		return nil, err
+	}
+	s.syncEmbeddings(result.RestoredIDs...)
+	return result, nil
+}
+
+// GetPackage 获取已安装的知识包
+func (s *Store) GetPackage(name string) (*types.InstalledPackage, error) {
+	return s.db.GetPackage(name)
+}
+
+// ListPackages 列出已安装的知识包
+func (s *Store) ListPackages() ([]types.InstalledPackage, error) {
+	return s.db.ListPackages()
+}
+
+// RegistryIndex 读取仓库索引（仓库中可安装的包）
+func (s *Store) RegistryIndex(source string) (*types.RegistryIndex, error) {
+	reg, err := registry.Open(source)
+	if err != nil {
+		return nil, err
+	}
+	return reg.Index()
+}
+
+// ConfiguredRegistries 读取服务端配置的每个仓库的索引（按配置顺序）
+func (s *Store) ConfiguredRegistries() ([]types.ConfiguredRegistry, error) {
+	registries := make([]types.ConfiguredRegistry, 0, len(s.registries))
+	for _, source := range s.registries {
+		reg, err := registry.Open(source)
+		if err != nil {
+			return nil, err
+		}
+		index, err := reg.Index()
+		if err != nil {
+			return nil, err
+		}
+		registries = append(registries, types.ConfiguredRegistry{Source: reg.Source(), Packages: index.Packages})
+	}
+	return registries, nil
+}
+
+// findRegistry 在服务端配置的仓库中查找提供该包（指定版本时为该版本）的仓库，
+// 多个仓库都提供时优先使用 preferred（升级时为安装来源），否则使用配置顺序中的第一个
+func (s *Store) findRegistry(name, version, preferred string) (string, error) {
+	if len(s.registries) == 0 {
+		return "", fmt.Errorf("invalid registry: no registry is configured on the server")
+	}
+	found := ""
+	for _, source := range s.registries {
+		reg, err := registry.Open(source)
+		if err != nil {
+			return "", err
+		}
+		index, err := reg.Index()
+		if err != nil {
+			return "", err
+		}
+		if _, err := registry.Resolve(index, name, version); err != nil {
+			continue
+		}
+		if reg.Source() == preferred {
+			return source, nil
+		}
+		if found == "" {
+			found = source
+		}
+	}
+	if found == "" {
+		return "", fmt.Errorf("package not found in configured registries: %s", name)
+	}
+	return found, nil
+}
@@ -0,0 +1,465 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/ystyle/cangjie-mem/pkg/segment"
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// 检索语法（空格分隔的词之间为 AND）：
+//
+//	a OR b            任意一个匹配
+//	"exact phrase"    短语精确匹配
+//	prefix*           前缀匹配
+//	-exclude          排除包含该词的记忆
+//	title:word        只在标题中匹配（支持 title:"phrase" 和 title:prefix*）
+//	library:tang      按库名过滤
+//	level:language    按层级过滤
+//	tag:web           按标签过滤（可重复，需同时包含）
+
+// 支持的字段
+const (
+	fieldTitle   = "title"
+	fieldLibrary = "library"
+	fieldLevel   = "level"
+	fieldTag     = "tag"
+)
+
+// ftsColumns 可在 FTS5 中限定的字段及对应的索引列
+var ftsColumns = map[string]string{
+	fieldTitle: "title_seg",
+}
+
+// queryTerm 检索词
+type queryTerm struct {
+	Text   string
+	Field  string // 为空表示全部字段，否则为 ftsColumns 中的字段
+	Phrase bool   // 是否为引号包围的短语
+	Prefix bool   // 是否为前缀匹配
+}
+
+// queryGroup 一组 OR 关系的检索词
+type queryGroup []queryTerm
+
+// parsedQuery 解析后的检索语句
+// Groups 之间为 AND，组内为 OR；Excludes 中的词不能出现
+type parsedQuery struct {
+	Groups      []queryGroup
+	Excludes    []queryTerm
+	Level       types.KnowledgeLevel
+	LibraryName string
+	Tags        []string
+}
+
+// queryToken 词法单元
+type queryToken struct {
+	pos    int // 在原始查询中的位置（按字符计）
+	or     bool
+	negate bool
+	field  string
+	term   queryTerm
+}
+
+// parseQuery 解析检索语句
+func parseQuery(input string) (*parsedQuery, error) {
+	tokens, err := tokenizeQuery(input)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &parsedQuery{}
+	pendingOr := false
+	for i, tok := range tokens {
+		if tok.or {
+			if len(q.Groups) == 0 || pendingOr || i > 0 && !tokens[i-1].isSearchTerm() {
+				return nil, fmt.Errorf("OR at position %d must follow a search term", tok.pos)
+			}
+			pendingOr = true
+			continue
+		}
+
+		if pendingOr && !tok.isSearchTerm() {
+			return nil, fmt.Errorf("OR at position %d must be followed by a search term", tok.pos)
+		}
+
+		switch {
+		case tok.field == fieldLevel || tok.field == fieldLibrary || tok.field == fieldTag:
+			if tok.negate {
+				return nil, fmt.Errorf("%s: filter cannot be excluded", tok.field)
+			}
+			if err := q.addFilter(tok); err != nil {
+				return nil, err
+			}
+		case tok.negate:
+			q.Excludes = append(q.Excludes, tok.term)
+		case pendingOr:
+			last := len(q.Groups) - 1
+			q.Groups[last] = append(q.Groups[last], tok.term)
+			pendingOr = false
+		default:
+			q.Groups = append(q.Groups, queryGroup{tok.term})
+		}
+	}
+	if pendingOr {
+		return nil, fmt.Errorf("OR must be followed by a search term")
+	}
+
+	q.dropUnsearchable()
+	if len(q.Groups) == 0 {
+		return nil, fmt.Errorf("query must contain at least one search term")
+	}
+
+	return q, nil
+}
+
+// isSearchTerm 是否为可参与 OR 的检索词（非排除词、非过滤条件）
+func (t queryToken) isSearchTerm() bool {
+	return !t.or && !t.negate && (t.field == "" || ftsColumns[t.field] != "")
+}
+
+// addFilter 记录字段过滤条件
+func (q *parsedQuery) addFilter(tok queryToken) error {
+	value := tok.term.Text
+	if tok.term.Prefix {
+		return fmt.Errorf("%s: filter does not support prefix matching", tok.field)
+	}
+
+	switch tok.field {
+	case fieldLevel:
+		level := types.KnowledgeLevel(strings.ToLower(value))
+		if !level.IsValid() {
+			return fmt.Errorf("invalid level: %s (must be one of: language, project, library)", value)
+		}
+		if q.Level != "" && q.Level != level {
+			return fmt.Errorf("conflicting level filters: %s and %s", q.Level, level)
+		}
+		q.Level = level
+	case fieldLibrary:
+		if q.LibraryName != "" && q.LibraryName != value {
+			return fmt.Errorf("conflicting library filters: %s and %s", q.LibraryName, value)
+		}
+		q.LibraryName = value
+	case fieldTag:
+		q.Tags = append(q.Tags, value)
+	}
+	return nil
+}
+
+// dropUnsearchable 去掉不含任何字母或数字的检索词（分词后为空，无法匹配）
+func (q *parsedQuery) dropUnsearchable() {
+	var groups []queryGroup
+	for _, g := range q.Groups {
+		var kept queryGroup
+		for _, t := range g {
+			if isSearchable(t.Text) {
+				kept = append(kept, t)
+			}
+		}
+		if len(kept) > 0 {
+			groups = append(groups, kept)
+		}
+	}
+	q.Groups = groups
+
+	var excludes []queryTerm
+	for _, t := range q.Excludes {
+		if isSearchable(t.Text) {
+			excludes = append(excludes, t)
+		}
+	}
+	q.Excludes = excludes
+}
+
+// isSearchable 文本中是否包含可被索引的字符
+func isSearchable(text string) bool {
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenizeQuery 将检索语句切分为词法单元
+func tokenizeQuery(input string) ([]queryToken, error) {
+	runes := []rune(input)
+	var tokens []queryToken
+
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		tok := queryToken{pos: i}
+		if runes[i] == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			tok.negate = true
+			i++
+		}
+
+		// 引号短语
+		if runes[i] == '"' {
+			text, next, err := readQuoted(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			tok.term = queryTerm{Text: text, Phrase: true}
+			i = readPrefixMarker(runes, next, &tok.term)
+			tokens = append(tokens, tok)
+			continue
+		}
+
+		// 普通词（可能带字段前缀）
+		start := i
+		for i < len(runes) && !unicode.IsSpace(runes[i]) {
+			if runes[i] == ':' && tok.field == "" && isFieldPrefix(runes, start, i) {
+				field := strings.ToLower(string(runes[start:i]))
+				if !isKnownField(field) {
+					return nil, fmt.Errorf("unknown field %q at position %d (supported: title, library, level, tag)", string(runes[start:i]), start)
+				}
+				tok.field = field
+				i++
+				start = i
+				if i < len(runes) && runes[i] == '"' {
+					text, next, err := readQuoted(runes, i)
+					if err != nil {
+						return nil, err
+					}
+					tok.term = queryTerm{Text: text, Phrase: true}
+					i = readPrefixMarker(runes, next, &tok.term)
+					start = -1
+					break
+				}
+				continue
+			}
+			i++
+		}
+
+		if start >= 0 {
+			word := string(runes[start:i])
+			if tok.field == "" && !tok.negate && word == "OR" {
+				tok.or = true
+				tokens = append(tokens, tok)
+				continue
+			}
+			if strings.HasSuffix(word, "*") {
+				word = strings.TrimRight(word, "*")
+				tok.term.Prefix = true
+				if word == "" {
+					return nil, fmt.Errorf("prefix at position %d must have at least one character before *", tok.pos)
+				}
+			}
+			tok.term.Text = word
+		}
+
+		if tok.term.Text == "" {
+			if tok.field != "" {
+				return nil, fmt.Errorf("missing value for %s: at position %d", tok.field, tok.pos)
+			}
+			continue
+		}
+		if ftsColumns[tok.field] != "" {
+			tok.term.Field = tok.field
+		}
+		tokens = append(tokens, tok)
+	}
+
+	return tokens, nil
+}
+
+// readQuoted 读取从 start（引号位置）开始的短语，返回短语内容和结束引号之后的位置
+func readQuoted(runes []rune, start int) (string, int, error) {
+	for i := start + 1; i < len(runes); i++ {
+		if runes[i] == '"' {
+			return string(runes[start+1 : i]), i + 1, nil
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated quote at position %d", start)
+}
+
+// readPrefixMarker 读取短语后紧跟的 *（前缀匹配），返回下一个位置
+func readPrefixMarker(runes []rune, i int, term *queryTerm) int {
+	for i < len(runes) && runes[i] == '*' {
+		term.Prefix = true
+		i++
+	}
+	return i
+}
+
+// isFieldPrefix runes[start:colon] 是否形如字段名（纯 ASCII 字母且冒号后不是 : 或 /），
+// 其他情况（如 std::io、http://）按普通文本处理
+func isFieldPrefix(runes []rune, start, colon int) bool {
+	if colon == start {
+		return false
+	}
+	if colon+1 < len(runes) && (runes[colon+1] == ':' || runes[colon+1] == '/') {
+		return false
+	}
+	for _, r := range runes[start:colon] {
+		if r > unicode.MaxASCII || !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// isKnownField 是否为支持的字段
+func isKnownField(field string) bool {
+	switch field {
+	case fieldTitle, fieldLibrary, fieldLevel, fieldTag:
+		return true
+	default:
+		return false
+	}
+}
+
+// MatchExpression 编译为 FTS5 MATCH 表达式（所有词都以引号转义，不会产生语法错误）
+func (q *parsedQuery) MatchExpression() string {
+	parts := make([]string, 0, len(q.Groups))
+	for _, g := range q.Groups {
+		alternatives := make([]string, len(g))
+		for i, t := range g {
+			alternatives[i] = t.fts()
+		}
+		if len(alternatives) == 1 {
+			parts = append(parts, alternatives[0])
+		} else {
+			parts = append(parts, "("+strings.Join(alternatives, " OR ")+")")
+		}
+	}
+
+	expr := strings.Join(parts, " AND ")
+	if len(q.Excludes) > 0 {
+		expr = "(" + expr + ")"
+		for _, t := range q.Excludes {
+			expr += " NOT " + t.fts()
+		}
+	}
+	return expr
+}
+
+// fts 将检索词编译为 FTS5 短语（与索引相同的 CJK 切分方式）
+func (t queryTerm) fts() string {
+	phrase := `"` + strings.ReplaceAll(segment.Text(t.Text), `"`, `""`) + `"`
+	if t.Prefix {
+		phrase += " *"
+	}
+	if column := ftsColumns[t.Field]; column != "" {
+		phrase = column + " : " + phrase
+	}
+	return phrase
+}
+
+// Terms 返回所有正向检索词的文本（用于置信度计算和摘要提取）
+func (q *parsedQuery) Terms() []string {
+	var terms []string
+	for _, g := range q.Groups {
+		for _, t := range g {
+			terms = append(terms, t.Text)
+		}
+	}
+	return terms
+}
+
+// PlainText 返回以空格连接的正向检索词
+func (q *parsedQuery) PlainText() string {
+	return strings.Join(q.Terms(), " ")
+}
+
+// applyFilters 将检索语句中的字段过滤条件合并到请求中
+func (q *parsedQuery) applyFilters(req *types.RecallRequest) error {
+	if q.Level != "" {
+		if req.Level != "" && req.Level != string(q.Level) {
+			return fmt.Errorf("level:%s conflicts with level parameter %s", q.Level, req.Level)
+		}
+		req.Level = string(q.Level)
+	}
+	if q.LibraryName != "" {
+		if req.LibraryName != "" && req.LibraryName != q.LibraryName {
+			return fmt.Errorf("library:%s conflicts with library_name parameter %s", q.LibraryName, req.LibraryName)
+		}
+		req.LibraryName = q.LibraryName
+	}
+	req.Tags = append(req.Tags, q.Tags...)
+	return nil
+}
+
+// 零结果时的放宽策略（按顺序尝试，结果写入 SearchStrategy 后缀）
+const (
+	relaxOr         = "relaxed_or"          // 所有词改为 OR
+	relaxPrefix     = "relaxed_prefix"      // 所有词改为 OR + 前缀匹配
+	relaxSingleTerm = "relaxed_single_term" // 只保留最具区分度的一个词（去掉字段限定，排除词保留）
+)
+
+// queryRelaxation 放宽后的检索语句
+type queryRelaxation struct {
+	Name  string
+	Query *parsedQuery
+}
+
+// Relaxations 返回逐步放宽的检索语句，跳过与前一步等价的语句
+func (q *parsedQuery) Relaxations() []queryRelaxation {
+	var relaxed []queryRelaxation
+	seen := map[string]bool{q.MatchExpression(): true}
+	add := func(name string, rq *parsedQuery) {
+		expr := rq.MatchExpression()
+		if seen[expr] {
+			return
+		}
+		seen[expr] = true
+		relaxed = append(relaxed, queryRelaxation{Name: name, Query: rq})
+	}
+
+	var all queryGroup
+	for _, g := range q.Groups {
+		all = append(all, g...)
+	}
+	add(relaxOr, q.withGroups([]queryGroup{all}, q.Excludes))
+
+	prefixed := make(queryGroup, len(all))
+	for i, t := range all {
+		t.Prefix = true
+		prefixed[i] = t
+	}
+	add(relaxPrefix, q.withGroups([]queryGroup{prefixed}, q.Excludes))
+
+	strongest := q.strongestTerm()
+	add(relaxSingleTerm, q.withGroups([]queryGroup{{strongest}}, q.Excludes))
+
+	return relaxed
+}
+
+// withGroups 复制检索语句并替换检索词（保留过滤条件）
+func (q *parsedQuery) withGroups(groups []queryGroup, excludes []queryTerm) *parsedQuery {
+	return &parsedQuery{
+		Groups:      groups,
+		Excludes:    excludes,
+		Level:       q.Level,
+		LibraryName: q.LibraryName,
+		Tags:        q.Tags,
+	}
+}
+
+// strongestTerm 返回最具区分度的检索词（可索引字符最多的词，同长取靠前的），
+// 去掉字段限定，改为前缀匹配
+func (q *parsedQuery) strongestTerm() queryTerm {
+	var best queryTerm
+	bestLen := -1
+	for _, g := range q.Groups {
+		for _, t := range g {
+			n := 0
+			for _, r := range t.Text {
+				if unicode.IsLetter(r) || unicode.IsDigit(r) {
+					n++
+				}
+			}
+			if n > bestLen {
+				best, bestLen = t, n
+			}
+		}
+	}
+	return queryTerm{Text: best.Text, Prefix: true}
+}
@@ -0,0 +1,118 @@
+package store
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		match string
+		level types.KnowledgeLevel
+		lib   string
+		tags  []string
+	}{
+		{"单词", "interface", `"interface"`, "", "", nil},
+		{"AND", "http 请求", `"http" AND "请 求"`, "", "", nil},
+		{"OR", "log OR 日志 配置", `("log" OR "日 志") AND "配 置"`, "", "", nil},
+		{"短语", `"var 声明"`, `"var 声 明"`, "", "", nil},
+		{"前缀", "inter*", `"inter" *`, "", "", nil},
+		{"短语前缀", `"std.co"*`, `"std.co" *`, "", "", nil},
+		{"排除", "log -debug", `("log") NOT "debug"`, "", "", nil},
+		{"标题", "title:日志 配置", `title_seg : "日 志" AND "配 置"`, "", "", nil},
+		{"标题短语前缀", `title:"std lo"*`, `title_seg : "std lo" *`, "", "", nil},
+		{"过滤", "log library:tang level:Library tag:web tag:http", `"log"`, types.LevelLibrary, "tang", []string{"web", "http"}},
+		{"特殊字符", `std::io a"b`, `"std::io" AND "a""b"`, "", "", nil},
+		{"纯标点被忽略", "log ::: -!!", `"log"`, "", "", nil},
+		{"小写 or 为普通词", "a or b", `"a" AND "or" AND "b"`, "", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := parseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("parseQuery(%q) error = %v", tt.query, err)
+			}
+			if got := q.MatchExpression(); got != tt.match {
+				t.Errorf("MatchExpression() = %s, want %s", got, tt.match)
+			}
+			if q.Level != tt.level || q.LibraryName != tt.lib || strings.Join(q.Tags, ",") != strings.Join(tt.tags, ",") {
+				t.Errorf("filters = %q/%q/%v, want %q/%q/%v", q.Level, q.LibraryName, q.Tags, tt.level, tt.lib, tt.tags)
+			}
+		})
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{``, "at least one search term"},
+		{`"unterminated`, "unterminated quote"},
+		{`*`, "prefix"},
+		{`OR log`, "must follow a search term"},
+		{`log OR`, "must be followed by a search term"},
+		{`log OR OR x`, "must follow a search term"},
+		{`log OR -debug`, "must be followed by a search term"},
+		{`log OR tag:web`, "must be followed by a search term"},
+		{`author:me log`, `unknown field "author"`},
+		{`title: log`, "missing value for title"},
+		{`log level:module`, "invalid level"},
+		{`log level:language level:project`, "conflicting level filters"},
+		{`log library:a library:b`, "conflicting library filters"},
+		{`log -tag:web`, "cannot be excluded"},
+		{`log library:tan*`, "does not support prefix"},
+		{`-debug`, "at least one search term"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := parseQuery(tt.query)
+			if err == nil {
+				t.Fatalf("parseQuery(%q) expected error", tt.query)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parseQuery(%q) error = %v, want containing %q", tt.query, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryRelaxations(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"log 配置", []string{
+			`relaxed_or ("log" OR "配 置")`,
+			`relaxed_prefix ("log" * OR "配 置" *)`,
+			`relaxed_single_term "log" *`,
+		}},
+		{"title:logger -debug", []string{
+			`relaxed_prefix (title_seg : "logger" *) NOT "debug"`,
+			`relaxed_single_term ("logger" *) NOT "debug"`,
+		}},
+		{"inter*", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := parseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("parseQuery(%q) error = %v", tt.query, err)
+			}
+			var got []string
+			for _, r := range q.Relaxations() {
+				got = append(got, r.Name+" "+r.Query.MatchExpression())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Relaxations() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
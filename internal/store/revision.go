@@ -0,0 +1,100 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ystyle/cangjie-mem/pkg/diff"
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// ListRevisions 列出记忆的修订历史（包含已删除记忆的历史）
+func (s *Store) ListRevisions(memoryID int64) (*types.RevisionListResponse, error) {
+	revisions, err := s.db.ListRevisions(memoryID)
+	if err != nil {
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		return nil, fmt.Errorf("memory not found: id=%d", memoryID)
+	}
+
+	return &types.RevisionListResponse{
+		MemoryID:  memoryID,
+		Total:     len(revisions),
+		Revisions: revisions,
+	}, nil
+}
+
+// DiffRevisions 比较两个修订版本的差异
+// to <= 0 时使用最新版本，from <= 0 时使用 to 的上一个版本
+func (s *Store) DiffRevisions(memoryID int64, from, to int) (*types.RevisionDiffResponse, error) {
+	if to <= 0 {
+		revisions, err := s.db.ListRevisions(memoryID)
+		if err != nil {
+			return nil, err
+		}
+		if len(revisions) == 0 {
+			return nil, fmt.Errorf("memory not found: id=%d", memoryID)
+		}
+		to = revisions[0].Revision
+	}
+	if from <= 0 {
+		from = to - 1
+	}
+	if from <= 0 {
+		return nil, fmt.Errorf("invalid revision range: %d..%d", from, to)
+	}
+
+	fromRev, err := s.db.GetRevision(memoryID, from)
+	if err != nil {
+		return nil, err
+	}
+	toRev, err := s.db.GetRevision(memoryID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.RevisionDiffResponse{
+		MemoryID: memoryID,
+		From:     from,
+		To:       to,
+		Diff: diff.Unified(
+			fmt.Sprintf("revision %d", from),
+			fmt.Sprintf("revision %d", to),
+			revisionText(fromRev), revisionText(toRev), diff.DefaultContext,
+		),
+	}, nil
+}
+
+// RestoreRevision 将记忆回滚到指定修订版本
+func (s *Store) RestoreRevision(memoryID int64, revision int, editor string) (*types.Memory, error) {
+	if revision <= 0 {
+		return nil, fmt.Errorf("invalid revision: %d", revision)
+	}
+
+	memory, err := s.db.RestoreRevision(memoryID, revision, editor)
+	if err == nil {
+		s.syncEmbeddings(memory.ID)
+	}
+	return memory, err
+}
+
+// revisionText 将修订版本渲染为用于比较差异的文本
+func revisionText(r *types.Revision) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "title: %s\n", r.Title)
+	fmt.Fprintf(&sb, "level: %s\n", r.Level)
+	fmt.Fprintf(&sb, "language_tag: %s\n", r.LanguageTag)
+	fmt.Fprintf(&sb, "library_name: %s\n", r.LibraryName)
+	fmt.Fprintf(&sb, "library_version: %s\n", r.LibraryVersion)
+	fmt.Fprintf(&sb, "project_path_pattern: %s\n", r.ProjectPathPattern)
+	fmt.Fprintf(&sb, "source: %s\n", r.Source)
+	fmt.Fprintf(&sb, "tags: %s\n", strings.Join(r.Tags, ", "))
+	fmt.Fprintf(&sb, "summary: %s\n", r.Summary)
+	sb.WriteString("\n")
+	sb.WriteString(r.Content)
+	if !strings.HasSuffix(r.Content, "\n") {
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
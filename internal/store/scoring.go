@@ -0,0 +1,237 @@
+package store
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// DefaultMinConfidence 默认最小置信度阈值
+// 相关度按本次结果中最相关的一条归一化，较弱的命中相关度接近 0；阈值低于默认权重下只由层级和来源得到的
+// 最低置信度（自动捕获的项目级记忆为 0.095），否则一条记忆能否返回取决于同时命中了哪些记忆
+const DefaultMinConfidence = 0.05
+
+// accessSaturation 访问次数达到该值时访问热度因子为 1
+const accessSaturation = 50
+
+// 置信度因子
+const (
+	factorRelevance = "relevance" // 检索相关度（BM25 归一化，混合检索时为融合分数）
+	factorLevel     = "level"     // 层级权威性
+	factorSource    = "source"    // 来源可信度
+	factorAccess    = "access"    // 访问热度
+	factorProject   = "project"   // 匹配当前项目
+)
+
+// 层级权威性因子取值
+var levelFactors = map[types.KnowledgeLevel]float64{
+	types.LevelLanguage: 1.0,
+	types.LevelLibrary:  0.6,
+	types.LevelProject:  0.3,
+}
+
+// 来源可信度因子取值
+var sourceFactors = map[types.KnowledgeSource]float64{
+	types.SourceManual:       1.0,
+	types.SourceAutoCaptured: 0.5,
+}
+
+// ScoringWeights 置信度各因子的权重
+// 置信度 = Σ(权重 × 因子) / Σ权重，各因子取值均在 [0, 1]
+type ScoringWeights struct {
+	Relevance float64 `json:"relevance"`
+	Level     float64 `json:"level"`
+	Source    float64 `json:"source"`
+	Access    float64 `json:"access"`
+	Project   float64 `json:"project"`
+}
+
+// DefaultScoringWeights 默认权重
+func DefaultScoringWeights() ScoringWeights {
+	return ScoringWeights{
+		Relevance: 0.45,
+		Level:     0.15,
+		Source:    0.1,
+		Access:    0.05,
+		Project:   0.25,
+	}
+}
+
+// Validate 验证权重
+func (w ScoringWeights) Validate() error {
+	for _, f := range w.factors() {
+		if f.weight < 0 || math.IsNaN(f.weight) || math.IsInf(f.weight, 0) {
+			return fmt.Errorf("invalid %s weight: %v", f.name, f.weight)
+		}
+	}
+	if w.total() <= 0 {
+		return fmt.Errorf("invalid weights: at least one weight must be positive")
+	}
+	return nil
+}
+
+// ParseScoringWeights 解析权重配置（如 "relevance=0.6,level=0.2"），未指定的因子使用默认权重
+func ParseScoringWeights(s string) (ScoringWeights, error) {
+	w := DefaultScoringWeights()
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return w, fmt.Errorf("invalid weight %q (expected name=value)", part)
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return w, fmt.Errorf("invalid weight %q: %v", part, err)
+		}
+		switch strings.TrimSpace(name) {
+		case factorRelevance:
+			w.Relevance = v
+		case factorLevel:
+			w.Level = v
+		case factorSource:
+			w.Source = v
+		case factorAccess:
+			w.Access = v
+		case factorProject:
+			w.Project = v
+		default:
+			return w, fmt.Errorf("invalid weight name %q (supported: relevance, level, source, access, project)", name)
+		}
+	}
+	return w, w.Validate()
+}
+
+type namedWeight struct {
+	name   string
+	weight float64
+}
+
+func (w ScoringWeights) factors() []namedWeight {
+	return []namedWeight{
+		{factorRelevance, w.Relevance},
+		{factorLevel, w.Level},
+		{factorSource, w.Source},
+		{factorAccess, w.Access},
+		{factorProject, w.Project},
+	}
+}
+
+func (w ScoringWeights) total() float64 {
+	var sum float64
+	for _, f := range w.factors() {
+		sum += f.weight
+	}
+	return sum
+}
+
+// SetScoringWeights 设置置信度权重
+func (s *Store) SetScoringWeights(w ScoringWeights) error {
+	if err := w.Validate(); err != nil {
+		return err
+	}
+	s.weights = &w
+	return nil
+}
+
+// scoringWeights 返回当前使用的权重
+func (s *Store) scoringWeights() ScoringWeights {
+	if s.weights == nil {
+		return DefaultScoringWeights()
+	}
+	return *s.weights
+}
+
+// normalizeRelevance 将检索相关度归一化到 (0, 1]，写入 Score（最相关的结果为 1）
+// 关键词检索使用 BM25（越小越相关，取反）；混合检索使用已有的融合分数
+func normalizeRelevance(results []types.RecallResult, hybrid bool) {
+	raw := func(r types.RecallResult) float64 {
+		if hybrid {
+			return r.Score
+		}
+		return -r.BM25
+	}
+
+	best := 0.0
+	for _, r := range results {
+		best = math.Max(best, raw(r))
+	}
+
+	for i := range results {
+		if best <= 0 {
+			results[i].Score = 1
+			continue
+		}
+		results[i].Score = math.Max(raw(results[i]), 0) / best
+	}
+}
+
+// calculateConfidence 计算置信度及各因子明细
+// result.Score 需已由 normalizeRelevance 归一化
+func (s *Store) calculateConfidence(result types.RecallResult, projectPath string) (float64, *types.ScoreExplanation) {
+	w := s.scoringWeights()
+	total := w.total()
+
+	project := 0.0
+	projectDetail := "not a project memory"
+	if result.Level == types.LevelProject {
+		projectDetail = "no project_context"
+		if projectPath != "" {
+			projectDetail = "pattern does not match project_context"
+			if s.matchesProjectPattern(projectPath, result.ProjectPathPattern) {
+				project = 1
+				projectDetail = "pattern matches project_context"
+			}
+		}
+	}
+
+	access := math.Min(math.Log1p(float64(result.AccessCount))/math.Log1p(accessSaturation), 1)
+
+	relevanceDetail := fmt.Sprintf("bm25=%.6g", result.BM25)
+	if result.Similarity != 0 {
+		relevanceDetail += fmt.Sprintf(", similarity=%.4g", result.Similarity)
+	}
+
+	values := map[string]struct {
+		value  float64
+		detail string
+	}{
+		factorRelevance: {result.Score, relevanceDetail},
+		factorLevel:     {levelFactors[result.Level], string(result.Level)},
+		factorSource:    {sourceFactors[result.Source], string(result.Source)},
+		factorAccess:    {access, fmt.Sprintf("access_count=%d", result.AccessCount)},
+		factorProject:   {project, projectDetail},
+	}
+
+	explanation := &types.ScoreExplanation{
+		BM25:       result.BM25,
+		Similarity: result.Similarity,
+	}
+	confidence := 0.0
+	for _, f := range w.factors() {
+		v := values[f.name]
+		contribution := f.weight * v.value / total
+		confidence += contribution
+		explanation.Factors = append(explanation.Factors, types.ScoreFactor{
+			Name:         f.name,
+			Value:        round4(v.value),
+			Weight:       f.weight,
+			Contribution: round4(contribution),
+			Detail:       v.detail,
+		})
+	}
+
+	confidence = round4(math.Min(confidence, 1.0))
+	explanation.Confidence = confidence
+	return confidence, explanation
+}
+
+// round4 保留 4 位小数（避免浮点误差影响展示和阈值比较）
+func round4(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
@@ -0,0 +1,170 @@
+package store
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/ystyle/cangjie-mem/pkg/db"
+	"github.com/ystyle/cangjie-mem/pkg/embed"
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+const (
+	// hybridAlpha 混合检索中关键词分数的权重（向量分数权重为 1-hybridAlpha）
+	hybridAlpha = 0.5
+	// minVectorSimilarity 仅由向量命中的结果所需的最低余弦相似度
+	minVectorSimilarity = 0.25
+	// embeddingBatchSize 向量回填每批处理的记忆数
+	embeddingBatchSize = 100
+)
+
+// SetEmbedder 设置向量模型，为 nil 时禁用混合检索
+func (s *Store) SetEmbedder(e embed.Embedder) {
+	s.embedder = e
+}
+
+// BackfillEmbeddings 为缺少向量的记忆生成向量，返回处理的记忆数
+// batch <= 0 时处理全部缺失的记忆；stop 关闭后处理完当前批次即返回
+func (s *Store) BackfillEmbeddings(stop <-chan struct{}, batch int) (int, error) {
+	if s.embedder == nil {
+		return 0, fmt.Errorf("embedder not configured")
+	}
+
+	total := 0
+	for {
+		limit := embeddingBatchSize
+		if batch > 0 && batch-total < limit {
+			limit = batch - total
+		}
+		if limit <= 0 {
+			return total, nil
+		}
+		select {
+		case <-stop:
+			return total, nil
+		default:
+		}
+
+		sources, err := s.db.MissingEmbeddings(s.embedder.Name(), limit)
+		if err != nil {
+			return total, err
+		}
+		if len(sources) == 0 {
+			return total, nil
+		}
+
+		n, err := s.embedSources(sources)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// embedSources 为记忆生成并保存向量，返回处理的记忆数
+func (s *Store) embedSources(sources []db.EmbeddingSource) (int, error) {
+	for i, src := range sources {
+		vec, err := s.embedder.Embed(embeddingText(src))
+		if err != nil {
+			return i, fmt.Errorf("failed to embed memory %d: %w", src.ID, err)
+		}
+		if err := s.db.SaveEmbedding(src.ID, s.embedder.Name(), embed.Encode(vec)); err != nil {
+			return i, err
+		}
+	}
+	return len(sources), nil
+}
+
+// syncEmbeddings 为刚写入的记忆生成向量（未配置向量模型时跳过）
+// 向量仅用于检索增强，失败不影响写入结果，缺失部分由启动时的后台回填补齐
+func (s *Store) syncEmbeddings(ids ...int64) {
+	if s.embedder == nil || len(ids) == 0 {
+		return
+	}
+	sources, err := s.db.EmbeddingSources(ids)
+	if err != nil {
+		return
+	}
+	_, _ = s.embedSources(sources)
+}
+
+// importedIDs 导入结果中新增或覆盖的记忆 ID
+func importedIDs(result *types.ImportResult) []int64 {
+	var ids []int64
+	for _, item := range result.Items {
+		if (item.Action == types.ImportAdded || item.Action == types.ImportUpdated) && item.ID > 0 {
+			ids = append(ids, item.ID)
+		}
+	}
+	return ids
+}
+
+// embeddingText 拼接用于生成向量的文本
+func embeddingText(src db.EmbeddingSource) string {
+	text := src.Title + "\n" + src.Content
+	if src.Summary != "" {
+		text += "\n" + src.Summary
+	}
+	return text
+}
+
+// hybridRecall 融合关键词检索与向量检索结果
+// 关键词分数为归一化后的 BM25，向量分数为余弦相似度，两者加权求和写入 Score
+func (s *Store) hybridRecall(query string, keyword []types.RecallResult, opts db.RecallOptions) ([]types.RecallResult, error) {
+	vec, err := s.embedder.Embed(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+
+	semantic, err := s.db.VectorSearch(embed.Encode(vec), s.embedder.Name(), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// BM25 越小越相关，取反后做 min-max 归一化
+	minRank, maxRank := math.Inf(1), math.Inf(-1)
+	for _, r := range keyword {
+		minRank = math.Min(minRank, -r.BM25)
+		maxRank = math.Max(maxRank, -r.BM25)
+	}
+
+	merged := make(map[int64]*types.RecallResult, len(keyword)+len(semantic))
+	var order []int64
+	for _, r := range keyword {
+		r := r
+		keywordScore := 1.0
+		if maxRank > minRank {
+			keywordScore = (-r.BM25 - minRank) / (maxRank - minRank)
+		}
+		r.Score = hybridAlpha * keywordScore
+		merged[r.ID] = &r
+		order = append(order, r.ID)
+	}
+
+	for _, r := range semantic {
+		similarity := math.Max(r.Similarity, 0)
+		if existing, ok := merged[r.ID]; ok {
+			existing.Similarity = r.Similarity
+			existing.Score += (1 - hybridAlpha) * similarity
+			continue
+		}
+		if similarity < minVectorSimilarity {
+			continue
+		}
+		r := r
+		r.Score = (1 - hybridAlpha) * similarity
+		merged[r.ID] = &r
+		order = append(order, r.ID)
+	}
+
+	results := make([]types.RecallResult, 0, len(order))
+	for _, id := range order {
+		results = append(results, *merged[id])
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	return results, nil
+}
@@ -0,0 +1,33 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/ystyle/cangjie-mem/pkg/signing"
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// SetKeyring 设置受信任的签名公钥，require 为 true 时导入的知识包必须带有密钥环中公钥的有效签名
+func (s *Store) SetKeyring(keyring *signing.Keyring, require bool) {
+	s.keyring = keyring
+	s.requireSignature = require
+}
+
+// verifySignature 验证知识包签名：没有签名时返回 nil（要求签名时返回错误），签名无效时总是返回错误
+func (s *Store) verifySignature(pkg *types.KnowledgePackage) (*types.PackageSigner, error) {
+	if pkg.Signature == nil {
+		if s.requireSignature {
+			return nil, fmt.Errorf("invalid signature: package is not signed, a signature from a trusted key is required")
+		}
+		return nil, nil
+	}
+
+	signer, err := s.keyring.Verify(pkg)
+	if err != nil {
+		return nil, err
+	}
+	if s.requireSignature && !signer.Trusted {
+		return nil, fmt.Errorf("invalid signature: signing key %s (%s) is not in the keyring", signer.KeyID, signer.Signer)
+	}
+	return signer, nil
+}
@@ -0,0 +1,211 @@
+package store
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// 片段默认参数
+const (
+	DefaultSnippetLength    = 100 // 每个片段的长度（字符数）
+	DefaultSnippetFragments = 3   // 每条记忆最多返回的片段数
+	maxSnippetFragments     = 10
+	snippetEllipsis         = "..."
+	snippetSeparator        = " "
+)
+
+// snippetMarkers 高亮标记
+type snippetMarkers struct {
+	open, close string
+	escape      func(string) string
+}
+
+// highlightMarkers 根据 highlight 参数返回高亮标记
+func highlightMarkers(style string) (snippetMarkers, error) {
+	switch style {
+	case "", types.HighlightMarkdown:
+		return snippetMarkers{open: "**", close: "**", escape: identity}, nil
+	case types.HighlightHTML:
+		return snippetMarkers{open: "<mark>", close: "</mark>", escape: html.EscapeString}, nil
+	case types.HighlightNone:
+		return snippetMarkers{escape: identity}, nil
+	default:
+		return snippetMarkers{}, fmt.Errorf("invalid highlight: %s (must be one of: markdown, html, none)", style)
+	}
+}
+
+func identity(s string) string { return s }
+
+// termMatch 检索词在内容中的一次出现（按字符计的 [Start, End)）
+type termMatch struct {
+	term       string
+	start, end int
+}
+
+// findTermMatches 查找所有检索词在内容中的出现位置（忽略大小写，按字符计），
+// 重叠的匹配只保留靠前且更长的
+func findTermMatches(content []rune, terms []string) []termMatch {
+	lower := make([]rune, len(content))
+	for i, r := range content {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	var matches []termMatch
+	for _, term := range terms {
+		needle := []rune(strings.ToLower(strings.TrimSpace(term)))
+		if len(needle) == 0 {
+			continue
+		}
+		for i := 0; i+len(needle) <= len(lower); {
+			if runesEqual(lower[i:i+len(needle)], needle) {
+				matches = append(matches, termMatch{term: term, start: i, end: i + len(needle)})
+				i += len(needle)
+				continue
+			}
+			i++
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].start != matches[j].start {
+			return matches[i].start < matches[j].start
+		}
+		return matches[i].end > matches[j].end
+	})
+
+	var merged []termMatch
+	for _, m := range matches {
+		if len(merged) > 0 && m.start < merged[len(merged)-1].end {
+			continue
+		}
+		merged = append(merged, m)
+	}
+	return merged
+}
+
+func runesEqual(a, b []rune) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// snippetWindow 片段窗口 [start, end)
+type snippetWindow struct {
+	start, end int
+	matches    []termMatch
+}
+
+// selectWindows 选出覆盖检索词最多的若干个不重叠窗口，按在内容中的顺序返回
+func selectWindows(length int, matches []termMatch, size, fragments int) []snippetWindow {
+	if len(matches) == 0 {
+		return []snippetWindow{{start: 0, end: min(size, length)}}
+	}
+
+	// 以每个匹配为候选起点，匹配前保留约 1/4 窗口的上下文
+	lead := size / 4
+	var candidates []snippetWindow
+	for _, m := range matches {
+		start := max(m.start-lead, 0)
+		end := min(start+size, length)
+		start = max(end-size, 0)
+
+		w := snippetWindow{start: start, end: end}
+		for _, other := range matches {
+			if other.start >= start && other.end <= end {
+				w.matches = append(w.matches, other)
+			}
+		}
+		candidates = append(candidates, w)
+	}
+
+	// 评分：不同检索词数优先，其次匹配次数
+	score := func(w snippetWindow) int {
+		distinct := map[string]bool{}
+		for _, m := range w.matches {
+			distinct[strings.ToLower(m.term)] = true
+		}
+		return len(distinct)*1000 + len(w.matches)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return score(candidates[i]) > score(candidates[j])
+	})
+
+	var chosen []snippetWindow
+	for _, c := range candidates {
+		if len(chosen) >= fragments {
+			break
+		}
+		overlaps := false
+		for _, w := range chosen {
+			if c.start < w.end && w.start < c.end {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			chosen = append(chosen, c)
+		}
+	}
+
+	sort.Slice(chosen, func(i, j int) bool { return chosen[i].start < chosen[j].start })
+	return chosen
+}
+
+// buildSnippets 生成带高亮的匹配片段（按字符切分，不会截断多字节字符）
+// 偏移量均为 Unicode 字符（code point）在 content 中的位置
+func buildSnippets(content string, terms []string, markers snippetMarkers, size, fragments int) []types.Snippet {
+	runes := []rune(content)
+	if len(runes) == 0 {
+		return nil
+	}
+
+	matches := findTermMatches(runes, terms)
+	windows := selectWindows(len(runes), matches, size, fragments)
+
+	snippets := make([]types.Snippet, 0, len(windows))
+	for _, w := range windows {
+		var b strings.Builder
+		if w.start > 0 {
+			b.WriteString(snippetEllipsis)
+		}
+		pos := w.start
+		offsets := make([]types.TermOffset, 0, len(w.matches))
+		for _, m := range w.matches {
+			b.WriteString(markers.escape(string(runes[pos:m.start])))
+			b.WriteString(markers.open)
+			b.WriteString(markers.escape(string(runes[m.start:m.end])))
+			b.WriteString(markers.close)
+			pos = m.end
+			offsets = append(offsets, types.TermOffset{Term: m.term, Start: m.start, End: m.end})
+		}
+		b.WriteString(markers.escape(string(runes[pos:w.end])))
+		if w.end < len(runes) {
+			b.WriteString(snippetEllipsis)
+		}
+
+		snippets = append(snippets, types.Snippet{
+			Text:    b.String(),
+			Start:   w.start,
+			End:     w.end,
+			Matches: offsets,
+		})
+	}
+	return snippets
+}
+
+// joinSnippets 将多个片段拼接为 matched_text
+func joinSnippets(snippets []types.Snippet) string {
+	texts := make([]string, len(snippets))
+	for i, s := range snippets {
+		texts[i] = s.Text
+	}
+	return strings.Join(texts, snippetSeparator)
+}
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+func TestBuildSnippets(t *testing.T) {
+	markdown, _ := highlightMarkers(types.HighlightMarkdown)
+	html, _ := highlightMarkers(types.HighlightHTML)
+	none, _ := highlightMarkers(types.HighlightNone)
+
+	t.Run("短内容整体高亮", func(t *testing.T) {
+		got := buildSnippets("使用 Interface 定义接口", []string{"interface", "接口"}, markdown, 100, 3)
+		if len(got) != 1 || got[0].Text != "使用 **Interface** 定义**接口**" {
+			t.Fatalf("buildSnippets() = %+v", got)
+		}
+		want := []types.TermOffset{{Term: "interface", Start: 3, End: 12}, {Term: "接口", Start: 15, End: 17}}
+		if len(got[0].Matches) != 2 || got[0].Matches[0] != want[0] || got[0].Matches[1] != want[1] {
+			t.Errorf("Matches = %+v, want %+v", got[0].Matches, want)
+		}
+	})
+
+	t.Run("中文不截断且返回多个片段", func(t *testing.T) {
+		content := "日志" + strings.Repeat("中文填充内容", 30) + "配置" + strings.Repeat("更多的中文内容", 30)
+		got := buildSnippets(content, []string{"日志", "配置"}, markdown, 20, 3)
+		if len(got) != 2 {
+			t.Fatalf("buildSnippets() returned %d fragments, want 2: %+v", len(got), got)
+		}
+		for _, s := range got {
+			if !utf8.ValidString(s.Text) {
+				t.Errorf("fragment is not valid UTF-8: %q", s.Text)
+			}
+			if s.End-s.Start > 20 {
+				t.Errorf("fragment length = %d, want <= 20", s.End-s.Start)
+			}
+		}
+		if !strings.HasPrefix(got[0].Text, "**日志**") || !strings.HasSuffix(got[0].Text, "...") {
+			t.Errorf("first fragment = %q", got[0].Text)
+		}
+		if !strings.HasPrefix(got[1].Text, "...") || !strings.Contains(got[1].Text, "**配置**") {
+			t.Errorf("second fragment = %q", got[1].Text)
+		}
+		runes := []rune(content)
+		m := got[1].Matches[0]
+		if string(runes[m.Start:m.End]) != "配置" {
+			t.Errorf("offset points to %q, want 配置", string(runes[m.Start:m.End]))
+		}
+	})
+
+	t.Run("片段数上限", func(t *testing.T) {
+		content := strings.Repeat("log 占位内容占位内容占位内容占位内容 ", 10)
+		got := buildSnippets(content, []string{"log"}, none, 10, 2)
+		if len(got) != 2 {
+			t.Fatalf("buildSnippets() returned %d fragments, want 2", len(got))
+		}
+		if strings.Contains(got[0].Text, "*") {
+			t.Errorf("none highlight should not add markers: %q", got[0].Text)
+		}
+	})
+
+	t.Run("HTML 转义", func(t *testing.T) {
+		got := buildSnippets("a<b> & Log", []string{"log"}, html, 100, 3)
+		if got[0].Text != "a&lt;b&gt; &amp; <mark>Log</mark>" {
+			t.Errorf("buildSnippets() = %q", got[0].Text)
+		}
+	})
+
+	t.Run("无匹配时返回开头", func(t *testing.T) {
+		got := buildSnippets(strings.Repeat("字", 50), []string{"log"}, markdown, 10, 3)
+		if len(got) != 1 || got[0].Text != strings.Repeat("字", 10)+"..." || len(got[0].Matches) != 0 {
+			t.Errorf("buildSnippets() = %+v", got)
+		}
+	})
+
+	if _, err := highlightMarkers("bold"); err == nil {
+		t.Error("highlightMarkers(bold) expected error")
+	}
+}
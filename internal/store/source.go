@@ -0,0 +1,53 @@
+package store
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// SetSourceRoots 设置服务端配置的源码根目录
+// REST API 只能导入这些目录下的库源码和符号，未配置时只能用命令行导入
+func (s *Store) SetSourceRoots(roots []string) {
+	s.sourceRoots = nil
+	for _, root := range roots {
+		if root = strings.TrimSpace(root); root != "" {
+			s.sourceRoots = append(s.sourceRoots, root)
+		}
+	}
+}
+
+// ResolveSourcePath 把客户端指定的源码路径解析为真实路径（解析符号链接），
+// 路径不在服务端配置的源码根目录下时返回错误
+func (s *Store) ResolveSourcePath(path string) (string, error) {
+	if len(s.sourceRoots) == 0 {
+		return "", fmt.Errorf("invalid path: no source root is configured on the server")
+	}
+	if strings.TrimSpace(path) == "" {
+		return "", fmt.Errorf("invalid path: source path cannot be empty")
+	}
+	resolved, err := realPath(path)
+	if err != nil {
+		return "", fmt.Errorf("invalid path: %w", err)
+	}
+	for _, root := range s.sourceRoots {
+		resolvedRoot, err := realPath(root)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(resolvedRoot, resolved)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("invalid path: %s is outside the configured source roots", path)
+}
+
+// realPath 返回路径的绝对路径并解析符号链接
+func realPath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(abs)
+}
@@ -2,18 +2,29 @@ package store
 
 import (
 	"fmt"
-	"math"
-	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
-	"time"
 
 	"github.com/ystyle/cangjie-mem/pkg/db"
+	"github.com/ystyle/cangjie-mem/pkg/embed"
+	"github.com/ystyle/cangjie-mem/pkg/semver"
+	"github.com/ystyle/cangjie-mem/pkg/signing"
 	"github.com/ystyle/cangjie-mem/pkg/types"
 )
 
 // Store 记忆存储
 type Store struct {
-	db *db.Database
+	db       *db.Database
+	embedder embed.Embedder  // 可选，用于混合检索
+	weights  *ScoringWeights // 置信度权重（为空时使用默认权重）
+
+	projectDeps bool     // 读取项目 cjpm.toml 的依赖（默认关闭）
+	registries  []string // 服务端配置的知识包仓库（未指定仓库的安装、升级只能使用这些仓库）
+	sourceRoots []string // 服务端配置的源码根目录（REST API 只能导入这些目录下的库源码和符号）
+
+	keyring          *signing.Keyring // 受信任的签名公钥（可选）
+	requireSignature bool             // 导入知识包时要求受信任的签名
 }
 
 // New 创建新的 Store
@@ -21,9 +32,23 @@ func New(database *db.Database) *Store {
 	return &Store{db: database}
 }
 
+// inTx 在一个事务中执行 fn：tx 是共享配置、读写都在事务中的 Store
+func (s *Store) inTx(fn func(tx *Store) error) error {
+	return s.db.InTx(func(db *db.Database) error {
+		tx := *s
+		tx.db = db
+		return fn(&tx)
+	})
+}
+
 // StoreMemory 存储记忆
 func (s *Store) StoreMemory(req types.StoreRequest) (*types.StoreResponse, error) {
-	return s.db.Store(req)
+	prepareDiagnosticStore(&req)
+	resp, err := s.db.Store(req)
+	if err == nil {
+		s.syncEmbeddings(resp.ID)
+	}
+	return resp, err
 }
 
 // RecallMemories 智能检索记忆
@@ -35,10 +60,38 @@ func (s *Store) RecallMemories(req types.RecallRequest) (*types.RecallResponse,
 		req.MaxResults = 10
 	}
 	if req.MinConfidence <= 0 {
-		req.MinConfidence = 0.5
+		req.MinConfidence = DefaultMinConfidence
+	}
+	if req.Mode == "" {
+		req.Mode = types.RecallModeKeyword
+	}
+	if req.Mode != types.RecallModeKeyword && req.Mode != types.RecallModeHybrid {
+		return nil, fmt.Errorf("invalid mode: %s", req.Mode)
+	}
+	if req.Mode == types.RecallModeHybrid && s.embedder == nil {
+		return nil, fmt.Errorf("hybrid mode requires an embedder")
 	}
 
-	ftsQuery := s.buildFTSQuery(req.Query)
+	parsed, err := parseQuery(req.Query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+	if err := parsed.applyFilters(&req); err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+	if req.SnippetFragments <= 0 {
+		req.SnippetFragments = DefaultSnippetFragments
+	}
+	if req.SnippetFragments > maxSnippetFragments {
+		req.SnippetFragments = maxSnippetFragments
+	}
+	markers, err := highlightMarkers(req.Highlight)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateVersion(req.LibraryVersion); err != nil {
+		return nil, err
+	}
 
 	// 确定搜索的层级和策略
 	var level types.KnowledgeLevel
@@ -50,30 +103,91 @@ func (s *Store) RecallMemories(req types.RecallRequest) (*types.RecallResponse,
 			return nil, fmt.Errorf("invalid level: %s", req.Level)
 		}
 		strategy = fmt.Sprintf("user_specified_%s", level)
+	} else if req.ProjectContext != "" {
+		// 分层检索：语言级 + 库级 + 匹配当前项目的项目级
+		strategy = "auto_determined_all_with_project"
 	} else {
 		strategy = "auto_determined_all"
 	}
 
-	results, err := s.db.Recall(ftsQuery, level, req.LanguageTag, req.ProjectContext, req.LibraryName, req.MaxResults*3)
+	opts := db.RecallOptions{
+		Level:          level,
+		LanguageTag:    req.LanguageTag,
+		ProjectPath:    req.ProjectContext,
+		LibraryName:    req.LibraryName,
+		LibraryVersion: req.LibraryVersion,
+		Tags:           req.Tags,
+		Limit:          req.MaxResults * 3,
+	}
+	// 项目声明了依赖时，库级记忆只保留这些依赖库的
+	deps := s.dependencyScope(req, level)
+	opts.Libraries = deps
+	// query 为实际命中的检索语句（放宽后会替换），片段和向量检索只使用其正向检索词
+	query := parsed
+	results, err := s.db.Recall(query.MatchExpression(), opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to recall memories: %w", err)
 	}
 
+	// 没有结果时逐步放宽：OR → 前缀 → 单个最强词
+	if len(results) == 0 {
+		for _, relaxed := range parsed.Relaxations() {
+			results, err = s.db.Recall(relaxed.Query.MatchExpression(), opts)
+			if err != nil {
+				return nil, fmt.Errorf("failed to recall memories: %w", err)
+			}
+			if len(results) > 0 {
+				strategy += "_" + relaxed.Name
+				query = relaxed.Query
+				break
+			}
+		}
+	}
+
+	hybrid := req.Mode == types.RecallModeHybrid
+	if hybrid {
+		results, err = s.hybridRecall(query.PlainText(), results, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to recall memories: %w", err)
+		}
+		strategy += "_hybrid"
+	}
+
+	normalizeRelevance(results, hybrid)
 	for i := range results {
-		results[i].Confidence = s.calculateConfidence(results[i], req.Query, req.ProjectContext)
-		results[i].MatchedText = s.extractMatchedText(results[i].Content, req.Query, 100)
+		confidence, explanation := s.calculateConfidence(results[i], req.ProjectContext)
+		results[i].Confidence = confidence
+		if req.Explain {
+			results[i].Explanation = explanation
+		}
 	}
 
-	filtered := s.filterAndSortResults(results, req.MinConfidence)
+	filtered := s.filterAndSortResults(results, req.MinConfidence, req.ProjectContext)
 
 	if len(filtered) > req.MaxResults {
 		filtered = filtered[:req.MaxResults]
 	}
 
+	terms := query.Terms()
+	for i := range filtered {
+		filtered[i].Snippets = buildSnippets(filtered[i].Content, terms, markers, DefaultSnippetLength, req.SnippetFragments)
+		filtered[i].MatchedText = joinSnippets(filtered[i].Snippets)
+	}
+
+	if req.IncludeRelated {
+		if err := s.attachRelated(filtered); err != nil {
+			return nil, fmt.Errorf("failed to load related memories: %w", err)
+		}
+	}
+
 	for _, r := range filtered {
 		_ = s.db.UpdateAccessCount(r.ID)
 	}
 
+	if len(deps) > 0 {
+		strategy += dependencyStrategy(deps)
+	}
+
 	return &types.RecallResponse{
 		Total:          len(filtered),
 		Results:        filtered,
@@ -120,97 +234,56 @@ func (s *Store) determineLevel(query string, projectPath string) types.Knowledge
 	return types.LevelLibrary
 }
 
-// calculateConfidence 计算置信度
-func (s *Store) calculateConfidence(result types.RecallResult, query string, projectPath string) float64 {
-	base := 0.5
-	queryLower := strings.ToLower(query)
-
-	// 1. 精确匹配加分
-	if strings.Contains(strings.ToLower(result.Title), queryLower) {
-		base = 1.0
-	} else if strings.Contains(strings.ToLower(result.Content), queryLower) {
-		base = 0.9
-	}
-
-	// 2. 项目上下文匹配
-	if result.Level == types.LevelProject && projectPath != "" {
-		if s.matchesProjectPattern(projectPath, result.ProjectPathPattern) {
-			base += 0.3
-		}
-	}
-
-	// 3. 语言级权威性
-	if result.Level == types.LevelLanguage {
-		base += 0.2
-	}
-
-	// 4. 来源可信度
-	if result.Source == types.SourceManual {
-		base += 0.1
-	}
-
-	// 5. 访问热度（轻微影响）
-	if result.AccessCount > 10 {
-		base += 0.05
-	}
-
-	return math.Min(base, 1.0)
-}
-
 // matchesProjectPattern 检查项目路径是否匹配模式
+// 与数据库检索使用的 SQLite GLOB 语义一致：* 可以跨越路径分隔符
 func (s *Store) matchesProjectPattern(projectPath, pattern string) bool {
 	if pattern == "" {
 		return false
 	}
 
-	// 简单的 GLOB 匹配
-	matched, err := filepath.Match(pattern, projectPath)
+	re, err := regexp.Compile(globToRegexp(pattern))
 	if err != nil {
 		return false
 	}
 
-	return matched
+	return re.MatchString(projectPath)
 }
 
-// extractMatchedText 提取匹配的文本片段
-func (s *Store) extractMatchedText(content, query string, maxLen int) string {
-	if len(content) <= maxLen {
-		return content
-	}
-
-	queryLower := strings.ToLower(query)
-	contentLower := strings.ToLower(content)
-
-	// 查找查询词在内容中的位置
-	idx := strings.Index(contentLower, queryLower)
-	if idx == -1 {
-		// 没找到，返回前 maxLen 个字符
-		return content[:maxLen] + "..."
-	}
-
-	// 找到了，提取周围的文本
-	start := idx - 50
-	if start < 0 {
-		start = 0
-	}
-	end := idx + len(query) + 50
-	if end > len(content) {
-		end = len(content)
-	}
-
-	text := content[start:end]
-	if start > 0 {
-		text = "..." + text
-	}
-	if end < len(content) {
-		text = text + "..."
+// globToRegexp 将 SQLite GLOB 模式转换为正则表达式
+func globToRegexp(pattern string) string {
+	var sb strings.Builder
+	sb.WriteString("^")
+	inClass := false
+	for _, r := range pattern {
+		switch {
+		case inClass:
+			if r == ']' {
+				inClass = false
+			}
+			if r == '\\' {
+				sb.WriteString(`\\`)
+				continue
+			}
+			sb.WriteRune(r)
+		case r == '*':
+			sb.WriteString(".*")
+		case r == '?':
+			sb.WriteString(".")
+		case r == '[':
+			inClass = true
+			sb.WriteRune(r)
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
 	}
-
-	return text
+	sb.WriteString("$")
+	return sb.String()
 }
 
 // filterAndSortResults 过滤并排序结果
-func (s *Store) filterAndSortResults(results []types.RecallResult, minConfidence float64) []types.RecallResult {
+// 按置信度降序；置信度相同时，传了项目路径则匹配该项目的项目级记忆优先，
+// 其余保持数据库返回的 language→library→project 顺序
+func (s *Store) filterAndSortResults(results []types.RecallResult, minConfidence float64, projectPath string) []types.RecallResult {
 	// 过滤
 	var filtered []types.RecallResult
 	for _, r := range results {
@@ -219,32 +292,20 @@ func (s *Store) filterAndSortResults(results []types.RecallResult, minConfidence
 		}
 	}
 
-	// 排序（按置信度降序）
-	for i := 0; i < len(filtered); i++ {
-		for j := i + 1; j < len(filtered); j++ {
-			if filtered[j].Confidence > filtered[i].Confidence {
-				filtered[i], filtered[j] = filtered[j], filtered[i]
-			}
-		}
-	}
-
-	return filtered
-}
-
-// buildFTSQuery 构建 FTS5 查询字符串（空格分隔的 AND 模式）
-func (s *Store) buildFTSQuery(query string) string {
-	// 按空格分割查询
-	words := strings.Fields(query)
-
-	// 如果没有关键词或只有一个，直接返回
-	if len(words) <= 1 {
-		return query
+	inProject := func(r types.RecallResult) bool {
+		return projectPath != "" && r.Level == types.LevelProject &&
+			s.matchesProjectPattern(projectPath, r.ProjectPathPattern)
 	}
 
-	// 用空格连接所有关键词（FTS5 默认 AND 模式）
-	cleanQuery := strings.Join(words, " ")
+	// 排序（稳定排序，保留同分结果的层级顺序）
+	sort.SliceStable(filtered, func(i, j int) bool {
+		if filtered[i].Confidence != filtered[j].Confidence {
+			return filtered[i].Confidence > filtered[j].Confidence
+		}
+		return inProject(filtered[i]) && !inProject(filtered[j])
+	})
 
-	return cleanQuery
+	return filtered
 }
 
 // ListMemories 列出记忆
@@ -259,10 +320,24 @@ func (s *Store) ListMemories(req types.ListRequest) (*types.ListResponse, error)
 	if req.OrderBy == "" {
 		req.OrderBy = "created_at"
 	}
+	if err := validateVersion(req.LibraryVersion); err != nil {
+		return nil, err
+	}
 
 	return s.db.List(req)
 }
 
+// validateVersion 验证检索时传入的具体库版本（如 1.2.3）
+func validateVersion(version string) error {
+	if version == "" {
+		return nil
+	}
+	if _, err := semver.Parse(version); err != nil {
+		return fmt.Errorf("invalid library_version: %w", err)
+	}
+	return nil
+}
+
 // ListCategories 列出所有库和项目分类
 func (s *Store) ListCategories(req types.ListCategoriesRequest) (*types.ListCategoriesResponse, error) {
 	if req.LanguageTag == "" {
@@ -274,7 +349,7 @@ func (s *Store) ListCategories(req types.ListCategoriesRequest) (*types.ListCate
 
 // DeleteMemory 删除记忆
 func (s *Store) DeleteMemory(req types.DeleteRequest) (*types.DeleteResponse, error) {
-	err := s.db.Delete(req.ID)
+	err := s.db.Delete(req.ID, req.Editor)
 	if err != nil {
 		return &types.DeleteResponse{
 			Success: false,
@@ -286,7 +361,7 @@ func (s *Store) DeleteMemory(req types.DeleteRequest) (*types.DeleteResponse, er
 	return &types.DeleteResponse{
 		Success: true,
 		ID:      req.ID,
-		Message: "记忆已成功删除",
+		Message: "记忆已移入回收站，可通过回收站恢复",
 	}, nil
 }
 
@@ -295,39 +370,63 @@ func (s *Store) GetMemory(id int64) (*types.Memory, error) {
 	return s.db.GetByID(id)
 }
 
-// UpdateMemory 更新记忆
-func (s *Store) UpdateMemory(id int64, req types.StoreRequest) (*types.Memory, error) {
-	return s.db.Update(id, req)
+// UpdateMemory 部分更新记忆（仅修改请求中提供的字段）
+func (s *Store) UpdateMemory(req types.UpdateRequest) (*types.Memory, error) {
+	if req.ID <= 0 {
+		return nil, fmt.Errorf("invalid id: %d", req.ID)
+	}
+	if req.IsEmpty() {
+		return nil, fmt.Errorf("no fields to update")
+	}
+	if err := s.prepareDiagnosticUpdate(&req); err != nil {
+		return nil, err
+	}
+	memory, err := s.db.Patch(req)
+	if err == nil {
+		s.syncEmbeddings(memory.ID)
+	}
+	return memory, err
 }
 
 // ExportMemories 导出记忆
-func (s *Store) ExportMemories(req types.ExportRequest) ([]types.StoreRequest, error) {
+func (s *Store) ExportMemories(req types.ExportRequest) ([]types.PackageMemory, error) {
 	return s.db.ExportForImport(req)
 }
 
-// PreviewImport 预览导入（检测冲突）
-func (s *Store) PreviewImport(memories []types.StoreRequest) (*types.ImportPreview, error) {
+// PreviewImport 预览导入（检测冲突），ImportID 由 CreateImportSession 设置为导入会话 ID
+func (s *Store) PreviewImport(memories []types.PackageMemory) (*types.ImportPreview, error) {
 	// 检测冲突
 	conflicts, err := s.db.FindConflicts(memories)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find conflicts: %w", err)
 	}
 
-	// 生成预览 ID
-	importID := fmt.Sprintf("import-%d", time.Now().Unix())
-
-	return &types.ImportPreview{
-		ImportID:  importID,
+	preview := &types.ImportPreview{
 		Total:     len(memories),
 		ToAdd:     len(memories) - len(conflicts),
-		ToUpdate:  len(conflicts),
 		Conflicts: conflicts,
-	}, nil
+	}
+	for _, c := range conflicts {
+		if len(c.Diffs) == 0 {
+			preview.ToSkip++
+		} else {
+			preview.ToUpdate++
+		}
+	}
+	return preview, nil
 }
 
-// ImportMemories 导入记忆
-func (s *Store) ImportMemories(memories []types.StoreRequest) (*types.ImportResult, error) {
-	return s.db.ImportMemories(memories)
+// ImportMemories 在一个事务中导入记忆（有失败时全部回滚），冲突按 opts 中的策略处理，试运行时只返回导入结果不写入
+func (s *Store) ImportMemories(memories []types.PackageMemory, opts types.ImportOptions) (*types.ImportResult, error) {
+	if err := validateImportOptions(opts, len(memories)); err != nil {
+		return nil, err
+	}
+
+	result, err := s.db.ImportMemories(memories, opts)
+	if err == nil && !result.RolledBack {
+		s.syncEmbeddings(importedIDs(result)...)
+	}
+	return result, err
 }
 
 // Close 关闭数据库连接
@@ -1,12 +1,21 @@
 package store
 
 import (
+	"crypto/ed25519"
+	"encoding/json"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ystyle/cangjie-mem/pkg/db"
+	"github.com/ystyle/cangjie-mem/pkg/embed"
+	"github.com/ystyle/cangjie-mem/pkg/registry"
+	"github.com/ystyle/cangjie-mem/pkg/signing"
 	"github.com/ystyle/cangjie-mem/pkg/types"
 )
 
@@ -348,3 +357,1454 @@ func TestDefaultValues(t *testing.T) {
 		t.Errorf("RecallMemories() with defaults error = %v", err)
 	}
 }
+
+func TestApproveSuggestion(t *testing.T) {
+	store := getTestStore(t)
+
+	resp, err := store.SuggestMemory(types.SuggestRequest{
+		Query:            "tang 中间件",
+		SuggestedTitle:   "Tang 中间件注册",
+		SuggestedContent: "使用 router.use() 注册中间件",
+		SuggestedLevel:   types.LevelLibrary,
+		LibraryName:      "tang",
+	})
+	if err != nil {
+		t.Fatalf("SuggestMemory() error = %v", err)
+	}
+	if resp.Status != types.SuggestionPending {
+		t.Errorf("SuggestMemory() status = %v, want %v", resp.Status, types.SuggestionPending)
+	}
+
+	// 批准失败（项目级缺少路径模式）时不生成记忆，建议仍待审核
+	if _, err := store.ApproveSuggestion(types.SuggestionReviewRequest{ID: resp.SuggestionID, Level: types.LevelProject}); err == nil {
+		t.Fatal("ApproveSuggestion() with invalid level error = nil")
+	}
+	if pending, _ := store.db.GetSuggestion(resp.SuggestionID); pending.Status != types.SuggestionPending {
+		t.Fatalf("suggestion status after failed approval = %v, want pending", pending.Status)
+	}
+
+	suggestion, err := store.ApproveSuggestion(types.SuggestionReviewRequest{
+		ID:     resp.SuggestionID,
+		Title:  "Tang 中间件",
+		Editor: types.EditorAPI,
+	})
+	if err != nil {
+		t.Fatalf("ApproveSuggestion() error = %v", err)
+	}
+	if suggestion.Status != types.SuggestionApproved || suggestion.MemoryID == 0 {
+		t.Fatalf("ApproveSuggestion() = %+v, want approved with memory id", suggestion)
+	}
+
+	memory, err := store.GetMemory(suggestion.MemoryID)
+	if err != nil {
+		t.Fatalf("GetMemory() error = %v", err)
+	}
+	if memory.Title != "Tang 中间件" || memory.LibraryName != "tang" {
+		t.Errorf("approved memory = %+v, want title override and library tang", memory)
+	}
+	// 审核者记录为修订历史的操作者
+	revisions, err := store.db.ListRevisions(suggestion.MemoryID)
+	if err != nil || len(revisions) != 1 || revisions[0].Editor != types.EditorAPI {
+		t.Errorf("ListRevisions() = %+v, %v, want one revision by %s", revisions, err, types.EditorAPI)
+	}
+
+	// 重复批准不会生成新记忆
+	if _, err := store.ApproveSuggestion(types.SuggestionReviewRequest{ID: resp.SuggestionID}); err == nil {
+		t.Error("ApproveSuggestion() should fail for already approved suggestion")
+	}
+}
+
+func TestRecallCJKSubstring(t *testing.T) {
+	store := getTestStore(t)
+
+	memories := []types.StoreRequest{
+		{
+			Level:              types.LevelProject,
+			ProjectPathPattern: "/test/*",
+			Title:              "日志配置位置",
+			Content:            "项目的日志配置位于 config/log.toml",
+		},
+		{
+			Level:   types.LevelLanguage,
+			Title:   "变量声明",
+			Content: "使用 let 声明不可变变量，var 声明可变变量",
+		},
+	}
+	for _, mem := range memories {
+		if _, err := store.StoreMemory(mem); err != nil {
+			t.Fatalf("failed to store test memory: %v", err)
+		}
+	}
+
+	tests := []struct {
+		query     string
+		wantCount int
+	}{
+		{"配置", 1},
+		{"日志配置", 1},
+		{"声明", 1},
+		{"let 声明", 1},
+		{"配置 声明", 2}, // AND 无结果，放宽为 OR
+		{"志位", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			resp, err := store.RecallMemories(types.RecallRequest{Query: tt.query})
+			if err != nil {
+				t.Fatalf("RecallMemories() error = %v", err)
+			}
+			if resp.Total != tt.wantCount {
+				t.Errorf("RecallMemories(%q) total = %v, want %v", tt.query, resp.Total, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestHybridRecall(t *testing.T) {
+	store := getTestStore(t)
+	store.SetEmbedder(embed.NewHashEmbedder(embed.DefaultDimensions))
+
+	memories := []types.StoreRequest{
+		{
+			Level:   types.LevelLanguage,
+			Title:   "变量声明",
+			Content: "使用 let 声明不可变变量，var 声明可变变量",
+		},
+		{
+			Level:   types.LevelLanguage,
+			Title:   "HTTP 客户端",
+			Content: "通过 net.http 包发起网络请求",
+		},
+	}
+	// 直接写入数据库的记忆没有向量，StoreMemory 只为新写入的记忆生成向量，其余留给回填
+	legacy, err := store.db.Store(types.StoreRequest{Level: types.LevelLanguage, Title: "旧记忆", Content: "尚未生成向量"})
+	if err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	for _, mem := range memories {
+		if _, err := store.StoreMemory(mem); err != nil {
+			t.Fatalf("failed to store test memory: %v", err)
+		}
+	}
+	if missing, _ := store.db.MissingEmbeddings(store.embedder.Name(), 10); len(missing) != 1 || missing[0].ID != legacy.ID {
+		t.Fatalf("MissingEmbeddings() = %+v, want only the legacy memory", missing)
+	}
+	// 已通知停止时不再处理
+	stop := make(chan struct{})
+	close(stop)
+	if n, err := store.BackfillEmbeddings(stop, 0); err != nil || n != 0 {
+		t.Fatalf("BackfillEmbeddings(stopped) = %d, %v, want 0", n, err)
+	}
+	if n, err := store.BackfillEmbeddings(nil, 0); err != nil || n != 1 {
+		t.Fatalf("BackfillEmbeddings() = %d, %v, want 1", n, err)
+	}
+
+	// 换一种说法提问：关键词 AND 匹配不到
+	query := "怎样声明不可变的变量"
+	resp, err := store.RecallMemories(types.RecallRequest{Query: query})
+	if err != nil {
+		t.Fatalf("RecallMemories() error = %v", err)
+	}
+	if resp.Total != 0 {
+		t.Fatalf("keyword RecallMemories() total = %v, want 0", resp.Total)
+	}
+
+	resp, err = store.RecallMemories(types.RecallRequest{Query: query, Mode: types.RecallModeHybrid})
+	if err != nil {
+		t.Fatalf("hybrid RecallMemories() error = %v", err)
+	}
+	if resp.Total != 1 || resp.Results[0].Title != "变量声明" {
+		t.Fatalf("hybrid RecallMemories() = %+v, want 变量声明", resp.Results)
+	}
+	if resp.Results[0].Score <= 0 {
+		t.Errorf("hybrid result score = %v, want > 0", resp.Results[0].Score)
+	}
+	if resp.SearchStrategy != "auto_determined_all_hybrid" {
+		t.Errorf("SearchStrategy = %v, want auto_determined_all_hybrid", resp.SearchStrategy)
+	}
+
+	// 关键词命中的结果排在前面
+	resp, err = store.RecallMemories(types.RecallRequest{Query: "声明", Mode: types.RecallModeHybrid})
+	if err != nil {
+		t.Fatalf("hybrid RecallMemories() error = %v", err)
+	}
+	if resp.Total == 0 || resp.Results[0].Title != "变量声明" {
+		t.Errorf("hybrid RecallMemories(声明) = %+v, want 变量声明 first", resp.Results)
+	}
+
+	// 未配置向量模型时拒绝混合检索
+	store.SetEmbedder(nil)
+	if _, err := store.RecallMemories(types.RecallRequest{Query: query, Mode: types.RecallModeHybrid}); err == nil {
+		t.Error("hybrid RecallMemories() without embedder should fail")
+	}
+	if _, err := store.RecallMemories(types.RecallRequest{Query: query, Mode: "fuzzy"}); err == nil {
+		t.Error("RecallMemories() with invalid mode should fail")
+	}
+}
+
+func TestRevisionDiffAndRestore(t *testing.T) {
+	store := getTestStore(t)
+
+	resp, err := store.StoreMemory(types.StoreRequest{
+		Level:   types.LevelLanguage,
+		Title:   "变量声明",
+		Content: "let 声明不可变变量\nvar 声明可变变量",
+	})
+	if err != nil {
+		t.Fatalf("StoreMemory() error = %v", err)
+	}
+
+	content := "let 声明不可变变量\nvar 声明可变变量（错误修改）"
+	if _, err := store.UpdateMemory(types.UpdateRequest{ID: resp.ID, Content: &content}); err != nil {
+		t.Fatalf("UpdateMemory() error = %v", err)
+	}
+
+	// 默认比较最新版本与上一版本
+	diffResp, err := store.DiffRevisions(resp.ID, 0, 0)
+	if err != nil {
+		t.Fatalf("DiffRevisions() error = %v", err)
+	}
+	if diffResp.From != 1 || diffResp.To != 2 {
+		t.Errorf("DiffRevisions() range = %d..%d, want 1..2", diffResp.From, diffResp.To)
+	}
+	for _, want := range []string{"--- revision 1", "+++ revision 2", "-var 声明可变变量\n", "+var 声明可变变量（错误修改）\n", " let 声明不可变变量\n"} {
+		if !strings.Contains(diffResp.Diff, want) {
+			t.Errorf("DiffRevisions() diff missing %q:\n%s", want, diffResp.Diff)
+		}
+	}
+
+	// 回滚后内容恢复，且产生新版本
+	memory, err := store.RestoreRevision(resp.ID, 1, types.EditorAPI)
+	if err != nil {
+		t.Fatalf("RestoreRevision() error = %v", err)
+	}
+	if memory.Content != "let 声明不可变变量\nvar 声明可变变量" {
+		t.Errorf("RestoreRevision() content = %q", memory.Content)
+	}
+	listResp, err := store.ListRevisions(resp.ID)
+	if err != nil {
+		t.Fatalf("ListRevisions() error = %v", err)
+	}
+	if listResp.Total != 3 || listResp.Revisions[0].Action != types.RevisionRestore {
+		t.Errorf("ListRevisions() = %+v, want 3 revisions with restore first", listResp)
+	}
+	if diffResp, _ := store.DiffRevisions(resp.ID, 1, 3); diffResp == nil || diffResp.Diff != "" {
+		t.Errorf("DiffRevisions(1, 3) should be empty after restore, got %+v", diffResp)
+	}
+
+	if _, err := store.ListRevisions(99999); err == nil {
+		t.Error("ListRevisions() for unknown memory should fail")
+	}
+}
+
+func TestTrashRetention(t *testing.T) {
+	store := getTestStore(t)
+
+	resp, err := store.StoreMemory(types.StoreRequest{Level: types.LevelLanguage, Title: "临时", Content: "稍后删除"})
+	if err != nil {
+		t.Fatalf("StoreMemory() error = %v", err)
+	}
+	if _, err := store.DeleteMemory(types.DeleteRequest{ID: resp.ID}); err != nil {
+		t.Fatalf("DeleteMemory() error = %v", err)
+	}
+
+	// 保留期内不会被清理，0 表示永久保留
+	if n, err := store.PurgeExpiredTrash(DefaultTrashRetention); err != nil || n != 0 {
+		t.Errorf("PurgeExpiredTrash(default) = %d, %v, want 0", n, err)
+	}
+	if n, err := store.PurgeExpiredTrash(0); err != nil || n != 0 {
+		t.Errorf("PurgeExpiredTrash(0) = %d, %v, want 0", n, err)
+	}
+
+	if _, err := store.RestoreMemory(resp.ID, types.EditorAPI); err != nil {
+		t.Fatalf("RestoreMemory() error = %v", err)
+	}
+	recallResp, err := store.RecallMemories(types.RecallRequest{Query: "临时"})
+	if err != nil {
+		t.Fatalf("RecallMemories() error = %v", err)
+	}
+	if recallResp.Total != 1 {
+		t.Errorf("RecallMemories() after restore total = %d, want 1", recallResp.Total)
+	}
+
+	if _, err := store.DeleteMemory(types.DeleteRequest{ID: resp.ID}); err != nil {
+		t.Fatalf("DeleteMemory() error = %v", err)
+	}
+	purgeResp, err := store.EmptyTrash()
+	if err != nil {
+		t.Fatalf("EmptyTrash() error = %v", err)
+	}
+	if purgeResp.Purged != 1 {
+		t.Errorf("EmptyTrash() purged = %d, want 1", purgeResp.Purged)
+	}
+	if _, err := store.RestoreMemory(resp.ID, ""); err == nil {
+		t.Error("RestoreMemory() after purge should fail")
+	}
+}
+
+func TestLayeredRecall(t *testing.T) {
+	store := getTestStore(t)
+
+	memories := []types.StoreRequest{
+		{
+			Level:   types.LevelLanguage,
+			Title:   "LAYER log 标准库",
+			Content: "std.log 提供日志接口",
+		},
+		{
+			Level:       types.LevelLibrary,
+			LibraryName: "tang",
+			Title:       "LAYER log 中间件",
+			Content:     "tang 的日志中间件",
+		},
+		{
+			Level:              types.LevelProject,
+			ProjectPathPattern: "/work/app/*",
+			Title:              "LAYER log 配置（app）",
+			Content:            "app 项目的日志配置位于 config/log.toml",
+		},
+		{
+			Level:              types.LevelProject,
+			ProjectPathPattern: "/work/other/*",
+			Title:              "LAYER log 配置（other）",
+			Content:            "other 项目的日志配置",
+		},
+	}
+	for _, mem := range memories {
+		if _, err := store.StoreMemory(mem); err != nil {
+			t.Fatalf("failed to store test memory: %v", err)
+		}
+	}
+
+	titles := func(results []types.RecallResult) []string {
+		var out []string
+		for _, r := range results {
+			out = append(out, r.Title)
+		}
+		return out
+	}
+
+	// 传 project_context：语言级、库级和匹配项目的项目级一起返回，当前项目的记忆优先
+	resp, err := store.RecallMemories(types.RecallRequest{
+		Query:          "LAYER log",
+		ProjectContext: "/work/app/src/main.cj",
+	})
+	if err != nil {
+		t.Fatalf("RecallMemories() error = %v", err)
+	}
+	got := titles(resp.Results)
+	want := []string{"LAYER log 配置（app）", "LAYER log 标准库", "LAYER log 中间件"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("RecallMemories() with project = %v, want %v", got, want)
+	}
+	if resp.SearchStrategy != "auto_determined_all_with_project" {
+		t.Errorf("SearchStrategy = %v, want auto_determined_all_with_project", resp.SearchStrategy)
+	}
+
+	// 不传 project_context：所有层级都参与，按 language→library→project 排序
+	resp, err = store.RecallMemories(types.RecallRequest{Query: "LAYER log"})
+	if err != nil {
+		t.Fatalf("RecallMemories() error = %v", err)
+	}
+	if resp.Total != 4 || resp.Results[0].Level != types.LevelLanguage || resp.Results[1].Level != types.LevelLibrary {
+		t.Errorf("RecallMemories() without project = %v", titles(resp.Results))
+	}
+
+	// 项目路径不匹配任何项目：只返回语言级和库级
+	resp, err = store.RecallMemories(types.RecallRequest{
+		Query:          "LAYER log",
+		ProjectContext: "/elsewhere/demo",
+	})
+	if err != nil {
+		t.Fatalf("RecallMemories() error = %v", err)
+	}
+	if resp.Total != 2 {
+		t.Errorf("RecallMemories() with unknown project = %v, want language and library only", titles(resp.Results))
+	}
+
+	// 显式指定层级时仍按层级过滤
+	resp, err = store.RecallMemories(types.RecallRequest{
+		Query:          "LAYER log",
+		Level:          "library",
+		ProjectContext: "/work/app/src",
+	})
+	if err != nil {
+		t.Fatalf("RecallMemories() error = %v", err)
+	}
+	if resp.Total != 1 || resp.Results[0].Level != types.LevelLibrary {
+		t.Errorf("RecallMemories() level=library = %v", titles(resp.Results))
+	}
+}
+
+func TestMatchesProjectPattern(t *testing.T) {
+	store := &Store{}
+
+	tests := []struct {
+		path    string
+		pattern string
+		want    bool
+	}{
+		{"/work/app/src/main.cj", "/work/app/*", true},
+		{"/work/app", "/work/app/*", false},
+		{"/work/app", "/work/app", true},
+		{"/work/app1/src", "/work/app?/*", true},
+		{"/work/appx/src", "/work/app[0-9]/*", false},
+		{"/work/a.b/src", "/work/a.b/*", true},
+		{"/work/axb/src", "/work/a.b/*", false},
+		{"/work/app", "", false},
+	}
+
+	for _, tt := range tests {
+		if got := store.matchesProjectPattern(tt.path, tt.pattern); got != tt.want {
+			t.Errorf("matchesProjectPattern(%q, %q) = %v, want %v", tt.path, tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestRecallQueryDSL(t *testing.T) {
+	store := getTestStore(t)
+
+	memories := []types.StoreRequest{
+		{
+			Level:   types.LevelLanguage,
+			Title:   "DSL 日志 配置",
+			Content: "std.log 的 logger 配置方式",
+			Tags:    []string{"log"},
+		},
+		{
+			Level:   types.LevelLanguage,
+			Title:   "DSL 调试 输出",
+			Content: "debug 模式下的 println 输出",
+		},
+		{
+			Level:       types.LevelLibrary,
+			LibraryName: "tang",
+			Title:       "DSL 路由 分组",
+			Content:     "tang 路由分组和 logger 中间件",
+			Tags:        []string{"web"},
+		},
+	}
+	for _, mem := range memories {
+		if _, err := store.StoreMemory(mem); err != nil {
+			t.Fatalf("failed to store test memory: %v", err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"DSL 日志 OR 调试", []string{"DSL 日志 配置", "DSL 调试 输出"}},
+		{`DSL "路由分组"`, []string{"DSL 路由 分组"}},
+		{"DSL logg*", []string{"DSL 日志 配置", "DSL 路由 分组"}},
+		{"DSL logger -中间件", []string{"DSL 日志 配置"}},
+		{"title:DSL title:路由", []string{"DSL 路由 分组"}},
+		{"DSL library:tang", []string{"DSL 路由 分组"}},
+		{"DSL level:library", []string{"DSL 路由 分组"}},
+		{"DSL tag:log", []string{"DSL 日志 配置"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			resp, err := store.RecallMemories(types.RecallRequest{Query: tt.query})
+			if err != nil {
+				t.Fatalf("RecallMemories(%q) error = %v", tt.query, err)
+			}
+			var got []string
+			for _, r := range resp.Results {
+				got = append(got, r.Title)
+			}
+			sort.Strings(got)
+			want := append([]string(nil), tt.want...)
+			sort.Strings(want)
+			if strings.Join(got, "|") != strings.Join(want, "|") {
+				t.Errorf("RecallMemories(%q) = %v, want %v", tt.query, got, want)
+			}
+		})
+	}
+
+	// 语法错误返回明确的错误而不是 SQL 错误
+	if _, err := store.RecallMemories(types.RecallRequest{Query: `DSL "x`}); err == nil || !strings.Contains(err.Error(), "invalid query: unterminated quote") {
+		t.Errorf("RecallMemories() error = %v, want invalid query", err)
+	}
+
+	// 检索语句中的过滤条件与请求参数冲突
+	if _, err := store.RecallMemories(types.RecallRequest{Query: "DSL level:library", Level: "language"}); err == nil {
+		t.Error("RecallMemories() expected conflict error")
+	}
+}
+
+func TestRecallRelaxation(t *testing.T) {
+	store := getTestStore(t)
+
+	memories := []types.StoreRequest{
+		{
+			Level:   types.LevelLanguage,
+			Title:   "RELAX 接口定义",
+			Content: "使用 interface 关键字定义接口",
+		},
+		{
+			Level:   types.LevelLanguage,
+			Title:   "RELAX 泛型约束",
+			Content: "where 子句声明 generic 约束",
+		},
+	}
+	for _, mem := range memories {
+		if _, err := store.StoreMemory(mem); err != nil {
+			t.Fatalf("failed to store test memory: %v", err)
+		}
+	}
+
+	tests := []struct {
+		query     string
+		wantCount int
+		strategy  string
+	}{
+		{"RELAX interface", 1, "auto_determined_all"},
+		{"interface generic", 2, "auto_determined_all_relaxed_or"},
+		{"interf gener", 2, "auto_determined_all_relaxed_prefix"},
+		{"title:interface", 1, "auto_determined_all_relaxed_single_term"},
+		{"interface -接口", 0, "auto_determined_all"},
+		{"nothing matches", 0, "auto_determined_all"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			resp, err := store.RecallMemories(types.RecallRequest{Query: tt.query})
+			if err != nil {
+				t.Fatalf("RecallMemories(%q) error = %v", tt.query, err)
+			}
+			if resp.Total != tt.wantCount {
+				t.Errorf("RecallMemories(%q) total = %v, want %v", tt.query, resp.Total, tt.wantCount)
+			}
+			if resp.SearchStrategy != tt.strategy {
+				t.Errorf("RecallMemories(%q) strategy = %v, want %v", tt.query, resp.SearchStrategy, tt.strategy)
+			}
+		})
+	}
+}
+
+func TestConfidenceScoring(t *testing.T) {
+	store := getTestStore(t)
+
+	memories := []types.StoreRequest{
+		{
+			Level:   types.LevelLanguage,
+			Title:   "SCORE 泛型",
+			Content: "泛型函数的声明方式，与 SCORE 无关的长篇内容，用来降低 bm25 相关度，再补充一些说明文字",
+		},
+		{
+			Level:   types.LevelLanguage,
+			Title:   "SCORE SCORE 泛型约束",
+			Content: "SCORE 泛型约束 SCORE",
+		},
+		{
+			Level:   types.LevelLibrary,
+			Title:   "SCORE 库",
+			Content: "SCORE",
+			Source:  types.SourceAutoCaptured,
+		},
+	}
+	for _, mem := range memories {
+		if _, err := store.StoreMemory(mem); err != nil {
+			t.Fatalf("failed to store test memory: %v", err)
+		}
+	}
+
+	resp, err := store.RecallMemories(types.RecallRequest{Query: "SCORE 泛型", Explain: true})
+	if err != nil {
+		t.Fatalf("RecallMemories() error = %v", err)
+	}
+	if resp.Total != 2 {
+		t.Fatalf("RecallMemories() total = %d, want 2", resp.Total)
+	}
+
+	// 同层级按 BM25 相关度排序，置信度不再全部并列
+	if resp.Results[0].Title != "SCORE SCORE 泛型约束" {
+		t.Errorf("first result = %s, want SCORE SCORE 泛型约束", resp.Results[0].Title)
+	}
+	if resp.Results[0].Confidence <= resp.Results[1].Confidence {
+		t.Errorf("confidences = %v, %v, want strictly decreasing", resp.Results[0].Confidence, resp.Results[1].Confidence)
+	}
+
+	// explain 明细：各因子贡献之和等于置信度
+	for _, r := range resp.Results {
+		if r.Explanation == nil {
+			t.Fatalf("result %d missing explanation", r.ID)
+		}
+		var sum float64
+		names := make([]string, 0, len(r.Explanation.Factors))
+		for _, f := range r.Explanation.Factors {
+			sum += f.Contribution
+			names = append(names, f.Name)
+		}
+		if math.Abs(sum-r.Confidence) > 0.001 {
+			t.Errorf("result %d contributions sum = %v, confidence = %v", r.ID, sum, r.Confidence)
+		}
+		if got := strings.Join(names, ","); got != "relevance,level,source,access,project" {
+			t.Errorf("factors = %s", got)
+		}
+		if r.Explanation.BM25 >= 0 {
+			t.Errorf("result %d bm25 = %v, want negative", r.ID, r.Explanation.BM25)
+		}
+	}
+
+	// 不传 explain 时不返回明细
+	resp, _ = store.RecallMemories(types.RecallRequest{Query: "SCORE"})
+	for _, r := range resp.Results {
+		if r.Explanation != nil {
+			t.Errorf("result %d has explanation without explain=true", r.ID)
+		}
+	}
+
+	// 只看相关度时，库级的短文档排在最前
+	if err := store.SetScoringWeights(ScoringWeights{Relevance: 1}); err != nil {
+		t.Fatalf("SetScoringWeights() error = %v", err)
+	}
+	resp, _ = store.RecallMemories(types.RecallRequest{Query: "SCORE", MinConfidence: 0.01})
+	if resp.Total != 3 || resp.Results[0].Title != "SCORE 库" {
+		t.Errorf("relevance-only ranking = %+v", resp.Results)
+	}
+	if err := store.SetScoringWeights(ScoringWeights{}); err == nil {
+		t.Error("SetScoringWeights() with all-zero weights expected error")
+	}
+}
+
+func TestConfidenceWeakMatch(t *testing.T) {
+	store := getTestStore(t)
+
+	// 同时命中一条强相关和一条弱相关的库级记忆（自动捕获、无访问），弱命中的相对相关度很低
+	memories := []types.StoreRequest{
+		{
+			Level:       types.LevelLibrary,
+			LibraryName: "tang",
+			Title:       "WEAKMATCH 路由 WEAKMATCH",
+			Content:     "WEAKMATCH WEAKMATCH WEAKMATCH",
+			Source:      types.SourceAutoCaptured,
+		},
+		{
+			Level:       types.LevelLibrary,
+			LibraryName: "tang",
+			Title:       "中间件",
+			Content:     "WEAKMATCH " + strings.Repeat("中间件按注册顺序执行，可以在处理请求前后插入逻辑。", 40),
+			Source:      types.SourceAutoCaptured,
+		},
+	}
+	for _, mem := range memories {
+		if _, err := store.StoreMemory(mem); err != nil {
+			t.Fatalf("failed to store test memory: %v", err)
+		}
+	}
+
+	resp, err := store.RecallMemories(types.RecallRequest{Query: "WEAKMATCH", Explain: true})
+	if err != nil {
+		t.Fatalf("RecallMemories() error = %v", err)
+	}
+	if resp.Total != 2 {
+		t.Fatalf("RecallMemories() total = %d, want both the strong and the weak match: %+v", resp.Total, resp.Results)
+	}
+	weak := resp.Results[1]
+	if weak.Title != "中间件" || weak.Explanation.Factors[0].Value >= 0.36 {
+		t.Errorf("weak match = %s, relevance %v, want 中间件 with relevance < 0.36", weak.Title, weak.Explanation.Factors[0].Value)
+	}
+}
+
+func TestParseScoringWeights(t *testing.T) {
+	w, err := ParseScoringWeights("relevance=0.8, project=0")
+	if err != nil {
+		t.Fatalf("ParseScoringWeights() error = %v", err)
+	}
+	want := DefaultScoringWeights()
+	want.Relevance, want.Project = 0.8, 0
+	if w != want {
+		t.Errorf("ParseScoringWeights() = %+v, want %+v", w, want)
+	}
+
+	for _, s := range []string{"relevance", "speed=1", "level=abc", "level=-1", "relevance=0,level=0,source=0,access=0,project=0"} {
+		if _, err := ParseScoringWeights(s); err == nil {
+			t.Errorf("ParseScoringWeights(%q) expected error", s)
+		}
+	}
+}
+
+func TestMemoryGraph(t *testing.T) {
+	store := getTestStore(t)
+
+	var ids []int64
+	for _, title := range []string{"GRAPH 路由", "GRAPH 中间件", "GRAPH 上下文", "GRAPH 旧版路由"} {
+		resp, err := store.StoreMemory(types.StoreRequest{Level: types.LevelLibrary, LibraryName: "tang", Title: title, Content: title})
+		if err != nil {
+			t.Fatalf("StoreMemory() error = %v", err)
+		}
+		ids = append(ids, resp.ID)
+	}
+	routing, middleware, ctx, legacy := ids[0], ids[1], ids[2], ids[3]
+
+	links := []types.LinkRequest{
+		{SourceID: routing, TargetID: middleware, Relation: types.RelationDependsOn},
+		{SourceID: middleware, TargetID: ctx, Relation: types.RelationDependsOn},
+		{SourceID: routing, TargetID: legacy, Relation: types.RelationSupersedes},
+	}
+	for _, l := range links {
+		if _, err := store.LinkMemories(l); err != nil {
+			t.Fatalf("LinkMemories() error = %v", err)
+		}
+	}
+
+	// 参数校验
+	if _, err := store.LinkMemories(types.LinkRequest{SourceID: routing, TargetID: routing, Relation: types.RelationSeeAlso}); err == nil {
+		t.Error("LinkMemories() self link expected error")
+	}
+	if _, err := store.LinkMemories(types.LinkRequest{SourceID: routing, TargetID: ctx, Relation: "blocks"}); err == nil {
+		t.Error("LinkMemories() invalid relation expected error")
+	}
+
+	nodeIDs := func(resp *types.GraphResponse) map[int64]int {
+		out := map[int64]int{}
+		for _, n := range resp.Nodes {
+			out[n.ID] = n.Depth
+		}
+		return out
+	}
+
+	tests := []struct {
+		name string
+		req  types.GraphRequest
+		want map[int64]int
+	}{
+		{"默认一跳", types.GraphRequest{ID: routing}, map[int64]int{routing: 0, middleware: 1, legacy: 1}},
+		{"两跳依赖", types.GraphRequest{ID: routing, Depth: 2, Relation: types.RelationDependsOn, Direction: types.LinkDirectionOutgoing}, map[int64]int{routing: 0, middleware: 1, ctx: 2}},
+		{"反向", types.GraphRequest{ID: legacy, Direction: types.LinkDirectionIncoming}, map[int64]int{legacy: 0, routing: 1}},
+		{"方向不符", types.GraphRequest{ID: legacy, Direction: types.LinkDirectionOutgoing}, map[int64]int{legacy: 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := store.MemoryGraph(tt.req)
+			if err != nil {
+				t.Fatalf("MemoryGraph() error = %v", err)
+			}
+			got := nodeIDs(resp)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("MemoryGraph() nodes = %v, want %v", got, tt.want)
+			}
+			if len(resp.Links) != len(tt.want)-1 {
+				t.Errorf("MemoryGraph() links = %d, want %d", len(resp.Links), len(tt.want)-1)
+			}
+		})
+	}
+
+	// 检索结果附带一跳关联记忆
+	resp, err := store.RecallMemories(types.RecallRequest{Query: "GRAPH 中间件", IncludeRelated: true})
+	if err != nil {
+		t.Fatalf("RecallMemories() error = %v", err)
+	}
+	if resp.Total != 1 {
+		t.Fatalf("RecallMemories() total = %d, want 1", resp.Total)
+	}
+	related := map[int64]string{}
+	for _, r := range resp.Results[0].Related {
+		related[r.ID] = string(r.Relation) + "/" + r.Direction
+	}
+	want := map[int64]string{routing: "depends_on/incoming", ctx: "depends_on/outgoing"}
+	if fmt.Sprint(related) != fmt.Sprint(want) {
+		t.Errorf("Related = %v, want %v", related, want)
+	}
+
+	// 删除关系
+	if unlinked, err := store.UnlinkMemories(types.LinkRequest{SourceID: routing, TargetID: legacy}); err != nil || unlinked.Removed != 1 {
+		t.Errorf("UnlinkMemories() = %+v, %v", unlinked, err)
+	}
+}
+
+func TestRecallLibraryVersion(t *testing.T) {
+	store := getTestStore(t)
+
+	for _, req := range []types.StoreRequest{
+		{Level: types.LevelLibrary, LibraryName: "tang", LibraryVersion: "<1.0", Title: "VERSIONED 中间件注册", Content: "0.x 版本通过 addMiddleware 注册"},
+		{Level: types.LevelLibrary, LibraryName: "tang", LibraryVersion: "^1.0", Title: "VERSIONED 中间件注册", Content: "1.x 版本通过 use 注册"},
+	} {
+		if _, err := store.StoreMemory(req); err != nil {
+			t.Fatalf("StoreMemory() error = %v", err)
+		}
+	}
+
+	resp, err := store.RecallMemories(types.RecallRequest{Query: "VERSIONED", LibraryVersion: "1.2.0", MinConfidence: 0.01})
+	if err != nil {
+		t.Fatalf("RecallMemories() error = %v", err)
+	}
+	if resp.Total != 1 || resp.Results[0].LibraryVersion != "^1.0" {
+		t.Errorf("RecallMemories(1.2.0) = %+v, want only ^1.0", resp.Results)
+	}
+
+	resp, _ = store.RecallMemories(types.RecallRequest{Query: "VERSIONED", MinConfidence: 0.01})
+	if resp.Total != 2 {
+		t.Errorf("RecallMemories() without version total = %d, want 2", resp.Total)
+	}
+
+	if _, err := store.RecallMemories(types.RecallRequest{Query: "VERSIONED", LibraryVersion: "^1.0"}); err == nil || !strings.Contains(err.Error(), "invalid library_version") {
+		t.Errorf("RecallMemories() range as version error = %v", err)
+	}
+	if _, err := store.ListMemories(types.ListRequest{LibraryVersion: "latest"}); err == nil {
+		t.Error("ListMemories() invalid version expected error")
+	}
+}
+
+func TestRecallProjectDependencies(t *testing.T) {
+	store := getTestStore(t)
+
+	projectDir := t.TempDir()
+	manifest := "[package]\n  name = \"demo\"\n\n[dependencies]\n  tang = { git = \"https://github.com/ystyle/tang\" }\n  json = \"1.0.0\"\n"
+	if err := os.WriteFile(filepath.Join(projectDir, "cjpm.toml"), []byte(manifest), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	for _, req := range []types.StoreRequest{
+		{Level: types.LevelLibrary, LibraryName: "tang", Title: "DEPSCOPE 路由", Content: "tang 路由注册"},
+		{Level: types.LevelLibrary, LibraryName: "http", Title: "DEPSCOPE 请求", Content: "http 客户端请求"},
+		{Level: types.LevelLanguage, Title: "DEPSCOPE 语法", Content: "语言级记忆不受依赖影响"},
+		{Level: types.LevelLibrary, Title: "DEPSCOPE 通用", Content: "未指定库名的库级记忆不受依赖影响"},
+	} {
+		if _, err := store.StoreMemory(req); err != nil {
+			t.Fatalf("StoreMemory() error = %v", err)
+		}
+	}
+
+	// 默认不读取客户端传入路径下的 cjpm.toml
+	resp, err := store.RecallMemories(types.RecallRequest{Query: "DEPSCOPE", ProjectContext: projectDir, MinConfidence: 0.01})
+	if err != nil || resp.Total != 4 || strings.Contains(resp.SearchStrategy, "cjpm") {
+		t.Fatalf("RecallMemories() with dependencies disabled = %+v, %v", resp, err)
+	}
+	store.SetProjectDependencies(true)
+
+	libraries := func(resp *types.RecallResponse) map[string]bool {
+		out := map[string]bool{}
+		for _, r := range resp.Results {
+			out[string(r.Level)+":"+r.LibraryName] = true
+		}
+		return out
+	}
+
+	// 项目依赖 tang 和 json：http 库的记忆被排除，语言级和未指定库名的库级记忆保留
+	resp, err = store.RecallMemories(types.RecallRequest{Query: "DEPSCOPE", ProjectContext: projectDir, MinConfidence: 0.01})
+	if err != nil {
+		t.Fatalf("RecallMemories() error = %v", err)
+	}
+	got := libraries(resp)
+	if len(got) != 3 || !got["library:tang"] || !got["language:"] || !got["library:"] {
+		t.Errorf("RecallMemories() with cjpm.toml = %v", got)
+	}
+	if !strings.HasSuffix(resp.SearchStrategy, "_cjpm_deps(tang,json)") {
+		t.Errorf("SearchStrategy = %q, want dependency suffix", resp.SearchStrategy)
+	}
+
+	// 显式指定库名时不按依赖限定
+	resp, _ = store.RecallMemories(types.RecallRequest{Query: "DEPSCOPE", ProjectContext: projectDir, LibraryName: "http", MinConfidence: 0.01})
+	if got := libraries(resp); !got["library:http"] || strings.Contains(resp.SearchStrategy, "cjpm") {
+		t.Errorf("RecallMemories(library_name=http) = %v, strategy %q", got, resp.SearchStrategy)
+	}
+
+	// 没有 cjpm.toml 或关闭依赖读取时不限定
+	resp, _ = store.RecallMemories(types.RecallRequest{Query: "DEPSCOPE", ProjectContext: t.TempDir(), MinConfidence: 0.01})
+	if resp.Total != 4 || strings.Contains(resp.SearchStrategy, "cjpm") {
+		t.Errorf("RecallMemories() without cjpm.toml total = %d, strategy %q", resp.Total, resp.SearchStrategy)
+	}
+	store.SetProjectDependencies(false)
+	resp, _ = store.RecallMemories(types.RecallRequest{Query: "DEPSCOPE", ProjectContext: projectDir, MinConfidence: 0.01})
+	if resp.Total != 4 {
+		t.Errorf("RecallMemories() with dependencies disabled total = %d, want 4", resp.Total)
+	}
+}
+
+func TestDiagnose(t *testing.T) {
+	store := getTestStore(t)
+
+	// 存储时直接粘贴错误信息，签名被规范化并自动添加 diagnostic 标签
+	resp, err := store.StoreMemory(types.StoreRequest{
+		Level:               types.LevelLanguage,
+		Title:               "未声明的标识符",
+		Content:             "检查是否缺少 import 或拼写错误",
+		DiagnosticSignature: "error: undeclared identifier 'foo'",
+	})
+	if err != nil {
+		t.Fatalf("StoreMemory() error = %v", err)
+	}
+	memory, _ := store.GetMemory(resp.ID)
+	if memory.DiagnosticSignature != "undeclared identifier <id>" {
+		t.Errorf("DiagnosticSignature = %q", memory.DiagnosticSignature)
+	}
+	if len(memory.Tags) != 1 || memory.Tags[0] != types.TagDiagnostic {
+		t.Errorf("Tags = %v, want [%s]", memory.Tags, types.TagDiagnostic)
+	}
+
+	codeResp, _ := store.StoreMemory(types.StoreRequest{
+		Level:               types.LevelLanguage,
+		Title:               "类型不匹配",
+		Content:             "使用显式类型转换",
+		DiagnosticSignature: "error[E0012]: mismatched types 'Int64' and 'String'",
+	})
+	textResp, _ := store.StoreMemory(types.StoreRequest{
+		Level:   types.LevelLanguage,
+		Title:   "unused variable 警告",
+		Content: "unused variable 可以用下划线命名消除",
+		Tags:    []string{types.TagDiagnostic},
+	})
+
+	output := "error: undeclared identifier 'barBaz'\n ==> src/main.cj:3:5:\n" +
+		"error[E0012]: mismatched types 'Float64' and 'Int32'\n" +
+		"error[E0012]: cannot convert 'a' to 'b'\n" +
+		"warning: unused variable 'x'\n"
+
+	result, err := store.Diagnose(types.DiagnoseRequest{Output: output})
+	if err != nil {
+		t.Fatalf("Diagnose() error = %v", err)
+	}
+	if result.Total != 3 || result.Matched != 3 {
+		t.Fatalf("Diagnose() total = %d, matched = %d, want 3, 3", result.Total, result.Matched)
+	}
+	first := result.Results[0]
+	if first.File != "src/main.cj" || len(first.Fixes) != 1 || first.Fixes[0].ID != resp.ID || first.Fixes[0].Match != types.DiagnosticMatchSignature {
+		t.Errorf("Diagnose() first result = %+v", first)
+	}
+	if fixes := result.Results[1].Fixes; len(fixes) != 1 || fixes[0].ID != codeResp.ID || fixes[0].Match != types.DiagnosticMatchSignature {
+		t.Errorf("Diagnose() signature match = %+v", fixes)
+	}
+	if fixes := result.Results[2].Fixes; len(fixes) != 1 || fixes[0].ID != codeResp.ID || fixes[0].Match != types.DiagnosticMatchCode {
+		t.Errorf("Diagnose() code match = %+v", fixes)
+	}
+
+	// 包含警告时，警告通过全文检索匹配 diagnostic 标签的记忆
+	result, _ = store.Diagnose(types.DiagnoseRequest{Output: output, IncludeWarnings: true})
+	warning := result.Results[len(result.Results)-1]
+	if warning.Severity != "warning" || len(warning.Fixes) != 1 || warning.Fixes[0].ID != textResp.ID || warning.Fixes[0].Match != types.DiagnosticMatchText {
+		t.Errorf("Diagnose() warning result = %+v", warning)
+	}
+
+	if _, err := store.Diagnose(types.DiagnoseRequest{Output: "  "}); err == nil || !strings.HasPrefix(err.Error(), "invalid") {
+		t.Errorf("Diagnose(empty) error = %v", err)
+	}
+}
+
+func TestDiagnoseProjectDependencies(t *testing.T) {
+	store := getTestStore(t)
+	store.SetProjectDependencies(true)
+
+	projectDir := t.TempDir()
+	manifest := "[package]\n  name = \"demo\"\n\n[dependencies]\n  tang = \"1.0.0\"\n"
+	if err := os.WriteFile(filepath.Join(projectDir, "cjpm.toml"), []byte(manifest), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	// 未指定库名的库级诊断记忆不受项目依赖限定，非依赖库的诊断记忆被排除
+	unnamed, err := store.StoreMemory(types.StoreRequest{
+		Level:               types.LevelLibrary,
+		Title:               "宏展开失败",
+		Content:             "检查宏包是否已在 cjpm.toml 中声明",
+		DiagnosticSignature: "error: macro expansion failed",
+	})
+	if err != nil {
+		t.Fatalf("StoreMemory() error = %v", err)
+	}
+	if _, err := store.StoreMemory(types.StoreRequest{
+		Level:               types.LevelLibrary,
+		LibraryName:         "http",
+		Title:               "http 宏展开失败",
+		Content:             "升级 http 库",
+		DiagnosticSignature: "error: macro expansion failed",
+	}); err != nil {
+		t.Fatalf("StoreMemory() error = %v", err)
+	}
+
+	result, err := store.Diagnose(types.DiagnoseRequest{Output: "error: macro expansion failed\n", ProjectContext: projectDir})
+	if err != nil {
+		t.Fatalf("Diagnose() error = %v", err)
+	}
+	if result.Matched != 1 || len(result.Results[0].Fixes) != 1 || result.Results[0].Fixes[0].ID != unnamed.ID {
+		t.Errorf("Diagnose() with cjpm.toml = %+v, want only the unnamed library memory", result.Results)
+	}
+}
+
+func TestImportSymbols(t *testing.T) {
+	store := getTestStore(t)
+
+	root := t.TempDir()
+	source := "package tang\n\n/** 路由器 */\npublic class Router {\n    /** 注册 GET 路由 */\n    public func get(path: String): Router { this }\n}\n\npublic func newRouter(): Router { Router() }\n"
+	if err := os.WriteFile(filepath.Join(root, "router.cj"), []byte(source), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	req := types.SymbolImportRequest{Path: root, LibraryName: "tang", CreateMemories: true}
+	result, err := store.ImportSymbols(req)
+	if err != nil {
+		t.Fatalf("ImportSymbols() error = %v", err)
+	}
+	if result.Files != 1 || result.Symbols != 3 || result.MemoriesAdded != 2 || result.MemoriesUpdated != 0 {
+		t.Errorf("ImportSymbols() = %+v", result)
+	}
+
+	resp, err := store.LookupSymbols(types.SymbolRequest{Name: "Router.get"})
+	if err != nil {
+		t.Fatalf("LookupSymbols() error = %v", err)
+	}
+	if resp.Total != 1 || resp.Symbols[0].Signature != "public func get(path: String): Router" || resp.Symbols[0].MemoryTitle != "tang.Router" {
+		t.Fatalf("LookupSymbols() = %+v", resp.Symbols)
+	}
+	memory, _ := store.GetMemory(resp.Symbols[0].KnowledgeID)
+	if memory.Level != types.LevelLibrary || memory.LibraryName != "tang" || memory.Summary != "路由器" ||
+		!strings.Contains(memory.Content, "    // 注册 GET 路由\n    public func get(path: String): Router") {
+		t.Errorf("symbol memory = %+v", memory)
+	}
+
+	// 重复导入更新原记忆，不新建
+	result, err = store.ImportSymbols(req)
+	if err != nil {
+		t.Fatalf("ImportSymbols() again error = %v", err)
+	}
+	if result.MemoriesAdded != 0 || result.MemoriesUpdated != 2 {
+		t.Errorf("ImportSymbols() again = %+v", result)
+	}
+	list, _ := store.ListMemories(types.ListRequest{LibraryName: "tang"})
+	if list.Total != 2 {
+		t.Errorf("memories after reimport = %d, want 2", list.Total)
+	}
+
+	if _, err := store.LookupSymbols(types.SymbolRequest{Name: " "}); err == nil || !strings.HasPrefix(err.Error(), "invalid") {
+		t.Errorf("LookupSymbols(empty) error = %v", err)
+	}
+	if _, err := store.ImportSymbols(types.SymbolImportRequest{Path: filepath.Join(root, "missing")}); err == nil || !strings.HasPrefix(err.Error(), "invalid") {
+		t.Errorf("ImportSymbols(missing) error = %v", err)
+	}
+}
+
+func TestIngestLibrary(t *testing.T) {
+	store := getTestStore(t)
+
+	root := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	write("README.md", "# Tang\n\n轻量级 Web 框架。\n\n## 安装\n\n在 cjpm.toml 中添加依赖。\n")
+	write("docs/guide/middleware.md", "# 中间件\n\n使用 use 注册中间件。\n")
+	write("CHANGELOG.md", "# 1.0\n\n不导入\n")
+	write("src/router.cj", "package tang\n\n/** 路由器 */\npublic class Router {\n    public func get(path: String): Router { this }\n}\n")
+
+	req := types.IngestRequest{Name: "tang", Path: root, LibraryVersion: "^1.0"}
+	result, err := store.IngestLibrary(req)
+	if err != nil {
+		t.Fatalf("IngestLibrary() error = %v", err)
+	}
+	want := types.IngestResult{Library: "tang", Files: 3, Sections: 3, Symbols: 2, Added: 4}
+	if *result != want {
+		t.Errorf("IngestLibrary() = %+v, want %+v", *result, want)
+	}
+
+	list, _ := store.ListMemories(types.ListRequest{LibraryName: "tang"})
+	titles := map[string]int64{}
+	for _, r := range list.Results {
+		titles[r.Title] = r.ID
+	}
+	id, ok := titles["README.md: Tang > 安装"]
+	if !ok || titles["docs/guide/middleware.md: 中间件"] == 0 || titles["tang.Router"] == 0 {
+		t.Fatalf("ingested titles = %v", titles)
+	}
+	memory, _ := store.GetMemory(id)
+	if memory.Level != types.LevelLibrary || memory.LibraryVersion != "^1.0" || memory.Summary != "在 cjpm.toml 中添加依赖。" {
+		t.Errorf("section memory = %+v", memory)
+	}
+	if memory.Provenance == nil || memory.Provenance.File != "README.md" || memory.Provenance.Section != "Tang > 安装" {
+		t.Errorf("section provenance = %+v", memory.Provenance)
+	}
+	router, _ := store.GetMemory(titles["tang.Router"])
+	if router.Provenance == nil || router.Provenance.File != "src/router.cj" || router.Provenance.Section != "tang.Router" {
+		t.Errorf("declaration provenance = %+v", router.Provenance)
+	}
+
+	// 重新导入：未变化的跳过，修改的按出处更新原记忆
+	write("README.md", "# Tang\n\n轻量级 Web 框架。\n\n## 安装\n\n运行 cjpm update 安装依赖。\n")
+	result, err = store.IngestLibrary(req)
+	if err != nil {
+		t.Fatalf("IngestLibrary() again error = %v", err)
+	}
+	if result.Added != 0 || result.Updated != 1 || result.Unchanged != 3 {
+		t.Errorf("IngestLibrary() again = %+v", result)
+	}
+	memory, _ = store.GetMemory(id)
+	if memory.Content != "运行 cjpm update 安装依赖。" {
+		t.Errorf("updated content = %q", memory.Content)
+	}
+	if list, _ := store.ListMemories(types.ListRequest{LibraryName: "tang"}); list.Total != 4 {
+		t.Errorf("memories after reingest = %d, want 4", list.Total)
+	}
+
+	// 原记忆在回收站中时新建
+	if _, err := store.DeleteMemory(types.DeleteRequest{ID: id}); err != nil {
+		t.Fatalf("DeleteMemory() error = %v", err)
+	}
+	if result, _ = store.IngestLibrary(req); result.Added != 1 {
+		t.Errorf("IngestLibrary() after delete = %+v", result)
+	}
+
+	// 上游改名或删除的章节、声明：旧记忆移入回收站
+	write("README.md", "# Tang\n\n轻量级 Web 框架。\n\n## 安装依赖\n\n运行 cjpm update 安装依赖。\n")
+	if err := os.Remove(filepath.Join(root, "docs", "guide", "middleware.md")); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	result, err = store.IngestLibrary(req)
+	if err != nil || result.Added != 1 || result.Removed != 2 {
+		t.Errorf("IngestLibrary() after rename = %+v, %v, want 1 added and 2 removed", result, err)
+	}
+	list, _ = store.ListMemories(types.ListRequest{LibraryName: "tang"})
+	titles = map[string]int64{}
+	for _, r := range list.Results {
+		titles[r.Title] = r.ID
+	}
+	if len(titles) != 3 || titles["README.md: Tang > 安装依赖"] == 0 || titles["tang.Router"] == 0 {
+		t.Errorf("titles after rename = %v", titles)
+	}
+
+	// 任一文件读取失败时整个导入回滚
+	write("README.md", "# Tang\n\n新的介绍。\n")
+	if err := os.Symlink(filepath.Join(root, "missing.md"), filepath.Join(root, "docs", "broken.md")); err != nil {
+		t.Fatalf("Symlink() error = %v", err)
+	}
+	if _, err := store.IngestLibrary(req); err == nil {
+		t.Error("IngestLibrary() with unreadable doc error = nil")
+	}
+	if after, _ := store.ListMemories(types.ListRequest{LibraryName: "tang"}); after.Total != 3 {
+		t.Errorf("memories after failed ingest = %d, want 3", after.Total)
+	}
+	if memory, _ := store.GetMemory(titles["README.md: Tang"]); memory == nil || memory.Content != "轻量级 Web 框架。" {
+		t.Errorf("README memory after failed ingest = %+v", memory)
+	}
+	if err := os.Remove(filepath.Join(root, "docs", "broken.md")); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+
+	for _, bad := range []types.IngestRequest{
+		{Path: root},
+		{Name: "tang"},
+		{Name: "tang", Path: filepath.Join(root, "missing")},
+		{Name: "tang", Path: filepath.Join(root, "README.md")},
+		{Name: "tang", Path: root, LibraryVersion: "1.x.3"},
+	} {
+		if _, err := store.IngestLibrary(bad); err == nil || !strings.HasPrefix(err.Error(), "invalid") {
+			t.Errorf("IngestLibrary(%+v) error = %v", bad, err)
+		}
+	}
+}
+
+func TestResolveSourcePath(t *testing.T) {
+	store := getTestStore(t)
+
+	root := t.TempDir()
+	lib := filepath.Join(root, "tang")
+	if err := os.Mkdir(lib, 0755); err != nil {
+		t.Fatalf("Mkdir() error = %v", err)
+	}
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Fatalf("Symlink() error = %v", err)
+	}
+
+	// 未配置源码根目录时拒绝所有路径
+	if _, err := store.ResolveSourcePath(lib); err == nil || !strings.HasPrefix(err.Error(), "invalid path") {
+		t.Errorf("ResolveSourcePath(no configured root) error = %v", err)
+	}
+
+	store.SetSourceRoots([]string{"", " " + root})
+	want, _ := filepath.EvalSymlinks(lib)
+	if got, err := store.ResolveSourcePath(lib); err != nil || got != want {
+		t.Errorf("ResolveSourcePath(%s) = %q, %v, want %q", lib, got, err, want)
+	}
+	for _, path := range []string{outside, "/", filepath.Join(lib, "..", ".."), filepath.Join(root, "escape")} {
+		if _, err := store.ResolveSourcePath(path); err == nil || !strings.Contains(err.Error(), "outside the configured source roots") {
+			t.Errorf("ResolveSourcePath(%s) error = %v", path, err)
+		}
+	}
+}
+
+func TestIngestLibraryDuplicateHeadings(t *testing.T) {
+	store := getTestStore(t)
+
+	root := t.TempDir()
+	readme := "# Tang\n\n## Example\n\n第一个示例。\n\n## Example\n\n第二个示例。\n"
+	if err := os.WriteFile(filepath.Join(root, "README.md"), []byte(readme), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	// 同一文件中标题路径相同的章节各自生成一条记忆
+	req := types.IngestRequest{Name: "tang", Path: root}
+	result, err := store.IngestLibrary(req)
+	if err != nil {
+		t.Fatalf("IngestLibrary() error = %v", err)
+	}
+	if result.Added != 2 {
+		t.Fatalf("IngestLibrary() = %+v, want 2 added", result)
+	}
+	list, _ := store.ListMemories(types.ListRequest{LibraryName: "tang"})
+	contents := map[string]string{}
+	for _, r := range list.Results {
+		memory, _ := store.GetMemory(r.ID)
+		contents[r.Title] = memory.Content
+	}
+	if contents["README.md: Tang > Example"] != "第一个示例。" || contents["README.md: Tang > Example (2)"] != "第二个示例。" {
+		t.Errorf("ingested sections = %v", contents)
+	}
+
+	// 重复导入保持幂等
+	result, err = store.IngestLibrary(req)
+	if err != nil {
+		t.Fatalf("IngestLibrary() again error = %v", err)
+	}
+	if result.Added != 0 || result.Updated != 0 || result.Unchanged != 2 {
+		t.Errorf("IngestLibrary() again = %+v, want 2 unchanged", result)
+	}
+}
+
+func TestSplitMarkdown(t *testing.T) {
+	store := getTestStore(t)
+
+	doc := "目录\n\n# 泛型\n\n泛型允许参数化类型。\n\n## 泛型约束\n\n使用 where 声明约束：\n\n```cangjie\n// # 不是标题\nfunc f<T>(a: T) where T <: ToString {}\n```\n\n## 示例\n\n第一个示例\n\n# 集合\n\n## 示例\n\n第二个示例\n\n### 细节\n\n三级标题保留在正文中\n"
+
+	memories, err := store.SplitMarkdown(types.MarkdownImportRequest{Content: doc, HeadingDepth: 2, Tags: []string{"语法"}})
+	if err != nil {
+		t.Fatalf("SplitMarkdown() error = %v", err)
+	}
+	var titles []string
+	for _, m := range memories {
+		titles = append(titles, m.Title)
+	}
+	want := []string{"泛型", "泛型 > 泛型约束", "泛型 > 示例", "集合 > 示例"}
+	if strings.Join(titles, "|") != strings.Join(want, "|") {
+		t.Fatalf("SplitMarkdown() titles = %q, want %q", titles, want)
+	}
+	constraint := memories[1]
+	if constraint.Level != types.LevelLanguage || constraint.Summary != "使用 where 声明约束：" ||
+		!strings.Contains(constraint.Content, "```cangjie\n// # 不是标题\nfunc f<T>(a: T) where T <: ToString {}\n```") ||
+		len(constraint.Tags) != 1 {
+		t.Errorf("section memory = %+v", constraint)
+	}
+	if !strings.Contains(memories[3].Content, "### 细节") {
+		t.Errorf("deeper heading missing from content: %q", memories[3].Content)
+	}
+
+	// 指定文档标题时作为前缀，第一个标题之前的内容也导入；重复的标题路径加序号
+	memories, _ = store.SplitMarkdown(types.MarkdownImportRequest{Content: doc + "\n# 集合\n\n## 示例\n\n第三个示例\n", Title: "仓颉语法", HeadingDepth: 2})
+	if memories[0].Title != "仓颉语法" || memories[0].Content != "目录" || memories[len(memories)-1].Title != "仓颉语法 > 集合 > 示例 (2)" {
+		t.Errorf("SplitMarkdown(title) first = %q, last = %q", memories[0].Title, memories[len(memories)-1].Title)
+	}
+
+	// 经过预览/确认流程导入，同一文档重复导入时内容未变的章节跳过
+	req := types.MarkdownImportRequest{Content: doc, Level: types.LevelLibrary, LibraryName: "syntax", HeadingDepth: 2}
+	memories, _ = store.SplitMarkdown(req)
+	preview, err := store.PreviewImport(types.NewPackageMemories(memories))
+	if err != nil {
+		t.Fatalf("PreviewImport() error = %v", err)
+	}
+	if preview.ToAdd != 4 || preview.ToUpdate != 0 {
+		t.Errorf("PreviewImport() = %+v", preview)
+	}
+	if result, err := store.ImportMemories(types.NewPackageMemories(memories), types.ImportOptions{}); err != nil || result.Added != 4 {
+		t.Fatalf("ImportMemories() = %+v, %v", result, err)
+	}
+	memories, _ = store.SplitMarkdown(req)
+	if preview, _ = store.PreviewImport(types.NewPackageMemories(memories)); preview.ToSkip != 4 || len(preview.Conflicts) != 4 {
+		t.Errorf("PreviewImport() again = %+v", preview)
+	}
+	if result, _ := store.ImportMemories(types.NewPackageMemories(memories), types.ImportOptions{}); result.Added != 0 || result.Skipped != 4 {
+		t.Errorf("ImportMemories() again = %+v", result)
+	}
+
+	for _, bad := range []types.MarkdownImportRequest{
+		{Content: " "},
+		{Content: doc, Level: "global"},
+		{Content: doc, Level: types.LevelLibrary},
+		{Content: doc, Level: types.LevelProject},
+		{Content: doc, HeadingDepth: 7},
+		{Content: "只有前言，没有标题"},
+	} {
+		if _, err := store.SplitMarkdown(bad); err == nil || !strings.HasPrefix(err.Error(), "invalid") {
+			t.Errorf("SplitMarkdown(%+v) error = %v", bad.Level, err)
+		}
+	}
+}
+
+func TestImportSession(t *testing.T) {
+	store := getTestStore(t)
+
+	pkg := types.KnowledgePackage{
+		Version:  types.PackageFormatVersion,
+		Package:  types.PackageInfo{Name: "tang"},
+		Memories: types.NewPackageMemories([]types.StoreRequest{{Level: types.LevelLibrary, LibraryName: "tang", Title: "路由注册", Content: "使用 router.get 注册路由"}}),
+	}
+	preview, err := store.CreateImportSession(pkg, types.ImportSourcePackage, "alice")
+	if err != nil || preview.ImportID == "" || preview.ToAdd != 1 {
+		t.Fatalf("CreateImportSession() = %+v, %v", preview, err)
+	}
+
+	bad := types.ImportConfirmRequest{ImportID: preview.ImportID}
+	bad.Resolutions = []types.ConflictResolution{{Index: 1, Strategy: types.ConflictSkip}}
+	if _, err := store.ConfirmImport(bad); err == nil || !strings.HasPrefix(err.Error(), "invalid resolution") {
+		t.Errorf("ConfirmImport(out of range) error = %v", err)
+	}
+	if _, err := store.ConfirmImport(types.ImportConfirmRequest{ImportID: "missing"}); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("ConfirmImport(missing) error = %v", err)
+	}
+
+	result, err := store.ConfirmImport(types.ImportConfirmRequest{ImportID: preview.ImportID})
+	if err != nil || result.Added != 1 {
+		t.Fatalf("ConfirmImport() = %+v, %v", result, err)
+	}
+	history, err := store.ImportHistory(types.ImportHistoryRequest{})
+	if err != nil || history.Total != 1 || history.Sessions[0].Uploader != "alice" || len(history.Sessions[0].MemoryIDs) != 1 {
+		t.Errorf("ImportHistory() = %+v, %v", history, err)
+	}
+	if _, err := store.ImportHistory(types.ImportHistoryRequest{Status: "unknown"}); err == nil {
+		t.Error("ImportHistory(unknown status) expected error")
+	}
+}
+
+func TestImportSignature(t *testing.T) {
+	store := getTestStore(t)
+
+	dir := t.TempDir()
+	if _, err := signing.GenerateKey(dir, "alice"); err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	key, _ := signing.ReadPrivateKey(filepath.Join(dir, "alice"+signing.PrivateKeyExt))
+	_, untrusted, _ := ed25519.GenerateKey(nil)
+
+	newPackage := func(key ed25519.PrivateKey) types.KnowledgePackage {
+		pkg := types.KnowledgePackage{
+			Version:  types.PackageFormatV1,
+			Package:  types.PackageInfo{Name: "tang"},
+			Memories: types.NewPackageMemories([]types.StoreRequest{{Level: types.LevelLibrary, LibraryName: "tang", Title: "路由注册", Content: "使用 router.get 注册路由"}}),
+		}
+		if err := types.NegotiatePackage(&pkg); err != nil {
+			t.Fatalf("NegotiatePackage() error = %v", err)
+		}
+		if key != nil {
+			if err := signing.Sign(&pkg, key, "Alice", time.Now()); err != nil {
+				t.Fatalf("Sign() error = %v", err)
+			}
+		}
+		// 经过 JSON 传输后签名仍然有效
+		data, _ := json.Marshal(pkg)
+		var decoded types.KnowledgePackage
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("json.Unmarshal() error = %v", err)
+		}
+		return decoded
+	}
+
+	// 未要求签名时：未签名的包可以导入，签名者不受信任时预览中标记
+	if preview, err := store.CreateImportSession(newPackage(nil), types.ImportSourcePackage, ""); err != nil || preview.Signer != nil {
+		t.Errorf("CreateImportSession(unsigned) = %+v, %v", preview, err)
+	}
+	preview, err := store.CreateImportSession(newPackage(untrusted), types.ImportSourcePackage, "")
+	if err != nil || preview.Signer == nil || preview.Signer.Trusted || preview.Signer.Signer != "Alice" {
+		t.Errorf("CreateImportSession(untrusted) = %+v, %v", preview, err)
+	}
+
+	// 签名无效时总是拒绝
+	tampered := newPackage(key)
+	tampered.Package.Description = "篡改"
+	if _, err := store.CreateImportSession(tampered, types.ImportSourcePackage, ""); err == nil || !strings.HasPrefix(err.Error(), "invalid signature") {
+		t.Errorf("CreateImportSession(tampered) error = %v", err)
+	}
+
+	// 要求签名时：只接受密钥环中公钥的签名
+	keyring, err := signing.LoadKeyring(dir)
+	if err != nil {
+		t.Fatalf("LoadKeyring() error = %v", err)
+	}
+	store.SetKeyring(keyring, true)
+	for name, pkg := range map[string]types.KnowledgePackage{"unsigned": newPackage(nil), "untrusted": newPackage(untrusted)} {
+		if _, err := store.CreateImportSession(pkg, types.ImportSourcePackage, ""); err == nil || !strings.HasPrefix(err.Error(), "invalid signature") {
+			t.Errorf("CreateImportSession(%s) error = %v", name, err)
+		}
+	}
+	preview, err = store.CreateImportSession(newPackage(key), types.ImportSourcePackage, "")
+	if err != nil || !preview.Signer.Trusted || preview.Signer.Name != "alice" {
+		t.Fatalf("CreateImportSession(trusted) = %+v, %v", preview, err)
+	}
+	if result, err := store.ConfirmImport(types.ImportConfirmRequest{ImportID: preview.ImportID}); err != nil || result.Added != 1 {
+		t.Errorf("ConfirmImport() = %+v, %v", result, err)
+	}
+}
+
+func TestPackageRegistry(t *testing.T) {
+	store := getTestStore(t)
+
+	dir := t.TempDir()
+	write := func(version, content string) {
+		pkg := types.KnowledgePackage{
+			Version:  types.PackageFormatV1,
+			Package:  types.PackageInfo{Name: "tang", Version: version},
+			Memories: types.NewPackageMemories([]types.StoreRequest{{Level: types.LevelLibrary, LibraryName: "tang", Title: "路由注册", Content: content}}),
+		}
+		data, _ := json.Marshal(pkg)
+		if err := os.WriteFile(filepath.Join(dir, "tang-"+version+".json"), data, 0644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		if _, err := registry.BuildIndex(dir); err != nil {
+			t.Fatalf("BuildIndex() error = %v", err)
+		}
+	}
+	write("1.0.0", "使用 router.get 注册路由")
+
+	if _, err := store.InstallPackage(types.PackageInstallRequest{Registry: dir}); err == nil || !strings.HasPrefix(err.Error(), "invalid name") {
+		t.Errorf("InstallPackage(no name) error = %v", err)
+	}
+	if index, err := store.RegistryIndex(dir); err != nil || len(index.Packages) != 1 {
+		t.Errorf("RegistryIndex() = %+v, %v", index, err)
+	}
+	result, err := store.InstallPackage(types.PackageInstallRequest{Registry: dir, Name: "tang"})
+	if err != nil || result.Import.Added != 1 || result.Package.Version != "1.0.0" {
+		t.Fatalf("InstallPackage() = %+v, %v", result, err)
+	}
+
+	// 未指定仓库时只从服务端配置的仓库中查找
+	if _, err := store.UpgradePackage(types.PackageInstallRequest{Name: "tang"}); err == nil || !strings.HasPrefix(err.Error(), "invalid registry") {
+		t.Errorf("UpgradePackage(no configured registry) error = %v", err)
+	}
+	store.SetRegistries([]string{t.TempDir(), " " + dir})
+	if registries, err := store.ConfiguredRegistries(); err == nil {
+		t.Errorf("ConfiguredRegistries(missing index) = %+v, want error", registries)
+	}
+	empty := t.TempDir()
+	if _, err := registry.BuildIndex(empty); err != nil {
+		t.Fatalf("BuildIndex() error = %v", err)
+	}
+	store.SetRegistries([]string{empty, " " + dir, ""})
+	if registries, err := store.ConfiguredRegistries(); err != nil || len(registries) != 2 || len(registries[0].Packages) != 0 || len(registries[1].Packages) != 1 {
+		t.Errorf("ConfiguredRegistries() = %+v, %v", registries, err)
+	}
+	if _, err := store.InstallPackage(types.PackageInstallRequest{Name: "json"}); err == nil || !strings.HasPrefix(err.Error(), "package not found") {
+		t.Errorf("InstallPackage(not in configured registries) error = %v", err)
+	}
+
+	// 升级从配置的仓库中找到包，1.0 的包升级后按确定性 UUID 对应到已安装的记忆
+	write("1.1.0", "使用 router.get 或 router.post 注册路由")
+	result, err = store.UpgradePackage(types.PackageInstallRequest{Name: "tang"})
+	if err != nil || result.Diff.FromVersion != "1.0.0" || len(result.Diff.Changed) != 1 || result.Import.Updated != 1 {
+		t.Fatalf("UpgradePackage() = %+v, %v", result, err)
+	}
+	if packages, _ := store.ListPackages(); len(packages) != 1 || packages[0].Version != "1.1.0" {
+		t.Errorf("ListPackages() = %+v", packages)
+	}
+
+	if result, err := store.UninstallPackage("tang"); err != nil || result.Removed != 1 {
+		t.Errorf("UninstallPackage() = %+v, %v", result, err)
+	}
+	if _, err := store.GetPackage("tang"); err == nil {
+		t.Error("GetPackage(uninstalled) error = nil")
+	}
+}
@@ -0,0 +1,107 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// SuggestMemory 记录缺失知识的补充建议（待审核）
+func (s *Store) SuggestMemory(req types.SuggestRequest) (*types.SuggestResponse, error) {
+	if req.Query == "" || req.SuggestedTitle == "" || req.SuggestedContent == "" {
+		return nil, fmt.Errorf("query, suggested_title and suggested_content are required")
+	}
+
+	// 未指定层级时根据查询内容自动判断
+	if req.SuggestedLevel == "" {
+		req.SuggestedLevel = s.determineLevel(req.Query, req.ProjectPathPattern)
+	}
+
+	id, err := s.db.CreateSuggestion(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.SuggestResponse{
+		Success:      true,
+		SuggestionID: id,
+		Status:       types.SuggestionPending,
+		Message:      "建议已记录，待审核后加入记忆库",
+	}, nil
+}
+
+// ListSuggestions 列出补充建议
+func (s *Store) ListSuggestions(req types.SuggestionListRequest) (*types.SuggestionListResponse, error) {
+	switch req.Status {
+	case "", types.SuggestionPending, types.SuggestionApproved, types.SuggestionRejected:
+	default:
+		return nil, fmt.Errorf("invalid status: %s", req.Status)
+	}
+	if req.Limit <= 0 {
+		req.Limit = 20
+	}
+
+	return s.db.ListSuggestions(req)
+}
+
+// ApproveSuggestion 批准建议，将其转为正式记忆（审核者记录为修订历史的操作者）
+func (s *Store) ApproveSuggestion(req types.SuggestionReviewRequest) (*types.Suggestion, error) {
+	suggestion, err := s.db.GetSuggestion(req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if suggestion.Status != types.SuggestionPending {
+		return nil, fmt.Errorf("suggestion already reviewed: id=%d, status=%s", req.ID, suggestion.Status)
+	}
+
+	// 以建议内容为基础，审核时提供的字段优先
+	storeReq := types.StoreRequest{
+		Level:              suggestion.SuggestedLevel,
+		LanguageTag:        suggestion.LanguageTag,
+		LibraryName:        suggestion.LibraryName,
+		ProjectPathPattern: suggestion.ProjectPathPattern,
+		Title:              suggestion.SuggestedTitle,
+		Content:            suggestion.SuggestedContent,
+		Summary:            req.Summary,
+		Source:             types.SourceAutoCaptured,
+		Editor:             req.Editor,
+	}
+	if req.Level != "" {
+		storeReq.Level = req.Level
+	}
+	if req.LibraryName != "" {
+		storeReq.LibraryName = req.LibraryName
+	}
+	if req.ProjectPathPattern != "" {
+		storeReq.ProjectPathPattern = req.ProjectPathPattern
+	}
+	if req.Title != "" {
+		storeReq.Title = req.Title
+	}
+	if req.Content != "" {
+		storeReq.Content = req.Content
+	}
+
+	// 记忆和审核状态在同一事务中写入，审核失败时不留下记忆
+	err = s.inTx(func(tx *Store) error {
+		resp, err := tx.StoreMemory(storeReq)
+		if err != nil {
+			return fmt.Errorf("failed to store memory: %w", err)
+		}
+		return tx.db.ReviewSuggestion(req.ID, types.SuggestionApproved, resp.ID, req.Note)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.db.GetSuggestion(req.ID)
+}
+
+// RejectSuggestion 拒绝建议
+func (s *Store) RejectSuggestion(req types.SuggestionReviewRequest) (*types.Suggestion, error) {
+	if err := s.db.ReviewSuggestion(req.ID, types.SuggestionRejected, 0, req.Note); err != nil {
+		return nil, err
+	}
+
+	return s.db.GetSuggestion(req.ID)
+}
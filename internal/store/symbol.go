@@ -0,0 +1,206 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ystyle/cangjie-mem/pkg/symbol"
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// 符号查询返回数
+const (
+	defaultSymbolLimit = 20
+	maxSymbolLimit     = 100
+)
+
+// LookupSymbols 按完整名称（或其后缀）精确查询符号，prefix 时按前缀查询
+func (s *Store) LookupSymbols(req types.SymbolRequest) (*types.SymbolResponse, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return nil, fmt.Errorf("invalid name: symbol name cannot be empty")
+	}
+	if req.LanguageTag == "" {
+		req.LanguageTag = "cangjie"
+	}
+	if req.Limit <= 0 {
+		req.Limit = defaultSymbolLimit
+	}
+	if req.Limit > maxSymbolLimit {
+		req.Limit = maxSymbolLimit
+	}
+
+	symbols, err := s.db.FindSymbols(req)
+	if err != nil {
+		return nil, err
+	}
+	return &types.SymbolResponse{Total: len(symbols), Symbols: symbols}, nil
+}
+
+// ImportSymbols 解析 .cj 源码目录中的公开声明，替换该库已有的符号
+// CreateMemories 时为每个顶层声明（类型连同其成员、顶层函数/变量）生成一条 API 记忆，
+// 重复导入时更新符号原来关联的记忆而不是新建
+func (s *Store) ImportSymbols(req types.SymbolImportRequest) (*types.SymbolImportResult, error) {
+	var added, updated int
+	var written []int64
+	result, err := s.importSymbols(req, func(id int64, outcome upsertOutcome) {
+		if outcome == upsertAdded {
+			added++
+		} else {
+			updated++
+		}
+		if outcome != upsertUnchanged {
+			written = append(written, id)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.MemoriesAdded, result.MemoriesUpdated = added, updated
+	s.syncEmbeddings(written...)
+	return result, nil
+}
+
+// importSymbols 解析并替换符号，record 接收每条 API 记忆的写入结果
+func (s *Store) importSymbols(req types.SymbolImportRequest, record func(int64, upsertOutcome)) (*types.SymbolImportResult, error) {
+	if strings.TrimSpace(req.Path) == "" {
+		return nil, fmt.Errorf("invalid path: source path cannot be empty")
+	}
+	if req.LanguageTag == "" {
+		req.LanguageTag = "cangjie"
+	}
+
+	symbols, files, err := symbol.ParseDir(req.Path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path: %w", err)
+	}
+	for i := range symbols {
+		symbols[i].LanguageTag = req.LanguageTag
+		symbols[i].LibraryName = req.LibraryName
+	}
+
+	result := &types.SymbolImportResult{Files: files, Symbols: len(symbols)}
+	if req.CreateMemories {
+		if err := s.syncSymbolMemories(req, symbols, record); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.db.ReplaceSymbols(req.LanguageTag, req.LibraryName, symbols); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// symbolGroup 一个顶层声明及其成员（对应一条 API 记忆）
+type symbolGroup struct {
+	name    string
+	indexes []int
+}
+
+// syncSymbolMemories 为每个顶层声明创建或更新 API 记忆，并把记忆 ID 写回符号
+func (s *Store) syncSymbolMemories(req types.SymbolImportRequest, symbols []types.Symbol, record func(int64, upsertOutcome)) error {
+	existing, err := s.db.SymbolMemoryIDs(req.LanguageTag, req.LibraryName)
+	if err != nil {
+		return err
+	}
+
+	var groups []*symbolGroup
+	byName := map[string]*symbolGroup{}
+	for i, sym := range symbols {
+		name := sym.QualifiedName
+		if sym.Type != "" {
+			name = types.QualifyName(sym.Package, sym.Type)
+		}
+		g, ok := byName[name]
+		if !ok {
+			g = &symbolGroup{name: name}
+			byName[name] = g
+			groups = append(groups, g)
+		}
+		g.indexes = append(g.indexes, i)
+	}
+
+	level := types.LevelLanguage
+	if req.LibraryName != "" {
+		level = types.LevelLibrary
+	}
+	for _, g := range groups {
+		memory := symbolMemory(g, symbols)
+		memory.Level = level
+		memory.LanguageTag = req.LanguageTag
+		memory.LibraryName = req.LibraryName
+		memory.LibraryVersion = req.LibraryVersion
+
+		// 组内任一符号原来关联的记忆（extend 的成员没有类型本身的符号）
+		var id int64
+		for _, i := range g.indexes {
+			if id = existing[symbols[i].QualifiedName]; id > 0 {
+				break
+			}
+		}
+		first := symbols[g.indexes[0]]
+		provenance := types.Provenance{File: first.File, Section: g.name}
+		if id == 0 {
+			if id, err = s.db.FindByProvenance(req.LanguageTag, req.LibraryName, provenance); err != nil {
+				return err
+			}
+		}
+		id, outcome, err := s.upsertMemory(id, memory, provenance)
+		if err != nil {
+			return err
+		}
+		record(id, outcome)
+		for _, i := range g.indexes {
+			symbols[i].KnowledgeID = id
+		}
+	}
+	return nil
+}
+
+// symbolMemory 生成 API 记忆：文档注释 + 声明列表（成员声明前附带文档注释的第一行）
+func symbolMemory(g *symbolGroup, symbols []types.Symbol) types.StoreRequest {
+	var doc string
+	var code strings.Builder
+	for _, i := range g.indexes {
+		sym := symbols[i]
+		if sym.Type == "" || sym.Member == "" {
+			// 类型本身或顶层声明的文档作为正文
+			if doc == "" {
+				doc = sym.Doc
+			}
+			code.WriteString(sym.Signature + "\n")
+			continue
+		}
+		if first := firstLine(sym.Doc); first != "" {
+			code.WriteString("    // " + first + "\n")
+		}
+		code.WriteString("    " + sym.Signature + "\n")
+	}
+
+	summary := firstLine(doc)
+	if summary == "" {
+		summary = symbols[g.indexes[0]].Signature
+	}
+
+	var content strings.Builder
+	if doc != "" {
+		content.WriteString(doc + "\n\n")
+	}
+	content.WriteString("```cangjie\n" + code.String() + "```")
+
+	return types.StoreRequest{
+		Title:   g.name,
+		Content: content.String(),
+		Summary: summary,
+		Source:  types.SourceManual,
+		Tags:    []string{types.TagAPI},
+		Editor:  types.EditorImport,
+	}
+}
+
+// firstLine 返回文本的第一行
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	return strings.TrimSpace(line)
+}
@@ -0,0 +1,57 @@
+package store
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// DefaultTrashRetention 回收站默认保留时长
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+// ListTrash 列出回收站中的记忆
+func (s *Store) ListTrash(req types.TrashListRequest) (*types.ListResponse, error) {
+	if req.LanguageTag == "" {
+		req.LanguageTag = "cangjie"
+	}
+	if req.Limit <= 0 {
+		req.Limit = 20
+	}
+
+	return s.db.ListTrash(req)
+}
+
+// RestoreMemory 从回收站恢复记忆
+func (s *Store) RestoreMemory(id int64, editor string) (*types.Memory, error) {
+	if id <= 0 {
+		return nil, fmt.Errorf("invalid id: %d", id)
+	}
+	return s.db.Undelete(id, editor)
+}
+
+// PurgeMemory 彻底删除回收站中的记忆（不可恢复）
+func (s *Store) PurgeMemory(id int64) error {
+	if id <= 0 {
+		return fmt.Errorf("invalid id: %d", id)
+	}
+	return s.db.Purge(id)
+}
+
+// EmptyTrash 清空回收站，返回彻底删除的记忆数
+func (s *Store) EmptyTrash() (*types.PurgeResponse, error) {
+	purged, err := s.db.PurgeTrash(time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	return &types.PurgeResponse{Purged: purged}, nil
+}
+
+// PurgeExpiredTrash 彻底删除在回收站中超过保留时长的记忆
+// retention <= 0 表示永久保留
+func (s *Store) PurgeExpiredTrash(retention time.Duration) (int, error) {
+	if retention <= 0 {
+		return 0, nil
+	}
+	return s.db.PurgeTrash(time.Now().Add(-retention))
+}
@@ -0,0 +1,241 @@
+// Package cjpm 解析仓颉项目的 cjpm.toml 配置（仅包含记忆检索需要的包名和依赖）
+package cjpm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// FileName 项目配置文件名
+const FileName = "cjpm.toml"
+
+// Dependency 项目依赖
+type Dependency struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"` // 版本号（name = "1.0.0" 或 version = "1.0.0"）
+	Path    string `json:"path,omitempty"`    // 本地依赖路径
+	Git     string `json:"git,omitempty"`     // git 仓库地址
+}
+
+// Manifest cjpm.toml 内容
+type Manifest struct {
+	Name         string       `json:"name"` // [package] 中的包名
+	Dependencies []Dependency `json:"dependencies"`
+}
+
+// DependencyNames 返回全部依赖名
+func (m *Manifest) DependencyNames() []string {
+	names := make([]string, len(m.Dependencies))
+	for i, dep := range m.Dependencies {
+		names[i] = dep.Name
+	}
+	return names
+}
+
+// Load 读取项目目录下的 cjpm.toml
+func Load(dir string) (*Manifest, error) {
+	f, err := os.Open(filepath.Join(dir, FileName))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse 解析 cjpm.toml
+// 支持 [dependencies] 下的 name = "版本" 和 name = { path = "...", git = "..." } 写法，
+// 以及 [dependencies.name] 子表写法；其他表中的内容忽略（包括跨多行的数组等值）
+func Parse(r io.Reader) (*Manifest, error) {
+	m := &Manifest{}
+	index := map[string]int{} // 依赖名 → Dependencies 下标
+	dependency := func(name string) *Dependency {
+		i, ok := index[name]
+		if !ok {
+			i = len(m.Dependencies)
+			index[name] = i
+			m.Dependencies = append(m.Dependencies, Dependency{Name: name})
+		}
+		return &m.Dependencies[i]
+	}
+
+	section := ""
+	open := 0 // 上一个值中未闭合的括号数，大于 0 时当前行是该值的续行
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+		if open > 0 {
+			open = max(open+bracketDepth(line), 0)
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("invalid %s line %d: %q", FileName, lineNo, line)
+			}
+			// 数组表（如 [[bin]]）与依赖无关，只需确保后续键值不被误认为依赖
+			section = strings.TrimSpace(strings.Trim(line, "[]"))
+			if name, ok := strings.CutPrefix(section, "dependencies."); ok {
+				dependency(unquote(name))
+			}
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid %s line %d: %q", FileName, lineNo, line)
+		}
+		key = unquote(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		if section != "dependencies" && !strings.HasPrefix(section, "dependencies.") {
+			// 依赖表以外跨多行的值（如 [package] 中的 compile-option 数组）与包名和依赖无关，跳过其续行
+			if open = bracketDepth(value); open > 0 {
+				continue
+			}
+		}
+
+		switch {
+		case section == "package" && key == "name":
+			m.Name = unquote(value)
+		case section == "dependencies":
+			dep := dependency(key)
+			if strings.HasPrefix(value, "{") {
+				fields, err := parseInlineTable(value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid %s line %d: %w", FileName, lineNo, err)
+				}
+				setFields(dep, fields)
+			} else {
+				dep.Version = unquote(value)
+			}
+		case strings.HasPrefix(section, "dependencies."):
+			dep := dependency(unquote(strings.TrimPrefix(section, "dependencies.")))
+			setFields(dep, map[string]string{key: unquote(value)})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", FileName, err)
+	}
+
+	return m, nil
+}
+
+// setFields 设置依赖的已知字段
+func setFields(dep *Dependency, fields map[string]string) {
+	for k, v := range fields {
+		switch k {
+		case "version":
+			dep.Version = v
+		case "path":
+			dep.Path = v
+		case "git":
+			dep.Git = v
+		}
+	}
+}
+
+// parseInlineTable 解析单行内联表 { a = "x", b = "y" }
+func parseInlineTable(s string) (map[string]string, error) {
+	if !strings.HasSuffix(s, "}") {
+		return nil, fmt.Errorf("unterminated inline table: %s", s)
+	}
+	fields := map[string]string{}
+	for _, pair := range splitOutsideQuotes(s[1:len(s)-1], ',') {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid inline table field: %s", pair)
+		}
+		fields[unquote(strings.TrimSpace(key))] = unquote(strings.TrimSpace(value))
+	}
+	return fields, nil
+}
+
+// splitOutsideQuotes 按分隔符切分字符串，忽略引号内的分隔符
+func splitOutsideQuotes(s string, sep byte) []string {
+	var parts []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// bracketDepth 返回字符串中未闭合的 [ 和 { 数（引号内的括号忽略），闭合多于打开时为负数
+func bracketDepth(s string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++ // 跳过转义字符
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		}
+	}
+	return depth
+}
+
+// stripComment 去掉行内注释（引号内的 # 保留）
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// unquote 去掉 TOML 字符串的引号
+func unquote(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		if v, err := strconv.Unquote(s); err == nil {
+			return v
+		}
+		return s[1 : len(s)-1]
+	}
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,112 @@
+package cjpm
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sampleManifest = `
+[package]
+  cjc-version = "0.53.4"
+  name = "demo" # 包名
+  version = "1.0.0"
+  output-type = "executable"
+
+[dependencies]
+  tang = { git = "https://github.com/ystyle/tang", branch = "master" }
+  "http-client" = { path = "../http-client", version = "0.2.0" }
+  json = "1.2.0"
+
+[test-dependencies]
+  mock = { path = "../mock" }
+
+[dependencies.log]
+  path = "./libs/log#v2"
+
+[[bin]]
+  name = "tool"
+`
+
+func TestParse(t *testing.T) {
+	m, err := Parse(strings.NewReader(sampleManifest))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if m.Name != "demo" {
+		t.Errorf("Name = %q, want demo", m.Name)
+	}
+	want := []Dependency{
+		{Name: "tang", Git: "https://github.com/ystyle/tang"},
+		{Name: "http-client", Path: "../http-client", Version: "0.2.0"},
+		{Name: "json", Version: "1.2.0"},
+		{Name: "log", Path: "./libs/log#v2"},
+	}
+	if !reflect.DeepEqual(m.Dependencies, want) {
+		t.Errorf("Dependencies = %+v, want %+v", m.Dependencies, want)
+	}
+	if names := m.DependencyNames(); !reflect.DeepEqual(names, []string{"tang", "http-client", "json", "log"}) {
+		t.Errorf("DependencyNames() = %v", names)
+	}
+}
+
+func TestParseMultiLineValues(t *testing.T) {
+	manifest := `
+[package]
+  name = "demo"
+  compile-option = [
+    "-O2", # 优化
+    "--cfg=\"feature=[x\"",
+  ]
+  link-option = ["-lz",
+    "-lm"]
+
+[profile.build]
+  args = {
+    lto = "full" }
+
+[dependencies]
+  json = "1.2.0"
+`
+	m, err := Parse(strings.NewReader(manifest))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if m.Name != "demo" || !reflect.DeepEqual(m.DependencyNames(), []string{"json"}) {
+		t.Errorf("Parse() = %+v", m)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, s := range []string{
+		"[dependencies\ntang = \"1.0\"",
+		"[dependencies]\ntang",
+		"[dependencies]\ntang = { path = \"../tang\"",
+		"[dependencies]\ntang = { path }",
+	} {
+		if _, err := Parse(strings.NewReader(s)); err == nil {
+			t.Errorf("Parse(%q) expected error", s)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Load(dir); !os.IsNotExist(err) {
+		t.Errorf("Load() without manifest error = %v, want not exist", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, FileName), []byte(sampleManifest), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	m, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(m.Dependencies) != 4 {
+		t.Errorf("Load() dependencies = %+v", m.Dependencies)
+	}
+}
@@ -0,0 +1,164 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/ystyle/cangjie-mem/pkg/diff"
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// findExisting 查找与导入记忆冲突的已有记忆（同语言、同层级、同库、同版本范围、同项目路径模式、同标题，不含回收站），
+// 不存在时返回 0；预览和导入共用这一条规则，保证预览的冲突与实际导入一致
+func (d *Database) findExisting(mem types.StoreRequest) (int64, error) {
+	var id int64
+	err := d.db.QueryRow(`
+		SELECT id FROM knowledge_base
+		WHERE language_tag = ? AND level = ? AND COALESCE(library_name, '') = ? AND COALESCE(library_version, '') = ?
+			AND COALESCE(project_path_pattern, '') = ? AND title = ? AND deleted_at IS NULL
+		ORDER BY id
+		LIMIT 1
+	`, mem.LanguageTag, mem.Level, mem.LibraryName, mem.LibraryVersion, mem.ProjectPathPattern, mem.Title).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to find existing memory: %w", err)
+	}
+	return id, nil
+}
+
+// matchExisting 查找导入记忆对应的已有记忆：先按 UUID 匹配，没有时按 findExisting 的规则匹配，不存在时返回 0
+func (d *Database) matchExisting(mem types.PackageMemory) (int64, error) {
+	id, err := d.findByUUID(mem.UUID)
+	if err != nil || id > 0 {
+		return id, err
+	}
+	return d.findExisting(mem.StoreRequest)
+}
+
+// FindConflicts 查找导入记忆与已有记忆的冲突，并给出字段差异
+func (d *Database) FindConflicts(memories []types.PackageMemory) ([]types.ConflictInfo, error) {
+	var conflicts []types.ConflictInfo
+
+	for i, mem := range memories {
+		mem.StoreRequest = importDefaults(mem.StoreRequest)
+		id, err := d.matchExisting(mem)
+		if err != nil {
+			return nil, err
+		}
+		if id == 0 {
+			continue
+		}
+
+		current, err := d.GetByID(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get existing memory: %w", err)
+		}
+		conflicts = append(conflicts, types.ConflictInfo{
+			Index:          i,
+			ExistingID:     id,
+			Title:          mem.Title,
+			LibraryName:    mem.LibraryName,
+			LibraryVersion: mem.LibraryVersion,
+			Level:          mem.Level,
+			Diffs:          fieldDiffs(current, mem.StoreRequest),
+		})
+	}
+
+	return conflicts, nil
+}
+
+// importDefaults 设置导入记忆的默认值
+func importDefaults(mem types.StoreRequest) types.StoreRequest {
+	if mem.LanguageTag == "" {
+		mem.LanguageTag = "cangjie"
+	}
+	if mem.Source == "" {
+		mem.Source = types.SourceManual
+	}
+	if mem.Editor == "" {
+		mem.Editor = types.EditorImport
+	}
+	return mem
+}
+
+// fieldDiffs 比较已有记忆与导入记忆会被导入覆盖的字段，返回不同的字段（一致时为空）
+func fieldDiffs(current *types.Memory, mem types.StoreRequest) []types.FieldDiff {
+	diffs := []types.FieldDiff{}
+	add := func(field, existing, incoming string) {
+		if existing != incoming {
+			diffs = append(diffs, types.FieldDiff{Field: field, Existing: existing, Incoming: incoming})
+		}
+	}
+
+	if current.Content != mem.Content {
+		diffs = append(diffs, types.FieldDiff{
+			Field:    "content",
+			Existing: current.Content,
+			Incoming: mem.Content,
+			Diff:     diff.Unified("existing", "incoming", current.Content, mem.Content, diff.DefaultContext),
+		})
+	}
+	add("title", current.Title, mem.Title)
+	add("level", string(current.Level), string(mem.Level))
+	add("library_name", current.LibraryName, mem.LibraryName)
+	add("library_version", current.LibraryVersion, mem.LibraryVersion)
+	add("summary", current.Summary, mem.Summary)
+	add("tags", strings.Join(current.Tags, ", "), strings.Join(types.NormalizeTags(mem.Tags), ", "))
+	add("language_tag", current.LanguageTag, mem.LanguageTag)
+	add("project_path_pattern", current.ProjectPathPattern, mem.ProjectPathPattern)
+	add("source", string(current.Source), string(mem.Source))
+	add("diagnostic_signature", current.DiagnosticSignature, mem.DiagnosticSignature)
+	return diffs
+}
+
+// mergeMemory 合并已有记忆与导入记忆：导入的正文追加到已有正文后（已包含时不重复追加），
+// 标签取并集，摘要和诊断签名保留已有的（为空时使用导入的），标题等其他字段保留已有的
+func mergeMemory(current *types.Memory, mem types.StoreRequest) types.StoreRequest {
+	merged := mem
+	merged.Level = current.Level
+	merged.LibraryName = current.LibraryName
+	merged.LibraryVersion = current.LibraryVersion
+	merged.Title = current.Title
+	merged.LanguageTag = current.LanguageTag
+	merged.ProjectPathPattern = current.ProjectPathPattern
+	merged.Source = current.Source
+
+	merged.Content = current.Content
+	if incoming := strings.TrimSpace(mem.Content); incoming != "" && !strings.Contains(current.Content, incoming) {
+		merged.Content = strings.TrimRight(current.Content, "\n") + "\n\n" + incoming
+	}
+	if current.Summary != "" {
+		merged.Summary = current.Summary
+	}
+	if current.DiagnosticSignature != "" {
+		merged.DiagnosticSignature = current.DiagnosticSignature
+	}
+	merged.Tags = types.NormalizeTags(append(append([]string(nil), current.Tags...), mem.Tags...))
+	return merged
+}
+
+// keepBothTitle 为“都保留”策略选择导入记忆的新标题（原标题加序号）
+// 已有同序号标题且内容一致的记忆时返回其 ID，表示已经导入过
+func (d *Database) keepBothTitle(mem types.StoreRequest) (string, int64, error) {
+	for n := 2; ; n++ {
+		candidate := mem
+		candidate.Title = fmt.Sprintf("%s (%d)", mem.Title, n)
+		id, err := d.findExisting(candidate)
+		if err != nil {
+			return "", 0, err
+		}
+		if id == 0 {
+			return candidate.Title, 0, nil
+		}
+		current, err := d.GetByID(id)
+		if err != nil {
+			return "", 0, fmt.Errorf("failed to get existing memory: %w", err)
+		}
+		if len(fieldDiffs(current, candidate)) == 0 {
+			return candidate.Title, id, nil
+		}
+	}
+}
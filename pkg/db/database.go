@@ -2,12 +2,15 @@ package db
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	_ "modernc.org/sqlite"
 	_ "modernc.org/sqlite/vec"
 	"github.com/ystyle/cangjie-mem/pkg/types"
@@ -15,7 +18,8 @@ import (
 
 // Database 数据库实例
 type Database struct {
-	db *sql.DB
+	conn *sql.DB // 数据库连接
+	db   querier // 执行 SQL 的对象，事务中为 *sql.Tx
 }
 
 // Config 数据库配置
@@ -47,7 +51,7 @@ func New(cfg Config) (*Database, error) {
 	db.SetMaxIdleConns(1)
 
 	// 初始化数据库结构
-	database := &Database{db: db}
+	database := &Database{conn: db, db: db}
 	if err := database.init(); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
@@ -65,6 +69,7 @@ func (d *Database) init() error {
 		level TEXT NOT NULL CHECK (level IN ('language', 'project', 'library')),
 		language_tag TEXT NOT NULL DEFAULT 'cangjie',
 		library_name TEXT,
+		library_version TEXT,
 		project_path_pattern TEXT,
 		title TEXT NOT NULL,
 		content TEXT NOT NULL,
@@ -74,38 +79,17 @@ func (d *Database) init() error {
 		confidence REAL DEFAULT 1.0,
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		last_accessed_at TIMESTAMP
+		last_accessed_at TIMESTAMP,
+		title_seg TEXT,
+		content_seg TEXT,
+		summary_seg TEXT,
+		deleted_at TIMESTAMP
 	);
 
 	CREATE INDEX IF NOT EXISTS idx_knowledge_level ON knowledge_base(level);
 	CREATE INDEX IF NOT EXISTS idx_knowledge_language ON knowledge_base(language_tag);
 	CREATE INDEX IF NOT EXISTS idx_knowledge_project_pattern ON knowledge_base(project_path_pattern);
 	CREATE INDEX IF NOT EXISTS idx_knowledge_created_at ON knowledge_base(created_at DESC);
-
-	CREATE VIRTUAL TABLE IF NOT EXISTS knowledge_base_fts USING fts5(
-		title,
-		content,
-		summary,
-		content=knowledge_base,
-		content_rowid=rowid
-	);
-
-	CREATE TRIGGER IF NOT EXISTS knowledge_base_ai AFTER INSERT ON knowledge_base BEGIN
-		INSERT INTO knowledge_base_fts(rowid, title, content, summary)
-		VALUES (new.id, new.title, new.content, new.summary);
-	END;
-
-	CREATE TRIGGER IF NOT EXISTS knowledge_base_ad AFTER DELETE ON knowledge_base BEGIN
-		INSERT INTO knowledge_base_fts(knowledge_base_fts, rowid, title, content, summary)
-		VALUES ('delete', old.id, old.title, old.content, old.summary);
-	END;
-
-	CREATE TRIGGER IF NOT EXISTS knowledge_base_au AFTER UPDATE ON knowledge_base BEGIN
-		INSERT INTO knowledge_base_fts(knowledge_base_fts, rowid, title, content, summary)
-		VALUES ('delete', old.id, old.title, old.content, old.summary);
-		INSERT INTO knowledge_base_fts(rowid, title, content, summary)
-		VALUES (new.id, new.title, new.content, new.summary);
-	END;
 	`
 
 	_, err := d.db.Exec(schema)
@@ -113,15 +97,52 @@ func (d *Database) init() error {
 		return err
 	}
 
+	// 自动迁移（按顺序执行，每个迁移都必须幂等）
+	migrations := []func() error{
+		// 检查并添加 library_name 字段（兼容老数据库），并创建 library_name 索引
+		d.migrateLibraryName,
+		// 建议补充表
+		d.migrateSuggestions,
+		// CJK 分词影子列和 FTS5 索引（负责创建 FTS5 表和同步触发器）
+		d.migrateCJKSegmentation,
+		// 标签表
+		d.migrateTags,
+		// 语义检索向量表
+		d.migrateEmbeddings,
+		// 回收站（软删除）字段，GetByID 依赖该字段，需在修订历史之前
+		d.migrateSoftDelete,
+		// 库版本范围字段，GetByID 依赖该字段，需在修订历史之前
+		d.migrateLibraryVersion,
+		// 编译诊断签名表，GetByID 依赖该表，需在修订历史之前
+		d.migrateDiagnostics,
+		// 记忆出处表，GetByID 依赖该表，需在修订历史之前
+		d.migrateProvenance,
+		// 记忆 UUID 表，GetByID 依赖该表，需在修订历史之前
+		d.migrateUUIDs,
+		// 修订历史表（依赖标签表生成初始版本）
+		d.migrateRevisions,
+		// 记忆关系表
+		d.migrateLinks,
+		// API 符号表
+		d.migrateSymbols,
+		// 导入会话表
+		d.migrateImportSessions,
+		// 已安装知识包表
+		d.migratePackages,
+	}
+	for _, migrate := range migrations {
+		if err := migrate(); err != nil {
+			return err
+		}
+	}
+
 	// 重建 FTS5 索引（将现有数据同步到 FTS5 表）
 	if err := d.rebuildFTSIndex(); err != nil {
 		// 记录错误但不中断初始化
 		log.Printf("Warning: failed to rebuild FTS index: %v", err)
 	}
 
-	// 自动迁移：检查并添加 library_name 字段（兼容老数据库）
-	// 迁移函数会负责创建 library_name 索引
-	return d.migrateLibraryName()
+	return nil
 }
 
 // rebuildFTSIndex 重建 FTS5 全文索引
@@ -143,17 +164,8 @@ func (d *Database) rebuildFTSIndex() error {
 	if count != ftsCount {
 		log.Printf("Rebuilding FTS index: %d records in main table, %d in FTS table", count, ftsCount)
 
-		// 先清空 FTS5 表
-		_, err = d.db.Exec("DELETE FROM knowledge_base_fts")
-		if err != nil {
-			return fmt.Errorf("failed to clear FTS table: %w", err)
-		}
-
-		// 重新插入所有数据到 FTS5 表
-		_, err = d.db.Exec(`
-			INSERT INTO knowledge_base_fts(rowid, title, content, summary)
-			SELECT id, title, content, summary FROM knowledge_base
-		`)
+		// 从内容表（分词影子列）重建 FTS5 表
+		_, err = d.db.Exec(`INSERT INTO knowledge_base_fts(knowledge_base_fts) VALUES ('rebuild')`)
 		if err != nil {
 			return fmt.Errorf("failed to rebuild FTS index: %w", err)
 		}
@@ -176,6 +188,10 @@ func (d *Database) Store(req types.StoreRequest) (*types.StoreResponse, error) {
 		return nil, fmt.Errorf("project_path_pattern is required for project level")
 	}
 
+	if err := validateLibraryVersion(req.Level, req.LibraryVersion); err != nil {
+		return nil, err
+	}
+
 	// 设置默认值
 	if req.LanguageTag == "" {
 		req.LanguageTag = "cangjie"
@@ -189,13 +205,16 @@ func (d *Database) Store(req types.StoreRequest) (*types.StoreResponse, error) {
 	}
 
 	// 插入数据
+	titleSeg, contentSeg, summarySeg := segmentFields(req.Title, req.Content, req.Summary)
 	result, err := d.db.Exec(`
 		INSERT INTO knowledge_base (
-			level, language_tag, library_name, project_path_pattern,
-			title, content, summary, source, confidence
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, req.Level, req.LanguageTag, req.LibraryName, req.ProjectPathPattern,
-		req.Title, req.Content, req.Summary, req.Source, confidence)
+			level, language_tag, library_name, library_version, project_path_pattern,
+			title, content, summary, source, confidence,
+			title_seg, content_seg, summary_seg
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, req.Level, req.LanguageTag, req.LibraryName, req.LibraryVersion, req.ProjectPathPattern,
+		req.Title, req.Content, req.Summary, req.Source, confidence,
+		titleSeg, contentSeg, summarySeg)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to insert memory: %w", err)
@@ -206,6 +225,20 @@ func (d *Database) Store(req types.StoreRequest) (*types.StoreResponse, error) {
 		return nil, fmt.Errorf("failed to get last insert id: %w", err)
 	}
 
+	if err := d.setUUID(id, uuid.NewString()); err != nil {
+		return nil, err
+	}
+	if err := d.setTags(id, req.Tags); err != nil {
+		return nil, err
+	}
+	if err := d.setDiagnosticSignature(id, req.DiagnosticSignature); err != nil {
+		return nil, err
+	}
+
+	if err := d.recordRevision(id, types.RevisionCreate, req.Editor); err != nil {
+		return nil, err
+	}
+
 	return &types.StoreResponse{
 		Success: true,
 		ID:      id,
@@ -213,49 +246,36 @@ func (d *Database) Store(req types.StoreRequest) (*types.StoreResponse, error) {
 	}, nil
 }
 
-// Recall 查询记忆（基础查询，不包含智能逻辑）
-// level 为空时搜索所有层级；libraryName 为空时不按库名过滤；
-// projectPath 为空时不按项目路径过滤
-func (d *Database) Recall(query string, level types.KnowledgeLevel, languageTag string, projectPath string, libraryName string, limit int) ([]types.RecallResult, error) {
-	whereClause := "WHERE language_tag = ?"
-	args := []interface{}{languageTag}
-
-	if level.IsValid() {
-		whereClause += " AND level = ?"
-		args = append(args, level)
-	}
-
-	if libraryName != "" {
-		whereClause += " AND library_name = ?"
-		args = append(args, libraryName)
-	}
-
-	if projectPath != "" {
-		whereClause += ` AND (
-			project_path_pattern IS NOT NULL
-			AND project_path_pattern != ''
-			AND project_path_pattern GLOB ?
-		)`
-		args = append(args, projectPath)
-	}
+// RecallOptions 检索条件
+type RecallOptions struct {
+	Level          types.KnowledgeLevel // 为空时搜索所有层级
+	LanguageTag    string
+	ProjectPath    string   // 当前项目路径：非空时项目级记忆只保留路径模式匹配该路径的，其他层级不受影响
+	LibraryName    string   // 为空时不按库名过滤
+	LibraryVersion string   // 非空时库级记忆只保留版本范围包含该版本的
+	Libraries      []string // 非空时库级记忆只保留库名在其中的（如项目依赖）和未指定库名的，其他层级不受影响
+	Tags           []string // 为空时不按标签过滤
+	Limit          int
+}
 
-	queryClause := `
-		AND id IN (
-			SELECT rowid FROM knowledge_base_fts
-			WHERE knowledge_base_fts MATCH ?
-			ORDER BY bm25(knowledge_base_fts) LIMIT 100
-		)
-	`
-	args = append(args, query)
+// Recall 查询记忆（基础查询，不包含智能逻辑）
+// 结果按 bm25 相关度排序，BM25 字段为 FTS5 原始 bm25 分数（越小越相关）
+func (d *Database) Recall(query string, opts RecallOptions) ([]types.RecallResult, error) {
+	whereClause, whereArgs := recallWhere(opts)
 
 	sqlQuery := `
 		SELECT
-			id, level, title, content, summary,
-			library_name, project_path_pattern, source,
-			access_count, confidence, created_at, updated_at
+			` + recallColumns + `, fts.fts_rank
 		FROM knowledge_base
-	` + whereClause + queryClause + `
+		JOIN (
+			SELECT rowid AS fts_id, bm25(knowledge_base_fts) AS fts_rank
+			FROM knowledge_base_fts
+			WHERE knowledge_base_fts MATCH ?
+			ORDER BY fts_rank LIMIT 100
+		) fts ON fts.fts_id = knowledge_base.id
+	` + whereClause + `
 		ORDER BY
+			fts.fts_rank,
 			CASE level
 				WHEN 'language' THEN 0
 				WHEN 'library' THEN 1
@@ -265,7 +285,8 @@ func (d *Database) Recall(query string, level types.KnowledgeLevel, languageTag
 			access_count DESC
 		LIMIT ?
 	`
-	args = append(args, limit)
+	args := append([]interface{}{query}, whereArgs...)
+	args = append(args, opts.Limit)
 
 	rows, err := d.db.Query(sqlQuery, args...)
 	if err != nil {
@@ -275,35 +296,118 @@ func (d *Database) Recall(query string, level types.KnowledgeLevel, languageTag
 
 	var results []types.RecallResult
 	for rows.Next() {
-		var r types.RecallResult
-		var libName, pattern, summary sql.NullString
-		var createdAt, updatedAt time.Time
-
-		err := rows.Scan(
-			&r.ID, &r.Level, &r.Title, &r.Content, &summary,
-			&libName, &pattern, &r.Source,
-			&r.AccessCount, &r.Confidence, &createdAt, &updatedAt,
-		)
+		r, rank, err := scanRecallResult(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
+			return nil, err
 		}
+		r.BM25 = rank
+		results = append(results, r)
+	}
+	rows.Close()
 
-		if summary.Valid {
-			r.Summary = summary.String
-		}
-		if libName.Valid {
-			r.LibraryName = libName.String
-		}
-		if pattern.Valid {
-			r.ProjectPathPattern = pattern.String
+	return results, d.fillRecallTags(results)
+}
+
+// recallColumns 检索结果字段（与 scanRecallResult 对应）
+const recallColumns = `
+	knowledge_base.id, level, title, content, summary,
+	library_name, library_version, project_path_pattern, source,
+	access_count, confidence, knowledge_base.created_at, knowledge_base.updated_at`
+
+// recallWhere 构建检索过滤条件
+func recallWhere(opts RecallOptions) (string, []interface{}) {
+	whereClause := "WHERE deleted_at IS NULL AND language_tag = ?"
+	args := []interface{}{opts.LanguageTag}
+
+	if opts.Level.IsValid() {
+		whereClause += " AND level = ?"
+		args = append(args, opts.Level)
+	}
+
+	if opts.LibraryName != "" {
+		whereClause += " AND library_name = ?"
+		args = append(args, opts.LibraryName)
+	}
+
+	if len(opts.Libraries) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(opts.Libraries)), ",")
+		whereClause += " AND (level != 'library' OR COALESCE(library_name, '') = '' OR library_name IN (" + placeholders + "))"
+		for _, name := range opts.Libraries {
+			args = append(args, name)
 		}
-		r.CreatedAt = createdAt.Format(time.RFC3339)
-		r.UpdatedAt = updatedAt.Format(time.RFC3339)
+	}
 
-		results = append(results, r)
+	versionClause, versionArgs := libraryVersionClause(opts.LibraryVersion)
+	whereClause += versionClause
+	args = append(args, versionArgs...)
+
+	// 分层检索：语言级和库级记忆始终参与，项目级记忆只保留匹配当前项目的
+	if opts.ProjectPath != "" {
+		whereClause += ` AND (
+			level != 'project'
+			OR (
+				project_path_pattern IS NOT NULL
+				AND project_path_pattern != ''
+				AND ? GLOB project_path_pattern
+			)
+		)`
+		args = append(args, opts.ProjectPath)
 	}
 
-	return results, nil
+	tagClause, tagArgs := tagFilterClause(opts.Tags)
+	whereClause += tagClause
+	args = append(args, tagArgs...)
+
+	return whereClause, args
+}
+
+// scanRecallResult 扫描一行检索结果，同时返回 recallColumns 之后的分数列
+func scanRecallResult(rows *sql.Rows) (types.RecallResult, float64, error) {
+	var r types.RecallResult
+	var score float64
+	var libName, libVersion, pattern, summary sql.NullString
+	var createdAt, updatedAt time.Time
+
+	err := rows.Scan(
+		&r.ID, &r.Level, &r.Title, &r.Content, &summary,
+		&libName, &libVersion, &pattern, &r.Source,
+		&r.AccessCount, &r.Confidence, &createdAt, &updatedAt,
+		&score,
+	)
+	if err != nil {
+		return r, 0, fmt.Errorf("failed to scan row: %w", err)
+	}
+
+	if summary.Valid {
+		r.Summary = summary.String
+	}
+	if libName.Valid {
+		r.LibraryName = libName.String
+	}
+	r.LibraryVersion = libVersion.String
+	if pattern.Valid {
+		r.ProjectPathPattern = pattern.String
+	}
+	r.CreatedAt = createdAt.Format(time.RFC3339)
+	r.UpdatedAt = updatedAt.Format(time.RFC3339)
+
+	return r, score, nil
+}
+
+// fillRecallTags 填充检索结果的标签
+func (d *Database) fillRecallTags(results []types.RecallResult) error {
+	ids := make([]int64, len(results))
+	for i, r := range results {
+		ids[i] = r.ID
+	}
+	tags, err := d.loadTags(ids)
+	if err != nil {
+		return err
+	}
+	for i := range results {
+		results[i].Tags = tags[results[i].ID]
+	}
+	return nil
 }
 
 // UpdateAccessCount 更新访问次数和最后访问时间
@@ -321,18 +425,18 @@ func (d *Database) UpdateAccessCount(id int64) error {
 // GetByID 根据 ID 获取记忆
 func (d *Database) GetByID(id int64) (*types.Memory, error) {
 	var m types.Memory
-	var libraryName, pattern, summary sql.NullString
-	var lastAccessed sql.NullTime
+	var libraryName, libraryVersion, pattern, summary sql.NullString
+	var lastAccessed, deletedAt sql.NullTime
 
 	err := d.db.QueryRow(`
-		SELECT id, level, language_tag, library_name, project_path_pattern,
+		SELECT id, level, language_tag, library_name, library_version, project_path_pattern,
 		       title, content, summary, source,
-		       access_count, confidence, created_at, updated_at, last_accessed_at
+		       access_count, confidence, created_at, updated_at, last_accessed_at, deleted_at
 		FROM knowledge_base WHERE id = ?
 	`, id).Scan(
-		&m.ID, &m.Level, &m.LanguageTag, &libraryName, &pattern,
+		&m.ID, &m.Level, &m.LanguageTag, &libraryName, &libraryVersion, &pattern,
 		&m.Title, &m.Content, &summary, &m.Source,
-		&m.AccessCount, &m.Confidence, &m.CreatedAt, &m.UpdatedAt, &lastAccessed,
+		&m.AccessCount, &m.Confidence, &m.CreatedAt, &m.UpdatedAt, &lastAccessed, &deletedAt,
 	)
 
 	if err != nil {
@@ -342,6 +446,7 @@ func (d *Database) GetByID(id int64) (*types.Memory, error) {
 	if libraryName.Valid {
 		m.LibraryName = libraryName.String
 	}
+	m.LibraryVersion = libraryVersion.String
 	if pattern.Valid {
 		m.ProjectPathPattern = pattern.String
 	}
@@ -351,13 +456,35 @@ func (d *Database) GetByID(id int64) (*types.Memory, error) {
 	if lastAccessed.Valid {
 		m.LastAccessedAt = &lastAccessed.Time
 	}
+	if deletedAt.Valid {
+		m.DeletedAt = &deletedAt.Time
+	}
+
+	tags, err := d.loadTags([]int64{m.ID})
+	if err != nil {
+		return nil, err
+	}
+	m.Tags = tags[m.ID]
+
+	signatures, err := d.DiagnosticSignatures([]int64{m.ID})
+	if err != nil {
+		return nil, err
+	}
+	m.DiagnosticSignature = signatures[m.ID]
+
+	if m.Provenance, err = d.getProvenance(m.ID); err != nil {
+		return nil, err
+	}
+	if m.UUID, err = d.getUUID(m.ID); err != nil {
+		return nil, err
+	}
 
 	return &m, nil
 }
 
 // Close 关闭数据库连接
 func (d *Database) Close() error {
-	return d.db.Close()
+	return d.conn.Close()
 }
 
 // migrateLibraryName 自动迁移：添加 library_name 字段
@@ -393,10 +520,18 @@ func (d *Database) migrateLibraryName() error {
 	return nil
 }
 
-// List 列出记忆（支持筛选和分页）
+// List 列出记忆（支持筛选和分页，不包含回收站中的记忆）
 func (d *Database) List(req types.ListRequest) (*types.ListResponse, error) {
+	return d.list(req, false)
+}
+
+// list 列出记忆，trashed 为 true 时仅列出回收站中的记忆（按删除时间降序）
+func (d *Database) list(req types.ListRequest, trashed bool) (*types.ListResponse, error) {
 	// 构建动态 WHERE 条件
-	whereClause := "WHERE 1=1"
+	whereClause := "WHERE deleted_at IS NULL"
+	if trashed {
+		whereClause = "WHERE deleted_at IS NOT NULL"
+	}
 	args := []interface{}{}
 
 	if req.LanguageTag != "" {
@@ -423,6 +558,14 @@ func (d *Database) List(req types.ListRequest) (*types.ListResponse, error) {
 		args = append(args, req.ProjectPathPattern)
 	}
 
+	versionClause, versionArgs := libraryVersionClause(req.LibraryVersion)
+	whereClause += versionClause
+	args = append(args, versionArgs...)
+
+	tagClause, tagArgs := tagFilterClause(req.Tags)
+	whereClause += tagClause
+	args = append(args, tagArgs...)
+
 	// 查询总数
 	var total int
 	countQuery := "SELECT COUNT(*) FROM knowledge_base " + whereClause
@@ -438,6 +581,9 @@ func (d *Database) List(req types.ListRequest) (*types.ListResponse, error) {
 	} else if req.OrderBy == "updated_at" {
 		orderBy = "updated_at DESC"
 	}
+	if trashed {
+		orderBy = "deleted_at DESC"
+	}
 
 	// 设置默认值
 	limit := 20
@@ -453,10 +599,10 @@ func (d *Database) List(req types.ListRequest) (*types.ListResponse, error) {
 	var selectFields string
 	if req.Brief {
 		// 简洁模式：不查询 content 字段
-		selectFields = "id, level, language_tag, title, '' as content, summary, library_name, project_path_pattern, source, access_count, confidence, created_at, updated_at"
+		selectFields = "id, level, language_tag, title, '' as content, summary, library_name, library_version, project_path_pattern, source, access_count, confidence, created_at, updated_at, deleted_at"
 	} else {
 		// 详细模式：查询所有字段
-		selectFields = "id, level, language_tag, title, content, summary, library_name, project_path_pattern, source, access_count, confidence, created_at, updated_at"
+		selectFields = "id, level, language_tag, title, content, summary, library_name, library_version, project_path_pattern, source, access_count, confidence, created_at, updated_at, deleted_at"
 	}
 
 	// 查询数据
@@ -478,16 +624,17 @@ func (d *Database) List(req types.ListRequest) (*types.ListResponse, error) {
 	var results []types.Memory
 	for rows.Next() {
 		var m types.Memory
-		var libraryName, pattern, summary sql.NullString
+		var libraryName, libraryVersion, pattern, summary sql.NullString
 		var languageTag sql.NullString
+		var deletedAt sql.NullTime
 
 		err := rows.Scan(
 			&m.ID, &m.Level, &languageTag,
 			&m.Title, &m.Content, &summary,
-			&libraryName, &pattern,
+			&libraryName, &libraryVersion, &pattern,
 			&m.Source,
 			&m.AccessCount, &m.Confidence,
-			&m.CreatedAt, &m.UpdatedAt,
+			&m.CreatedAt, &m.UpdatedAt, &deletedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
@@ -505,12 +652,30 @@ func (d *Database) List(req types.ListRequest) (*types.ListResponse, error) {
 		if libraryName.Valid {
 			m.LibraryName = libraryName.String
 		}
+		m.LibraryVersion = libraryVersion.String
 		if pattern.Valid {
 			m.ProjectPathPattern = pattern.String
 		}
+		if deletedAt.Valid {
+			m.DeletedAt = &deletedAt.Time
+		}
 
 		results = append(results, m)
 	}
+	rows.Close()
+
+	// 填充标签
+	ids := make([]int64, len(results))
+	for i, m := range results {
+		ids[i] = m.ID
+	}
+	tags, err := d.loadTags(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Tags = tags[results[i].ID]
+	}
 
 	return &types.ListResponse{
 		Total:   total,
@@ -528,7 +693,7 @@ func (d *Database) ListCategories(languageTag string) (*types.ListCategoriesResp
 	libRows, err := d.db.Query(`
 		SELECT library_name, COUNT(*) as count
 		FROM knowledge_base
-		WHERE level = 'library' AND library_name IS NOT NULL AND library_name != '' AND language_tag = ?
+		WHERE level = 'library' AND library_name IS NOT NULL AND library_name != '' AND language_tag = ? AND deleted_at IS NULL
 		GROUP BY library_name
 		ORDER BY count DESC
 	`, languageTag)
@@ -552,11 +717,20 @@ func (d *Database) ListCategories(languageTag string) (*types.ListCategoriesResp
 		}
 	}
 
+	// 按版本范围细分库的记忆数
+	versions, err := d.listLibraryVersions(languageTag)
+	if err != nil {
+		return nil, err
+	}
+	for i := range libraries {
+		libraries[i].Versions = versions[libraries[i].Name]
+	}
+
 	// 查询所有项目
 	projectRows, err := d.db.Query(`
 		SELECT project_path_pattern, COUNT(*) as count
 		FROM knowledge_base
-		WHERE level = 'project' AND project_path_pattern IS NOT NULL AND project_path_pattern != '' AND language_tag = ?
+		WHERE level = 'project' AND project_path_pattern IS NOT NULL AND project_path_pattern != '' AND language_tag = ? AND deleted_at IS NULL
 		GROUP BY project_path_pattern
 		ORDER BY count DESC
 	`, languageTag)
@@ -580,15 +754,39 @@ func (d *Database) ListCategories(languageTag string) (*types.ListCategoriesResp
 		}
 	}
 
+	// 查询所有标签
+	tags, err := d.listTagCounts(languageTag)
+	if err != nil {
+		return nil, err
+	}
+
 	return &types.ListCategoriesResponse{
 		Libraries: libraries,
 		Projects:  projects,
+		Tags:      tags,
 	}, nil
 }
 
-// Delete 删除记忆
-func (d *Database) Delete(id int64) error {
-	result, err := d.db.Exec(`DELETE FROM knowledge_base WHERE id = ?`, id)
+// Delete 删除记忆：移入回收站（删除前的内容记录到修订历史）
+// 回收站中的记忆可通过 Undelete 恢复，通过 Purge 彻底删除
+func (d *Database) Delete(id int64, editor string) error {
+	memory, err := d.GetByID(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("memory not found: id=%d", id)
+		}
+		return fmt.Errorf("failed to get memory: %w", err)
+	}
+	if memory.DeletedAt != nil {
+		return fmt.Errorf("memory not found: id=%d", id)
+	}
+	if err := d.insertRevision(memory, types.RevisionDelete, editor); err != nil {
+		return err
+	}
+
+	result, err := d.db.Exec(`
+		UPDATE knowledge_base SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL
+	`, time.Now(), id)
 	if err != nil {
 		return fmt.Errorf("failed to delete memory: %w", err)
 	}
@@ -605,47 +803,114 @@ func (d *Database) Delete(id int64) error {
 	return nil
 }
 
-// Update 更新记忆
+// Update 更新记忆（覆盖全部字段）
 func (d *Database) Update(id int64, req types.StoreRequest) (*types.Memory, error) {
-	// 验证层级
-	if !req.Level.IsValid() {
-		return nil, fmt.Errorf("invalid knowledge level: %s", req.Level)
+	return d.Patch(types.NewUpdateRequest(id, req))
+}
+
+// Patch 部分更新记忆（仅修改请求中提供的字段）
+func (d *Database) Patch(req types.UpdateRequest) (*types.Memory, error) {
+	return d.patch(req, types.RevisionUpdate)
+}
+
+// patch 部分更新记忆，并以 action 记录修订版本
+func (d *Database) patch(req types.UpdateRequest, action string) (*types.Memory, error) {
+	existing, err := d.GetByID(req.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("memory not found: id=%d", req.ID)
+		}
+		return nil, fmt.Errorf("failed to get memory: %w", err)
+	}
+	if existing.DeletedAt != nil {
+		return nil, fmt.Errorf("memory is in trash: id=%d", req.ID)
+	}
+
+	// 合并字段
+	merged := *existing
+	req.ApplyTo(&merged)
+
+	// 设置默认值
+	if merged.LanguageTag == "" {
+		merged.LanguageTag = "cangjie"
+	}
+	if merged.Source == "" {
+		merged.Source = types.SourceManual
+	}
+
+	// 验证合并后的结果
+	if !merged.Level.IsValid() {
+		return nil, fmt.Errorf("invalid knowledge level: %s", merged.Level)
+	}
+	if !merged.Source.IsValid() {
+		return nil, fmt.Errorf("invalid knowledge source: %s", merged.Source)
+	}
+	if merged.Title == "" || merged.Content == "" {
+		return nil, fmt.Errorf("invalid content: title and content cannot be empty")
 	}
 
 	// 项目级必须提供项目路径模式
-	if req.Level == types.LevelProject && req.ProjectPathPattern == "" {
-		return nil, fmt.Errorf("project_path_pattern is required for project level")
+	if merged.Level == types.LevelProject && merged.ProjectPathPattern == "" {
+		return nil, fmt.Errorf("invalid project_path_pattern: project_path_pattern is required for project level")
 	}
 
-	// 设置默认值
-	if req.LanguageTag == "" {
-		req.LanguageTag = "cangjie"
+	if err := validateLibraryVersion(merged.Level, merged.LibraryVersion); err != nil {
+		return nil, err
 	}
-	if req.Source == "" {
-		req.Source = types.SourceManual
+
+	// 来源变化时同步调整置信度
+	confidence := existing.Confidence
+	if merged.Source != existing.Source {
+		confidence = 1.0
+		if merged.Source == types.SourceAutoCaptured {
+			confidence = 0.7
+		}
 	}
 
 	// 执行更新
-	_, err := d.db.Exec(`
+	titleSeg, contentSeg, summarySeg := segmentFields(merged.Title, merged.Content, merged.Summary)
+	_, err = d.db.Exec(`
 		UPDATE knowledge_base
-		SET level = ?, language_tag = ?, library_name = ?, project_path_pattern = ?,
-		    title = ?, content = ?, summary = ?, source = ?, updated_at = CURRENT_TIMESTAMP
+		SET level = ?, language_tag = ?, library_name = ?, library_version = ?, project_path_pattern = ?,
+		    title = ?, content = ?, summary = ?, source = ?, confidence = ?,
+		    title_seg = ?, content_seg = ?, summary_seg = ?,
+		    updated_at = CURRENT_TIMESTAMP
 		WHERE id = ?
-	`, req.Level, req.LanguageTag, req.LibraryName, req.ProjectPathPattern,
-		req.Title, req.Content, req.Summary, req.Source, id)
+	`, merged.Level, merged.LanguageTag, merged.LibraryName, merged.LibraryVersion, merged.ProjectPathPattern,
+		merged.Title, merged.Content, merged.Summary, merged.Source, confidence,
+		titleSeg, contentSeg, summarySeg, req.ID)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to update memory: %w", err)
 	}
 
+	if req.Tags != nil {
+		if err := d.setTags(req.ID, merged.Tags); err != nil {
+			return nil, err
+		}
+	}
+	if req.DiagnosticSignature != nil {
+		if err := d.setDiagnosticSignature(req.ID, merged.DiagnosticSignature); err != nil {
+			return nil, err
+		}
+	}
+
 	// 获取更新后的记录
-	return d.GetByID(id)
+	memory, err := d.GetByID(req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.insertRevision(memory, action, req.Editor); err != nil {
+		return nil, err
+	}
+
+	return memory, nil
 }
 
-// ExportForImport 导出记忆用于导入（返回 StoreRequest 格式）
-func (d *Database) ExportForImport(req types.ExportRequest) ([]types.StoreRequest, error) {
-	// 构建查询条件
-	whereClause := "WHERE 1=1"
+// ExportForImport 导出记忆用于导入（返回知识包记忆格式，包含 UUID、时间戳、访问次数和置信度）
+func (d *Database) ExportForImport(req types.ExportRequest) ([]types.PackageMemory, error) {
+	// 构建查询条件（不导出回收站中的记忆）
+	whereClause := "WHERE deleted_at IS NULL"
 	args := []interface{}{}
 
 	if req.LanguageTag != "" {
@@ -671,10 +936,14 @@ func (d *Database) ExportForImport(req types.ExportRequest) ([]types.StoreReques
 		args = append(args, req.ProjectPathPattern)
 	}
 
+	tagClause, tagArgs := tagFilterClause(req.MemoryTags)
+	whereClause += tagClause
+	args = append(args, tagArgs...)
+
 	// 查询数据
 	sqlQuery := `
-		SELECT level, language_tag, library_name, project_path_pattern,
-		       title, content, summary, source
+		SELECT id, level, language_tag, library_name, library_version, project_path_pattern,
+		       title, content, summary, source, created_at, updated_at, access_count, confidence
 		FROM knowledge_base
 	` + whereClause + `
 		ORDER BY created_at DESC
@@ -686,15 +955,17 @@ func (d *Database) ExportForImport(req types.ExportRequest) ([]types.StoreReques
 	}
 	defer rows.Close()
 
-	var results []types.StoreRequest
+	var results []types.PackageMemory
+	var ids []int64
 	for rows.Next() {
-		var r types.StoreRequest
-		var libraryName, pattern, summary sql.NullString
+		var r types.PackageMemory
+		var id int64
+		var libraryName, libraryVersion, pattern, summary sql.NullString
 		var source sql.NullString
 
 		err := rows.Scan(
-			&r.Level, &r.LanguageTag, &libraryName, &pattern,
-			&r.Title, &r.Content, &summary, &source,
+			&id, &r.Level, &r.LanguageTag, &libraryName, &libraryVersion, &pattern,
+			&r.Title, &r.Content, &summary, &source, &r.CreatedAt, &r.UpdatedAt, &r.AccessCount, &r.Confidence,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
@@ -703,6 +974,7 @@ func (d *Database) ExportForImport(req types.ExportRequest) ([]types.StoreReques
 		if libraryName.Valid {
 			r.LibraryName = libraryName.String
 		}
+		r.LibraryVersion = libraryVersion.String
 		if pattern.Valid {
 			r.ProjectPathPattern = pattern.String
 		}
@@ -714,104 +986,294 @@ func (d *Database) ExportForImport(req types.ExportRequest) ([]types.StoreReques
 		}
 
 		results = append(results, r)
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	// 填充标签和诊断签名
+	tags, err := d.loadTags(ids)
+	if err != nil {
+		return nil, err
+	}
+	signatures, err := d.DiagnosticSignatures(ids)
+	if err != nil {
+		return nil, err
+	}
+	uuids, err := d.loadUUIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Tags = tags[ids[i]]
+		results[i].DiagnosticSignature = signatures[ids[i]]
+		results[i].UUID = uuids[ids[i]]
 	}
 
 	return results, nil
 }
 
-// FindConflicts 查找冲突（同库同标题）
-func (d *Database) FindConflicts(memories []types.StoreRequest) ([]types.ConflictInfo, error) {
-	var conflicts []types.ConflictInfo
+// errImportRollback 导入有失败或为试运行时回滚事务
+var errImportRollback = errors.New("import rolled back")
+
+// ImportMemories 在一个事务中导入记忆：按层级、库名、版本范围和标题匹配已有记忆，冲突时按 opts 中的策略处理
+// （默认覆盖，内容未变时跳过）；任意一条失败时整个导入回滚，不写入任何记忆；
+// 试运行时执行全部操作后回滚，结果与实际导入一致
+func (d *Database) ImportMemories(memories []types.PackageMemory, opts types.ImportOptions) (*types.ImportResult, error) {
+	result := &types.ImportResult{
+		Total:  len(memories),
+		DryRun: opts.DryRun,
+		Items:  make([]types.ImportItemResult, 0, len(memories)),
+	}
 
-	for _, mem := range memories {
-		// 只对 library 层级检测冲突
-		if mem.Level != types.LevelLibrary || mem.LibraryName == "" {
-			continue
+	err := d.inTx(func(tx *Database) error {
+		seen := make(map[string]int, len(memories))
+		for i, mem := range memories {
+			key := importDefaults(mem.StoreRequest)
+			keys := []string{strings.Join([]string{key.LanguageTag, string(key.Level), key.LibraryName, key.LibraryVersion, key.ProjectPathPattern, key.Title}, "\x00")}
+			if mem.UUID != "" {
+				keys = append(keys, strings.ToLower(mem.UUID))
+			}
+			var item types.ImportItemResult
+			if first, ok := firstSeen(seen, keys); ok {
+				item = importItem(mem)
+				item.Action = types.ImportSkipped
+				item.Reason = fmt.Sprintf("duplicate of item %d", first)
+			} else {
+				for _, key := range keys {
+					seen[key] = i
+				}
+				item = tx.importMemory(mem, opts.StrategyFor(i))
+			}
+			item.Index = i
+
+			switch item.Action {
+			case types.ImportAdded:
+				result.Added++
+			case types.ImportUpdated:
+				result.Updated++
+			case types.ImportSkipped:
+				result.Skipped++
+			default:
+				result.Failed++
+			}
+			result.Items = append(result.Items, item)
 		}
 
-		// 查找已存在的记录
-		var id int64
-		err := d.db.QueryRow(`
-			SELECT id FROM knowledge_base
-			WHERE level = ? AND library_name = ? AND title = ?
-			LIMIT 1
-		`, mem.Level, mem.LibraryName, mem.Title).Scan(&id)
-
-		if err == nil {
-			// 找到冲突
-			conflicts = append(conflicts, types.ConflictInfo{
-				ExistingID:  id,
-				Title:       mem.Title,
-				LibraryName: mem.LibraryName,
-				Level:       mem.Level,
-			})
+		if opts.DryRun || result.Failed > 0 {
+			return errImportRollback
 		}
+		return nil
+	})
+	if err == errImportRollback {
+		result.RolledBack = true
+		// 回滚后新增记忆的 ID 不再有效
+		for i := range result.Items {
+			if result.Items[i].Action == types.ImportAdded {
+				result.Items[i].ID = 0
+			}
+		}
+		return result, nil
 	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
 
-	return conflicts, nil
+// firstSeen 返回最先出现任一 key 的记忆序号
+func firstSeen(seen map[string]int, keys []string) (int, bool) {
+	for _, key := range keys {
+		if i, ok := seen[key]; ok {
+			return i, true
+		}
+	}
+	return 0, false
 }
 
-// ImportMemories 导入记忆（支持覆盖）
-func (d *Database) ImportMemories(memories []types.StoreRequest) (*types.ImportResult, error) {
-	added := 0
-	updated := 0
-
-	for _, mem := range memories {
-		// 设置默认值
-		if mem.LanguageTag == "" {
-			mem.LanguageTag = "cangjie"
-		}
-		if mem.Source == "" {
-			mem.Source = types.SourceManual
-		}
-
-		// 查找是否已存在（同库同标题）
-		var existingID int64
-		err := d.db.QueryRow(`
-			SELECT id FROM knowledge_base
-			WHERE level = ? AND library_name = ? AND title = ?
-			LIMIT 1
-		`, mem.Level, mem.LibraryName, mem.Title).Scan(&existingID)
-
-		if err == nil {
-			// 已存在，更新
-			_, err = d.db.Exec(`
-				UPDATE knowledge_base
-				SET language_tag = ?, project_path_pattern = ?,
-				    content = ?, summary = ?, source = ?, updated_at = CURRENT_TIMESTAMP
-				WHERE id = ?
-			`, mem.LanguageTag, mem.ProjectPathPattern,
-				mem.Content, mem.Summary, mem.Source, existingID)
-
-			if err != nil {
-				return nil, fmt.Errorf("failed to update memory %s: %w", mem.Title, err)
-			}
-			updated++
-		} else {
-			// 不存在，插入
-			confidence := 1.0
-			if mem.Source == types.SourceAutoCaptured {
-				confidence = 0.7
-			}
+// importItem 生成单条记忆的导入结果（不含动作）
+func importItem(mem types.PackageMemory) types.ImportItemResult {
+	return types.ImportItemResult{
+		UUID:           mem.UUID,
+		Title:          mem.Title,
+		Level:          mem.Level,
+		LibraryName:    mem.LibraryName,
+		LibraryVersion: mem.LibraryVersion,
+	}
+}
 
-			_, err = d.db.Exec(`
-				INSERT INTO knowledge_base (
-					level, language_tag, library_name, project_path_pattern,
-					title, content, summary, source, confidence
-				) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-			`, mem.Level, mem.LanguageTag, mem.LibraryName, mem.ProjectPathPattern,
-				mem.Title, mem.Content, mem.Summary, mem.Source, confidence)
+// importMemory 按冲突处理策略导入单条记忆，失败原因记录在返回结果中
+func (d *Database) importMemory(mem types.PackageMemory, strategy types.ConflictStrategy) types.ImportItemResult {
+	item := importItem(mem)
+	fail := func(err error) types.ImportItemResult {
+		item.Action = types.ImportFailed
+		item.Reason = err.Error()
+		return item
+	}
 
-			if err != nil {
-				return nil, fmt.Errorf("failed to insert memory %s: %w", mem.Title, err)
-			}
-			added++
+	mem.StoreRequest = importDefaults(mem.StoreRequest)
+	if err := validateImport(mem.StoreRequest); err != nil {
+		return fail(err)
+	}
+
+	existingID, err := d.matchExisting(mem)
+	if err != nil {
+		return fail(err)
+	}
+	if existingID == 0 {
+		id, err := d.insertImported(mem)
+		if err != nil {
+			return fail(err)
 		}
+		item.ID = id
+		item.Action = types.ImportAdded
+		return item
 	}
 
-	return &types.ImportResult{
-		Added:   added,
-		Updated: updated,
-		Total:   added + updated,
-	}, nil
+	current, err := d.GetByID(existingID)
+	if err != nil {
+		return fail(fmt.Errorf("failed to get existing memory: %w", err))
+	}
+
+	switch strategy {
+	case types.ConflictSkip:
+		item.ID = existingID
+		item.Action = types.ImportSkipped
+		item.Reason = "conflict: kept existing memory"
+		return item
+
+	case types.ConflictKeepBoth:
+		if len(fieldDiffs(current, mem.StoreRequest)) == 0 {
+			break
+		}
+		title, keptID, err := d.keepBothTitle(mem.StoreRequest)
+		if err != nil {
+			return fail(err)
+		}
+		item.Title = title
+		if keptID > 0 {
+			item.ID = keptID
+			item.Action = types.ImportSkipped
+			item.Reason = "unchanged"
+			return item
+		}
+		mem.Title = title
+		id, err := d.insertImported(mem)
+		if err != nil {
+			return fail(err)
+		}
+		item.ID = id
+		item.Action = types.ImportAdded
+		item.Reason = fmt.Sprintf("conflict: kept both, imported as %q", title)
+		return item
+
+	case types.ConflictMerge:
+		mem.StoreRequest = mergeMemory(current, mem.StoreRequest)
+		item.Reason = "conflict: merged into existing memory"
+	}
+
+	// 覆盖（或合并后写回）已有记忆：内容未变时跳过
+	item.ID = existingID
+	if len(fieldDiffs(current, mem.StoreRequest)) == 0 {
+		item.Action = types.ImportSkipped
+		item.Reason = "unchanged"
+		return item
+	}
+	if err := d.updateImported(existingID, mem.StoreRequest); err != nil {
+		return fail(err)
+	}
+	item.Action = types.ImportUpdated
+	return item
+}
+
+// insertImported 新增导入的记忆并记录版本，保留知识包中的 UUID、创建时间、访问次数和置信度
+func (d *Database) insertImported(mem types.PackageMemory) (int64, error) {
+	confidence := mem.Confidence
+	if confidence <= 0 {
+		confidence = 1.0
+		if mem.Source == types.SourceAutoCaptured {
+			confidence = 0.7
+		}
+	}
+	createdAt := mem.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	titleSeg, contentSeg, summarySeg := segmentFields(mem.Title, mem.Content, mem.Summary)
+	result, err := d.db.Exec(`
+		INSERT INTO knowledge_base (
+			level, language_tag, library_name, library_version, project_path_pattern,
+			title, content, summary, source, confidence, access_count, created_at,
+			title_seg, content_seg, summary_seg
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, mem.Level, mem.LanguageTag, mem.LibraryName, mem.LibraryVersion, mem.ProjectPathPattern,
+		mem.Title, mem.Content, mem.Summary, mem.Source, confidence, mem.AccessCount, createdAt,
+		titleSeg, contentSeg, summarySeg)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert memory: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	if err := d.adoptUUID(id, mem.UUID); err != nil {
+		return 0, err
+	}
+	if err := d.setTags(id, mem.Tags); err != nil {
+		return 0, err
+	}
+	if err := d.setDiagnosticSignature(id, mem.DiagnosticSignature); err != nil {
+		return 0, err
+	}
+	if err := d.recordRevision(id, types.RevisionImport, mem.Editor); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// updateImported 用导入的记忆覆盖已有记忆并记录版本（按 UUID 匹配时标题、层级等也以导入的为准）
+func (d *Database) updateImported(id int64, mem types.StoreRequest) error {
+	titleSeg, contentSeg, summarySeg := segmentFields(mem.Title, mem.Content, mem.Summary)
+	_, err := d.db.Exec(`
+		UPDATE knowledge_base
+		SET level = ?, library_name = ?, library_version = ?, title = ?,
+		    language_tag = ?, project_path_pattern = ?,
+		    content = ?, summary = ?, source = ?,
+		    title_seg = ?, content_seg = ?, summary_seg = ?,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, mem.Level, mem.LibraryName, mem.LibraryVersion, mem.Title,
+		mem.LanguageTag, mem.ProjectPathPattern,
+		mem.Content, mem.Summary, mem.Source,
+		titleSeg, contentSeg, summarySeg, id)
+	if err != nil {
+		return fmt.Errorf("failed to update memory: %w", err)
+	}
+	if err := d.setTags(id, mem.Tags); err != nil {
+		return err
+	}
+	if err := d.setDiagnosticSignature(id, mem.DiagnosticSignature); err != nil {
+		return err
+	}
+	return d.recordRevision(id, types.RevisionImport, mem.Editor)
+}
+
+// validateImport 校验导入的记忆
+func validateImport(mem types.StoreRequest) error {
+	if !mem.Level.IsValid() {
+		return fmt.Errorf("invalid knowledge level: %s", mem.Level)
+	}
+	if strings.TrimSpace(mem.Title) == "" {
+		return fmt.Errorf("title cannot be empty")
+	}
+	if strings.TrimSpace(mem.Content) == "" {
+		return fmt.Errorf("content cannot be empty")
+	}
+	if mem.Level == types.LevelLibrary && mem.LibraryName == "" {
+		return fmt.Errorf("library_name is required for library level")
+	}
+	if mem.Level == types.LevelProject && mem.ProjectPathPattern == "" {
+		return fmt.Errorf("project_path_pattern is required for project level")
+	}
+	return validateLibraryVersion(mem.Level, mem.LibraryVersion)
 }
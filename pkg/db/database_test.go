@@ -5,9 +5,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	_ "modernc.org/sqlite"
+	"github.com/ystyle/cangjie-mem/pkg/embed"
 	"github.com/ystyle/cangjie-mem/pkg/types"
 )
 
@@ -520,19 +524,34 @@ func TestDelete(t *testing.T) {
 	}
 
 	// 删除记忆
-	err = db.Delete(storeResp.ID)
+	err = db.Delete(storeResp.ID, "")
 	if err != nil {
 		t.Fatalf("Delete() error = %v", err)
 	}
 
-	// 验证记忆已删除
-	_, err = db.GetByID(storeResp.ID)
-	if err == nil {
-		t.Error("memory should not exist after delete")
+	// 验证记忆已移入回收站
+	memory, err := db.GetByID(storeResp.ID)
+	if err != nil {
+		t.Fatalf("GetByID() after delete error = %v", err)
+	}
+	if memory.DeletedAt == nil {
+		t.Error("memory should be marked as deleted after delete")
+	}
+	listResp, err := db.List(types.ListRequest{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if listResp.Total != 0 {
+		t.Errorf("List() total after delete = %v, want 0", listResp.Total)
+	}
+
+	// 重复删除返回错误
+	if err := db.Delete(storeResp.ID, ""); err == nil {
+		t.Error("deleting trashed memory should return error")
 	}
 
 	// 删除不存在的记忆
-	err = db.Delete(99999)
+	err = db.Delete(99999, "")
 	if err == nil {
 		t.Error("deleting non-existent memory should return error")
 	}
@@ -571,7 +590,7 @@ func TestRecall(t *testing.T) {
 	}
 
 	// 测试英文全文搜索
-	results, err := db.Recall("RouterGroup", types.LevelLibrary, "cangjie", "", "", 10)
+	results, err := db.Recall("RouterGroup", RecallOptions{Level: types.LevelLibrary, LanguageTag: "cangjie", Limit: 10})
 	if err != nil {
 		t.Fatalf("Recall() error = %v", err)
 	}
@@ -824,6 +843,41 @@ func TestMigration(t *testing.T) {
 		} else {
 			t.Logf("✓ idx_knowledge_library 索引已成功创建")
 		}
+
+		// 验证 6: 旧 FTS 索引已按 CJK 分词重建，中文子串可以匹配
+		results, err := newDB.Recall(`"声 明"`, RecallOptions{LanguageTag: "cangjie", Limit: 10})
+		if err != nil {
+			t.Fatalf("Recall() after FTS migration failed: %v", err)
+		}
+		if len(results) != 1 {
+			t.Errorf("Recall() CJK substring results = %v, want 1", len(results))
+		}
+
+		// 验证 7: 旧数据已补充初始修订版本
+		listResp, err = newDB.List(types.ListRequest{})
+		if err != nil {
+			t.Fatalf("List() failed after migration: %v", err)
+		}
+		for _, m := range listResp.Results {
+			revisions, err := newDB.ListRevisions(m.ID)
+			if err != nil {
+				t.Fatalf("ListRevisions() after migration failed: %v", err)
+			}
+			if len(revisions) != 1 || revisions[0].Action != types.RevisionCreate {
+				t.Errorf("ListRevisions(%d) after migration = %+v, want 1 create revision", m.ID, revisions)
+			}
+		}
+
+		// 验证 8: 旧数据已补齐随机 UUID
+		for _, m := range listResp.Results {
+			memory, err := newDB.GetByID(m.ID)
+			if err != nil {
+				t.Fatalf("GetByID() after migration failed: %v", err)
+			}
+			if parsed, err := uuid.Parse(memory.UUID); err != nil || parsed.Version() != 4 {
+				t.Errorf("GetByID(%d).UUID after migration = %q, want a v4 uuid", m.ID, memory.UUID)
+			}
+		}
 	})
 
 	t.Run("迁移幂等性", func(t *testing.T) {
@@ -856,3 +910,1386 @@ func TestMigration(t *testing.T) {
 	})
 }
 
+
+func TestPatch(t *testing.T) {
+	db := getTestDB(t)
+
+	storeResp, err := db.Store(types.StoreRequest{
+		Level:       types.LevelLibrary,
+		LibraryName: "tang",
+		Title:       "Tang 路由",
+		Content:     "使用 RouterGroup 配置路由",
+		Summary:     "路由配置",
+	})
+	if err != nil {
+		t.Fatalf("failed to store test memory: %v", err)
+	}
+	if err := db.UpdateAccessCount(storeResp.ID); err != nil {
+		t.Fatalf("failed to update access count: %v", err)
+	}
+
+	t.Run("仅更新内容", func(t *testing.T) {
+		content := "使用 Router.group 配置路由分组"
+		memory, err := db.Patch(types.UpdateRequest{ID: storeResp.ID, Content: &content})
+		if err != nil {
+			t.Fatalf("Patch() error = %v", err)
+		}
+		if memory.Content != content {
+			t.Errorf("Content = %v, want %v", memory.Content, content)
+		}
+		// 未提供的字段保持不变
+		if memory.Title != "Tang 路由" || memory.Summary != "路由配置" || memory.LibraryName != "tang" {
+			t.Errorf("unchanged fields were modified: %+v", memory)
+		}
+		if memory.AccessCount != 1 {
+			t.Errorf("AccessCount = %v, want 1", memory.AccessCount)
+		}
+	})
+
+	t.Run("清空摘要", func(t *testing.T) {
+		empty := ""
+		memory, err := db.Patch(types.UpdateRequest{ID: storeResp.ID, Summary: &empty})
+		if err != nil {
+			t.Fatalf("Patch() error = %v", err)
+		}
+		if memory.Summary != "" {
+			t.Errorf("Summary = %v, want empty", memory.Summary)
+		}
+	})
+
+	t.Run("切换为项目级但缺少路径", func(t *testing.T) {
+		level := types.LevelProject
+		if _, err := db.Patch(types.UpdateRequest{ID: storeResp.ID, Level: &level}); err == nil {
+			t.Error("Patch() should fail when project_path_pattern is missing")
+		}
+	})
+
+	t.Run("无效层级", func(t *testing.T) {
+		level := types.KnowledgeLevel("invalid")
+		if _, err := db.Patch(types.UpdateRequest{ID: storeResp.ID, Level: &level}); err == nil {
+			t.Error("Patch() should fail with invalid level")
+		}
+	})
+
+	t.Run("记忆不存在", func(t *testing.T) {
+		title := "不存在"
+		if _, err := db.Patch(types.UpdateRequest{ID: 99999, Title: &title}); err == nil {
+			t.Error("Patch() should fail for non-existent memory")
+		}
+	})
+}
+
+func TestSuggestions(t *testing.T) {
+	db := getTestDB(t)
+
+	id, err := db.CreateSuggestion(types.SuggestRequest{
+		Query:            "泛型约束",
+		SuggestedTitle:   "泛型约束实现方法",
+		SuggestedContent: "使用 where 子句约束泛型参数",
+		SuggestedLevel:   types.LevelLanguage,
+		Reason:           "未找到相关记忆",
+	})
+	if err != nil {
+		t.Fatalf("CreateSuggestion() error = %v", err)
+	}
+
+	if _, err := db.CreateSuggestion(types.SuggestRequest{
+		Query:            "test",
+		SuggestedTitle:   "test",
+		SuggestedContent: "test",
+		SuggestedLevel:   "invalid",
+	}); err == nil {
+		t.Error("CreateSuggestion() should fail with invalid level")
+	}
+
+	s, err := db.GetSuggestion(id)
+	if err != nil {
+		t.Fatalf("GetSuggestion() error = %v", err)
+	}
+	if s.Status != types.SuggestionPending {
+		t.Errorf("Status = %v, want %v", s.Status, types.SuggestionPending)
+	}
+	if s.LanguageTag != "cangjie" {
+		t.Errorf("LanguageTag = %v, want cangjie", s.LanguageTag)
+	}
+
+	listResp, err := db.ListSuggestions(types.SuggestionListRequest{Status: types.SuggestionPending})
+	if err != nil {
+		t.Fatalf("ListSuggestions() error = %v", err)
+	}
+	if listResp.Total != 1 {
+		t.Errorf("ListSuggestions() total = %v, want 1", listResp.Total)
+	}
+
+	if err := db.ReviewSuggestion(id, types.SuggestionRejected, 0, "重复"); err != nil {
+		t.Fatalf("ReviewSuggestion() error = %v", err)
+	}
+
+	// 已审核的建议不能再次审核
+	if err := db.ReviewSuggestion(id, types.SuggestionApproved, 1, ""); err == nil {
+		t.Error("ReviewSuggestion() should fail for already reviewed suggestion")
+	}
+
+	if err := db.ReviewSuggestion(99999, types.SuggestionRejected, 0, ""); err == nil {
+		t.Error("ReviewSuggestion() should fail for non-existent suggestion")
+	}
+
+	listResp, err = db.ListSuggestions(types.SuggestionListRequest{Status: types.SuggestionPending})
+	if err != nil {
+		t.Fatalf("ListSuggestions() error = %v", err)
+	}
+	if listResp.Total != 0 {
+		t.Errorf("ListSuggestions() pending total = %v, want 0", listResp.Total)
+	}
+}
+
+func TestTags(t *testing.T) {
+	db := getTestDB(t)
+
+	resp, err := db.Store(types.StoreRequest{
+		Level:   types.LevelLanguage,
+		Title:   "接口定义",
+		Content: "使用 interface 关键字定义接口",
+		Tags:    []string{"语法", " Interface ", "语法", ""},
+	})
+	if err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	if _, err := db.Store(types.StoreRequest{
+		Level:   types.LevelLanguage,
+		Title:   "变量声明",
+		Content: "使用 let/var 声明变量",
+		Tags:    []string{"语法"},
+	}); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+
+	// 标签已规范化
+	memory, err := db.GetByID(resp.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if len(memory.Tags) != 2 || memory.Tags[0] != "语法" || memory.Tags[1] != "interface" {
+		t.Errorf("Tags = %v, want [语法 interface]", memory.Tags)
+	}
+
+	// 标签筛选（需同时包含所有标签）
+	listResp, err := db.List(types.ListRequest{Tags: []string{"语法"}})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if listResp.Total != 2 {
+		t.Errorf("List() by tag total = %v, want 2", listResp.Total)
+	}
+	listResp, err = db.List(types.ListRequest{Tags: []string{"语法", "interface"}})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if listResp.Total != 1 {
+		t.Errorf("List() by two tags total = %v, want 1", listResp.Total)
+	}
+
+	results, err := db.Recall("interface", RecallOptions{LanguageTag: "cangjie", Tags: []string{"interface"}, Limit: 10})
+	if err != nil {
+		t.Fatalf("Recall() error = %v", err)
+	}
+	if len(results) != 1 || len(results[0].Tags) != 2 {
+		t.Errorf("Recall() by tag = %+v, want 1 result with tags", results)
+	}
+
+	// 分类统计包含标签
+	categories, err := db.ListCategories("cangjie")
+	if err != nil {
+		t.Fatalf("ListCategories() error = %v", err)
+	}
+	if len(categories.Tags) != 2 || categories.Tags[0].Name != "语法" || categories.Tags[0].Count != 2 {
+		t.Errorf("ListCategories() tags = %+v, want 语法:2 first", categories.Tags)
+	}
+
+	// 导出/导入保留标签
+	exported, err := db.ExportForImport(types.ExportRequest{MemoryTags: []string{"interface"}})
+	if err != nil {
+		t.Fatalf("ExportForImport() error = %v", err)
+	}
+	if len(exported) != 1 || len(exported[0].Tags) != 2 {
+		t.Fatalf("ExportForImport() = %+v, want 1 memory with 2 tags", exported)
+	}
+	exported[0].Tags = []string{"syntax"}
+	if _, err := db.ImportMemories(exported, types.ImportOptions{}); err != nil {
+		t.Fatalf("ImportMemories() error = %v", err)
+	}
+	memory, err = db.GetByID(resp.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if len(memory.Tags) != 1 || memory.Tags[0] != "syntax" {
+		t.Errorf("Tags after import = %v, want [syntax]", memory.Tags)
+	}
+
+	// 部分更新：未传标签时保持不变，传空数组时清空
+	title := "接口定义方式"
+	memory, err = db.Patch(types.UpdateRequest{ID: resp.ID, Title: &title})
+	if err != nil {
+		t.Fatalf("Patch() error = %v", err)
+	}
+	if len(memory.Tags) != 1 {
+		t.Errorf("Tags after patch without tags = %v, want unchanged", memory.Tags)
+	}
+	empty := []string{}
+	memory, err = db.Patch(types.UpdateRequest{ID: resp.ID, Tags: &empty})
+	if err != nil {
+		t.Fatalf("Patch() error = %v", err)
+	}
+	if len(memory.Tags) != 0 {
+		t.Errorf("Tags after clearing = %v, want empty", memory.Tags)
+	}
+
+	// 删除记忆时清理标签
+	if err := db.Delete(resp.ID, ""); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	var count int
+	if err := db.db.QueryRow(`SELECT COUNT(*) FROM knowledge_tags WHERE knowledge_id = ?`, resp.ID).Scan(&count); err != nil {
+		t.Fatalf("failed to count tags: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("tags of deleted memory = %d, want 0", count)
+	}
+}
+
+func TestEmbeddings(t *testing.T) {
+	db := getTestDB(t)
+	const model = "test-model"
+
+	first, err := db.Store(types.StoreRequest{Level: types.LevelLanguage, Title: "A", Content: "alpha"})
+	if err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	second, err := db.Store(types.StoreRequest{Level: types.LevelLanguage, Title: "B", Content: "beta"})
+	if err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+
+	missing, err := db.MissingEmbeddings(model, 10)
+	if err != nil {
+		t.Fatalf("MissingEmbeddings() error = %v", err)
+	}
+	if len(missing) != 2 {
+		t.Fatalf("MissingEmbeddings() = %d, want 2", len(missing))
+	}
+
+	if err := db.SaveEmbedding(first.ID, model, embed.Encode([]float32{1, 0, 0})); err != nil {
+		t.Fatalf("SaveEmbedding() error = %v", err)
+	}
+	if err := db.SaveEmbedding(second.ID, model, embed.Encode([]float32{0, 1, 0})); err != nil {
+		t.Fatalf("SaveEmbedding() error = %v", err)
+	}
+
+	// 按余弦相似度排序
+	results, err := db.VectorSearch(embed.Encode([]float32{0.1, 1, 0}), model, RecallOptions{LanguageTag: "cangjie", Limit: 10})
+	if err != nil {
+		t.Fatalf("VectorSearch() error = %v", err)
+	}
+	if len(results) != 2 || results[0].ID != second.ID {
+		t.Fatalf("VectorSearch() = %+v, want B first", results)
+	}
+	if results[0].Similarity < 0.99 || results[1].Similarity > 0.2 {
+		t.Errorf("Similarity = %v, %v", results[0].Similarity, results[1].Similarity)
+	}
+
+	// 其他模型的向量不参与检索
+	if missing, _ := db.MissingEmbeddings("other-model", 10); len(missing) != 2 {
+		t.Errorf("MissingEmbeddings(other) = %d, want 2", len(missing))
+	}
+
+	// 内容修改后向量失效
+	content := "alpha updated"
+	if _, err := db.Patch(types.UpdateRequest{ID: first.ID, Content: &content}); err != nil {
+		t.Fatalf("Patch() error = %v", err)
+	}
+	missing, _ = db.MissingEmbeddings(model, 10)
+	if len(missing) != 1 || missing[0].ID != first.ID || missing[0].Content != content {
+		t.Errorf("MissingEmbeddings() after update = %+v", missing)
+	}
+
+	// 删除记忆后向量一并删除
+	if err := db.Delete(second.ID, ""); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	results, err = db.VectorSearch(embed.Encode([]float32{0, 1, 0}), model, RecallOptions{LanguageTag: "cangjie", Limit: 10})
+	if err != nil {
+		t.Fatalf("VectorSearch() error = %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("VectorSearch() after delete = %d, want 0", len(results))
+	}
+
+	// 回收站中的记忆不生成向量
+	if missing, _ := db.MissingEmbeddings("other-model", 10); len(missing) != 1 || missing[0].ID != first.ID {
+		t.Errorf("MissingEmbeddings(other) after delete = %+v, want only A", missing)
+	}
+	sources, err := db.EmbeddingSources([]int64{first.ID, second.ID})
+	if err != nil || len(sources) != 1 || sources[0].ID != first.ID {
+		t.Errorf("EmbeddingSources() = %+v, %v, want only A", sources, err)
+	}
+}
+
+func TestRevisions(t *testing.T) {
+	db := getTestDB(t)
+
+	resp, err := db.Store(types.StoreRequest{
+		Level:       types.LevelLibrary,
+		LibraryName: "tang",
+		Title:       "路由注册",
+		Content:     "使用 router.get 注册路由",
+		Tags:        []string{"web"},
+		Editor:      types.EditorMCP,
+	})
+	if err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+
+	content := "使用 router.post 注册路由"
+	if _, err := db.Patch(types.UpdateRequest{ID: resp.ID, Content: &content, Editor: types.EditorAPI}); err != nil {
+		t.Fatalf("Patch() error = %v", err)
+	}
+	if _, err := db.ImportMemories(types.NewPackageMemories([]types.StoreRequest{{
+		Level:       types.LevelLibrary,
+		LibraryName: "tang",
+		Title:       "路由注册",
+		Content:     "导入覆盖的内容",
+	}}), types.ImportOptions{}); err != nil {
+		t.Fatalf("ImportMemories() error = %v", err)
+	}
+
+	revisions, err := db.ListRevisions(resp.ID)
+	if err != nil {
+		t.Fatalf("ListRevisions() error = %v", err)
+	}
+	if len(revisions) != 3 {
+		t.Fatalf("ListRevisions() = %d, want 3", len(revisions))
+	}
+	wantActions := []string{types.RevisionImport, types.RevisionUpdate, types.RevisionCreate}
+	wantEditors := []string{types.EditorImport, types.EditorAPI, types.EditorMCP}
+	for i, r := range revisions {
+		if r.Revision != 3-i || r.Action != wantActions[i] || r.Editor != wantEditors[i] {
+			t.Errorf("revision[%d] = %d/%s/%s, want %d/%s/%s", i, r.Revision, r.Action, r.Editor, 3-i, wantActions[i], wantEditors[i])
+		}
+	}
+	if revisions[0].Content != "导入覆盖的内容" || len(revisions[2].Tags) != 1 {
+		t.Errorf("revision snapshot mismatch: %+v", revisions)
+	}
+
+	// 回滚到第 1 版
+	memory, err := db.RestoreRevision(resp.ID, 1, types.EditorAPI)
+	if err != nil {
+		t.Fatalf("RestoreRevision() error = %v", err)
+	}
+	if memory.Content != "使用 router.get 注册路由" || len(memory.Tags) != 1 || memory.Tags[0] != "web" {
+		t.Errorf("RestoreRevision() = %+v", memory)
+	}
+	latest, err := db.GetRevision(resp.ID, 4)
+	if err != nil {
+		t.Fatalf("GetRevision() error = %v", err)
+	}
+	if latest.Action != types.RevisionRestore {
+		t.Errorf("latest action = %s, want restore", latest.Action)
+	}
+	if _, err := db.RestoreRevision(resp.ID, 99, ""); err == nil {
+		t.Error("RestoreRevision() with missing revision should fail")
+	}
+
+	// 移入回收站后历史保留
+	if err := db.Delete(resp.ID, types.EditorMCP); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	revisions, err = db.ListRevisions(resp.ID)
+	if err != nil {
+		t.Fatalf("ListRevisions() error = %v", err)
+	}
+	if len(revisions) != 5 || revisions[0].Action != types.RevisionDelete || revisions[0].Content != "使用 router.get 注册路由" {
+		t.Errorf("ListRevisions() after delete = %+v", revisions[0])
+	}
+}
+
+func TestTrash(t *testing.T) {
+	db := getTestDB(t)
+
+	resp, err := db.Store(types.StoreRequest{
+		Level:       types.LevelLibrary,
+		LibraryName: "tang",
+		Title:       "中间件",
+		Content:     "使用 use 注册中间件",
+		Tags:        []string{"web"},
+	})
+	if err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	kept, err := db.Store(types.StoreRequest{Level: types.LevelLanguage, Title: "保留", Content: "中间件之外的记忆"})
+	if err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+
+	if err := db.Delete(resp.ID, types.EditorAPI); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	// 回收站中的记忆不出现在检索、分类和导出中
+	results, err := db.Recall(`"中 间 件"`, RecallOptions{LanguageTag: "cangjie", Limit: 10})
+	if err != nil {
+		t.Fatalf("Recall() error = %v", err)
+	}
+	if len(results) != 1 || results[0].ID != kept.ID {
+		t.Errorf("Recall() = %+v, want only kept memory", results)
+	}
+	categories, err := db.ListCategories("cangjie")
+	if err != nil {
+		t.Fatalf("ListCategories() error = %v", err)
+	}
+	if len(categories.Libraries) != 0 || len(categories.Tags) != 0 {
+		t.Errorf("ListCategories() = %+v, want no libraries and tags", categories)
+	}
+	exported, err := db.ExportForImport(types.ExportRequest{})
+	if err != nil {
+		t.Fatalf("ExportForImport() error = %v", err)
+	}
+	if len(exported) != 1 {
+		t.Errorf("ExportForImport() = %d, want 1", len(exported))
+	}
+	content := "修改回收站中的记忆"
+	if _, err := db.Patch(types.UpdateRequest{ID: resp.ID, Content: &content}); err == nil {
+		t.Error("Patch() on trashed memory should fail")
+	}
+
+	trash, err := db.ListTrash(types.TrashListRequest{})
+	if err != nil {
+		t.Fatalf("ListTrash() error = %v", err)
+	}
+	if trash.Total != 1 || trash.Results[0].ID != resp.ID || trash.Results[0].DeletedAt == nil {
+		t.Fatalf("ListTrash() = %+v, want trashed memory", trash)
+	}
+
+	// 恢复
+	memory, err := db.Undelete(resp.ID, types.EditorAPI)
+	if err != nil {
+		t.Fatalf("Undelete() error = %v", err)
+	}
+	if memory.DeletedAt != nil || len(memory.Tags) != 1 {
+		t.Errorf("Undelete() = %+v, want restored memory with tags", memory)
+	}
+	if _, err := db.Undelete(resp.ID, ""); err == nil {
+		t.Error("Undelete() on live memory should fail")
+	}
+	if err := db.Purge(resp.ID); err == nil {
+		t.Error("Purge() on live memory should fail")
+	}
+
+	// 按保留期限清理
+	if err := db.Delete(resp.ID, ""); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	purged, err := db.PurgeTrash(time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("PurgeTrash() error = %v", err)
+	}
+	if purged != 0 {
+		t.Errorf("PurgeTrash(1h ago) = %d, want 0", purged)
+	}
+	purged, err = db.PurgeTrash(time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("PurgeTrash() error = %v", err)
+	}
+	if purged != 1 {
+		t.Errorf("PurgeTrash(1h later) = %d, want 1", purged)
+	}
+	if _, err := db.GetByID(resp.ID); err != sql.ErrNoRows {
+		t.Errorf("GetByID() after purge error = %v, want sql.ErrNoRows", err)
+	}
+	if revisions, _ := db.ListRevisions(resp.ID); len(revisions) != 0 {
+		t.Errorf("ListRevisions() after purge = %d, want 0", len(revisions))
+	}
+}
+
+func TestLinks(t *testing.T) {
+	db := getTestDB(t)
+
+	var ids []int64
+	for _, title := range []string{"tang 路由", "tang 中间件", "旧版路由"} {
+		resp, err := db.Store(types.StoreRequest{Level: types.LevelLibrary, LibraryName: "tang", Title: title, Content: title})
+		if err != nil {
+			t.Fatalf("Store() error = %v", err)
+		}
+		ids = append(ids, resp.ID)
+	}
+	routing, middleware, legacy := ids[0], ids[1], ids[2]
+
+	link, err := db.Link(types.LinkRequest{SourceID: routing, TargetID: middleware, Relation: types.RelationDependsOn, Note: "路由注册依赖中间件"})
+	if err != nil {
+		t.Fatalf("Link() error = %v", err)
+	}
+	if link.ID == 0 || link.Note != "路由注册依赖中间件" {
+		t.Errorf("Link() = %+v", link)
+	}
+	if _, err := db.Link(types.LinkRequest{SourceID: routing, TargetID: legacy, Relation: types.RelationSupersedes}); err != nil {
+		t.Fatalf("Link() error = %v", err)
+	}
+
+	// 重复关系和不存在的记忆
+	if _, err := db.Link(types.LinkRequest{SourceID: routing, TargetID: middleware, Relation: types.RelationDependsOn}); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Link() duplicate error = %v", err)
+	}
+	if _, err := db.Link(types.LinkRequest{SourceID: routing, TargetID: 9999, Relation: types.RelationSeeAlso}); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Link() missing target error = %v", err)
+	}
+
+	links, err := db.Neighbors([]int64{middleware}, "")
+	if err != nil {
+		t.Fatalf("Neighbors() error = %v", err)
+	}
+	if len(links) != 1 || links[0].SourceID != routing || links[0].Note != "路由注册依赖中间件" {
+		t.Errorf("Neighbors() = %+v", links)
+	}
+	links, _ = db.Neighbors([]int64{routing}, types.RelationSupersedes)
+	if len(links) != 1 || links[0].TargetID != legacy {
+		t.Errorf("Neighbors(supersedes) = %+v", links)
+	}
+
+	// 回收站中的记忆不参与遍历，彻底删除后关系一并清理
+	if err := db.Delete(legacy, types.EditorAPI); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if links, _ := db.Neighbors([]int64{routing}, ""); len(links) != 1 {
+		t.Errorf("Neighbors() after delete = %+v, want 1 link", links)
+	}
+	if err := db.Purge(legacy); err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	var count int
+	if err := db.db.QueryRow(`SELECT COUNT(*) FROM knowledge_links WHERE target_id = ?`, legacy).Scan(&count); err != nil {
+		t.Fatalf("count links error = %v", err)
+	}
+	if count != 0 {
+		t.Errorf("links after purge = %d, want 0", count)
+	}
+
+	removed, err := db.Unlink(types.LinkRequest{SourceID: routing, TargetID: middleware})
+	if err != nil || removed != 1 {
+		t.Errorf("Unlink() = %d, %v, want 1", removed, err)
+	}
+	if _, err := db.Unlink(types.LinkRequest{SourceID: routing, TargetID: middleware}); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Unlink() missing link error = %v", err)
+	}
+}
+
+func TestLibraryVersion(t *testing.T) {
+	db := getTestDB(t)
+
+	memories := []types.StoreRequest{
+		{Level: types.LevelLibrary, LibraryName: "tang", LibraryVersion: "^1.0", Title: "tang 路由注册", Content: "1.x 使用 router.get 注册路由"},
+		{Level: types.LevelLibrary, LibraryName: "tang", LibraryVersion: ">=2.0", Title: "tang 路由注册", Content: "2.x 使用 group.route 注册路由"},
+		{Level: types.LevelLibrary, LibraryName: "tang", Title: "tang 路由中间件", Content: "中间件在注册路由前调用 use"},
+		{Level: types.LevelLanguage, Title: "路由语法", Content: "语言级路由无关版本"},
+	}
+	ids := make([]int64, len(memories))
+	for i, m := range memories {
+		resp, err := db.Store(m)
+		if err != nil {
+			t.Fatalf("Store() error = %v", err)
+		}
+		ids[i] = resp.ID
+	}
+
+	// 非法版本范围和非库级记忆不能设置版本范围
+	if _, err := db.Store(types.StoreRequest{Level: types.LevelLibrary, LibraryName: "tang", LibraryVersion: "1.x.3", Title: "t", Content: "c"}); err == nil || !strings.Contains(err.Error(), "invalid library_version") {
+		t.Errorf("Store() invalid range error = %v", err)
+	}
+	if _, err := db.Store(types.StoreRequest{Level: types.LevelLanguage, LibraryVersion: "^1.0", Title: "t", Content: "c"}); err == nil {
+		t.Error("Store() language level with library_version expected error")
+	}
+
+	memory, err := db.GetByID(ids[0])
+	if err != nil || memory.LibraryVersion != "^1.0" {
+		t.Fatalf("GetByID() = %+v, %v", memory, err)
+	}
+
+	titles := func(results []types.Memory) map[int64]bool {
+		out := map[int64]bool{}
+		for _, m := range results {
+			out[m.ID] = true
+		}
+		return out
+	}
+
+	// 列出：库级记忆只保留版本范围包含该版本的，未标注版本和其他层级不受影响
+	resp, err := db.List(types.ListRequest{LibraryVersion: "1.5.0"})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	got := titles(resp.Results)
+	if resp.Total != 3 || !got[ids[0]] || got[ids[1]] || !got[ids[2]] || !got[ids[3]] {
+		t.Errorf("List(1.5.0) = %v, want ids %v", got, []int64{ids[0], ids[2], ids[3]})
+	}
+	resp, _ = db.List(types.ListRequest{LibraryName: "tang", LibraryVersion: "2.3.1"})
+	got = titles(resp.Results)
+	if resp.Total != 2 || !got[ids[1]] || !got[ids[2]] {
+		t.Errorf("List(tang, 2.3.1) = %v", got)
+	}
+
+	// 检索
+	results, err := db.Recall(`"路 由"`, RecallOptions{LanguageTag: "cangjie", LibraryVersion: "2.0.0", Limit: 10})
+	if err != nil {
+		t.Fatalf("Recall() error = %v", err)
+	}
+	for _, r := range results {
+		if r.ID == ids[0] {
+			t.Errorf("Recall(2.0.0) returned memory for ^1.0: %+v", r)
+		}
+		if r.ID == ids[1] && r.LibraryVersion != ">=2.0" {
+			t.Errorf("Recall() LibraryVersion = %q, want >=2.0", r.LibraryVersion)
+		}
+	}
+	if len(results) != 3 {
+		t.Errorf("Recall(2.0.0) = %d results, want 3", len(results))
+	}
+
+	// 分类按版本范围细分
+	categories, err := db.ListCategories("cangjie")
+	if err != nil {
+		t.Fatalf("ListCategories() error = %v", err)
+	}
+	if len(categories.Libraries) != 1 || categories.Libraries[0].Count != 3 {
+		t.Fatalf("ListCategories() libraries = %+v", categories.Libraries)
+	}
+	versions := map[string]int{}
+	for _, v := range categories.Libraries[0].Versions {
+		versions[v.Name] = v.Count
+	}
+	if len(versions) != 3 || versions["^1.0"] != 1 || versions[">=2.0"] != 1 || versions["*"] != 1 {
+		t.Errorf("ListCategories() versions = %v", versions)
+	}
+
+	// 更新版本范围并记录到修订历史
+	newRange := "^1.0 || ^3.0"
+	updated, err := db.Patch(types.UpdateRequest{ID: ids[0], LibraryVersion: &newRange})
+	if err != nil || updated.LibraryVersion != newRange {
+		t.Fatalf("Patch() = %+v, %v", updated, err)
+	}
+	revisions, err := db.ListRevisions(ids[0])
+	if err != nil || len(revisions) != 2 || revisions[0].LibraryVersion != newRange || revisions[1].LibraryVersion != "^1.0" {
+		t.Errorf("ListRevisions() = %+v, %v", revisions, err)
+	}
+
+	// 导出保留版本范围，导入时按库名、版本范围和标题匹配已有记忆
+	exported, err := db.ExportForImport(types.ExportRequest{LibraryName: "tang"})
+	if err != nil {
+		t.Fatalf("ExportForImport() error = %v", err)
+	}
+	exportedVersions := map[string]bool{}
+	for _, m := range exported {
+		exportedVersions[m.LibraryVersion] = true
+	}
+	if !exportedVersions[newRange] || !exportedVersions[">=2.0"] || !exportedVersions[""] {
+		t.Errorf("ExportForImport() versions = %v", exportedVersions)
+	}
+
+	imports := []types.StoreRequest{
+		{Level: types.LevelLibrary, LibraryName: "tang", LibraryVersion: ">=2.0", Title: "tang 路由注册", Content: "2.x 改用 group.route"},
+		{Level: types.LevelLibrary, LibraryName: "tang", LibraryVersion: "~0.9", Title: "tang 路由注册", Content: "0.9 使用 addRoute"},
+	}
+	conflicts, err := db.FindConflicts(types.NewPackageMemories(imports))
+	if err != nil || len(conflicts) != 1 || conflicts[0].ExistingID != ids[1] || conflicts[0].LibraryVersion != ">=2.0" {
+		t.Errorf("FindConflicts() = %+v, %v", conflicts, err)
+	}
+	result, err := db.ImportMemories(types.NewPackageMemories(imports), types.ImportOptions{})
+	if err != nil || result.Added != 1 || result.Updated != 1 {
+		t.Fatalf("ImportMemories() = %+v, %v", result, err)
+	}
+	memory, _ = db.GetByID(ids[1])
+	if memory.Content != "2.x 改用 group.route" {
+		t.Errorf("imported memory content = %q", memory.Content)
+	}
+	resp, _ = db.List(types.ListRequest{LibraryName: "tang", LibraryVersion: "0.9.4"})
+	if resp.Total != 2 {
+		t.Errorf("List(tang, 0.9.4) total = %d, want 2", resp.Total)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	db := getTestDB(t)
+
+	memories := []types.StoreRequest{
+		{Level: types.LevelLanguage, Title: "未声明标识符", Content: "检查导入和拼写", DiagnosticSignature: "undeclared identifier <id>"},
+		{Level: types.LevelProject, ProjectPathPattern: "/demo/*", Title: "项目未声明标识符", Content: "先运行代码生成", DiagnosticSignature: "undeclared identifier <id>"},
+		{Level: types.LevelLanguage, Title: "类型不匹配", Content: "使用显式转换", DiagnosticSignature: "E0012: mismatched types <id> and <id>"},
+		{Level: types.LevelLanguage, Title: "普通记忆", Content: "没有诊断签名"},
+	}
+	ids := make([]int64, len(memories))
+	for i, m := range memories {
+		resp, err := db.Store(m)
+		if err != nil {
+			t.Fatalf("Store() error = %v", err)
+		}
+		ids[i] = resp.ID
+	}
+
+	memory, err := db.GetByID(ids[2])
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if memory.DiagnosticSignature != "E0012: mismatched types <id> and <id>" {
+		t.Errorf("GetByID() signature = %q", memory.DiagnosticSignature)
+	}
+
+	// 完全匹配：项目级排在语言级之前
+	opts := RecallOptions{LanguageTag: "cangjie", ProjectPath: "/demo/app", Limit: 10}
+	results, err := db.FindDiagnosticFixes("undeclared identifier <id>", false, opts)
+	if err != nil {
+		t.Fatalf("FindDiagnosticFixes() error = %v", err)
+	}
+	if len(results) != 2 || results[0].ID != ids[1] || results[1].ID != ids[0] {
+		t.Errorf("FindDiagnosticFixes(exact) = %+v", results)
+	}
+
+	// 错误码前缀匹配
+	results, _ = db.FindDiagnosticFixes("E0012: ", true, opts)
+	if len(results) != 1 || results[0].ID != ids[2] {
+		t.Errorf("FindDiagnosticFixes(prefix) = %+v", results)
+	}
+
+	// 更新签名，传空字符串移除
+	signature := "E0013: cannot infer type"
+	if _, err := db.Patch(types.UpdateRequest{ID: ids[2], DiagnosticSignature: &signature}); err != nil {
+		t.Fatalf("Patch() error = %v", err)
+	}
+	results, _ = db.FindDiagnosticFixes("E0012: ", true, opts)
+	if len(results) != 0 {
+		t.Errorf("FindDiagnosticFixes(old prefix) = %d results, want 0", len(results))
+	}
+	empty := ""
+	if _, err := db.Patch(types.UpdateRequest{ID: ids[2], DiagnosticSignature: &empty}); err != nil {
+		t.Fatalf("Patch() error = %v", err)
+	}
+	signatures, err := db.DiagnosticSignatures(ids)
+	if err != nil {
+		t.Fatalf("DiagnosticSignatures() error = %v", err)
+	}
+	if len(signatures) != 2 || signatures[ids[0]] != "undeclared identifier <id>" {
+		t.Errorf("DiagnosticSignatures() = %v", signatures)
+	}
+
+	// 回收站中的记忆不参与匹配，彻底删除后签名一并清理
+	if err := db.Delete(ids[0], types.EditorAPI); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	results, _ = db.FindDiagnosticFixes("undeclared identifier <id>", false, opts)
+	if len(results) != 1 {
+		t.Errorf("FindDiagnosticFixes() after delete = %d results, want 1", len(results))
+	}
+	if err := db.Purge(ids[0]); err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	var count int
+	if err := db.db.QueryRow(`SELECT COUNT(*) FROM knowledge_diagnostics WHERE knowledge_id = ?`, ids[0]).Scan(&count); err != nil {
+		t.Fatalf("count diagnostics error = %v", err)
+	}
+	if count != 0 {
+		t.Errorf("diagnostics after purge = %d, want 0", count)
+	}
+}
+
+func TestSymbols(t *testing.T) {
+	db := getTestDB(t)
+
+	resp, err := db.Store(types.StoreRequest{Level: types.LevelLanguage, Title: "std.collection.ArrayList", Content: "动态数组"})
+	if err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+
+	symbols := []types.Symbol{
+		{Package: "std.collection", Type: "ArrayList", QualifiedName: "std.collection.ArrayList", Kind: types.SymbolClass, Signature: "public class ArrayList<T>", KnowledgeID: resp.ID},
+		{Package: "std.collection", Type: "ArrayList", Member: "append", QualifiedName: "std.collection.ArrayList.append", Kind: types.SymbolFunc, Signature: "public func append(element: T): Unit", Doc: "追加元素", KnowledgeID: resp.ID},
+		{Package: "std.collection", Type: "ArrayList", Member: "append", QualifiedName: "std.collection.ArrayList.append", Kind: types.SymbolFunc, Signature: "public func append(elements: Collection<T>): Unit", KnowledgeID: resp.ID},
+		{Package: "std.collection", Type: "HashMap", Member: "append_all", QualifiedName: "std.collection.HashMap.append_all", Kind: types.SymbolFunc, Signature: "public func append_all(): Unit"},
+	}
+	if err := db.ReplaceSymbols("cangjie", "", symbols); err != nil {
+		t.Fatalf("ReplaceSymbols() error = %v", err)
+	}
+	if err := db.ReplaceSymbols("cangjie", "tang", []types.Symbol{
+		{Package: "tang", Type: "Router", Member: "append", QualifiedName: "tang.Router.append", Kind: types.SymbolFunc, Signature: "public func append(): Unit"},
+	}); err != nil {
+		t.Fatalf("ReplaceSymbols(tang) error = %v", err)
+	}
+
+	find := func(req types.SymbolRequest) []types.Symbol {
+		t.Helper()
+		req.LanguageTag = "cangjie"
+		req.Limit = 10
+		found, err := db.FindSymbols(req)
+		if err != nil {
+			t.Fatalf("FindSymbols() error = %v", err)
+		}
+		return found
+	}
+
+	// 完整名称精确匹配（重载都返回），带关联记忆标题
+	found := find(types.SymbolRequest{Name: "std.collection.ArrayList.append"})
+	if len(found) != 2 || found[0].Doc != "追加元素" || found[0].KnowledgeID != resp.ID || found[0].MemoryTitle != "std.collection.ArrayList" {
+		t.Errorf("FindSymbols(exact) = %+v", found)
+	}
+	// 按 . 分隔的后缀匹配，不匹配名称中间的片段
+	if found := find(types.SymbolRequest{Name: "ArrayList.append"}); len(found) != 2 {
+		t.Errorf("FindSymbols(suffix) = %d symbols, want 2", len(found))
+	}
+	if found := find(types.SymbolRequest{Name: "append"}); len(found) != 3 {
+		t.Errorf("FindSymbols(append) = %d symbols, want 3", len(found))
+	}
+	if found := find(types.SymbolRequest{Name: "append", LibraryName: "tang"}); len(found) != 1 || found[0].LibraryName != "tang" {
+		t.Errorf("FindSymbols(library=tang) = %+v", found)
+	}
+	// 前缀匹配：完整名称排在最前
+	found = find(types.SymbolRequest{Name: "std.collection.ArrayList", Prefix: true})
+	if len(found) != 3 || found[0].Kind != types.SymbolClass {
+		t.Errorf("FindSymbols(prefix) = %+v", found)
+	}
+	if found := find(types.SymbolRequest{Name: "ArrayList", Prefix: true, Kind: types.SymbolFunc}); len(found) != 2 {
+		t.Errorf("FindSymbols(prefix, kind=func) = %d symbols, want 2", len(found))
+	}
+	// LIKE 通配符按字面匹配
+	if found := find(types.SymbolRequest{Name: "append_", Prefix: true}); len(found) != 1 {
+		t.Errorf("FindSymbols(append_) = %d symbols, want 1", len(found))
+	}
+
+	ids, err := db.SymbolMemoryIDs("cangjie", "")
+	if err != nil {
+		t.Fatalf("SymbolMemoryIDs() error = %v", err)
+	}
+	if len(ids) != 2 || ids["std.collection.ArrayList"] != resp.ID {
+		t.Errorf("SymbolMemoryIDs() = %v", ids)
+	}
+
+	// 重新导入替换同一库的符号，不影响其他库
+	if err := db.ReplaceSymbols("cangjie", "", symbols[:1]); err != nil {
+		t.Fatalf("ReplaceSymbols() error = %v", err)
+	}
+	if found := find(types.SymbolRequest{Name: "append"}); len(found) != 1 {
+		t.Errorf("FindSymbols(append) after replace = %d symbols, want 1", len(found))
+	}
+
+	// 回收站中的记忆不显示关联，彻底删除后解除关联
+	if err := db.Delete(resp.ID, types.EditorAPI); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if found := find(types.SymbolRequest{Name: "ArrayList"}); len(found) != 1 || found[0].KnowledgeID != 0 {
+		t.Errorf("FindSymbols() after delete = %+v", found)
+	}
+	if err := db.Purge(resp.ID); err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	if ids, _ := db.SymbolMemoryIDs("cangjie", ""); len(ids) != 0 {
+		t.Errorf("SymbolMemoryIDs() after purge = %v", ids)
+	}
+}
+
+func TestProvenance(t *testing.T) {
+	db := getTestDB(t)
+
+	first, _ := db.Store(types.StoreRequest{Level: types.LevelLibrary, LibraryName: "tang", Title: "安装", Content: "添加依赖"})
+	second, _ := db.Store(types.StoreRequest{Level: types.LevelLibrary, LibraryName: "tang", Title: "安装（新）", Content: "添加依赖"})
+
+	p := types.Provenance{File: "README.md", Section: "Tang > 安装"}
+	if err := db.SetProvenance(first.ID, "cangjie", "tang", p); err != nil {
+		t.Fatalf("SetProvenance() error = %v", err)
+	}
+	id, err := db.FindByProvenance("cangjie", "tang", p)
+	if err != nil || id != first.ID {
+		t.Errorf("FindByProvenance() = %d, %v, want %d", id, err, first.ID)
+	}
+	if id, _ := db.FindByProvenance("cangjie", "http", p); id != 0 {
+		t.Errorf("FindByProvenance(other library) = %d, want 0", id)
+	}
+	memory, _ := db.GetByID(first.ID)
+	if memory.Provenance == nil || *memory.Provenance != p {
+		t.Errorf("GetByID() provenance = %+v", memory.Provenance)
+	}
+
+	// 同一出处转移给另一条记忆
+	if err := db.SetProvenance(second.ID, "cangjie", "tang", p); err != nil {
+		t.Fatalf("SetProvenance() error = %v", err)
+	}
+	if id, _ := db.FindByProvenance("cangjie", "tang", p); id != second.ID {
+		t.Errorf("FindByProvenance() after move = %d, want %d", id, second.ID)
+	}
+	if memory, _ := db.GetByID(first.ID); memory.Provenance != nil {
+		t.Errorf("old memory provenance = %+v, want nil", memory.Provenance)
+	}
+
+	// 彻底删除后出处一并清理
+	db.Delete(second.ID, types.EditorAPI)
+	if err := db.Purge(second.ID); err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	if id, _ := db.FindByProvenance("cangjie", "tang", p); id != 0 {
+		t.Errorf("FindByProvenance() after purge = %d, want 0", id)
+	}
+}
+
+func TestImportTransaction(t *testing.T) {
+	db := getTestDB(t)
+
+	resp, err := db.Store(types.StoreRequest{
+		Level:       types.LevelLibrary,
+		LibraryName: "tang",
+		Title:       "路由注册",
+		Content:     "使用 router.get 注册路由",
+		Tags:        []string{"router"},
+	})
+	if err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+
+	memories := []types.StoreRequest{
+		{Level: types.LevelLibrary, LibraryName: "tang", Title: "中间件", Content: "使用 use 注册中间件"},
+		{Level: types.LevelLibrary, LibraryName: "tang", Title: "路由注册", Content: "使用 router.post 注册路由", Tags: []string{"router"}},
+		{Level: types.LevelLibrary, LibraryName: "tang", Title: "中间件", Content: "包内重复"},
+	}
+	count := func() int {
+		list, err := db.List(types.ListRequest{LibraryName: "tang"})
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		return list.Total
+	}
+
+	// 有失败时整个导入回滚
+	bad := append(append([]types.StoreRequest(nil), memories...), types.StoreRequest{Level: types.LevelLibrary, LibraryName: "tang", Content: "没有标题"})
+	result, err := db.ImportMemories(types.NewPackageMemories(bad), types.ImportOptions{})
+	if err != nil {
+		t.Fatalf("ImportMemories(bad) error = %v", err)
+	}
+	if !result.RolledBack || result.Failed != 1 || result.Added != 1 || result.Updated != 1 || result.Skipped != 1 {
+		t.Errorf("ImportMemories(bad) = %+v", result)
+	}
+	if item := result.Items[3]; item.Action != types.ImportFailed || item.Index != 3 || item.Reason != "title cannot be empty" {
+		t.Errorf("failed item = %+v", item)
+	}
+	if item := result.Items[2]; item.Action != types.ImportSkipped || item.Reason != "duplicate of item 0" {
+		t.Errorf("duplicate item = %+v", item)
+	}
+	if result.Items[0].ID != 0 || result.Items[1].ID != resp.ID {
+		t.Errorf("item ids after rollback = %d, %d", result.Items[0].ID, result.Items[1].ID)
+	}
+	if memory, _ := db.GetByID(resp.ID); count() != 1 || memory.Content != "使用 router.get 注册路由" {
+		t.Errorf("rolled back import changed data: total = %d, content = %q", count(), memory.Content)
+	}
+
+	// 试运行返回与实际导入一致的结果，但不写入
+	dry, err := db.ImportMemories(types.NewPackageMemories(memories), types.ImportOptions{DryRun: true})
+	if err != nil || !dry.DryRun || !dry.RolledBack || dry.Added != 1 || dry.Updated != 1 || dry.Skipped != 1 {
+		t.Fatalf("ImportMemories(dry run) = %+v, %v", dry, err)
+	}
+	if count() != 1 {
+		t.Errorf("dry run wrote memories: total = %d", count())
+	}
+
+	result, err = db.ImportMemories(types.NewPackageMemories(memories), types.ImportOptions{})
+	if err != nil || result.RolledBack || result.Added != dry.Added || result.Updated != dry.Updated || result.Skipped != dry.Skipped {
+		t.Fatalf("ImportMemories() = %+v, %v", result, err)
+	}
+	if result.Items[0].ID == 0 || count() != 2 {
+		t.Errorf("ImportMemories() added id = %d, total = %d", result.Items[0].ID, count())
+	}
+
+	// 重复导入同一批记忆：内容未变的全部跳过，不产生新版本
+	revisions, _ := db.ListRevisions(resp.ID)
+	result, err = db.ImportMemories(types.NewPackageMemories(memories), types.ImportOptions{})
+	if err != nil || result.Skipped != 3 || result.Added != 0 || result.Updated != 0 || result.Items[1].Reason != "unchanged" {
+		t.Errorf("ImportMemories() again = %+v, %v", result, err)
+	}
+	if again, _ := db.ListRevisions(resp.ID); len(again) != len(revisions) {
+		t.Errorf("unchanged import recorded revision: %d -> %d", len(revisions), len(again))
+	}
+}
+
+func TestImportConflicts(t *testing.T) {
+	db := getTestDB(t)
+
+	var ids []int64
+	for _, req := range []types.StoreRequest{
+		{Level: types.LevelLanguage, Title: "match 语法", Content: "case 后不能接 {}", Tags: []string{"syntax"}},
+		{Level: types.LevelProject, ProjectPathPattern: "/work/xisp", Title: "构建命令", Content: "cjpm build"},
+		{Level: types.LevelLibrary, LibraryName: "tang", Title: "路由注册", Content: "使用 router.get 注册路由"},
+	} {
+		resp, err := db.Store(req)
+		if err != nil {
+			t.Fatalf("Store() error = %v", err)
+		}
+		ids = append(ids, resp.ID)
+	}
+
+	incoming := []types.StoreRequest{
+		{Level: types.LevelLanguage, Title: "match 语法", Content: "case 后直接写多行", Tags: []string{"match"}},
+		{Level: types.LevelProject, ProjectPathPattern: "/work/xisp", Title: "构建命令", Content: "cjpm build -V"},
+		{Level: types.LevelLibrary, LibraryName: "tang", Title: "路由注册", Content: "使用 router.post 注册路由"},
+		{Level: types.LevelLibrary, LibraryName: "tang", Title: "中间件", Content: "使用 use 注册中间件"},
+	}
+
+	// 所有层级都检测冲突，并给出字段差异
+	conflicts, err := db.FindConflicts(types.NewPackageMemories(incoming))
+	if err != nil || len(conflicts) != 3 {
+		t.Fatalf("FindConflicts() = %+v, %v", conflicts, err)
+	}
+	for i, c := range conflicts {
+		if c.Index != i || c.ExistingID != ids[i] {
+			t.Errorf("conflict[%d] = index %d, existing %d", i, c.Index, c.ExistingID)
+		}
+	}
+	diffs := conflicts[0].Diffs
+	if len(diffs) != 2 || diffs[0].Field != "content" || diffs[1].Field != "tags" || diffs[1].Existing != "syntax" || diffs[1].Incoming != "match" {
+		t.Fatalf("conflict diffs = %+v", diffs)
+	}
+	if !strings.Contains(diffs[0].Diff, "-case 后不能接 {}") || !strings.Contains(diffs[0].Diff, "+case 后直接写多行") {
+		t.Errorf("content diff = %q", diffs[0].Diff)
+	}
+
+	opts := types.ImportOptions{
+		Strategy: types.ConflictSkip,
+		Resolutions: []types.ConflictResolution{
+			{Index: 0, Strategy: types.ConflictMerge},
+			{Index: 2, Strategy: types.ConflictKeepBoth},
+		},
+	}
+	result, err := db.ImportMemories(types.NewPackageMemories(incoming), opts)
+	if err != nil || result.RolledBack {
+		t.Fatalf("ImportMemories() = %+v, %v", result, err)
+	}
+	wantActions := []string{types.ImportUpdated, types.ImportSkipped, types.ImportAdded, types.ImportAdded}
+	for i, item := range result.Items {
+		if item.Action != wantActions[i] {
+			t.Errorf("item[%d] = %+v, want %s", i, item, wantActions[i])
+		}
+	}
+
+	merged, _ := db.GetByID(ids[0])
+	if merged.Content != "case 后不能接 {}\n\ncase 后直接写多行" || strings.Join(merged.Tags, ",") != "syntax,match" {
+		t.Errorf("merged memory = %q %v", merged.Content, merged.Tags)
+	}
+	if project, _ := db.GetByID(ids[1]); project.Content != "cjpm build" {
+		t.Errorf("skipped memory content = %q", project.Content)
+	}
+	if original, _ := db.GetByID(ids[2]); original.Content != "使用 router.get 注册路由" {
+		t.Errorf("keep_both changed existing memory: %q", original.Content)
+	}
+	kept, _ := db.GetByID(result.Items[2].ID)
+	if kept == nil || kept.Title != "路由注册 (2)" || result.Items[2].Title != "路由注册 (2)" {
+		t.Errorf("keep_both memory = %+v", kept)
+	}
+
+	// 相同选项重复导入不再产生变化
+	result, err = db.ImportMemories(types.NewPackageMemories(incoming), opts)
+	if err != nil || result.Skipped != 4 {
+		t.Errorf("ImportMemories() again = %+v, %v", result, err)
+	}
+
+	// 项目路径模式或语言不同的同名记忆不冲突，默认策略下也不会覆盖
+	others := []types.StoreRequest{
+		{Level: types.LevelProject, ProjectPathPattern: "/work/other", Title: "构建命令", Content: "cjpm build --release"},
+		{Level: types.LevelLanguage, LanguageTag: "go", Title: "match 语法", Content: "使用 switch"},
+	}
+	if conflicts, err := db.FindConflicts(types.NewPackageMemories(others)); err != nil || len(conflicts) != 0 {
+		t.Fatalf("FindConflicts(other project) = %+v, %v", conflicts, err)
+	}
+	result, err = db.ImportMemories(types.NewPackageMemories(others), types.ImportOptions{})
+	if err != nil || result.Added != 2 {
+		t.Fatalf("ImportMemories(other project) = %+v, %v", result, err)
+	}
+	if project, _ := db.GetByID(ids[1]); project.Content != "cjpm build" {
+		t.Errorf("memory of another project was overwritten: %q", project.Content)
+	}
+}
+
+func TestImportSessions(t *testing.T) {
+	db := getTestDB(t)
+
+	now := time.Now()
+	memories := []types.StoreRequest{
+		{Level: types.LevelLibrary, LibraryName: "tang", Title: "路由注册", Content: "使用 router.get 注册路由"},
+		{Level: types.LevelLibrary, LibraryName: "tang", Title: "中间件", Content: "使用 use 注册中间件"},
+	}
+	session := types.ImportSession{
+		ID:            "session-1",
+		Source:        types.ImportSourcePackage,
+		Uploader:      "alice",
+		FormatVersion: types.PackageFormatVersion,
+		Package:       types.PackageInfo{Name: "tang", Version: "1.0.0"},
+		CreatedAt:     now,
+		ExpiresAt:     now.Add(30 * time.Minute),
+	}
+	if err := db.CreateImportSession(session, types.NewPackageMemories(memories)); err != nil {
+		t.Fatalf("CreateImportSession() error = %v", err)
+	}
+	expired := session
+	expired.ID, expired.CreatedAt, expired.ExpiresAt = "session-2", now.Add(-time.Hour), now.Add(-30*time.Minute)
+	if err := db.CreateImportSession(expired, types.NewPackageMemories(memories)); err != nil {
+		t.Fatalf("CreateImportSession(expired) error = %v", err)
+	}
+
+	got, err := db.GetImportSession("session-1")
+	if err != nil || got.Status != types.ImportSessionPending || got.Total != 2 || got.Uploader != "alice" || got.Package.Name != "tang" {
+		t.Fatalf("GetImportSession() = %+v, %v", got, err)
+	}
+	if _, err := db.ConfirmImport("session-2", types.ImportOptions{}); err == nil || !strings.Contains(err.Error(), "already expired") {
+		t.Errorf("ConfirmImport(expired) error = %v", err)
+	}
+	if _, err := db.GetImportSession("missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("GetImportSession(missing) error = %v", err)
+	}
+
+	// 试运行不改变会话状态
+	result, err := db.ConfirmImport("session-1", types.ImportOptions{DryRun: true})
+	if err != nil || result.Added != 2 || !result.RolledBack {
+		t.Fatalf("ConfirmImport(dry run) = %+v, %v", result, err)
+	}
+	if got, _ := db.GetImportSession("session-1"); got.Status != types.ImportSessionPending {
+		t.Errorf("status after dry run = %s", got.Status)
+	}
+
+	result, err = db.ConfirmImport("session-1", types.ImportOptions{})
+	if err != nil || result.Added != 2 || result.RolledBack {
+		t.Fatalf("ConfirmImport() = %+v, %v", result, err)
+	}
+	if _, err := db.ConfirmImport("session-1", types.ImportOptions{}); err == nil || !strings.Contains(err.Error(), "already completed") {
+		t.Errorf("ConfirmImport() twice error = %v", err)
+	}
+
+	got, err = db.GetImportSession("session-1")
+	if err != nil || got.Status != types.ImportSessionCompleted || got.CompletedAt == nil || got.Result == nil || len(got.Result.Items) != 2 {
+		t.Fatalf("GetImportSession() after import = %+v, %v", got, err)
+	}
+	if len(got.MemoryIDs) != 2 || got.MemoryIDs[0] != result.Items[0].ID {
+		t.Errorf("MemoryIDs = %v, items = %+v", got.MemoryIDs, result.Items)
+	}
+
+	// 有记忆导入失败时回滚，会话标记为 failed
+	failed := session
+	failed.ID, failed.CreatedAt = "session-3", now.Add(-time.Minute)
+	if err := db.CreateImportSession(failed, types.NewPackageMemories([]types.StoreRequest{{Level: types.LevelLibrary, LibraryName: "tang", Content: "没有标题"}})); err != nil {
+		t.Fatalf("CreateImportSession(failed) error = %v", err)
+	}
+	if result, err := db.ConfirmImport("session-3", types.ImportOptions{}); err != nil || !result.RolledBack || result.Failed != 1 {
+		t.Errorf("ConfirmImport(failed) = %+v, %v", result, err)
+	}
+	if got, _ := db.GetImportSession("session-3"); got.Status != types.ImportSessionFailed || len(got.MemoryIDs) != 0 {
+		t.Errorf("failed session = %+v", got)
+	}
+
+	// 导入历史按上传时间倒序，列表不含逐条结果
+	history, err := db.ListImportSessions(types.ImportHistoryRequest{Limit: 10})
+	if err != nil || history.Total != 3 || history.Sessions[0].ID != "session-1" || history.Sessions[2].Status != types.ImportSessionExpired {
+		t.Fatalf("ListImportSessions() = %+v, %v", history, err)
+	}
+	if history.Sessions[0].Result.Items != nil || len(history.Sessions[0].MemoryIDs) != 2 {
+		t.Errorf("history session = %+v", history.Sessions[0])
+	}
+	if history, _ := db.ListImportSessions(types.ImportHistoryRequest{Status: types.ImportSessionExpired, Limit: 10}); history.Total != 1 {
+		t.Errorf("ListImportSessions(expired) total = %d", history.Total)
+	}
+}
+
+func TestPackageUUIDs(t *testing.T) {
+	source := getTestDB(t)
+	target, err := New(Config{Path: filepath.Join(t.TempDir(), "target.db")})
+	if err != nil {
+		t.Fatalf("failed to create target database: %v", err)
+	}
+	defer target.Close()
+
+	resp, err := source.Store(types.StoreRequest{Level: types.LevelLibrary, LibraryName: "tang", Title: "路由注册", Content: "使用 router.get 注册路由"})
+	if err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	if err := source.UpdateAccessCount(resp.ID); err != nil {
+		t.Fatalf("UpdateAccessCount() error = %v", err)
+	}
+	memory, _ := source.GetByID(resp.ID)
+	if memory.UUID == "" {
+		t.Fatal("GetByID() uuid is empty")
+	}
+
+	// 导出的记忆带 UUID、创建时间和访问次数，导入到另一个库后保持不变
+	exported, err := source.ExportForImport(types.ExportRequest{})
+	if err != nil || len(exported) != 1 || exported[0].UUID != memory.UUID || exported[0].AccessCount != 1 || exported[0].CreatedAt.IsZero() {
+		t.Fatalf("ExportForImport() = %+v, %v", exported, err)
+	}
+	result, err := target.ImportMemories(exported, types.ImportOptions{})
+	if err != nil || result.Added != 1 || result.Items[0].UUID != memory.UUID {
+		t.Fatalf("ImportMemories() = %+v, %v", result, err)
+	}
+	imported, _ := target.GetByID(result.Items[0].ID)
+	if imported.UUID != memory.UUID || imported.AccessCount != 1 || !imported.CreatedAt.Equal(memory.CreatedAt) {
+		t.Errorf("imported memory = %+v, want uuid %s", imported, memory.UUID)
+	}
+
+	// 源库改了标题后再导入，按 UUID 匹配并更新原记忆而不是新增
+	renamed := "路由注册（GET）"
+	if _, err := source.Patch(types.UpdateRequest{ID: resp.ID, Title: &renamed}); err != nil {
+		t.Fatalf("Patch() error = %v", err)
+	}
+	exported, _ = source.ExportForImport(types.ExportRequest{})
+	conflicts, err := target.FindConflicts(exported)
+	if err != nil || len(conflicts) != 1 || conflicts[0].ExistingID != imported.ID || conflicts[0].Diffs[0].Field != "title" {
+		t.Fatalf("FindConflicts() = %+v, %v", conflicts, err)
+	}
+	result, err = target.ImportMemories(exported, types.ImportOptions{})
+	if err != nil || result.Updated != 1 || result.Items[0].ID != imported.ID {
+		t.Fatalf("ImportMemories(renamed) = %+v, %v", result, err)
+	}
+	if updated, _ := target.GetByID(imported.ID); updated.Title != renamed || updated.UUID != memory.UUID {
+		t.Errorf("updated memory = %+v", updated)
+	}
+}
+
+func TestPackages(t *testing.T) {
+	db := getTestDB(t)
+
+	const (
+		uuidA = "00000000-0000-4000-8000-00000000000a"
+		uuidB = "00000000-0000-4000-8000-00000000000b"
+		uuidD = "00000000-0000-4000-8000-00000000000d"
+		uuidE = "00000000-0000-4000-8000-00000000000e"
+	)
+	memory := func(uuid, title, content string) types.PackageMemory {
+		return types.PackageMemory{UUID: uuid, StoreRequest: types.StoreRequest{Level: types.LevelLibrary, LibraryName: "tang", Title: title, Content: content}}
+	}
+	v1 := types.NewKnowledgePackage(types.PackageInfo{Name: "tang", Version: "1.0.0"}, []types.PackageMemory{
+		memory(uuidA, "路由注册", "使用 router.get 注册路由"),
+		memory(uuidB, "旧接口", "即将删除"),
+		memory(uuidD, "中间件", "使用 use 注册中间件"),
+	})
+	v2 := types.NewKnowledgePackage(types.PackageInfo{Name: "tang", Version: "1.1.0"}, []types.PackageMemory{
+		memory(uuidA, "路由注册", "使用 router.get 或 router.post 注册路由"),
+		memory(uuidE, "路由分组", "使用 group 注册分组路由"),
+	})
+
+	// 与手写的同名记忆冲突：默认都保留，包新增一份副本
+	handwritten, err := db.Store(types.StoreRequest{Level: types.LevelLibrary, LibraryName: "tang", Title: "中间件", Content: "手写的中间件说明"})
+	if err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+
+	result, err := db.InstallPackage(v1, "./registry", "", types.ImportOptions{DryRun: true}, false)
+	if err != nil || result.Import.Added != 3 || len(result.Diff.Added) != 2 ||
+		len(result.Diff.Conflicts) != 1 || result.Diff.Conflicts[0].ID != handwritten.ID || result.Diff.Conflicts[0].Index != 2 || result.Diff.Conflicts[0].Package != "" {
+		t.Fatalf("InstallPackage(v1 dry run) = %+v, %v", result, err)
+	}
+
+	// 显式覆盖手写的记忆：覆盖后归包所有
+	overwrite := types.ImportOptions{Resolutions: []types.ConflictResolution{{Index: 2, Strategy: types.ConflictOverwrite}}}
+	result, err = db.InstallPackage(v1, "./registry", "", overwrite, false)
+	if err != nil || result.Import.Added != 2 || result.Import.Updated != 1 || len(result.Package.MemoryIDs) != 3 {
+		t.Fatalf("InstallPackage(v1) = %+v, %v", result, err)
+	}
+	if m, _ := db.GetByID(handwritten.ID); m.Content != "使用 use 注册中间件" {
+		t.Errorf("overwritten memory content = %q", m.Content)
+	}
+	if _, err := db.InstallPackage(v1, "./registry", "", types.ImportOptions{}, false); err == nil || !strings.HasPrefix(err.Error(), "package already installed") {
+		t.Errorf("InstallPackage(again) error = %v", err)
+	}
+	if _, err := db.InstallPackage(types.NewKnowledgePackage(types.PackageInfo{Name: "json"}, nil), "", "", types.ImportOptions{}, true); err == nil || !strings.HasPrefix(err.Error(), "package not found") {
+		t.Errorf("InstallPackage(upgrade missing) error = %v", err)
+	}
+	installed, err := db.GetPackage("tang")
+	if err != nil || installed.Version != "1.0.0" || installed.Registry != "./registry" || len(installed.MemoryIDs) != 3 || installed.MemoryIDs[0] != handwritten.ID {
+		t.Fatalf("GetPackage() = %+v, %v", installed, err)
+	}
+	idA, idB := installed.MemoryIDs[1], installed.MemoryIDs[2]
+
+	// 试运行升级：返回差异，不写入
+	result, err = db.InstallPackage(v2, "./registry", "", types.ImportOptions{DryRun: true}, true)
+	if err != nil || !result.Import.RolledBack || result.Removed != 1 || result.Restored != 1 {
+		t.Fatalf("InstallPackage(v2 dry run) = %+v, %v", result, err)
+	}
+	diff := result.Diff
+	if diff.FromVersion != "1.0.0" || diff.ToVersion != "1.1.0" || len(diff.Added) != 1 || diff.Added[0].UUID != uuidE ||
+		len(diff.Changed) != 1 || diff.Changed[0].ID != idA || diff.Changed[0].Diffs[0].Field != "content" ||
+		len(diff.Removed) != 2 || diff.Removed[0].ID != handwritten.ID || diff.Removed[1].ID != idB || len(diff.Conflicts) != 0 || diff.Unchanged != 0 {
+		t.Errorf("InstallPackage(v2 dry run) diff = %+v", diff)
+	}
+	if m, _ := db.GetByID(idB); m.DeletedAt != nil {
+		t.Error("dry run moved memory to trash")
+	}
+
+	// 升级：更新、新增，新版本中已删除的记忆移入回收站，包覆盖过的手写记忆恢复原样
+	result, err = db.InstallPackage(v2, "./registry", "alice", types.ImportOptions{Strategy: types.ConflictSkip}, true)
+	if err != nil || result.Import.Updated != 1 || result.Import.Added != 1 || result.Removed != 1 ||
+		result.Restored != 1 || len(result.RestoredIDs) != 1 || result.RestoredIDs[0] != handwritten.ID {
+		t.Fatalf("InstallPackage(v2) = %+v, %v", result, err)
+	}
+	if m, _ := db.GetByID(idA); m.Content != "使用 router.get 或 router.post 注册路由" {
+		t.Errorf("upgraded memory content = %q", m.Content)
+	}
+	if m, _ := db.GetByID(idB); m.DeletedAt == nil {
+		t.Error("removed memory not moved to trash")
+	}
+	if m, _ := db.GetByID(handwritten.ID); m.DeletedAt != nil || m.Content != "手写的中间件说明" {
+		t.Errorf("restored memory = %+v", m)
+	}
+	installed, _ = db.GetPackage("tang")
+	if installed.Version != "1.1.0" || installed.Signer != "alice" || len(installed.MemoryIDs) != 2 || installed.MemoryIDs[0] != idA {
+		t.Errorf("GetPackage(upgraded) = %+v", installed)
+	}
+
+	// 与其他包的记忆冲突：即使要求覆盖也都保留
+	extra := types.NewKnowledgePackage(types.PackageInfo{Name: "tang-extra", Version: "1.0.0"}, []types.PackageMemory{
+		memory(uuidA, "路由注册", "使用 router.any 注册路由"),
+	})
+	result, err = db.InstallPackage(extra, "./registry", "", types.ImportOptions{Strategy: types.ConflictOverwrite}, false)
+	if err != nil || result.Import.Added != 1 || len(result.Diff.Conflicts) != 1 || result.Diff.Conflicts[0].ID != idA || result.Diff.Conflicts[0].Package != "tang" {
+		t.Fatalf("InstallPackage(extra) = %+v, %v", result, err)
+	}
+	if m, _ := db.GetByID(idA); m.Content != "使用 router.get 或 router.post 注册路由" {
+		t.Errorf("memory of another package overwritten: %q", m.Content)
+	}
+	if uninstalled, err := db.UninstallPackage("tang-extra"); err != nil || uninstalled.Removed != 1 {
+		t.Fatalf("UninstallPackage(extra) = %+v, %v", uninstalled, err)
+	}
+
+	// 卸载：包拥有的记忆移入回收站，手写的记忆保留
+	uninstalled, err := db.UninstallPackage("tang")
+	if err != nil || uninstalled.Removed != 2 || uninstalled.Restored != 0 {
+		t.Fatalf("UninstallPackage() = %+v, %v", uninstalled, err)
+	}
+	if m, _ := db.GetByID(handwritten.ID); m.DeletedAt != nil {
+		t.Error("uninstall removed a memory the package does not own")
+	}
+	if packages, err := db.ListPackages(); err != nil || len(packages) != 0 {
+		t.Errorf("ListPackages() = %+v, %v", packages, err)
+	}
+	if _, err := db.UninstallPackage("tang"); err == nil || !strings.HasPrefix(err.Error(), "package not found") {
+		t.Errorf("UninstallPackage(again) error = %v", err)
+	}
+
+	// 重新安装：新记忆取回回收站中记忆的 UUID
+	result, err = db.InstallPackage(v2, "./registry", "", types.ImportOptions{}, false)
+	if err != nil || result.Import.Added != 2 {
+		t.Fatalf("InstallPackage(reinstall) = %+v, %v", result, err)
+	}
+	if m, _ := db.GetByID(result.Import.Items[0].ID); m.UUID != uuidA {
+		t.Errorf("reinstalled memory uuid = %q, want %q", m.UUID, uuidA)
+	}
+
+	// 卸载覆盖过手写记忆的包：恢复为安装前的版本
+	middleware := types.NewKnowledgePackage(types.PackageInfo{Name: "tang-middleware", Version: "1.0.0"}, []types.PackageMemory{
+		memory(uuidD, "中间件", "使用 use 注册中间件"),
+	})
+	result, err = db.InstallPackage(middleware, "./registry", "", types.ImportOptions{Strategy: types.ConflictOverwrite}, false)
+	if err != nil || result.Import.Updated != 1 || len(result.Package.MemoryIDs) != 1 || result.Package.MemoryIDs[0] != handwritten.ID {
+		t.Fatalf("InstallPackage(middleware) = %+v, %v", result, err)
+	}
+	uninstalled, err = db.UninstallPackage("tang-middleware")
+	if err != nil || uninstalled.Removed != 0 || uninstalled.Restored != 1 {
+		t.Fatalf("UninstallPackage(middleware) = %+v, %v", uninstalled, err)
+	}
+	if m, _ := db.GetByID(handwritten.ID); m.DeletedAt != nil || m.Content != "手写的中间件说明" {
+		t.Errorf("restored memory = %+v", m)
+	}
+}
@@ -0,0 +1,136 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// migrateDiagnostics 自动迁移：创建编译诊断签名表（每条记忆最多一个签名）
+func (d *Database) migrateDiagnostics() error {
+	_, err := d.db.Exec(`
+	CREATE TABLE IF NOT EXISTS knowledge_diagnostics (
+		knowledge_id INTEGER PRIMARY KEY,
+		signature TEXT NOT NULL,
+		FOREIGN KEY (knowledge_id) REFERENCES knowledge_base(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_diagnostics_signature ON knowledge_diagnostics(signature);
+
+	-- 连接未开启 foreign_keys 时 ON DELETE CASCADE 不生效，用触发器兜底
+	CREATE TRIGGER IF NOT EXISTS knowledge_diagnostics_cleanup AFTER DELETE ON knowledge_base BEGIN
+		DELETE FROM knowledge_diagnostics WHERE knowledge_id = old.id;
+	END;
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create knowledge_diagnostics table: %w", err)
+	}
+	return nil
+}
+
+// setDiagnosticSignature 设置记忆的诊断签名（为空时移除）
+func (d *Database) setDiagnosticSignature(id int64, signature string) error {
+	if signature == "" {
+		if _, err := d.db.Exec(`DELETE FROM knowledge_diagnostics WHERE knowledge_id = ?`, id); err != nil {
+			return fmt.Errorf("failed to clear diagnostic signature: %w", err)
+		}
+		return nil
+	}
+	_, err := d.db.Exec(`
+		INSERT INTO knowledge_diagnostics (knowledge_id, signature) VALUES (?, ?)
+		ON CONFLICT(knowledge_id) DO UPDATE SET signature = excluded.signature
+	`, id, signature)
+	if err != nil {
+		return fmt.Errorf("failed to set diagnostic signature: %w", err)
+	}
+	return nil
+}
+
+// DiagnosticSignatures 批量查询记忆的诊断签名
+func (d *Database) DiagnosticSignatures(ids []int64) (map[int64]string, error) {
+	result := make(map[int64]string, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	rows, err := d.db.Query(`
+		SELECT knowledge_id, signature FROM knowledge_diagnostics
+		WHERE knowledge_id IN (`+placeholders+`)
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load diagnostic signatures: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var signature string
+		if err := rows.Scan(&id, &signature); err != nil {
+			return nil, fmt.Errorf("failed to scan diagnostic row: %w", err)
+		}
+		result[id] = signature
+	}
+
+	return result, rows.Err()
+}
+
+// FindDiagnosticFixes 按诊断签名查找记忆（不包含回收站中的记忆）
+// prefix 为 true 时按签名前缀匹配（用于错误码匹配），结果按层级、置信度和访问次数排序
+func (d *Database) FindDiagnosticFixes(signature string, prefix bool, opts RecallOptions) ([]types.RecallResult, error) {
+	whereClause, whereArgs := recallWhere(opts)
+
+	match := "kd.signature = ?"
+	arg := signature
+	if prefix {
+		match = `kd.signature LIKE ? ESCAPE '\'`
+		arg = escapeLike(signature) + "%"
+	}
+
+	sqlQuery := `
+		SELECT
+			` + recallColumns + `, 0.0
+		FROM knowledge_base
+		JOIN knowledge_diagnostics kd ON kd.knowledge_id = knowledge_base.id
+	` + whereClause + ` AND ` + match + `
+		ORDER BY
+			CASE level
+				WHEN 'project' THEN 0
+				WHEN 'library' THEN 1
+				WHEN 'language' THEN 2
+			END,
+			confidence DESC,
+			access_count DESC
+		LIMIT ?
+	`
+	args := append(whereArgs, arg, opts.Limit)
+
+	rows, err := d.db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find diagnostic fixes: %w", err)
+	}
+	defer rows.Close()
+
+	var results []types.RecallResult
+	for rows.Next() {
+		r, _, err := scanRecallResult(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	rows.Close()
+
+	return results, d.fillRecallTags(results)
+}
+
+// escapeLike 转义 LIKE 通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
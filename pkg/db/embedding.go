@@ -0,0 +1,146 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// EmbeddingSource 待生成向量的记忆文本
+type EmbeddingSource struct {
+	ID      int64
+	Title   string
+	Content string
+	Summary string
+}
+
+// migrateEmbeddings 自动迁移：创建向量表
+func (d *Database) migrateEmbeddings() error {
+	_, err := d.db.Exec(`
+	CREATE TABLE IF NOT EXISTS knowledge_embeddings (
+		knowledge_id INTEGER PRIMARY KEY,
+		model TEXT NOT NULL,
+		vector BLOB NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (knowledge_id) REFERENCES knowledge_base(id) ON DELETE CASCADE
+	);
+
+	CREATE TRIGGER IF NOT EXISTS knowledge_embeddings_cleanup AFTER DELETE ON knowledge_base BEGIN
+		DELETE FROM knowledge_embeddings WHERE knowledge_id = old.id;
+	END;
+
+	-- 文本变化后向量失效，由回填任务重新生成
+	CREATE TRIGGER IF NOT EXISTS knowledge_embeddings_stale AFTER UPDATE OF title, content, summary ON knowledge_base BEGIN
+		DELETE FROM knowledge_embeddings WHERE knowledge_id = old.id;
+	END;
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create knowledge_embeddings table: %w", err)
+	}
+	return nil
+}
+
+// SaveEmbedding 保存（覆盖）记忆的向量
+func (d *Database) SaveEmbedding(id int64, model string, vector []byte) error {
+	_, err := d.db.Exec(`
+		INSERT OR REPLACE INTO knowledge_embeddings (knowledge_id, model, vector, updated_at)
+		VALUES (?, ?, ?, ?)
+	`, id, model, vector, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to save embedding: %w", err)
+	}
+	return nil
+}
+
+// MissingEmbeddings 查询缺少指定模型向量的记忆（不含回收站中的记忆）
+func (d *Database) MissingEmbeddings(model string, limit int) ([]EmbeddingSource, error) {
+	rows, err := d.db.Query(`
+		SELECT k.id, k.title, k.content, COALESCE(k.summary, '')
+		FROM knowledge_base k
+		LEFT JOIN knowledge_embeddings e ON e.knowledge_id = k.id AND e.model = ?
+		WHERE e.knowledge_id IS NULL AND k.deleted_at IS NULL
+		ORDER BY k.id
+		LIMIT ?
+	`, model, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query missing embeddings: %w", err)
+	}
+	return scanEmbeddingSources(rows)
+}
+
+// EmbeddingSources 查询指定记忆的文本（不含回收站中的记忆）
+func (d *Database) EmbeddingSources(ids []int64) ([]EmbeddingSource, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	rows, err := d.db.Query(`
+		SELECT id, title, content, COALESCE(summary, '')
+		FROM knowledge_base
+		WHERE id IN (`+placeholders+`) AND deleted_at IS NULL
+		ORDER BY id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query embedding sources: %w", err)
+	}
+	return scanEmbeddingSources(rows)
+}
+
+// scanEmbeddingSources 读取记忆文本并关闭 rows
+func scanEmbeddingSources(rows *sql.Rows) ([]EmbeddingSource, error) {
+	defer rows.Close()
+
+	var sources []EmbeddingSource
+	for rows.Next() {
+		var s EmbeddingSource
+		if err := rows.Scan(&s.ID, &s.Title, &s.Content, &s.Summary); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		sources = append(sources, s)
+	}
+	return sources, rows.Err()
+}
+
+// VectorSearch 按向量余弦相似度检索记忆
+// 结果的 Similarity 字段为余弦相似度（越大越相关）
+func (d *Database) VectorSearch(vector []byte, model string, opts RecallOptions) ([]types.RecallResult, error) {
+	whereClause, whereArgs := recallWhere(opts)
+
+	sqlQuery := `
+		SELECT
+			` + recallColumns + `, 1 - vec_distance_cosine(e.vector, ?) AS similarity
+		FROM knowledge_base
+		JOIN knowledge_embeddings e ON e.knowledge_id = knowledge_base.id AND e.model = ?
+	` + whereClause + `
+		ORDER BY similarity DESC
+		LIMIT ?
+	`
+	args := append([]interface{}{vector, model}, whereArgs...)
+	args = append(args, opts.Limit)
+
+	rows, err := d.db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	var results []types.RecallResult
+	for rows.Next() {
+		r, similarity, err := scanRecallResult(rows)
+		if err != nil {
+			return nil, err
+		}
+		r.Similarity = similarity
+		results = append(results, r)
+	}
+	rows.Close()
+
+	return results, d.fillRecallTags(results)
+}
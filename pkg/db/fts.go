@@ -0,0 +1,146 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/ystyle/cangjie-mem/pkg/segment"
+)
+
+// ftsSchema FTS5 全文索引表及同步触发器
+// 索引的是经过 CJK 切分的影子列（*_seg），由 Go 代码在写入时生成
+const ftsSchema = `
+	CREATE VIRTUAL TABLE IF NOT EXISTS knowledge_base_fts USING fts5(
+		title_seg,
+		content_seg,
+		summary_seg,
+		content=knowledge_base,
+		content_rowid=id
+	);
+
+	CREATE TRIGGER IF NOT EXISTS knowledge_base_ai AFTER INSERT ON knowledge_base BEGIN
+		INSERT INTO knowledge_base_fts(rowid, title_seg, content_seg, summary_seg)
+		VALUES (new.id, new.title_seg, new.content_seg, new.summary_seg);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS knowledge_base_ad AFTER DELETE ON knowledge_base BEGIN
+		INSERT INTO knowledge_base_fts(knowledge_base_fts, rowid, title_seg, content_seg, summary_seg)
+		VALUES ('delete', old.id, old.title_seg, old.content_seg, old.summary_seg);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS knowledge_base_au AFTER UPDATE ON knowledge_base BEGIN
+		INSERT INTO knowledge_base_fts(knowledge_base_fts, rowid, title_seg, content_seg, summary_seg)
+		VALUES ('delete', old.id, old.title_seg, old.content_seg, old.summary_seg);
+		INSERT INTO knowledge_base_fts(rowid, title_seg, content_seg, summary_seg)
+		VALUES (new.id, new.title_seg, new.content_seg, new.summary_seg);
+	END;
+`
+
+// segmentFields 生成写入影子列的分词文本
+func segmentFields(title, content, summary string) (string, string, string) {
+	return segment.Text(title), segment.Text(content), segment.Text(summary)
+}
+
+// migrateCJKSegmentation 自动迁移：添加 CJK 分词影子列并重建 FTS5 索引
+// 老版本的 FTS5 表直接索引 title/content/summary，连续汉字会被当作一个词
+func (d *Database) migrateCJKSegmentation() error {
+	// 1. 添加影子列
+	for _, column := range []string{"title_seg", "content_seg", "summary_seg"} {
+		var hasColumn bool
+		err := d.db.QueryRow(`
+			SELECT COUNT(*) > 0 FROM pragma_table_info('knowledge_base') WHERE name = ?
+		`, column).Scan(&hasColumn)
+		if err != nil {
+			return fmt.Errorf("failed to check %s column: %w", column, err)
+		}
+		if !hasColumn {
+			if _, err := d.db.Exec(`ALTER TABLE knowledge_base ADD COLUMN ` + column + ` TEXT`); err != nil {
+				return fmt.Errorf("failed to add %s column: %w", column, err)
+			}
+			fmt.Printf("✓ Migrated database: added %s column\n", column)
+		}
+	}
+
+	// 2. 检查 FTS5 表是否已经是分词版本
+	var ftsSQL sql.NullString
+	err := d.db.QueryRow(`
+		SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'knowledge_base_fts'
+	`).Scan(&ftsSQL)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to check FTS table: %w", err)
+	}
+	if ftsSQL.Valid && strings.Contains(ftsSQL.String, "content_seg") {
+		return nil
+	}
+
+	// 3. 删除旧的 FTS5 表和触发器
+	_, err = d.db.Exec(`
+		DROP TRIGGER IF EXISTS knowledge_base_ai;
+		DROP TRIGGER IF EXISTS knowledge_base_ad;
+		DROP TRIGGER IF EXISTS knowledge_base_au;
+		DROP TABLE IF EXISTS knowledge_base_fts;
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to drop old FTS table: %w", err)
+	}
+
+	// 4. 回填影子列（触发器已删除，不会重复索引）
+	if err := d.backfillSegments(); err != nil {
+		return err
+	}
+
+	// 5. 创建新的 FTS5 表和触发器，并从影子列重建索引
+	if _, err := d.db.Exec(ftsSchema); err != nil {
+		return fmt.Errorf("failed to create FTS table: %w", err)
+	}
+	if _, err := d.db.Exec(`INSERT INTO knowledge_base_fts(knowledge_base_fts) VALUES ('rebuild')`); err != nil {
+		return fmt.Errorf("failed to rebuild FTS index: %w", err)
+	}
+
+	if ftsSQL.Valid {
+		fmt.Println("✓ Migrated database: rebuilt FTS index with CJK segmentation")
+	}
+	return nil
+}
+
+// backfillSegments 为所有记忆重新生成分词影子列
+func (d *Database) backfillSegments() error {
+	type row struct {
+		id                      int64
+		title, content, summary string
+	}
+
+	rows, err := d.db.Query(`SELECT id, title, content, summary FROM knowledge_base`)
+	if err != nil {
+		return fmt.Errorf("failed to query memories for segmentation: %w", err)
+	}
+
+	var pending []row
+	for rows.Next() {
+		var r row
+		var summary sql.NullString
+		if err := rows.Scan(&r.id, &r.title, &r.content, &summary); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+		r.summary = summary.String
+		pending = append(pending, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate memories: %w", err)
+	}
+
+	for _, r := range pending {
+		titleSeg, contentSeg, summarySeg := segmentFields(r.title, r.content, r.summary)
+		_, err := d.db.Exec(`
+			UPDATE knowledge_base SET title_seg = ?, content_seg = ?, summary_seg = ? WHERE id = ?
+		`, titleSeg, contentSeg, summarySeg, r.id)
+		if err != nil {
+			return fmt.Errorf("failed to segment memory %d: %w", r.id, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,297 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// migrateImportSessions 自动迁移：创建导入会话表（预览后等待确认的知识包，确认后作为导入历史）
+// 和导入会话记忆表（每次导入新增或修改的记忆，记忆删除后仍保留作为历史）
+func (d *Database) migrateImportSessions() error {
+	_, err := d.db.Exec(`
+	CREATE TABLE IF NOT EXISTS import_sessions (
+		id TEXT PRIMARY KEY,
+		status TEXT NOT NULL DEFAULT 'pending',
+		source TEXT NOT NULL DEFAULT 'package',
+		uploader TEXT NOT NULL DEFAULT '',
+		format_version TEXT NOT NULL DEFAULT '',
+		package TEXT NOT NULL DEFAULT '{}',
+		total INTEGER NOT NULL DEFAULT 0,
+		memories TEXT,
+		result TEXT,
+		created_at DATETIME NOT NULL,
+		expires_at DATETIME NOT NULL,
+		completed_at DATETIME
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_import_sessions_created ON import_sessions(created_at);
+
+	CREATE TABLE IF NOT EXISTS import_session_memories (
+		session_id TEXT NOT NULL,
+		knowledge_id INTEGER NOT NULL,
+		action TEXT NOT NULL,
+		PRIMARY KEY (session_id, knowledge_id),
+		FOREIGN KEY (session_id) REFERENCES import_sessions(id) ON DELETE CASCADE
+	);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create import_sessions table: %w", err)
+	}
+	return nil
+}
+
+// importSessionColumns 导入会话查询字段（与 scanImportSession 对应）
+const importSessionColumns = `
+	id, status, source, uploader, format_version, package, total, result, created_at, expires_at, completed_at`
+
+// CreateImportSession 保存导入会话和待导入的记忆，并把超时未确认的会话标记为过期（清空待导入的记忆）
+func (d *Database) CreateImportSession(session types.ImportSession, memories []types.PackageMemory) error {
+	pkg, err := json.Marshal(session.Package)
+	if err != nil {
+		return fmt.Errorf("failed to encode package info: %w", err)
+	}
+	data, err := json.Marshal(memories)
+	if err != nil {
+		return fmt.Errorf("failed to encode memories: %w", err)
+	}
+
+	return d.inTx(func(tx *Database) error {
+		if _, err := tx.db.Exec(`
+			UPDATE import_sessions SET status = ?, memories = NULL
+			WHERE status = ? AND expires_at < ?
+		`, types.ImportSessionExpired, types.ImportSessionPending, time.Now()); err != nil {
+			return fmt.Errorf("failed to expire import sessions: %w", err)
+		}
+
+		_, err := tx.db.Exec(`
+			INSERT INTO import_sessions (id, status, source, uploader, format_version, package, total, memories, created_at, expires_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, session.ID, types.ImportSessionPending, session.Source, session.Uploader, session.FormatVersion,
+			string(pkg), len(memories), string(data), session.CreatedAt, session.ExpiresAt)
+		if err != nil {
+			return fmt.Errorf("failed to create import session: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetImportSession 获取导入会话（包含导入结果和新增或修改的记忆 ID）
+func (d *Database) GetImportSession(id string) (*types.ImportSession, error) {
+	session, err := scanImportSession(d.db.QueryRow(`
+		SELECT `+importSessionColumns+` FROM import_sessions WHERE id = ?
+	`, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("import session not found: %s", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get import session: %w", err)
+	}
+
+	ids, err := d.importSessionMemoryIDs([]string{id})
+	if err != nil {
+		return nil, err
+	}
+	if memoryIDs, ok := ids[id]; ok {
+		session.MemoryIDs = memoryIDs
+	}
+	return session, nil
+}
+
+// ListImportSessions 列出导入历史（按上传时间倒序，不含逐条导入结果）
+func (d *Database) ListImportSessions(req types.ImportHistoryRequest) (*types.ImportHistoryResponse, error) {
+	condition := `1 = 1`
+	var args []interface{}
+	switch req.Status {
+	case "":
+	case types.ImportSessionPending:
+		condition = `status = ? AND expires_at >= ?`
+		args = append(args, types.ImportSessionPending, time.Now())
+	case types.ImportSessionExpired:
+		condition = `(status = ? OR (status = ? AND expires_at < ?))`
+		args = append(args, types.ImportSessionExpired, types.ImportSessionPending, time.Now())
+	default:
+		condition = `status = ?`
+		args = append(args, req.Status)
+	}
+
+	resp := &types.ImportHistoryResponse{Sessions: []types.ImportSession{}}
+	if err := d.db.QueryRow(`SELECT COUNT(*) FROM import_sessions WHERE `+condition, args...).Scan(&resp.Total); err != nil {
+		return nil, fmt.Errorf("failed to count import sessions: %w", err)
+	}
+
+	rows, err := d.db.Query(`
+		SELECT `+importSessionColumns+` FROM import_sessions
+		WHERE `+condition+`
+		ORDER BY created_at DESC, rowid DESC
+		LIMIT ? OFFSET ?
+	`, append(args, req.Limit, req.Offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list import sessions: %w", err)
+	}
+	var ids []string
+	for rows.Next() {
+		session, err := scanImportSession(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan import session: %w", err)
+		}
+		if session.Result != nil {
+			session.Result.Items = nil
+		}
+		resp.Sessions = append(resp.Sessions, *session)
+		ids = append(ids, session.ID)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, fmt.Errorf("failed to list import sessions: %w", err)
+	}
+	rows.Close()
+
+	memoryIDs, err := d.importSessionMemoryIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range resp.Sessions {
+		if ids, ok := memoryIDs[resp.Sessions[i].ID]; ok {
+			resp.Sessions[i].MemoryIDs = ids
+		}
+	}
+	return resp, nil
+}
+
+// ConfirmImport 确认导入会话：在一个事务中检查会话状态并导入记忆，同一会话只能成功导入一次
+// 试运行不改变会话状态；有记忆导入失败时会话标记为 failed
+func (d *Database) ConfirmImport(id string, opts types.ImportOptions) (*types.ImportResult, error) {
+	var result *types.ImportResult
+	err := d.inTx(func(tx *Database) error {
+		session, err := tx.GetImportSession(id)
+		if err != nil {
+			return err
+		}
+		if session.Status != types.ImportSessionPending {
+			return fmt.Errorf("import session already %s: %s", session.Status, id)
+		}
+
+		var data sql.NullString
+		if err := tx.db.QueryRow(`SELECT memories FROM import_sessions WHERE id = ?`, id).Scan(&data); err != nil {
+			return fmt.Errorf("failed to load import session: %w", err)
+		}
+		var memories []types.PackageMemory
+		if err := json.Unmarshal([]byte(data.String), &memories); err != nil {
+			return fmt.Errorf("failed to decode import session memories: %w", err)
+		}
+
+		result, err = tx.ImportMemories(memories, opts)
+		if err != nil || opts.DryRun {
+			return err
+		}
+		return tx.completeImportSession(id, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// completeImportSession 记录导入结果和新增或修改的记忆，清空待导入的记忆
+func (d *Database) completeImportSession(id string, result *types.ImportResult) error {
+	status := types.ImportSessionCompleted
+	if result.RolledBack {
+		status = types.ImportSessionFailed
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode import result: %w", err)
+	}
+
+	if _, err := d.db.Exec(`
+		UPDATE import_sessions SET status = ?, result = ?, memories = NULL, completed_at = ? WHERE id = ?
+	`, status, string(data), time.Now(), id); err != nil {
+		return fmt.Errorf("failed to complete import session: %w", err)
+	}
+
+	if result.RolledBack {
+		return nil
+	}
+	for _, item := range result.Items {
+		if item.Action != types.ImportAdded && item.Action != types.ImportUpdated {
+			continue
+		}
+		if _, err := d.db.Exec(`
+			INSERT OR IGNORE INTO import_session_memories (session_id, knowledge_id, action) VALUES (?, ?, ?)
+		`, id, item.ID, item.Action); err != nil {
+			return fmt.Errorf("failed to record imported memory: %w", err)
+		}
+	}
+	return nil
+}
+
+// importSessionMemoryIDs 批量查询导入会话新增或修改的记忆 ID
+func (d *Database) importSessionMemoryIDs(ids []string) (map[string][]int64, error) {
+	result := make(map[string][]int64, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	rows, err := d.db.Query(`
+		SELECT session_id, knowledge_id FROM import_session_memories
+		WHERE session_id IN (`+placeholders+`)
+		ORDER BY knowledge_id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load imported memories: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sessionID string
+		var knowledgeID int64
+		if err := rows.Scan(&sessionID, &knowledgeID); err != nil {
+			return nil, fmt.Errorf("failed to scan imported memory: %w", err)
+		}
+		result[sessionID] = append(result[sessionID], knowledgeID)
+	}
+	return result, rows.Err()
+}
+
+// scanImportSession 扫描单个导入会话（超时未确认的待导入会话显示为 expired）
+func scanImportSession(scanner interface{ Scan(...interface{}) error }) (*types.ImportSession, error) {
+	var s types.ImportSession
+	var pkg string
+	var result sql.NullString
+	var completedAt sql.NullTime
+
+	err := scanner.Scan(&s.ID, &s.Status, &s.Source, &s.Uploader, &s.FormatVersion, &pkg, &s.Total,
+		&result, &s.CreatedAt, &s.ExpiresAt, &completedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(pkg), &s.Package); err != nil {
+		return nil, fmt.Errorf("failed to decode package info: %w", err)
+	}
+	if result.Valid {
+		s.Result = &types.ImportResult{}
+		if err := json.Unmarshal([]byte(result.String), s.Result); err != nil {
+			return nil, fmt.Errorf("failed to decode import result: %w", err)
+		}
+	}
+	if completedAt.Valid {
+		s.CompletedAt = &completedAt.Time
+	}
+	if s.Status == types.ImportSessionPending && time.Now().After(s.ExpiresAt) {
+		s.Status = types.ImportSessionExpired
+	}
+	s.MemoryIDs = []int64{}
+	return &s, nil
+}
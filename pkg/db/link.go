@@ -0,0 +1,194 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// migrateLinks 自动迁移：创建记忆关系表
+func (d *Database) migrateLinks() error {
+	_, err := d.db.Exec(`
+	CREATE TABLE IF NOT EXISTS knowledge_links (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		source_id INTEGER NOT NULL,
+		target_id INTEGER NOT NULL,
+		relation TEXT NOT NULL CHECK (relation IN ('see_also', 'depends_on', 'supersedes', 'contradicts')),
+		note TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		CHECK (source_id != target_id),
+		FOREIGN KEY (source_id) REFERENCES knowledge_base(id) ON DELETE CASCADE,
+		FOREIGN KEY (target_id) REFERENCES knowledge_base(id) ON DELETE CASCADE
+	);
+
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_links_unique ON knowledge_links(source_id, target_id, relation);
+	CREATE INDEX IF NOT EXISTS idx_links_target ON knowledge_links(target_id);
+
+	CREATE TRIGGER IF NOT EXISTS knowledge_links_cleanup AFTER DELETE ON knowledge_base BEGIN
+		DELETE FROM knowledge_links WHERE source_id = old.id OR target_id = old.id;
+	END;
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create knowledge_links table: %w", err)
+	}
+	return nil
+}
+
+// getLinkable 获取可建立关系的记忆（存在且不在回收站）
+func (d *Database) getLinkable(id int64) error {
+	memory, err := d.GetByID(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("memory not found: id=%d", id)
+		}
+		return fmt.Errorf("failed to get memory: %w", err)
+	}
+	if memory.DeletedAt != nil {
+		return fmt.Errorf("memory is in trash: id=%d", id)
+	}
+	return nil
+}
+
+// Link 建立记忆关系
+func (d *Database) Link(req types.LinkRequest) (*types.Link, error) {
+	if err := d.getLinkable(req.SourceID); err != nil {
+		return nil, err
+	}
+	if err := d.getLinkable(req.TargetID); err != nil {
+		return nil, err
+	}
+
+	var exists bool
+	if err := d.db.QueryRow(`
+		SELECT COUNT(*) > 0 FROM knowledge_links WHERE source_id = ? AND target_id = ? AND relation = ?
+	`, req.SourceID, req.TargetID, req.Relation).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check link: %w", err)
+	}
+	if exists {
+		return nil, fmt.Errorf("link already exists: %d -[%s]-> %d", req.SourceID, req.Relation, req.TargetID)
+	}
+
+	now := time.Now()
+	result, err := d.db.Exec(`
+		INSERT INTO knowledge_links (source_id, target_id, relation, note, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, req.SourceID, req.TargetID, req.Relation, req.Note, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create link: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get link id: %w", err)
+	}
+
+	return &types.Link{
+		ID:        id,
+		SourceID:  req.SourceID,
+		TargetID:  req.TargetID,
+		Relation:  req.Relation,
+		Note:      req.Note,
+		CreatedAt: now,
+	}, nil
+}
+
+// Unlink 删除记忆关系，relation 为空时删除 source → target 的所有关系
+func (d *Database) Unlink(req types.LinkRequest) (int, error) {
+	query := `DELETE FROM knowledge_links WHERE source_id = ? AND target_id = ?`
+	args := []interface{}{req.SourceID, req.TargetID}
+	if req.Relation != "" {
+		query += ` AND relation = ?`
+		args = append(args, req.Relation)
+	}
+
+	result, err := d.db.Exec(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete link: %w", err)
+	}
+	affected, _ := result.RowsAffected()
+	if affected == 0 {
+		return 0, fmt.Errorf("link not found: %d -> %d", req.SourceID, req.TargetID)
+	}
+	return int(affected), nil
+}
+
+// Neighbors 查询与指定记忆直接相连的关系（任一方向），两端都不在回收站
+// relation 为空表示所有类型
+func (d *Database) Neighbors(ids []int64, relation types.LinkRelation) ([]types.Link, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	args := make([]interface{}, 0, len(ids)*2+1)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+
+	query := `
+		SELECT l.id, l.source_id, l.target_id, l.relation, COALESCE(l.note, ''), l.created_at
+		FROM knowledge_links l
+		JOIN knowledge_base s ON s.id = l.source_id AND s.deleted_at IS NULL
+		JOIN knowledge_base t ON t.id = l.target_id AND t.deleted_at IS NULL
+		WHERE (l.source_id IN (` + placeholders + `) OR l.target_id IN (` + placeholders + `))`
+	if relation != "" {
+		query += ` AND l.relation = ?`
+		args = append(args, relation)
+	}
+	query += ` ORDER BY l.id`
+
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query links: %w", err)
+	}
+	defer rows.Close()
+
+	var links []types.Link
+	for rows.Next() {
+		var l types.Link
+		if err := rows.Scan(&l.ID, &l.SourceID, &l.TargetID, &l.Relation, &l.Note, &l.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan link: %w", err)
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
+
+// GraphNodes 批量获取关系图节点信息（不含回收站中的记忆）
+func (d *Database) GraphNodes(ids []int64) (map[int64]types.GraphNode, error) {
+	nodes := make(map[int64]types.GraphNode, len(ids))
+	if len(ids) == 0 {
+		return nodes, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	rows, err := d.db.Query(`
+		SELECT id, level, title, COALESCE(summary, ''), COALESCE(library_name, '')
+		FROM knowledge_base
+		WHERE deleted_at IS NULL AND id IN (`+placeholders+`)
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query graph nodes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var n types.GraphNode
+		if err := rows.Scan(&n.ID, &n.Level, &n.Title, &n.Summary, &n.LibraryName); err != nil {
+			return nil, fmt.Errorf("failed to scan graph node: %w", err)
+		}
+		nodes[n.ID] = n
+	}
+	return nodes, rows.Err()
+}
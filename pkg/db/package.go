@@ -0,0 +1,437 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// migratePackages 自动迁移：创建已安装知识包表和包记忆表（每条记忆最多属于一个包，记忆彻底删除时解除关联）
+// restore_revision 为包覆盖已有记忆前该记忆的最新修订版本，卸载或新版本删除该记忆时恢复为这一版本，为 0 时移入回收站
+func (d *Database) migratePackages() error {
+	_, err := d.db.Exec(`
+	CREATE TABLE IF NOT EXISTS packages (
+		name TEXT PRIMARY KEY,
+		version TEXT NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		author TEXT NOT NULL DEFAULT '',
+		format_version TEXT NOT NULL DEFAULT '',
+		registry TEXT NOT NULL DEFAULT '',
+		checksum TEXT NOT NULL DEFAULT '',
+		signer TEXT NOT NULL DEFAULT '',
+		installed_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS package_memories (
+		knowledge_id INTEGER PRIMARY KEY,
+		package_name TEXT NOT NULL,
+		uuid TEXT NOT NULL DEFAULT '',
+		restore_revision INTEGER NOT NULL DEFAULT 0,
+		FOREIGN KEY (package_name) REFERENCES packages(name) ON DELETE CASCADE,
+		FOREIGN KEY (knowledge_id) REFERENCES knowledge_base(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_package_memories_package ON package_memories(package_name);
+
+	CREATE TRIGGER IF NOT EXISTS package_memories_cleanup AFTER DELETE ON knowledge_base BEGIN
+		DELETE FROM package_memories WHERE knowledge_id = old.id;
+	END;
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create packages table: %w", err)
+	}
+	return nil
+}
+
+// packageColumns 已安装知识包查询字段（与 scanPackage 对应）
+const packageColumns = `name, version, description, author, format_version, registry, checksum, signer, installed_at, updated_at`
+
+// GetPackage 获取已安装的知识包（包含包拥有的记忆 ID，不含回收站中的）
+func (d *Database) GetPackage(name string) (*types.InstalledPackage, error) {
+	p, err := d.findPackage(name)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, fmt.Errorf("package not found: %s", name)
+	}
+	return p, nil
+}
+
+// findPackage 查询已安装的知识包，未安装时返回 nil
+func (d *Database) findPackage(name string) (*types.InstalledPackage, error) {
+	p, err := scanPackage(d.db.QueryRow(`SELECT `+packageColumns+` FROM packages WHERE name = ?`, name))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get package: %w", err)
+	}
+
+	owned, err := d.packageMemories(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range owned {
+		p.MemoryIDs = append(p.MemoryIDs, m.id)
+	}
+	return p, nil
+}
+
+// ListPackages 列出已安装的知识包（按包名排序）
+func (d *Database) ListPackages() ([]types.InstalledPackage, error) {
+	rows, err := d.db.Query(`SELECT ` + packageColumns + ` FROM packages ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list packages: %w", err)
+	}
+	packages := []types.InstalledPackage{}
+	for rows.Next() {
+		p, err := scanPackage(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan package: %w", err)
+		}
+		packages = append(packages, *p)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, fmt.Errorf("failed to list packages: %w", err)
+	}
+	rows.Close()
+
+	for i := range packages {
+		owned, err := d.packageMemories(packages[i].Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range owned {
+			packages[i].MemoryIDs = append(packages[i].MemoryIDs, m.id)
+		}
+	}
+	return packages, nil
+}
+
+// InstallPackage 在一个事务中安装或升级知识包（upgrade 为 true 时包必须已安装，否则必须未安装）：
+// 按 UUID 对比包已拥有的记忆得出差异，包自己的记忆总是被覆盖；与不属于该包的已有记忆冲突时按 opts 中的策略处理
+// （默认都保留，属于其他包的记忆总是都保留），显式覆盖或合并的已有记忆归包所有，并记录覆盖前的版本。
+// 包新增的记忆归包所有，新版本中已删除的记忆移入回收站，包覆盖过的恢复为覆盖前的版本。试运行或有记忆导入失败时不写入
+func (d *Database) InstallPackage(pkg types.KnowledgePackage, registry, signer string, opts types.ImportOptions, upgrade bool) (*types.PackageInstallResult, error) {
+	var result *types.PackageInstallResult
+	err := d.inTx(func(tx *Database) error {
+		installed, err := tx.findPackage(pkg.Package.Name)
+		if err != nil {
+			return err
+		}
+		if installed == nil && upgrade {
+			return fmt.Errorf("package not found: %s", pkg.Package.Name)
+		}
+		if installed != nil && !upgrade {
+			return fmt.Errorf("package already installed: %s@%s", installed.Name, installed.Version)
+		}
+
+		owned, err := tx.packageMemories(pkg.Package.Name)
+		if err != nil {
+			return err
+		}
+		diff, ownedIndexes, err := tx.packageDiff(pkg, owned)
+		if err != nil {
+			return err
+		}
+		if installed != nil {
+			diff.FromVersion = installed.Version
+		}
+
+		// 包自己的记忆总是覆盖，属于其他包的记忆总是都保留，优先于请求中的策略；其余冲突默认都保留，不覆盖手写的记忆
+		if opts.Strategy == "" {
+			opts.Strategy = types.ConflictKeepBoth
+		}
+		resolutions := make([]types.ConflictResolution, 0, len(ownedIndexes)+len(diff.Conflicts)+len(opts.Resolutions))
+		for _, i := range ownedIndexes {
+			resolutions = append(resolutions, types.ConflictResolution{Index: i, Strategy: types.ConflictOverwrite})
+		}
+		overwritten := make(map[int64]int, len(diff.Conflicts)) // 可能被覆盖的已有记忆 → 覆盖前的最新修订版本
+		for _, c := range diff.Conflicts {
+			if c.Package != "" {
+				resolutions = append(resolutions, types.ConflictResolution{Index: c.Index, Strategy: types.ConflictKeepBoth})
+				continue
+			}
+			if overwritten[c.ID], err = tx.latestRevision(c.ID); err != nil {
+				return err
+			}
+		}
+		opts.Resolutions = append(resolutions, opts.Resolutions...)
+
+		importResult, err := tx.ImportMemories(pkg.Memories, opts)
+		if err != nil {
+			return err
+		}
+		result = &types.PackageInstallResult{
+			Package: types.InstalledPackage{
+				Name:          pkg.Package.Name,
+				Version:       pkg.Package.Version,
+				Description:   pkg.Package.Description,
+				Author:        pkg.Package.Author,
+				FormatVersion: pkg.Version,
+				Registry:      registry,
+				Checksum:      pkg.Checksum,
+				Signer:        signer,
+				MemoryIDs:     []int64{},
+			},
+			Diff:        diff,
+			Import:      importResult,
+			RestoredIDs: []int64{},
+		}
+		previous := make(map[int64]packageMemory, len(owned))
+		for _, m := range owned {
+			previous[m.id] = m
+		}
+		if importResult.RolledBack {
+			if opts.DryRun {
+				for _, item := range diff.Removed {
+					if previous[item.ID].restore > 0 {
+						result.Restored++
+					} else {
+						result.Removed++
+					}
+				}
+			}
+			return nil
+		}
+
+		now := time.Now()
+		result.Package.InstalledAt, result.Package.UpdatedAt = now, now
+		if installed != nil {
+			result.Package.InstalledAt = installed.InstalledAt
+		}
+		if _, err := tx.db.Exec(`
+			INSERT INTO packages (`+packageColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(name) DO UPDATE SET
+				version = excluded.version,
+				description = excluded.description,
+				author = excluded.author,
+				format_version = excluded.format_version,
+				registry = excluded.registry,
+				checksum = excluded.checksum,
+				signer = excluded.signer,
+				updated_at = excluded.updated_at
+		`, result.Package.Name, result.Package.Version, result.Package.Description, result.Package.Author,
+			result.Package.FormatVersion, result.Package.Registry, result.Package.Checksum, result.Package.Signer,
+			result.Package.InstalledAt, result.Package.UpdatedAt); err != nil {
+			return fmt.Errorf("failed to save package: %w", err)
+		}
+
+		// 记录包拥有的记忆：包新增的、原来就属于包的，以及包覆盖或合并的已有记忆（记录覆盖前的版本）
+		if _, err := tx.db.Exec(`DELETE FROM package_memories WHERE package_name = ?`, pkg.Package.Name); err != nil {
+			return fmt.Errorf("failed to save package memories: %w", err)
+		}
+		for _, item := range importResult.Items {
+			if item.ID == 0 {
+				continue
+			}
+			restore := 0
+			if m, ok := previous[item.ID]; ok {
+				restore = m.restore
+			} else if revision, ok := overwritten[item.ID]; ok && item.Action == types.ImportUpdated {
+				restore = revision
+			} else if item.Action != types.ImportAdded {
+				continue
+			}
+			if _, err := tx.db.Exec(`
+				INSERT OR REPLACE INTO package_memories (knowledge_id, package_name, uuid, restore_revision) VALUES (?, ?, ?, ?)
+			`, item.ID, pkg.Package.Name, pkg.Memories[item.Index].UUID, restore); err != nil {
+				return fmt.Errorf("failed to save package memories: %w", err)
+			}
+			result.Package.MemoryIDs = append(result.Package.MemoryIDs, item.ID)
+		}
+
+		// 新版本中已删除的记忆移入回收站，包覆盖过的已有记忆恢复为覆盖前的版本
+		for _, item := range diff.Removed {
+			restored, err := tx.releasePackageMemory(previous[item.ID])
+			if err != nil {
+				return err
+			}
+			if restored {
+				result.Restored++
+				result.RestoredIDs = append(result.RestoredIDs, item.ID)
+			} else {
+				result.Removed++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// UninstallPackage 卸载知识包：包新增的记忆移入回收站，包覆盖过的已有记忆恢复为覆盖前的版本
+func (d *Database) UninstallPackage(name string) (*types.PackageUninstallResult, error) {
+	result := &types.PackageUninstallResult{Name: name, RestoredIDs: []int64{}}
+	err := d.inTx(func(tx *Database) error {
+		if _, err := tx.GetPackage(name); err != nil {
+			return err
+		}
+		owned, err := tx.packageMemories(name)
+		if err != nil {
+			return err
+		}
+		for _, m := range owned {
+			restored, err := tx.releasePackageMemory(m)
+			if err != nil {
+				return err
+			}
+			if restored {
+				result.Restored++
+				result.RestoredIDs = append(result.RestoredIDs, m.id)
+			} else {
+				result.Removed++
+			}
+		}
+		if _, err := tx.db.Exec(`DELETE FROM package_memories WHERE package_name = ?`, name); err != nil {
+			return fmt.Errorf("failed to uninstall package: %w", err)
+		}
+		if _, err := tx.db.Exec(`DELETE FROM packages WHERE name = ?`, name); err != nil {
+			return fmt.Errorf("failed to uninstall package: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// releasePackageMemory 解除包对记忆的所有权：包覆盖过的已有记忆恢复为覆盖前的版本（返回 true），包新增的记忆移入回收站
+func (d *Database) releasePackageMemory(m packageMemory) (bool, error) {
+	if m.restore > 0 {
+		if _, err := d.RestoreRevision(m.id, m.restore, types.EditorImport); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	return false, d.Delete(m.id, types.EditorImport)
+}
+
+// packageMemory 包拥有的记忆
+type packageMemory struct {
+	id      int64
+	uuid    string
+	restore int // 覆盖前的修订版本（包新增的记忆为 0）
+}
+
+// packageMemories 查询包拥有的记忆（不含回收站中的，按 ID 排序）
+func (d *Database) packageMemories(name string) ([]packageMemory, error) {
+	rows, err := d.db.Query(`
+		SELECT package_memories.knowledge_id, package_memories.uuid, package_memories.restore_revision FROM package_memories
+		JOIN knowledge_base ON knowledge_base.id = package_memories.knowledge_id
+		WHERE package_memories.package_name = ? AND knowledge_base.deleted_at IS NULL
+		ORDER BY package_memories.knowledge_id
+	`, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load package memories: %w", err)
+	}
+	defer rows.Close()
+
+	var owned []packageMemory
+	for rows.Next() {
+		var m packageMemory
+		if err := rows.Scan(&m.id, &m.uuid, &m.restore); err != nil {
+			return nil, fmt.Errorf("failed to scan package memory: %w", err)
+		}
+		owned = append(owned, m)
+	}
+	return owned, rows.Err()
+}
+
+// memoryPackage 查询记忆所属的包，不属于任何包时返回空字符串
+func (d *Database) memoryPackage(id int64) (string, error) {
+	var name string
+	err := d.db.QueryRow(`SELECT package_name FROM package_memories WHERE knowledge_id = ?`, id).Scan(&name)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get memory package: %w", err)
+	}
+	return name, nil
+}
+
+// packageDiff 对比新版本的记忆和包已拥有的记忆，返回差异和对应包已有记忆的序号；
+// 对应到不属于该包的已有记忆的列为冲突
+func (d *Database) packageDiff(pkg types.KnowledgePackage, owned []packageMemory) (types.PackageDiff, []int, error) {
+	diff := types.PackageDiff{
+		ToVersion: pkg.Package.Version,
+		Added:     []types.PackageDiffItem{},
+		Changed:   []types.PackageDiffItem{},
+		Removed:   []types.PackageDiffItem{},
+		Conflicts: []types.PackageDiffItem{},
+	}
+	ownedIDs := make(map[int64]bool, len(owned))
+	for _, m := range owned {
+		ownedIDs[m.id] = true
+	}
+
+	var ownedIndexes []int
+	matched := make(map[int64]bool, len(owned))
+	for i, mem := range pkg.Memories {
+		mem.StoreRequest = importDefaults(mem.StoreRequest)
+		item := types.PackageDiffItem{Index: i, UUID: mem.UUID, Title: mem.Title}
+		id, err := d.matchExisting(mem)
+		if err != nil {
+			return diff, nil, err
+		}
+		if id == 0 || matched[id] {
+			diff.Added = append(diff.Added, item)
+			continue
+		}
+		matched[id] = true
+
+		current, err := d.GetByID(id)
+		if err != nil {
+			return diff, nil, fmt.Errorf("failed to get memory: %w", err)
+		}
+		item.ID = id
+		item.Diffs = fieldDiffs(current, mem.StoreRequest)
+		switch {
+		case !ownedIDs[id]:
+			if item.Package, err = d.memoryPackage(id); err != nil {
+				return diff, nil, err
+			}
+			diff.Conflicts = append(diff.Conflicts, item)
+		case len(item.Diffs) > 0:
+			ownedIndexes = append(ownedIndexes, i)
+			diff.Changed = append(diff.Changed, item)
+		default:
+			ownedIndexes = append(ownedIndexes, i)
+			diff.Unchanged++
+		}
+	}
+
+	for _, m := range owned {
+		if matched[m.id] {
+			continue
+		}
+		current, err := d.GetByID(m.id)
+		if err != nil {
+			return diff, nil, fmt.Errorf("failed to get memory: %w", err)
+		}
+		diff.Removed = append(diff.Removed, types.PackageDiffItem{Index: -1, UUID: m.uuid, Title: current.Title, ID: m.id})
+	}
+	return diff, ownedIndexes, nil
+}
+
+// scanPackage 扫描单个已安装知识包
+func scanPackage(scanner interface{ Scan(...interface{}) error }) (*types.InstalledPackage, error) {
+	var p types.InstalledPackage
+	err := scanner.Scan(&p.Name, &p.Version, &p.Description, &p.Author, &p.FormatVersion, &p.Registry,
+		&p.Checksum, &p.Signer, &p.InstalledAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	p.MemoryIDs = []int64{}
+	return &p, nil
+}
@@ -0,0 +1,109 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// migrateProvenance 自动迁移：创建记忆出处表（每条记忆最多一个出处，同一库的同一出处只对应一条记忆）
+func (d *Database) migrateProvenance() error {
+	_, err := d.db.Exec(`
+	CREATE TABLE IF NOT EXISTS knowledge_provenance (
+		knowledge_id INTEGER PRIMARY KEY,
+		language_tag TEXT NOT NULL DEFAULT 'cangjie',
+		library_name TEXT NOT NULL DEFAULT '',
+		file TEXT NOT NULL,
+		section TEXT NOT NULL DEFAULT '',
+		FOREIGN KEY (knowledge_id) REFERENCES knowledge_base(id) ON DELETE CASCADE
+	);
+
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_provenance_source ON knowledge_provenance(language_tag, library_name, file, section);
+
+	CREATE TRIGGER IF NOT EXISTS knowledge_provenance_cleanup AFTER DELETE ON knowledge_base BEGIN
+		DELETE FROM knowledge_provenance WHERE knowledge_id = old.id;
+	END;
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create knowledge_provenance table: %w", err)
+	}
+	return nil
+}
+
+// SetProvenance 设置记忆的出处（同一出处原来对应的其他记忆会解除关联）
+func (d *Database) SetProvenance(id int64, languageTag, libraryName string, p types.Provenance) error {
+	if _, err := d.db.Exec(`
+		DELETE FROM knowledge_provenance
+		WHERE language_tag = ? AND library_name = ? AND file = ? AND section = ? AND knowledge_id != ?
+	`, languageTag, libraryName, p.File, p.Section, id); err != nil {
+		return fmt.Errorf("failed to set provenance: %w", err)
+	}
+
+	_, err := d.db.Exec(`
+		INSERT INTO knowledge_provenance (knowledge_id, language_tag, library_name, file, section) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(knowledge_id) DO UPDATE SET
+			language_tag = excluded.language_tag,
+			library_name = excluded.library_name,
+			file = excluded.file,
+			section = excluded.section
+	`, id, languageTag, libraryName, p.File, p.Section)
+	if err != nil {
+		return fmt.Errorf("failed to set provenance: %w", err)
+	}
+	return nil
+}
+
+// FindByProvenance 按出处查找记忆 ID，不存在时返回 0
+func (d *Database) FindByProvenance(languageTag, libraryName string, p types.Provenance) (int64, error) {
+	var id int64
+	err := d.db.QueryRow(`
+		SELECT knowledge_id FROM knowledge_provenance
+		WHERE language_tag = ? AND library_name = ? AND file = ? AND section = ?
+	`, languageTag, libraryName, p.File, p.Section).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to find provenance: %w", err)
+	}
+	return id, nil
+}
+
+// ProvenanceIDs 列出出处属于该库的记忆 ID（不含回收站）
+func (d *Database) ProvenanceIDs(languageTag, libraryName string) ([]int64, error) {
+	rows, err := d.db.Query(`
+		SELECT knowledge_provenance.knowledge_id FROM knowledge_provenance
+		JOIN knowledge_base ON knowledge_base.id = knowledge_provenance.knowledge_id
+		WHERE knowledge_provenance.language_tag = ? AND knowledge_provenance.library_name = ?
+		  AND knowledge_base.deleted_at IS NULL
+		ORDER BY knowledge_provenance.knowledge_id
+	`, languageTag, libraryName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list provenance: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan provenance: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// getProvenance 查询记忆的出处，没有时返回 nil
+func (d *Database) getProvenance(id int64) (*types.Provenance, error) {
+	var p types.Provenance
+	err := d.db.QueryRow(`SELECT file, section FROM knowledge_provenance WHERE knowledge_id = ?`, id).Scan(&p.File, &p.Section)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load provenance: %w", err)
+	}
+	return &p, nil
+}
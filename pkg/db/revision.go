@@ -0,0 +1,231 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// migrateRevisions 自动迁移：创建修订历史表，并为已有记忆补充初始版本
+func (d *Database) migrateRevisions() error {
+	// 修订历史不随记忆删除，保留删除前的快照以便追溯
+	_, err := d.db.Exec(`
+	CREATE TABLE IF NOT EXISTS knowledge_revisions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		knowledge_id INTEGER NOT NULL,
+		revision INTEGER NOT NULL,
+		action TEXT NOT NULL,
+		editor TEXT,
+		level TEXT NOT NULL,
+		language_tag TEXT NOT NULL,
+		library_name TEXT,
+		library_version TEXT,
+		project_path_pattern TEXT,
+		title TEXT NOT NULL,
+		content TEXT NOT NULL,
+		summary TEXT,
+		source TEXT,
+		tags TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_revisions_knowledge ON knowledge_revisions(knowledge_id, revision);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create knowledge_revisions table: %w", err)
+	}
+
+	// 老数据库的修订历史表没有库版本范围字段
+	var hasVersion bool
+	err = d.db.QueryRow(`
+		SELECT COUNT(*) > 0 FROM pragma_table_info('knowledge_revisions') WHERE name = 'library_version'
+	`).Scan(&hasVersion)
+	if err != nil {
+		return fmt.Errorf("failed to check knowledge_revisions columns: %w", err)
+	}
+	if !hasVersion {
+		if _, err := d.db.Exec(`ALTER TABLE knowledge_revisions ADD COLUMN library_version TEXT`); err != nil {
+			return fmt.Errorf("failed to add library_version column to knowledge_revisions: %w", err)
+		}
+	}
+
+	// 老数据库中的记忆没有任何版本，以当前内容作为初始版本
+	rows, err := d.db.Query(`
+		SELECT id FROM knowledge_base
+		WHERE id NOT IN (SELECT DISTINCT knowledge_id FROM knowledge_revisions)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to query memories without revisions: %w", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	for _, id := range ids {
+		if err := d.recordRevision(id, types.RevisionCreate, ""); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// recordRevision 读取记忆当前内容并记录为新版本
+func (d *Database) recordRevision(id int64, action, editor string) error {
+	memory, err := d.GetByID(id)
+	if err != nil {
+		return fmt.Errorf("failed to load memory for revision: %w", err)
+	}
+	return d.insertRevision(memory, action, editor)
+}
+
+// latestRevision 查询记忆的最新修订版本号（没有修订历史时为 0）
+func (d *Database) latestRevision(id int64) (int, error) {
+	var revision int
+	err := d.db.QueryRow(`SELECT COALESCE(MAX(revision), 0) FROM knowledge_revisions WHERE knowledge_id = ?`, id).Scan(&revision)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get latest revision: %w", err)
+	}
+	return revision, nil
+}
+
+// insertRevision 将记忆快照记录为新版本
+func (d *Database) insertRevision(m *types.Memory, action, editor string) error {
+	tags, err := json.Marshal(m.Tags)
+	if err != nil {
+		return fmt.Errorf("failed to encode tags: %w", err)
+	}
+
+	_, err = d.db.Exec(`
+		INSERT INTO knowledge_revisions (
+			knowledge_id, revision, action, editor,
+			level, language_tag, library_name, library_version, project_path_pattern,
+			title, content, summary, source, tags
+		) VALUES (
+			?, (SELECT COALESCE(MAX(revision), 0) + 1 FROM knowledge_revisions WHERE knowledge_id = ?), ?, ?,
+			?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+		)
+	`, m.ID, m.ID, action, editor,
+		m.Level, m.LanguageTag, m.LibraryName, m.LibraryVersion, m.ProjectPathPattern,
+		m.Title, m.Content, m.Summary, m.Source, string(tags))
+	if err != nil {
+		return fmt.Errorf("failed to insert revision: %w", err)
+	}
+	return nil
+}
+
+// revisionColumns 修订版本查询字段（与 scanRevision 对应）
+const revisionColumns = `
+	id, knowledge_id, revision, action, editor,
+	level, language_tag, library_name, library_version, project_path_pattern,
+	title, content, summary, source, tags, created_at`
+
+// scanRevision 扫描单个修订版本
+func scanRevision(scanner interface{ Scan(...interface{}) error }) (*types.Revision, error) {
+	var r types.Revision
+	var editor, libraryName, libraryVersion, pattern, summary, source, tags sql.NullString
+
+	err := scanner.Scan(
+		&r.ID, &r.MemoryID, &r.Revision, &r.Action, &editor,
+		&r.Level, &r.LanguageTag, &libraryName, &libraryVersion, &pattern,
+		&r.Title, &r.Content, &summary, &source, &tags, &r.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Editor = editor.String
+	r.LibraryName = libraryName.String
+	r.LibraryVersion = libraryVersion.String
+	r.ProjectPathPattern = pattern.String
+	r.Summary = summary.String
+	r.Source = types.KnowledgeSource(source.String)
+	if tags.Valid && tags.String != "" {
+		if err := json.Unmarshal([]byte(tags.String), &r.Tags); err != nil {
+			return nil, fmt.Errorf("failed to decode revision tags: %w", err)
+		}
+	}
+
+	return &r, nil
+}
+
+// ListRevisions 列出记忆的全部修订版本（按版本号降序）
+func (d *Database) ListRevisions(memoryID int64) ([]types.Revision, error) {
+	rows, err := d.db.Query(`
+		SELECT `+revisionColumns+`
+		FROM knowledge_revisions
+		WHERE knowledge_id = ?
+		ORDER BY revision DESC
+	`, memoryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revisions: %w", err)
+	}
+	defer rows.Close()
+
+	var revisions []types.Revision
+	for rows.Next() {
+		r, err := scanRevision(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		revisions = append(revisions, *r)
+	}
+
+	return revisions, rows.Err()
+}
+
+// GetRevision 获取记忆的指定修订版本
+func (d *Database) GetRevision(memoryID int64, revision int) (*types.Revision, error) {
+	row := d.db.QueryRow(`
+		SELECT `+revisionColumns+`
+		FROM knowledge_revisions
+		WHERE knowledge_id = ? AND revision = ?
+	`, memoryID, revision)
+
+	r, err := scanRevision(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("revision not found: id=%d, revision=%d", memoryID, revision)
+		}
+		return nil, fmt.Errorf("failed to get revision: %w", err)
+	}
+	return r, nil
+}
+
+// RestoreRevision 将记忆恢复到指定修订版本的内容（恢复操作本身记录为新版本）
+func (d *Database) RestoreRevision(memoryID int64, revision int, editor string) (*types.Memory, error) {
+	r, err := d.GetRevision(memoryID, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := r.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	req := types.UpdateRequest{
+		ID:                 memoryID,
+		Level:              &r.Level,
+		LanguageTag:        &r.LanguageTag,
+		LibraryName:        &r.LibraryName,
+		LibraryVersion:     &r.LibraryVersion,
+		ProjectPathPattern: &r.ProjectPathPattern,
+		Title:              &r.Title,
+		Content:            &r.Content,
+		Summary:            &r.Summary,
+		Source:             &r.Source,
+		Tags:               &tags,
+		Editor:             editor,
+	}
+
+	return d.patch(req, types.RevisionRestore)
+}
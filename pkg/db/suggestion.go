@@ -0,0 +1,196 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// migrateSuggestions 自动迁移：创建建议补充表
+func (d *Database) migrateSuggestions() error {
+	_, err := d.db.Exec(`
+	CREATE TABLE IF NOT EXISTS suggestions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		query TEXT NOT NULL,
+		suggested_title TEXT NOT NULL,
+		suggested_content TEXT NOT NULL,
+		suggested_level TEXT NOT NULL CHECK (suggested_level IN ('language', 'project', 'library')),
+		language_tag TEXT NOT NULL DEFAULT 'cangjie',
+		library_name TEXT,
+		project_path_pattern TEXT,
+		reason TEXT,
+		status TEXT NOT NULL CHECK (status IN ('pending_review', 'approved', 'rejected')) DEFAULT 'pending_review',
+		memory_id INTEGER,
+		review_note TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		reviewed_at TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_suggestions_status ON suggestions(status);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create suggestions table: %w", err)
+	}
+	return nil
+}
+
+// CreateSuggestion 记录一条待审核的建议
+func (d *Database) CreateSuggestion(req types.SuggestRequest) (int64, error) {
+	if !req.SuggestedLevel.IsValid() {
+		return 0, fmt.Errorf("invalid knowledge level: %s", req.SuggestedLevel)
+	}
+	if req.LanguageTag == "" {
+		req.LanguageTag = "cangjie"
+	}
+
+	result, err := d.db.Exec(`
+		INSERT INTO suggestions (
+			query, suggested_title, suggested_content, suggested_level,
+			language_tag, library_name, project_path_pattern, reason, status
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, req.Query, req.SuggestedTitle, req.SuggestedContent, req.SuggestedLevel,
+		req.LanguageTag, req.LibraryName, req.ProjectPathPattern, req.Reason, types.SuggestionPending)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert suggestion: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	return id, nil
+}
+
+// suggestionColumns 建议查询字段
+const suggestionColumns = `
+	id, query, suggested_title, suggested_content, suggested_level,
+	language_tag, library_name, project_path_pattern, reason, status,
+	memory_id, review_note, created_at, reviewed_at
+`
+
+// scanSuggestion 扫描一行建议数据
+func scanSuggestion(scanner interface{ Scan(...interface{}) error }) (*types.Suggestion, error) {
+	var s types.Suggestion
+	var libraryName, pattern, reason, note sql.NullString
+	var memoryID sql.NullInt64
+	var reviewedAt sql.NullTime
+
+	err := scanner.Scan(
+		&s.ID, &s.Query, &s.SuggestedTitle, &s.SuggestedContent, &s.SuggestedLevel,
+		&s.LanguageTag, &libraryName, &pattern, &reason, &s.Status,
+		&memoryID, &note, &s.CreatedAt, &reviewedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	s.LibraryName = libraryName.String
+	s.ProjectPathPattern = pattern.String
+	s.Reason = reason.String
+	s.ReviewNote = note.String
+	s.MemoryID = memoryID.Int64
+	if reviewedAt.Valid {
+		s.ReviewedAt = &reviewedAt.Time
+	}
+	return &s, nil
+}
+
+// GetSuggestion 根据 ID 获取建议
+func (d *Database) GetSuggestion(id int64) (*types.Suggestion, error) {
+	row := d.db.QueryRow(`SELECT `+suggestionColumns+` FROM suggestions WHERE id = ?`, id)
+	s, err := scanSuggestion(row)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("suggestion not found: id=%d", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get suggestion: %w", err)
+	}
+	return s, nil
+}
+
+// ListSuggestions 列出建议（支持按状态筛选和分页）
+func (d *Database) ListSuggestions(req types.SuggestionListRequest) (*types.SuggestionListResponse, error) {
+	whereClause := "WHERE 1=1"
+	args := []interface{}{}
+
+	if req.Status != "" {
+		whereClause += " AND status = ?"
+		args = append(args, req.Status)
+	}
+
+	var total int
+	err := d.db.QueryRow("SELECT COUNT(*) FROM suggestions "+whereClause, args...).Scan(&total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count suggestions: %w", err)
+	}
+
+	limit := 20
+	if req.Limit > 0 {
+		limit = req.Limit
+	}
+	offset := 0
+	if req.Offset > 0 {
+		offset = req.Offset
+	}
+
+	rows, err := d.db.Query(`SELECT `+suggestionColumns+` FROM suggestions `+whereClause+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`, append(args, limit, offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list suggestions: %w", err)
+	}
+	defer rows.Close()
+
+	var results []types.Suggestion
+	for rows.Next() {
+		s, err := scanSuggestion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		results = append(results, *s)
+	}
+
+	return &types.SuggestionListResponse{
+		Total:   total,
+		Results: results,
+	}, nil
+}
+
+// ReviewSuggestion 审核建议（仅待审核状态的建议可以被审核）
+// memoryID 为批准后生成的记忆 ID，拒绝时传 0
+func (d *Database) ReviewSuggestion(id int64, status string, memoryID int64, note string) error {
+	if status != types.SuggestionApproved && status != types.SuggestionRejected {
+		return fmt.Errorf("invalid review status: %s", status)
+	}
+
+	var memID interface{}
+	if memoryID > 0 {
+		memID = memoryID
+	}
+
+	result, err := d.db.Exec(`
+		UPDATE suggestions
+		SET status = ?, memory_id = ?, review_note = ?, reviewed_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND status = ?
+	`, status, memID, note, id, types.SuggestionPending)
+	if err != nil {
+		return fmt.Errorf("failed to review suggestion: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		// 区分不存在和已审核
+		s, err := d.GetSuggestion(id)
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("suggestion already reviewed: id=%d, status=%s", id, s.Status)
+	}
+
+	return nil
+}
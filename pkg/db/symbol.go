@@ -0,0 +1,157 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// migrateSymbols 自动迁移：创建 API 符号表
+func (d *Database) migrateSymbols() error {
+	_, err := d.db.Exec(`
+	CREATE TABLE IF NOT EXISTS knowledge_symbols (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		language_tag TEXT NOT NULL DEFAULT 'cangjie',
+		library_name TEXT NOT NULL DEFAULT '',
+		package TEXT NOT NULL DEFAULT '',
+		type_name TEXT NOT NULL DEFAULT '',
+		member TEXT NOT NULL DEFAULT '',
+		qualified_name TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		signature TEXT NOT NULL,
+		doc TEXT,
+		file TEXT,
+		line INTEGER,
+		knowledge_id INTEGER,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (knowledge_id) REFERENCES knowledge_base(id) ON DELETE SET NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_symbols_qualified_name ON knowledge_symbols(qualified_name);
+	CREATE INDEX IF NOT EXISTS idx_symbols_library ON knowledge_symbols(language_tag, library_name);
+	CREATE INDEX IF NOT EXISTS idx_symbols_knowledge ON knowledge_symbols(knowledge_id);
+
+	-- 记忆被彻底删除后符号保留，只解除关联
+	CREATE TRIGGER IF NOT EXISTS knowledge_symbols_cleanup AFTER DELETE ON knowledge_base BEGIN
+		UPDATE knowledge_symbols SET knowledge_id = NULL WHERE knowledge_id = old.id;
+	END;
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create knowledge_symbols table: %w", err)
+	}
+	return nil
+}
+
+// ReplaceSymbols 在一个事务中替换某个库的全部符号（标准库的 libraryName 为空）
+func (d *Database) ReplaceSymbols(languageTag, libraryName string, symbols []types.Symbol) error {
+	return d.inTx(func(tx *Database) error {
+		if _, err := tx.db.Exec(`DELETE FROM knowledge_symbols WHERE language_tag = ? AND library_name = ?`, languageTag, libraryName); err != nil {
+			return fmt.Errorf("failed to clear symbols: %w", err)
+		}
+
+		stmt, err := tx.db.Prepare(`
+			INSERT INTO knowledge_symbols (language_tag, library_name, package, type_name, member, qualified_name, kind, signature, doc, file, line, knowledge_id)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare symbol insert: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, s := range symbols {
+			var knowledgeID interface{}
+			if s.KnowledgeID > 0 {
+				knowledgeID = s.KnowledgeID
+			}
+			if _, err := stmt.Exec(languageTag, libraryName, s.Package, s.Type, s.Member, s.QualifiedName,
+				s.Kind, s.Signature, s.Doc, s.File, s.Line, knowledgeID); err != nil {
+				return fmt.Errorf("failed to insert symbol %s: %w", s.QualifiedName, err)
+			}
+		}
+		return nil
+	})
+}
+
+// SymbolMemoryIDs 查询某个库已关联记忆的符号，返回 完整名称 → 记忆 ID（用于重复导入时更新原记忆）
+func (d *Database) SymbolMemoryIDs(languageTag, libraryName string) (map[string]int64, error) {
+	rows, err := d.db.Query(`
+		SELECT qualified_name, knowledge_id FROM knowledge_symbols
+		WHERE language_tag = ? AND library_name = ? AND knowledge_id IS NOT NULL
+	`, languageTag, libraryName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load symbol memories: %w", err)
+	}
+	defer rows.Close()
+
+	result := map[string]int64{}
+	for rows.Next() {
+		var name string
+		var id int64
+		if err := rows.Scan(&name, &id); err != nil {
+			return nil, fmt.Errorf("failed to scan symbol row: %w", err)
+		}
+		result[name] = id
+	}
+	return result, rows.Err()
+}
+
+// FindSymbols 按名称查询符号
+// 名称可以是完整名称，也可以是按 . 分隔的后缀（如 ArrayList.append）；prefix 为 true 时按前缀匹配
+func (d *Database) FindSymbols(req types.SymbolRequest) ([]types.Symbol, error) {
+	var match string
+	var args []interface{}
+	if req.Prefix {
+		match = `(s.qualified_name LIKE ? ESCAPE '\' OR s.qualified_name LIKE ? ESCAPE '\')`
+		args = append(args, escapeLike(req.Name)+"%", "%."+escapeLike(req.Name)+"%")
+	} else {
+		match = `(s.qualified_name = ? OR s.qualified_name LIKE ? ESCAPE '\')`
+		args = append(args, req.Name, "%."+escapeLike(req.Name))
+	}
+
+	query := `
+		SELECT s.id, s.language_tag, s.library_name, s.package, s.type_name, s.member, s.qualified_name,
+			s.kind, s.signature, s.doc, s.file, s.line, kb.id, kb.title
+		FROM knowledge_symbols s
+		LEFT JOIN knowledge_base kb ON kb.id = s.knowledge_id AND kb.deleted_at IS NULL
+		WHERE s.language_tag = ? AND ` + match
+	args = append([]interface{}{req.LanguageTag}, args...)
+	if req.Kind != "" {
+		query += ` AND s.kind = ?`
+		args = append(args, req.Kind)
+	}
+	if req.LibraryName != "" {
+		query += ` AND s.library_name = ?`
+		args = append(args, req.LibraryName)
+	}
+	// 完整名称匹配的排在后缀匹配之前，再按名称长度（越短越接近）和源码位置排序
+	query += `
+		ORDER BY s.qualified_name != ?, length(s.qualified_name), s.qualified_name, s.file, s.line
+		LIMIT ?
+	`
+	args = append(args, req.Name, req.Limit)
+
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find symbols: %w", err)
+	}
+	defer rows.Close()
+
+	symbols := []types.Symbol{}
+	for rows.Next() {
+		var s types.Symbol
+		var doc, file, title sql.NullString
+		var line, knowledgeID sql.NullInt64
+		if err := rows.Scan(&s.ID, &s.LanguageTag, &s.LibraryName, &s.Package, &s.Type, &s.Member, &s.QualifiedName,
+			&s.Kind, &s.Signature, &doc, &file, &line, &knowledgeID, &title); err != nil {
+			return nil, fmt.Errorf("failed to scan symbol row: %w", err)
+		}
+		s.Doc = doc.String
+		s.File = file.String
+		s.Line = int(line.Int64)
+		s.KnowledgeID = knowledgeID.Int64
+		s.MemoryTitle = title.String
+		symbols = append(symbols, s)
+	}
+	return symbols, rows.Err()
+}
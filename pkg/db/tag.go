@@ -0,0 +1,135 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// migrateTags 自动迁移：创建标签表
+func (d *Database) migrateTags() error {
+	_, err := d.db.Exec(`
+	CREATE TABLE IF NOT EXISTS knowledge_tags (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		knowledge_id INTEGER NOT NULL,
+		tag TEXT NOT NULL,
+		FOREIGN KEY (knowledge_id) REFERENCES knowledge_base(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_tags_knowledge_id ON knowledge_tags(knowledge_id);
+	CREATE INDEX IF NOT EXISTS idx_tags_tag ON knowledge_tags(tag);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_unique ON knowledge_tags(knowledge_id, tag);
+
+	-- 连接未开启 foreign_keys 时 ON DELETE CASCADE 不生效，用触发器兜底
+	CREATE TRIGGER IF NOT EXISTS knowledge_tags_cleanup AFTER DELETE ON knowledge_base BEGIN
+		DELETE FROM knowledge_tags WHERE knowledge_id = old.id;
+	END;
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create knowledge_tags table: %w", err)
+	}
+	return nil
+}
+
+// setTags 替换记忆的全部标签
+func (d *Database) setTags(id int64, tags []string) error {
+	if _, err := d.db.Exec(`DELETE FROM knowledge_tags WHERE knowledge_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to clear tags: %w", err)
+	}
+
+	for _, tag := range types.NormalizeTags(tags) {
+		if _, err := d.db.Exec(`INSERT INTO knowledge_tags (knowledge_id, tag) VALUES (?, ?)`, id, tag); err != nil {
+			return fmt.Errorf("failed to insert tag %s: %w", tag, err)
+		}
+	}
+
+	return nil
+}
+
+// loadTags 批量查询记忆的标签
+func (d *Database) loadTags(ids []int64) (map[int64][]string, error) {
+	result := make(map[int64][]string, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+
+	rows, err := d.db.Query(`
+		SELECT knowledge_id, tag FROM knowledge_tags
+		WHERE knowledge_id IN (`+strings.Join(placeholders, ", ")+`)
+		ORDER BY id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tags: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var tag string
+		if err := rows.Scan(&id, &tag); err != nil {
+			return nil, fmt.Errorf("failed to scan tag row: %w", err)
+		}
+		result[id] = append(result[id], tag)
+	}
+
+	return result, rows.Err()
+}
+
+// tagFilterClause 构建标签筛选条件（记忆需同时包含所有标签）
+func tagFilterClause(tags []string) (string, []interface{}) {
+	tags = types.NormalizeTags(tags)
+	if len(tags) == 0 {
+		return "", nil
+	}
+
+	placeholders := make([]string, len(tags))
+	args := make([]interface{}, 0, len(tags)+1)
+	for i, tag := range tags {
+		placeholders[i] = "?"
+		args = append(args, tag)
+	}
+	args = append(args, len(tags))
+
+	clause := ` AND id IN (
+		SELECT knowledge_id FROM knowledge_tags
+		WHERE tag IN (` + strings.Join(placeholders, ", ") + `)
+		GROUP BY knowledge_id
+		HAVING COUNT(DISTINCT tag) = ?
+	)`
+	return clause, args
+}
+
+// listTagCounts 统计每个标签的记忆数
+func (d *Database) listTagCounts(languageTag string) ([]types.CategoryInfo, error) {
+	rows, err := d.db.Query(`
+		SELECT t.tag, COUNT(*) as count
+		FROM knowledge_tags t
+		JOIN knowledge_base k ON k.id = t.knowledge_id
+		WHERE k.language_tag = ? AND k.deleted_at IS NULL
+		GROUP BY t.tag
+		ORDER BY count DESC, t.tag
+	`, languageTag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []types.CategoryInfo
+	for rows.Next() {
+		var info types.CategoryInfo
+		if err := rows.Scan(&info.Name, &info.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan tag row: %w", err)
+		}
+		tags = append(tags, info)
+	}
+
+	return tags, rows.Err()
+}
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// migrateSoftDelete 自动迁移：添加 deleted_at 字段（回收站）
+func (d *Database) migrateSoftDelete() error {
+	var hasColumn bool
+	err := d.db.QueryRow(`
+		SELECT COUNT(*) > 0 FROM pragma_table_info('knowledge_base') WHERE name = 'deleted_at'
+	`).Scan(&hasColumn)
+	if err != nil {
+		return fmt.Errorf("failed to check deleted_at column: %w", err)
+	}
+
+	if !hasColumn {
+		if _, err := d.db.Exec(`ALTER TABLE knowledge_base ADD COLUMN deleted_at TIMESTAMP`); err != nil {
+			return fmt.Errorf("failed to add deleted_at column: %w", err)
+		}
+		fmt.Println("✓ Migrated database: added deleted_at column")
+	}
+
+	if _, err := d.db.Exec(`CREATE INDEX IF NOT EXISTS idx_knowledge_deleted_at ON knowledge_base(deleted_at)`); err != nil {
+		return fmt.Errorf("failed to create deleted_at index: %w", err)
+	}
+	return nil
+}
+
+// ListTrash 列出回收站中的记忆（按删除时间降序）
+func (d *Database) ListTrash(req types.TrashListRequest) (*types.ListResponse, error) {
+	return d.list(types.ListRequest{
+		LanguageTag: req.LanguageTag,
+		Limit:       req.Limit,
+		Offset:      req.Offset,
+	}, true)
+}
+
+// getTrashed 获取回收站中的记忆
+func (d *Database) getTrashed(id int64) (*types.Memory, error) {
+	memory, err := d.GetByID(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("memory not found: id=%d", id)
+		}
+		return nil, fmt.Errorf("failed to get memory: %w", err)
+	}
+	if memory.DeletedAt == nil {
+		return nil, fmt.Errorf("memory not in trash: id=%d", id)
+	}
+	return memory, nil
+}
+
+// Undelete 从回收站恢复记忆
+func (d *Database) Undelete(id int64, editor string) (*types.Memory, error) {
+	if _, err := d.getTrashed(id); err != nil {
+		return nil, err
+	}
+
+	if _, err := d.db.Exec(`UPDATE knowledge_base SET deleted_at = NULL WHERE id = ?`, id); err != nil {
+		return nil, fmt.Errorf("failed to restore memory: %w", err)
+	}
+
+	memory, err := d.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.insertRevision(memory, types.RevisionUndelete, editor); err != nil {
+		return nil, err
+	}
+	return memory, nil
+}
+
+// Purge 彻底删除回收站中的记忆（连同标签、向量和修订历史）
+func (d *Database) Purge(id int64) error {
+	if _, err := d.getTrashed(id); err != nil {
+		return err
+	}
+	return d.purgeWhere(`id = ?`, id)
+}
+
+// PurgeTrash 彻底删除在 before 之前移入回收站的记忆，返回删除数量
+// before 为零值时清空整个回收站
+func (d *Database) PurgeTrash(before time.Time) (int, error) {
+	condition := `deleted_at IS NOT NULL`
+	var args []interface{}
+	if !before.IsZero() {
+		condition += ` AND deleted_at < ?`
+		args = append(args, before)
+	}
+
+	var count int
+	if err := d.db.QueryRow(`SELECT COUNT(*) FROM knowledge_base WHERE `+condition, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count trash: %w", err)
+	}
+	if count == 0 {
+		return 0, nil
+	}
+
+	if err := d.purgeWhere(condition, args...); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// purgeWhere 彻底删除满足条件的记忆
+// 标签和向量由删除触发器清理，修订历史在这里一并删除
+func (d *Database) purgeWhere(condition string, args ...interface{}) error {
+	if _, err := d.db.Exec(`
+		DELETE FROM knowledge_revisions
+		WHERE knowledge_id IN (SELECT id FROM knowledge_base WHERE `+condition+`)
+	`, args...); err != nil {
+		return fmt.Errorf("failed to purge revisions: %w", err)
+	}
+
+	if _, err := d.db.Exec(`DELETE FROM knowledge_base WHERE `+condition, args...); err != nil {
+		return fmt.Errorf("failed to purge memories: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// querier 执行 SQL 的对象（*sql.DB 或事务中的 *sql.Tx）
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Prepare(query string) (*sql.Stmt, error)
+}
+
+// InTx 在一个事务中执行 fn（供上层把多个写入操作合并为一个事务），语义同 inTx
+func (d *Database) InTx(fn func(tx *Database) error) error {
+	return d.inTx(fn)
+}
+
+// inTx 在一个事务中执行 fn：fn 中通过 tx 执行的所有操作一起提交，fn 返回错误时全部回滚
+// 已经在事务中时使用保存点，fn 返回错误时只回滚 fn 中的操作
+func (d *Database) inTx(fn func(tx *Database) error) error {
+	if _, ok := d.db.(*sql.Tx); ok {
+		return d.inSavepoint(fn)
+	}
+
+	tx, err := d.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(&Database{conn: d.conn, db: tx}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// inSavepoint 在当前事务的保存点中执行 fn，fn 返回错误时回滚到保存点
+func (d *Database) inSavepoint(fn func(tx *Database) error) error {
+	if _, err := d.db.Exec(`SAVEPOINT nested`); err != nil {
+		return fmt.Errorf("failed to create savepoint: %w", err)
+	}
+	if err := fn(d); err != nil {
+		if _, rbErr := d.db.Exec(`ROLLBACK TO nested`); rbErr != nil {
+			return fmt.Errorf("failed to roll back savepoint: %w", rbErr)
+		}
+		d.db.Exec(`RELEASE nested`)
+		return err
+	}
+	if _, err := d.db.Exec(`RELEASE nested`); err != nil {
+		return fmt.Errorf("failed to release savepoint: %w", err)
+	}
+	return nil
+}
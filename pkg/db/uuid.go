@@ -0,0 +1,147 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// migrateUUIDs 自动迁移：创建记忆 UUID 表（跨服务器稳定的记忆 ID，用于知识包导入导出），
+// 并为已有记忆补齐随机 UUID；新记忆的 UUID 在插入时由 setUUID 写入
+func (d *Database) migrateUUIDs() error {
+	_, err := d.db.Exec(`
+	CREATE TABLE IF NOT EXISTS knowledge_uuids (
+		knowledge_id INTEGER PRIMARY KEY,
+		uuid TEXT NOT NULL UNIQUE,
+		FOREIGN KEY (knowledge_id) REFERENCES knowledge_base(id) ON DELETE CASCADE
+	);
+
+	DROP TRIGGER IF EXISTS knowledge_uuids_insert;
+
+	CREATE TRIGGER IF NOT EXISTS knowledge_uuids_cleanup AFTER DELETE ON knowledge_base BEGIN
+		DELETE FROM knowledge_uuids WHERE knowledge_id = old.id;
+	END;
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create knowledge_uuids table: %w", err)
+	}
+
+	rows, err := d.db.Query(`SELECT id FROM knowledge_base WHERE id NOT IN (SELECT knowledge_id FROM knowledge_uuids)`)
+	if err != nil {
+		return fmt.Errorf("failed to backfill uuids: %w", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to backfill uuids: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to backfill uuids: %w", err)
+	}
+	for _, id := range ids {
+		if err := d.setUUID(id, uuid.NewString()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setUUID 为新记忆写入 UUID
+func (d *Database) setUUID(id int64, value string) error {
+	if _, err := d.db.Exec(`INSERT INTO knowledge_uuids (knowledge_id, uuid) VALUES (?, ?)`, id, value); err != nil {
+		return fmt.Errorf("failed to set uuid: %w", err)
+	}
+	return nil
+}
+
+// getUUID 查询记忆的 UUID，没有时返回空字符串
+func (d *Database) getUUID(id int64) (string, error) {
+	var uuid string
+	err := d.db.QueryRow(`SELECT uuid FROM knowledge_uuids WHERE knowledge_id = ?`, id).Scan(&uuid)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to load uuid: %w", err)
+	}
+	return uuid, nil
+}
+
+// loadUUIDs 批量查询记忆的 UUID
+func (d *Database) loadUUIDs(ids []int64) (map[int64]string, error) {
+	result := make(map[int64]string, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	rows, err := d.db.Query(`SELECT knowledge_id, uuid FROM knowledge_uuids WHERE knowledge_id IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load uuids: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var uuid string
+		if err := rows.Scan(&id, &uuid); err != nil {
+			return nil, fmt.Errorf("failed to scan uuid: %w", err)
+		}
+		result[id] = uuid
+	}
+	return result, rows.Err()
+}
+
+// findByUUID 按 UUID 查找记忆 ID（不含回收站），不存在时返回 0
+func (d *Database) findByUUID(uuid string) (int64, error) {
+	if uuid == "" {
+		return 0, nil
+	}
+	var id int64
+	err := d.db.QueryRow(`
+		SELECT knowledge_base.id FROM knowledge_uuids
+		JOIN knowledge_base ON knowledge_base.id = knowledge_uuids.knowledge_id
+		WHERE knowledge_uuids.uuid = ? AND knowledge_base.deleted_at IS NULL
+	`, strings.ToLower(uuid)).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to find memory by uuid: %w", err)
+	}
+	return id, nil
+}
+
+// adoptUUID 为导入的新记忆写入指定的 UUID（保留知识包中的身份）；UUID 被回收站中的记忆使用时转给新记忆
+// （回收站中的记忆换用新的随机 UUID），被其他记忆使用或未指定时使用新的随机 UUID
+func (d *Database) adoptUUID(id int64, want string) error {
+	if want == "" {
+		return d.setUUID(id, uuid.NewString())
+	}
+	want = strings.ToLower(want)
+	if _, err := d.db.Exec(`
+		UPDATE knowledge_uuids SET uuid = ?
+		WHERE uuid = ? AND knowledge_id IN (SELECT id FROM knowledge_base WHERE deleted_at IS NOT NULL)
+	`, uuid.NewString(), want); err != nil {
+		return fmt.Errorf("failed to set uuid: %w", err)
+	}
+	var taken bool
+	if err := d.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM knowledge_uuids WHERE uuid = ?)`, want).Scan(&taken); err != nil {
+		return fmt.Errorf("failed to set uuid: %w", err)
+	}
+	if taken {
+		return d.setUUID(id, uuid.NewString())
+	}
+	return d.setUUID(id, want)
+}
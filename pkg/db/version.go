@@ -0,0 +1,97 @@
+package db
+
+import (
+	"database/sql/driver"
+	"fmt"
+
+	"github.com/ystyle/cangjie-mem/pkg/semver"
+	"github.com/ystyle/cangjie-mem/pkg/types"
+	"modernc.org/sqlite"
+)
+
+// unversioned 未标注版本范围的库级记忆在分类统计中的名称
+const unversioned = "*"
+
+func init() {
+	// semver_satisfies(range, version)：版本是否在范围内（范围为空时视为适用于所有版本）
+	sqlite.MustRegisterDeterministicScalarFunction("semver_satisfies", 2, func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		constraint, _ := args[0].(string)
+		version, _ := args[1].(string)
+		if constraint == "" {
+			return int64(1), nil
+		}
+		ok, err := semver.Satisfies(version, constraint)
+		if err != nil || !ok {
+			return int64(0), nil
+		}
+		return int64(1), nil
+	})
+}
+
+// migrateLibraryVersion 自动迁移：添加 library_version 字段（库版本范围）
+func (d *Database) migrateLibraryVersion() error {
+	var hasColumn bool
+	err := d.db.QueryRow(`
+		SELECT COUNT(*) > 0 FROM pragma_table_info('knowledge_base') WHERE name = 'library_version'
+	`).Scan(&hasColumn)
+	if err != nil {
+		return fmt.Errorf("failed to check library_version column: %w", err)
+	}
+
+	if !hasColumn {
+		if _, err := d.db.Exec(`ALTER TABLE knowledge_base ADD COLUMN library_version TEXT`); err != nil {
+			return fmt.Errorf("failed to add library_version column: %w", err)
+		}
+		fmt.Println("✓ Migrated database: added library_version column")
+	}
+	return nil
+}
+
+// validateLibraryVersion 验证库版本范围（为空表示适用于所有版本）
+func validateLibraryVersion(level types.KnowledgeLevel, constraint string) error {
+	if constraint == "" {
+		return nil
+	}
+	if level != types.LevelLibrary {
+		return fmt.Errorf("invalid library_version: only library level memories can have a version range")
+	}
+	if _, err := semver.ParseRange(constraint); err != nil {
+		return fmt.Errorf("invalid library_version: %w", err)
+	}
+	return nil
+}
+
+// libraryVersionClause 构建库版本过滤条件：库级记忆只保留版本范围包含 version 的，其他层级不受影响
+// version 无法解析时库级记忆中只有未标注版本范围的会保留
+func libraryVersionClause(version string) (string, []interface{}) {
+	if version == "" {
+		return "", nil
+	}
+	return " AND (level != 'library' OR semver_satisfies(library_version, ?))", []interface{}{version}
+}
+
+// listLibraryVersions 统计每个库按版本范围细分的记忆数
+func (d *Database) listLibraryVersions(languageTag string) (map[string][]types.CategoryInfo, error) {
+	rows, err := d.db.Query(`
+		SELECT library_name, COALESCE(NULLIF(library_version, ''), ?) AS version, COUNT(*) as count
+		FROM knowledge_base
+		WHERE level = 'library' AND library_name IS NOT NULL AND library_name != '' AND language_tag = ? AND deleted_at IS NULL
+		GROUP BY library_name, version
+		ORDER BY count DESC, version
+	`, unversioned, languageTag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list library versions: %w", err)
+	}
+	defer rows.Close()
+
+	versions := make(map[string][]types.CategoryInfo)
+	for rows.Next() {
+		var name, version string
+		var count int
+		if err := rows.Scan(&name, &version, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan library version row: %w", err)
+		}
+		versions[name] = append(versions[name], types.CategoryInfo{Name: version, Count: count})
+	}
+	return versions, rows.Err()
+}
@@ -0,0 +1,158 @@
+// Package diagnostic 解析 cjc/cjpm 编译输出并生成用于匹配记忆的诊断签名
+//
+// 签名是去掉标识符、路径和数字后的规范化错误信息，同一类错误在不同代码中得到相同的签名：
+//
+//	error: undeclared identifier 'foo'   →  undeclared identifier <id>
+//	error[E0012]: cannot find 'a/b.cj'   →  E0012: cannot find <id>
+package diagnostic
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// 诊断级别
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// 规范化占位符
+const (
+	placeholderIdent  = "<id>"
+	placeholderPath   = "<path>"
+	placeholderNumber = "<n>"
+)
+
+// Diagnostic 单条编译诊断
+type Diagnostic struct {
+	Severity  string `json:"severity"`       // error / warning
+	Code      string `json:"code,omitempty"` // 错误码（如 error[E0012] 中的 E0012）
+	Message   string `json:"message"`        // 原始错误信息
+	Signature string `json:"signature"`      // 规范化签名
+	File      string `json:"file,omitempty"`
+	Line      int    `json:"line,omitempty"`
+	Column    int    `json:"column,omitempty"`
+}
+
+var (
+	// error: msg / error[E0012]: msg / Error: msg（cjpm）
+	headerPattern = regexp.MustCompile(`(?i)^\s*(error|warning)(?:\[([A-Za-z0-9_.-]+)\])?\s*:\s*(.+?)\s*$`)
+	// file.cj:3:5: error: msg（gcc 风格）
+	inlinePattern = regexp.MustCompile(`(?i)^\s*(.+?):(\d+):(\d+):\s*(error|warning)(?:\[([A-Za-z0-9_.-]+)\])?\s*:\s*(.+?)\s*$`)
+	// ==> file.cj:3:5: / --> file.cj:3:5
+	locationPattern = regexp.MustCompile(`^\s*(?:==>|-->)\s*(.+?):(\d+)(?::(\d+))?:?\s*$`)
+
+	quotedPattern = regexp.MustCompile("'[^']*'|\"[^\"]*\"|`[^`]*`|‘[^’]*’|“[^”]*”")
+	pathPattern   = regexp.MustCompile(`(?:[A-Za-z]:)?(?:[\w.~-]*[/\\])+[\w.-]+|\b[\w-]+\.cj\b`)
+	numberPattern = regexp.MustCompile(`\b\d+(?:\.\d+)*\b`)
+	spacePattern  = regexp.MustCompile(`\s+`)
+)
+
+// Parse 将编译输出解析为诊断列表（忽略 note/help 等附加信息和源码片段）
+func Parse(output string) []Diagnostic {
+	var diags []Diagnostic
+	seen := map[string]bool{}
+	add := func(d Diagnostic) {
+		d.Signature = Signature(d.Code, d.Message)
+		diags = append(diags, d)
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		if m := inlinePattern.FindStringSubmatch(line); m != nil {
+			lineNo, _ := strconv.Atoi(m[2])
+			column, _ := strconv.Atoi(m[3])
+			add(Diagnostic{Severity: strings.ToLower(m[4]), Code: m[5], Message: m[6], File: m[1], Line: lineNo, Column: column})
+			continue
+		}
+		if m := headerPattern.FindStringSubmatch(line); m != nil {
+			add(Diagnostic{Severity: strings.ToLower(m[1]), Code: m[2], Message: m[3]})
+			continue
+		}
+		// 位置行属于上一条诊断
+		if m := locationPattern.FindStringSubmatch(line); m != nil && len(diags) > 0 {
+			last := &diags[len(diags)-1]
+			if last.File == "" {
+				last.File = m[1]
+				last.Line, _ = strconv.Atoi(m[2])
+				last.Column, _ = strconv.Atoi(m[3])
+			}
+		}
+	}
+
+	// 去掉同一位置的重复诊断
+	result := diags[:0]
+	for _, d := range diags {
+		key := d.Signature + "\x00" + d.File + "\x00" + strconv.Itoa(d.Line) + ":" + strconv.Itoa(d.Column)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, d)
+	}
+	return result
+}
+
+// Normalize 规范化错误信息：引号中的标识符、路径和数字替换为占位符，统一小写并合并空白
+func Normalize(message string) string {
+	s := quotedPattern.ReplaceAllString(message, placeholderIdent)
+	s = pathPattern.ReplaceAllString(s, placeholderPath)
+	s = numberPattern.ReplaceAllString(s, placeholderNumber)
+	s = strings.ToLower(spacePattern.ReplaceAllString(s, " "))
+	return strings.Trim(s, " .:;,")
+}
+
+// Signature 由错误码和错误信息生成签名（有错误码时以错误码开头）
+func Signature(code, message string) string {
+	normalized := Normalize(message)
+	if code == "" {
+		return normalized
+	}
+	return code + ": " + normalized
+}
+
+// ParseSignature 将用户提供的诊断文本转换为签名：
+// 可以是完整的编译输出（取第一条诊断）、error: 开头的单行，或仅错误信息本身
+func ParseSignature(text string) string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return ""
+	}
+	if diags := Parse(text); len(diags) > 0 {
+		return diags[0].Signature
+	}
+	return Signature("", text)
+}
+
+// CodePrefix 返回签名中的错误码前缀（如 "E0012: "），没有错误码时返回空字符串
+func CodePrefix(code string) string {
+	if code == "" {
+		return ""
+	}
+	return code + ": "
+}
+
+// Keywords 提取签名中的检索词（去掉占位符和标点），用于签名未命中时的全文检索
+func Keywords(signature string) []string {
+	for _, p := range []string{placeholderIdent, placeholderPath, placeholderNumber} {
+		signature = strings.ReplaceAll(signature, p, " ")
+	}
+	fields := strings.FieldsFunc(signature, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+
+	var words []string
+	seen := map[string]bool{}
+	for _, f := range fields {
+		if len([]rune(f)) < 2 || seen[f] {
+			continue
+		}
+		seen[f] = true
+		words = append(words, f)
+	}
+	return words
+}
@@ -0,0 +1,77 @@
+package diagnostic
+
+import (
+	"reflect"
+	"testing"
+)
+
+const sampleOutput = `error: undeclared identifier 'fooBar'
+ ==> /home/user/demo/src/main.cj:12:9:
+   |
+12 |     fooBar(1)
+   |     ^^^^^^
+   |
+note: did you mean 'foobar'?
+
+warning[W0003]: unused variable: 'count'
+ --> src/util.cj:3:9
+src/lib/http.cj:40:5: error: mismatched types 'Int64' and 'String'
+error: undeclared identifier 'fooBar'
+ ==> /home/user/demo/src/main.cj:12:9:
+Error: failed to compile package 'demo', return code is 1
+`
+
+func TestParse(t *testing.T) {
+	got := Parse(sampleOutput)
+	want := []Diagnostic{
+		{Severity: SeverityError, Message: "undeclared identifier 'fooBar'", Signature: "undeclared identifier <id>", File: "/home/user/demo/src/main.cj", Line: 12, Column: 9},
+		{Severity: SeverityWarning, Code: "W0003", Message: "unused variable: 'count'", Signature: "W0003: unused variable: <id>", File: "src/util.cj", Line: 3, Column: 9},
+		{Severity: SeverityError, Message: "mismatched types 'Int64' and 'String'", Signature: "mismatched types <id> and <id>", File: "src/lib/http.cj", Line: 40, Column: 5},
+		{Severity: SeverityError, Message: "failed to compile package 'demo', return code is 1", Signature: "failed to compile package <id>, return code is <n>"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"undeclared identifier 'x'", "undeclared identifier <id>"},
+		{"Undeclared  identifier `Foo`.", "undeclared identifier <id>"},
+		{"cannot open file /tmp/a/b.cj", "cannot open file <path>"},
+		{"cannot open file main.cj at line 3", "cannot open file <path> at line <n>"},
+		{"expected 2 arguments, found 3", "expected <n> arguments, found <n>"},
+		{"类型 “Int64” 不匹配", "类型 <id> 不匹配"},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.in); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseSignature(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"error: undeclared identifier 'foo'", "undeclared identifier <id>"},
+		{"error[E0012]: cannot find 'a/b.cj'", "E0012: cannot find <id>"},
+		{"undeclared identifier 'bar'", "undeclared identifier <id>"},
+		{"  ", ""},
+	}
+	for _, tt := range tests {
+		if got := ParseSignature(tt.in); got != tt.want {
+			t.Errorf("ParseSignature(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestKeywords(t *testing.T) {
+	got := Keywords("E0012: mismatched types <id> and <id>, found <n>")
+	want := []string{"E0012", "mismatched", "types", "and", "found"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Keywords() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,158 @@
+// Package diff 提供基于行的统一格式（unified）文本差异
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultContext 统一格式差异默认的上下文行数
+const DefaultContext = 3
+
+// opKind 编辑操作类型
+type opKind byte
+
+const (
+	opEqual  opKind = ' '
+	opDelete opKind = '-'
+	opInsert opKind = '+'
+)
+
+// op 单行编辑操作
+type op struct {
+	kind opKind
+	text string
+	a, b int // 该行在旧/新文本中的行号（从 0 开始）
+}
+
+// Unified 生成 a 到 b 的统一格式差异，文本相同时返回空字符串
+// context 为每个变更块前后保留的上下文行数，小于 0 时使用 DefaultContext
+func Unified(fromName, toName, a, b string, context int) string {
+	if a == b {
+		return ""
+	}
+	if context < 0 {
+		context = DefaultContext
+	}
+
+	ops := lineOps(splitLines(a), splitLines(b))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+	for _, h := range hunks(ops, context) {
+		writeHunk(&sb, h)
+	}
+	return sb.String()
+}
+
+// splitLines 按行切分文本（保留空文本为零行）
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// lineOps 基于最长公共子序列计算逐行编辑操作
+func lineOps(a, b []string) []op {
+	n, m := len(a), len(b)
+
+	// lcs[i][j] 为 a[i:] 与 b[j:] 的最长公共子序列长度
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	ops := make([]op, 0, n+m)
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, op{opEqual, a[i], i, j})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, op{opDelete, a[i], i, j})
+			i++
+		default:
+			ops = append(ops, op{opInsert, b[j], i, j})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, op{opDelete, a[i], i, j})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, op{opInsert, b[j], i, j})
+	}
+	return ops
+}
+
+// hunks 将编辑操作分组为带上下文的变更块
+func hunks(ops []op, context int) [][]op {
+	var result [][]op
+	start, end := -1, -1
+
+	for idx, o := range ops {
+		if o.kind == opEqual {
+			continue
+		}
+		lo := max(idx-context, 0)
+		hi := min(idx+context+1, len(ops))
+		if start >= 0 && lo <= end {
+			end = hi
+			continue
+		}
+		if start >= 0 {
+			result = append(result, ops[start:end])
+		}
+		start, end = lo, hi
+	}
+	if start >= 0 {
+		result = append(result, ops[start:end])
+	}
+	return result
+}
+
+// writeHunk 输出单个变更块
+func writeHunk(sb *strings.Builder, h []op) {
+	aStart, bStart := h[0].a, h[0].b
+	aLen, bLen := 0, 0
+	for _, o := range h {
+		if o.kind != opInsert {
+			aLen++
+		}
+		if o.kind != opDelete {
+			bLen++
+		}
+	}
+
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(aStart, aLen), hunkRange(bStart, bLen))
+	for _, o := range h {
+		sb.WriteByte(byte(o.kind))
+		sb.WriteString(o.text)
+		sb.WriteByte('\n')
+	}
+}
+
+// hunkRange 格式化变更块范围（行号从 1 开始，空范围指向前一行）
+func hunkRange(start, length int) string {
+	if length == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
@@ -0,0 +1,47 @@
+package diff
+
+import "testing"
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		context int
+		want    string
+	}{
+		{"相同文本", "a\nb\n", "a\nb\n", 3, ""},
+		{
+			"修改一行",
+			"a\nb\nc\n", "a\nB\nc\n", 3,
+			"--- old\n+++ new\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			"新增到空文本",
+			"", "x\ny", 3,
+			"--- old\n+++ new\n@@ -0,0 +1,2 @@\n+x\n+y\n",
+		},
+		{
+			"删除全部",
+			"x\n", "", 3,
+			"--- old\n+++ new\n@@ -1 +0,0 @@\n-x\n",
+		},
+		{
+			"相距较远的修改拆分为两个块",
+			"1\n2\n3\n4\n5\n6\n7\n8\n", "0\n2\n3\n4\n5\n6\n7\n9\n", 1,
+			"--- old\n+++ new\n@@ -1,2 +1,2 @@\n-1\n+0\n 2\n@@ -7,2 +7,2 @@\n 7\n-8\n+9\n",
+		},
+		{
+			"相近的修改合并为一个块",
+			"1\n2\n3\n", "0\n2\n4\n", 1,
+			"--- old\n+++ new\n@@ -1,3 +1,3 @@\n-1\n+0\n 2\n-3\n+4\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified("old", "new", tt.a, tt.b, tt.context); got != tt.want {
+				t.Errorf("Unified() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+// Package embed 提供文本向量化（Embedding）能力，用于语义检索
+package embed
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/ystyle/cangjie-mem/pkg/segment"
+)
+
+// Embedder 文本向量化接口
+// 实现需要保证同一 Name() 下相同文本得到相同的向量
+type Embedder interface {
+	// Name 模型标识，模型或维度变化时必须不同（用于判断已存向量是否需要重新生成）
+	Name() string
+	// Dimensions 向量维度
+	Dimensions() int
+	// Embed 将文本转换为 L2 归一化的向量
+	Embed(text string) ([]float32, error)
+}
+
+// DefaultDimensions 内置哈希向量的默认维度
+const DefaultDimensions = 512
+
+// HashEmbedder 基于哈希 n-gram 的离线向量化实现（无需模型文件）
+// 英文按单词及其字符三元组提取特征，CJK 按单字和相邻二字提取特征，
+// 再通过特征哈希映射到固定维度，适合对措辞不同但用字相近的问题做语义召回
+type HashEmbedder struct {
+	dims int
+}
+
+// NewHashEmbedder 创建哈希向量化器（dims <= 0 时使用默认维度）
+func NewHashEmbedder(dims int) *HashEmbedder {
+	if dims <= 0 {
+		dims = DefaultDimensions
+	}
+	return &HashEmbedder{dims: dims}
+}
+
+// Name 模型标识
+func (e *HashEmbedder) Name() string {
+	return fmt.Sprintf("hash-ngram-%d", e.dims)
+}
+
+// Dimensions 向量维度
+func (e *HashEmbedder) Dimensions() int {
+	return e.dims
+}
+
+// Embed 将文本转换为向量
+func (e *HashEmbedder) Embed(text string) ([]float32, error) {
+	vec := make([]float32, e.dims)
+	for _, feature := range features(text) {
+		h := fnv.New64a()
+		h.Write([]byte(feature.text))
+		sum := h.Sum64()
+		// 低位决定维度，高位决定符号，减少哈希冲突带来的偏差
+		idx := int(sum % uint64(e.dims))
+		if sum>>63 == 1 {
+			vec[idx] -= feature.weight
+		} else {
+			vec[idx] += feature.weight
+		}
+	}
+	normalize(vec)
+	return vec, nil
+}
+
+// feature 文本特征
+type feature struct {
+	text   string
+	weight float32
+}
+
+// features 提取文本特征
+func features(text string) []feature {
+	var result []feature
+	for _, word := range strings.Fields(segment.Text(strings.ToLower(text))) {
+		word = strings.TrimFunc(word, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+		})
+		if word == "" {
+			continue
+		}
+
+		runes := []rune(word)
+		if len(runes) == 1 && segment.IsCJK(runes[0]) {
+			// CJK 单字
+			result = append(result, feature{text: word, weight: 1})
+			continue
+		}
+
+		// 整词特征权重更高，字符三元组用于匹配词形变化（define/definition）
+		result = append(result, feature{text: "w:" + word, weight: 2})
+		padded := []rune("^" + word + "$")
+		for i := 0; i+3 <= len(padded); i++ {
+			result = append(result, feature{text: "g:" + string(padded[i:i+3]), weight: 0.5})
+		}
+	}
+
+	// CJK 相邻二字特征（跨越切分后的单字）
+	var prev rune
+	for _, r := range text {
+		if segment.IsCJK(r) {
+			if prev != 0 {
+				result = append(result, feature{text: "b:" + string([]rune{prev, r}), weight: 1.5})
+			}
+			prev = r
+		} else {
+			prev = 0
+		}
+	}
+
+	return result
+}
+
+// normalize L2 归一化
+func normalize(vec []float32) {
+	var sum float64
+	for _, v := range vec {
+		sum += float64(v) * float64(v)
+	}
+	if sum == 0 {
+		return
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range vec {
+		vec[i] /= norm
+	}
+}
+
+// Cosine 计算两个向量的余弦相似度
+func Cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// Encode 将向量编码为 little-endian float32 字节序列（与 sqlite-vec 的 BLOB 格式一致）
+func Encode(vec []float32) []byte {
+	buf := make([]byte, 4*len(vec))
+	for i, v := range vec {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(v))
+	}
+	return buf
+}
+
+// Decode 将字节序列解码为向量
+func Decode(buf []byte) ([]float32, error) {
+	if len(buf)%4 != 0 {
+		return nil, fmt.Errorf("invalid vector length: %d", len(buf))
+	}
+	vec := make([]float32, len(buf)/4)
+	for i := range vec {
+		vec[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[i*4:]))
+	}
+	return vec, nil
+}
@@ -0,0 +1,59 @@
+package embed
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHashEmbedder(t *testing.T) {
+	e := NewHashEmbedder(0)
+	if e.Dimensions() != DefaultDimensions {
+		t.Errorf("Dimensions() = %v, want %v", e.Dimensions(), DefaultDimensions)
+	}
+
+	embed := func(text string) []float32 {
+		t.Helper()
+		vec, err := e.Embed(text)
+		if err != nil {
+			t.Fatalf("Embed(%q) error = %v", text, err)
+		}
+		return vec
+	}
+
+	// 向量已归一化
+	vec := embed("如何定义接口")
+	if norm := Cosine(vec, vec); math.Abs(norm-1) > 1e-6 {
+		t.Errorf("Cosine(v, v) = %v, want 1", norm)
+	}
+
+	// 措辞不同但语义相近的文本相似度应高于无关文本
+	query := embed("怎么定义一个接口")
+	related := embed("接口定义：使用 interface 关键字定义接口")
+	unrelated := embed("项目日志配置位于 config/log.toml")
+	if Cosine(query, related) <= Cosine(query, unrelated) {
+		t.Errorf("related similarity %v should be greater than unrelated %v",
+			Cosine(query, related), Cosine(query, unrelated))
+	}
+
+	// 英文词形变化
+	if Cosine(embed("define function"), embed("function definition")) <= Cosine(embed("define function"), embed("http client")) {
+		t.Error("morphological variants should be closer than unrelated words")
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	vec := []float32{0.5, -1, 0, 3.25}
+	decoded, err := Decode(Encode(vec))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	for i := range vec {
+		if decoded[i] != vec[i] {
+			t.Errorf("decoded[%d] = %v, want %v", i, decoded[i], vec[i])
+		}
+	}
+
+	if _, err := Decode([]byte{1, 2, 3}); err == nil {
+		t.Error("Decode() should fail with invalid length")
+	}
+}
@@ -0,0 +1,125 @@
+// Package markdown 按标题把 Markdown 文档切分为章节
+//
+// 只识别 ATX 标题（# 开头），代码块中的 # 不会被当作标题；
+// 比切分深度更深的标题保留在所属章节的正文中
+package markdown
+
+import (
+	"regexp"
+	"strings"
+)
+
+// DefaultDepth 默认切分深度（按一、二、三级标题切分）
+const DefaultDepth = 3
+
+// MaxDepth Markdown 标题的最大级别
+const MaxDepth = 6
+
+var (
+	headingPattern = regexp.MustCompile(`^(#{1,6})[ \t]+(.+?)[ \t]*#*[ \t]*$`)
+	fencePattern   = regexp.MustCompile("^[ \t]*(```|~~~)")
+)
+
+// Section 文档章节
+type Section struct {
+	Headings []string // 从顶层到本章节的标题路径
+	Level    int      // 本章节标题级别（标题前的内容为 0）
+	Body     string   // 正文（不含标题行，保留代码块）
+	Line     int      // 标题所在行号（从 1 开始）
+}
+
+// Title 章节标题路径（如 "Tang > 路由 > 中间件"）
+func (s Section) Title() string {
+	return strings.Join(s.Headings, " > ")
+}
+
+// Heading 本章节的标题
+func (s Section) Heading() string {
+	if len(s.Headings) == 0 {
+		return ""
+	}
+	return s.Headings[len(s.Headings)-1]
+}
+
+// FirstParagraph 正文的第一个段落（跳过代码块、表格和引用块，多行合并为一行）
+func (s Section) FirstParagraph() string {
+	var para []string
+	inFence := false
+	for _, line := range strings.Split(s.Body, "\n") {
+		if fencePattern.MatchString(line) {
+			inFence = !inFence
+			if len(para) > 0 {
+				break
+			}
+			continue
+		}
+		if inFence {
+			continue
+		}
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || headingPattern.MatchString(trimmed) {
+			if len(para) > 0 {
+				break
+			}
+			continue
+		}
+		if len(para) == 0 && (strings.HasPrefix(trimmed, "|") || strings.HasPrefix(trimmed, ">") || strings.HasPrefix(trimmed, "<")) {
+			continue
+		}
+		para = append(para, trimmed)
+	}
+	return strings.Join(para, " ")
+}
+
+// Split 按不超过 depth 级的标题切分文档（depth 超出 1~6 时使用默认深度）
+// 正文为空的章节（如紧跟着子标题的父标题）不返回，但其标题仍出现在子章节的标题路径中
+func Split(source string, depth int) []Section {
+	if depth < 1 || depth > MaxDepth {
+		depth = DefaultDepth
+	}
+
+	var sections []Section
+	var path []string
+	var levels []int
+	current := Section{}
+	var body []string
+	inFence := false
+
+	flush := func() {
+		current.Body = strings.Trim(strings.Join(body, "\n"), "\n")
+		if strings.TrimSpace(current.Body) != "" {
+			sections = append(sections, current)
+		}
+		body = nil
+	}
+
+	lines := strings.Split(strings.ReplaceAll(source, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		if fencePattern.MatchString(line) {
+			inFence = !inFence
+		}
+		m := headingPattern.FindStringSubmatch(line)
+		if inFence || m == nil || len(m[1]) > depth {
+			body = append(body, line)
+			continue
+		}
+
+		flush()
+		level := len(m[1])
+		// 弹出同级及更深的标题
+		for len(levels) > 0 && levels[len(levels)-1] >= level {
+			levels = levels[:len(levels)-1]
+			path = path[:len(path)-1]
+		}
+		levels = append(levels, level)
+		path = append(path, strings.TrimSpace(m[2]))
+		current = Section{
+			Headings: append([]string(nil), path...),
+			Level:    level,
+			Line:     i + 1,
+		}
+	}
+	flush()
+
+	return sections
+}
@@ -0,0 +1,73 @@
+package markdown
+
+import (
+	"reflect"
+	"testing"
+)
+
+const sampleDoc = `简介前言
+
+# Tang
+
+轻量级 Web 框架，
+支持中间件。
+
+## 安装
+
+` + "```toml" + `
+# 这不是标题
+[dependencies]
+` + "```" + `
+
+## 路由
+
+### 分组
+
+| 方法 | 说明 |
+使用 group 注册分组路由。
+
+#### 细节
+
+四级标题保留在正文中。
+
+## 空章节 ##
+`
+
+func TestSplit(t *testing.T) {
+	sections := Split(sampleDoc, 3)
+
+	var titles []string
+	for _, s := range sections {
+		titles = append(titles, s.Title())
+	}
+	want := []string{"", "Tang", "Tang > 安装", "Tang > 路由 > 分组"}
+	if !reflect.DeepEqual(titles, want) {
+		t.Fatalf("Split() titles = %q, want %q", titles, want)
+	}
+
+	install := sections[2]
+	if install.Level != 2 || install.Line != 8 || install.Heading() != "安装" {
+		t.Errorf("install section = %+v", install)
+	}
+	if install.Body != "```toml\n# 这不是标题\n[dependencies]\n```" {
+		t.Errorf("install body = %q", install.Body)
+	}
+	if got := sections[1].FirstParagraph(); got != "轻量级 Web 框架， 支持中间件。" {
+		t.Errorf("FirstParagraph() = %q", got)
+	}
+	if got := sections[3].FirstParagraph(); got != "使用 group 注册分组路由。" {
+		t.Errorf("FirstParagraph() skipping table = %q", got)
+	}
+	if got := install.FirstParagraph(); got != "" {
+		t.Errorf("FirstParagraph() of code-only section = %q", got)
+	}
+
+	// 只按一级标题切分
+	if got := Split(sampleDoc, 1); len(got) != 2 || got[1].Title() != "Tang" {
+		t.Errorf("Split(depth=1) = %+v", got)
+	}
+	// 非法深度使用默认深度
+	if got := Split(sampleDoc, 0); len(got) != len(sections) {
+		t.Errorf("Split(depth=0) returned %d sections, want %d", len(got), len(sections))
+	}
+}
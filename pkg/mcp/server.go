@@ -4,20 +4,27 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/ystyle/cangjie-mem/internal/store"
 	"github.com/ystyle/cangjie-mem/pkg/db"
+	"github.com/ystyle/cangjie-mem/pkg/embed"
+	"github.com/ystyle/cangjie-mem/pkg/signing"
 	"github.com/ystyle/cangjie-mem/pkg/types"
 )
 
 // Server MCP 服务器
 type Server struct {
-	server     *server.MCPServer
-	store      *store.Store
-	httpToken  string // HTTP 认证 Token
+	server    *server.MCPServer
+	store     *store.Store
+	httpToken string         // HTTP 认证 Token
+	stop      chan struct{}  // 关闭后台任务
+	wg        sync.WaitGroup // 等待后台任务退出
 }
 
 // Config 服务器配置
@@ -29,6 +36,26 @@ type Config struct {
 	HTTPEndpoint  string // HTTP 端点路径（默认 "/mcp"）
 	HTTPStateless bool   // HTTP 无状态模式（默认 false）
 	HTTPToken     string // HTTP 认证 Token（空字符串表示不启用认证）
+
+	// 回收站保留时长，超过后自动彻底删除（0 表示永久保留）
+	TrashRetention time.Duration
+
+	// 检索置信度权重（为空时使用默认权重）
+	ScoringWeights *store.ScoringWeights
+
+	// 读取 project_context 下 cjpm.toml 的依赖来限定库级检索范围（会读取客户端指定的服务器路径）
+	ProjectDependencies bool
+
+	// 知识包仓库（目录或 URL），REST API 只能从这些仓库安装、升级知识包
+	Registries []string
+
+	// 源码根目录，REST API 只能导入这些目录下的库源码和符号
+	SourceRoots []string
+
+	// 受信任的签名公钥目录（每个 .pub 文件一个公钥）
+	KeyringDir string
+	// 导入知识包时要求密钥环中公钥的有效签名
+	RequireSignature bool
 }
 
 // New 创建新的 MCP 服务器
@@ -42,6 +69,29 @@ func New(cfg Config) (*Server, error) {
 
 	// 创建 Store
 	st := store.New(database)
+	st.SetEmbedder(embed.NewHashEmbedder(embed.DefaultDimensions))
+	if cfg.ScoringWeights != nil {
+		if err := st.SetScoringWeights(*cfg.ScoringWeights); err != nil {
+			database.Close()
+			return nil, fmt.Errorf("invalid scoring weights: %w", err)
+		}
+	}
+	st.SetProjectDependencies(cfg.ProjectDependencies)
+	st.SetRegistries(cfg.Registries)
+	st.SetSourceRoots(cfg.SourceRoots)
+	if cfg.RequireSignature && cfg.KeyringDir == "" {
+		database.Close()
+		return nil, fmt.Errorf("a keyring is required when signatures are required")
+	}
+	if cfg.KeyringDir != "" {
+		keyring, err := signing.LoadKeyring(cfg.KeyringDir)
+		if err != nil {
+			database.Close()
+			return nil, err
+		}
+		st.SetKeyring(keyring, cfg.RequireSignature)
+		log.Printf("✓ 已加载签名密钥环: %s（%d 个公钥）", cfg.KeyringDir, len(keyring.Names()))
+	}
 
 	// 创建 MCP 服务器
 	mcpServer := server.NewMCPServer(
@@ -54,6 +104,17 @@ func New(cfg Config) (*Server, error) {
 		server:    mcpServer,
 		store:     st,
 		httpToken: cfg.HTTPToken,
+		stop:      make(chan struct{}),
+	}
+
+	// 后台为已有记忆回填向量（混合检索使用）
+	s.wg.Add(1)
+	go s.backfillEmbeddings()
+
+	// 定期清理回收站中过期的记忆
+	if cfg.TrashRetention > 0 {
+		s.wg.Add(1)
+		go s.purgeTrashLoop(cfg.TrashRetention)
 	}
 
 	// 注册工具
@@ -81,6 +142,9 @@ func (s *Server) registerTools() {
 		mcp.WithString("library_name",
 			mcp.Description("库名（可选，用于第三方库知识管理，如：tang、http-client）"),
 		),
+		mcp.WithString("library_version",
+			mcp.Description("适用的库版本范围（可选，仅 library 层级。如：^1.2、>=1.0 <2.0、1.x。不传表示适用于所有版本）"),
+		),
 		mcp.WithString("project_path_pattern",
 			mcp.Description("项目路径模式（project 层级必需，如：/path/to/project/*）"),
 		),
@@ -99,6 +163,13 @@ func (s *Server) registerTools() {
 			mcp.Description("来源（manual 手动记录 或 auto_captured AI 捕获，默认 manual）"),
 			mcp.Enum("manual", "auto_captured"),
 		),
+		mcp.WithArray("tags",
+			mcp.Description("标签（可选，如：[\"语法\", \"接口\"]）"),
+			mcp.WithStringItems(),
+		),
+		mcp.WithString("diagnostic_signature",
+			mcp.Description("这条记忆修复的编译错误（可选，直接粘贴错误信息，如：error: undeclared identifier 'foo'。会自动规范化并添加 diagnostic 标签，供 cangjie_mem_diagnose 匹配）"),
+		),
 	)
 	s.server.AddTool(storeTool, s.handleStoreMemory)
 
@@ -106,25 +177,22 @@ func (s *Server) registerTools() {
 	recallTool := mcp.NewTool("cangjie_mem_recall",
 		mcp.WithDescription("智能回忆仓颉语言实践经验（基于关键词全文搜索）。\n\n"+
 			"📌 搜索模式：使用**空格分隔的 AND 匹配**模式\n"+
-			"- 多个关键词必须**同时出现**才会匹配\n"+
-			"- 关键词越多越精准，但过多关键词（如同义词堆砌）可能导致返回空结果\n"+
-			"- **推荐使用 1-2 个核心关键词**进行查询\n\n"+
+			"- 多个关键词优先要求**同时出现**\n"+
+			"- 没有结果时自动放宽：任一关键词匹配 → 前缀匹配 → 只用最具区分度的一个词，search_strategy 会注明使用了哪一步（如 _relaxed_or）\n\n"+
 			"✅ 查询示例：\n"+
 			"- 「interface」→ 匹配包含接口的记忆\n"+
 			"- 「var 声明」→ 匹配包含变量和声明的记忆\n"+
 			"- 「log 配置」→ 匹配包含日志和配置的记忆\n\n"+
-			"❌ 不推荐示例：\n"+
-			"- 「interface 接口 定义 实现」→ 关键词过多，可能匹配不到\n"+
-			"- 「http 请求 get post」→ 同义词堆砌，容易返回空\n\n"+
 			"🎯 使用场景：\n"+
 			"1. 查询仓颉语法/关键字 → 不传 level，搜索全部层级\n"+
 			"2. 查询项目特定配置 → 传 project_context 或 level=project\n"+
-			"3. 查询特定库的知识 → 传 library_name\n"+
+			"3. 查询特定库的知识 → 传 library_name（知道库版本时同时传 library_version，避免拿到过时用法）\n"+
 			"4. 通用设计模式/最佳实践 → 不传 level，自动搜索全部层级\n\n"+
 			"💡 提示：通常只需传 query，搜索结果会按 language→library→project 优先级排序！"),
 		mcp.WithString("query",
 			mcp.Required(),
-			mcp.Description("查询内容（1-2个核心关键词，用空格分隔。如：interface、var 声明、http 请求。避免使用同义词堆砌）"),
+			mcp.Description("查询内容（核心关键词，用空格分隔，词之间为 AND。如：interface、var 声明、http 请求）。"+
+				"支持语法：a OR b（任一匹配）、\"精确短语\"、前缀*、-排除词、title:词（仅标题）、library:库名、level:language|project|library、tag:标签"),
 		),
 		mcp.WithString("level",
 			mcp.Description("记忆层级（可选。不传时搜索全部三级：language/project/library。传了则只搜该层级）"),
@@ -136,14 +204,39 @@ func (s *Server) registerTools() {
 		mcp.WithString("library_name",
 			mcp.Description("库名筛选（可选。如：tang、http-client）"),
 		),
+		mcp.WithString("library_version",
+			mcp.Description("当前使用的库版本（可选。如：1.2.3。传了则库级记忆只返回版本范围包含该版本的，未标注版本的记忆始终返回）"),
+		),
+		mcp.WithArray("tags",
+			mcp.Description("标签筛选（可选。记忆需同时包含所有标签）"),
+			mcp.WithStringItems(),
+		),
 		mcp.WithString("project_context",
-			mcp.Description("项目路径（可选。传了会优先匹配该项目相关的记忆）"),
+			mcp.Description("当前项目路径（可选。传了会同时返回语言级、库级记忆和路径模式匹配该项目的项目级记忆，并优先排序该项目的记忆；其他项目的记忆会被排除。"+
+				"服务器能访问该目录下的 cjpm.toml 时，库级记忆只返回 [dependencies] 中声明的库，search_strategy 会注明如 _cjpm_deps(tang)）"),
 		),
 		mcp.WithNumber("max_results",
 			mcp.Description("最大返回数量（默认 10）"),
 		),
 		mcp.WithNumber("min_confidence",
-			mcp.Description("最小置信度阈值（默认 0.5）"),
+			mcp.Description("最小置信度阈值（默认 0.05。置信度由 BM25 相关度、层级、来源、访问热度和项目匹配加权得出）"),
+		),
+		mcp.WithBoolean("explain",
+			mcp.Description("是否返回每条结果的置信度明细（各因子取值、权重和贡献，默认 false）"),
+		),
+		mcp.WithBoolean("include_related",
+			mcp.Description("是否附带每条结果的一跳关联记忆（依赖、参见、取代、矛盾，默认 false）"),
+		),
+		mcp.WithString("highlight",
+			mcp.Description("匹配片段的高亮标记（可选）：markdown 使用 **词**（默认）；html 使用 <mark>词</mark>；none 不加标记"),
+			mcp.Enum("markdown", "html", "none"),
+		),
+		mcp.WithNumber("snippet_fragments",
+			mcp.Description("每条结果最多返回的匹配片段数（默认 3，最多 10）"),
+		),
+		mcp.WithString("mode",
+			mcp.Description("检索模式（可选）：keyword 仅关键词匹配（默认）；hybrid 融合关键词与语义向量，适合换种说法提问时使用"),
+			mcp.Enum("keyword", "hybrid"),
 		),
 	)
 	s.server.AddTool(recallTool, s.handleRecallMemories)
@@ -166,12 +259,19 @@ func (s *Server) registerTools() {
 		mcp.WithString("library_name",
 			mcp.Description("库名筛选（仅对 library 层级有效，如：tang）"),
 		),
+		mcp.WithString("library_version",
+			mcp.Description("库版本筛选（如：1.2.3。库级记忆只返回版本范围包含该版本的，未标注版本的记忆始终返回）"),
+		),
 		mcp.WithString("project_path_pattern",
 			mcp.Description("项目路径模式筛选（如：/path/to/project/*）"),
 		),
 		mcp.WithString("language_tag",
 			mcp.Description("语言标签（默认 cangjie）"),
 		),
+		mcp.WithArray("tags",
+			mcp.Description("标签筛选（可选。记忆需同时包含所有标签）"),
+			mcp.WithStringItems(),
+		),
 		mcp.WithNumber("limit",
 			mcp.Description("返回数量（默认 20）"),
 		),
@@ -190,12 +290,14 @@ func (s *Server) registerTools() {
 
 	// 工具 4: cangjie_mem_list_categories
 	categoriesTool := mcp.NewTool("cangjie_mem_list_categories",
-		mcp.WithDescription("列出所有的库和项目分类（仅返回名称和统计，不包含具体记忆）。\n\n"+
+		mcp.WithDescription("列出所有的库、项目和标签分类（仅返回名称和统计，不包含具体记忆）。\n\n"+
 			"✅ 使用场景：\n"+
 			"- 查看都记录了哪些第三方库及其知识点数量\n"+
 			"- 查看都有哪些项目及其记忆数量\n"+
+			"- 查看都有哪些标签及其记忆数量\n"+
 			"- 快速浏览知识库的整体结构\n\n"+
-			"💡 提示：返回格式如 {\"libraries\": [{\"name\": \"tang\", \"count\": 12}], \"projects\": [...]}"),
+			"💡 提示：返回格式如 {\"libraries\": [{\"name\": \"tang\", \"count\": 12, \"versions\": [{\"name\": \"^1.0\", \"count\": 8}, {\"name\": \"*\", \"count\": 4}]}], \"projects\": [...], \"tags\": [...]}。"+
+			"versions 为按版本范围细分的数量，* 表示未标注版本"),
 		mcp.WithString("language_tag",
 			mcp.Description("语言标签（默认 cangjie）"),
 		),
@@ -207,15 +309,240 @@ func (s *Server) registerTools() {
 		mcp.WithDescription("删除指定 ID 的记忆。\n\n"+
 			"✅ 使用场景：\n"+
 			"- 删除错误的记忆\n"+
-			"- 配合 cangjie_mem_list 实现\"更新\"效果（先删除旧记忆，再插入新记忆）\n"+
 			"- 提炼项目记忆为库级记忆后，删除原始项目记忆\n\n"+
-			"⚠️ 注意：删除操作不可逆，请谨慎使用！"),
+			"💡 提示：删除的记忆会移入回收站，可在回收站中恢复，超过保留期限后自动彻底删除。如需修改记忆请使用 cangjie_mem_update"),
 		mcp.WithNumber("id",
 			mcp.Required(),
 			mcp.Description("记忆 ID（必需）"),
 		),
 	)
 	s.server.AddTool(deleteTool, s.handleDeleteMemory)
+
+	// 工具 6: cangjie_mem_update
+	updateTool := mcp.NewTool("cangjie_mem_update",
+		mcp.WithDescription("更新指定 ID 的记忆（部分更新，只修改传入的字段）。\n\n"+
+			"✅ 使用场景：\n"+
+			"- 修正记忆中错误或过时的内容\n"+
+			"- 补充摘要、调整标题\n"+
+			"- 将项目级记忆提炼为库级记忆（修改 level 和 library_name）\n\n"+
+			"💡 提示：未传入的字段保持不变，ID、访问次数和创建时间都会保留。可先用 cangjie_mem_list 或 cangjie_mem_recall 查到 ID"),
+		mcp.WithNumber("id",
+			mcp.Required(),
+			mcp.Description("记忆 ID（必需）"),
+		),
+		mcp.WithString("level",
+			mcp.Description("记忆层级（可选：language/project/library）"),
+			mcp.Enum("language", "project", "library"),
+		),
+		mcp.WithString("language_tag",
+			mcp.Description("语言标签（可选）"),
+		),
+		mcp.WithString("library_name",
+			mcp.Description("库名（可选）"),
+		),
+		mcp.WithString("library_version",
+			mcp.Description("适用的库版本范围（可选，如：^1.2。传空字符串表示适用于所有版本）"),
+		),
+		mcp.WithString("project_path_pattern",
+			mcp.Description("项目路径模式（可选，project 层级不能为空）"),
+		),
+		mcp.WithString("title",
+			mcp.Description("记忆标题（可选）"),
+		),
+		mcp.WithString("content",
+			mcp.Description("记忆内容（可选）"),
+		),
+		mcp.WithString("summary",
+			mcp.Description("简短摘要（可选）"),
+		),
+		mcp.WithString("source",
+			mcp.Description("来源（可选：manual/auto_captured）"),
+			mcp.Enum("manual", "auto_captured"),
+		),
+		mcp.WithArray("tags",
+			mcp.Description("标签（可选。传入时整体替换原有标签，传空数组清空标签）"),
+			mcp.WithStringItems(),
+		),
+		mcp.WithString("diagnostic_signature",
+			mcp.Description("这条记忆修复的编译错误（可选，会自动规范化并添加 diagnostic 标签）"),
+		),
+	)
+	s.server.AddTool(updateTool, s.handleUpdateMemory)
+
+	// 工具 7: cangjie_mem_suggest
+	suggestTool := mcp.NewTool("cangjie_mem_suggest",
+		mcp.WithDescription("当记忆库中找不到相关内容时，建议补充缺失的知识。\n\n"+
+			"✅ 使用场景：\n"+
+			"- cangjie_mem_recall 没有返回有用结果，但你通过文档、源码或调试弄清楚了答案\n"+
+			"- 发现某个知识点值得记录，但不确定是否足够权威直接存储\n\n"+
+			"💡 提示：建议会以 pending_review 状态保存，经人工审核批准后才会加入记忆库"),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("未找到答案的原始查询（如：如何实现泛型约束？）"),
+		),
+		mcp.WithString("suggested_title",
+			mcp.Required(),
+			mcp.Description("建议的记忆标题"),
+		),
+		mcp.WithString("suggested_content",
+			mcp.Required(),
+			mcp.Description("建议的记忆内容"),
+		),
+		mcp.WithString("suggested_level",
+			mcp.Description("建议的记忆层级（可选，不传时根据 query 自动判断）"),
+			mcp.Enum("language", "project", "library"),
+		),
+		mcp.WithString("library_name",
+			mcp.Description("库名（library 层级时建议填写，如：tang）"),
+		),
+		mcp.WithString("project_path_pattern",
+			mcp.Description("项目路径模式（project 层级时必需，如：/path/to/project/*）"),
+		),
+		mcp.WithString("reason",
+			mcp.Description("建议原因（如：未找到相关记忆，建议补充）"),
+		),
+	)
+	s.server.AddTool(suggestTool, s.handleSuggestMemory)
+
+	// 工具 8: cangjie_mem_history
+	historyTool := mcp.NewTool("cangjie_mem_history",
+		mcp.WithDescription("查看记忆的修订历史，比较版本差异，或回滚到历史版本。\n\n"+
+			"每次存储、更新、导入和删除都会记录一个版本（含操作者和来源）。\n\n"+
+			"✅ 使用场景：\n"+
+			"- 查看某条记忆被谁、在什么时候改过（action=list）\n"+
+			"- 对比两个版本改了什么（action=diff，返回统一格式差异）\n"+
+			"- 撤销错误的更新或导入（action=restore，回滚本身也会记录为新版本）"),
+		mcp.WithNumber("id",
+			mcp.Required(),
+			mcp.Description("记忆 ID（必需）"),
+		),
+		mcp.WithString("action",
+			mcp.Description("操作（可选，默认 list）"),
+			mcp.Enum("list", "diff", "restore"),
+		),
+		mcp.WithNumber("from",
+			mcp.Description("diff 起始版本号（可选，默认为 to 的上一版本）"),
+		),
+		mcp.WithNumber("to",
+			mcp.Description("diff 目标版本号（可选，默认为最新版本）"),
+		),
+		mcp.WithNumber("revision",
+			mcp.Description("restore 要恢复的版本号（restore 时必需）"),
+		),
+	)
+	s.server.AddTool(historyTool, s.handleHistory)
+
+	// 工具 9: cangjie_mem_link
+	linkTool := mcp.NewTool("cangjie_mem_link",
+		mcp.WithDescription("建立或删除两条记忆之间的关系（有方向：source → target）。\n\n"+
+			"🔗 关系类型：\n"+
+			"- see_also：参见\n"+
+			"- depends_on：source 依赖 target（如「tang 路由」依赖「tang 中间件」）\n"+
+			"- supersedes：source 取代 target（新笔记取代旧笔记）\n"+
+			"- contradicts：source 与 target 相互矛盾\n\n"+
+			"💡 提示：检索时传 include_related=true 可附带关联记忆，用 cangjie_mem_graph 查看完整关系图。"),
+		mcp.WithNumber("source_id",
+			mcp.Required(),
+			mcp.Description("起点记忆 ID（必需）"),
+		),
+		mcp.WithNumber("target_id",
+			mcp.Required(),
+			mcp.Description("终点记忆 ID（必需）"),
+		),
+		mcp.WithString("relation",
+			mcp.Description("关系类型（link 时必需；unlink 时不传则删除两者之间的所有关系）"),
+			mcp.Enum("see_also", "depends_on", "supersedes", "contradicts"),
+		),
+		mcp.WithString("note",
+			mcp.Description("关系说明（可选）"),
+		),
+		mcp.WithString("action",
+			mcp.Description("操作（可选，默认 link）"),
+			mcp.Enum("link", "unlink"),
+		),
+	)
+	s.server.AddTool(linkTool, s.handleLink)
+
+	// 工具 10: cangjie_mem_graph
+	graphTool := mcp.NewTool("cangjie_mem_graph",
+		mcp.WithDescription("从一条记忆出发遍历关系图，返回相关记忆（节点）和关系（边）。\n\n"+
+			"✅ 使用场景：\n"+
+			"- 查看某个知识点依赖哪些知识（relation=depends_on, direction=outgoing）\n"+
+			"- 查看某条记忆是否已被取代（relation=supersedes, direction=incoming）"),
+		mcp.WithNumber("id",
+			mcp.Required(),
+			mcp.Description("起点记忆 ID（必需）"),
+		),
+		mcp.WithNumber("depth",
+			mcp.Description("遍历深度（默认 1，最大 3）"),
+		),
+		mcp.WithString("relation",
+			mcp.Description("只遍历该类型的关系（可选）"),
+			mcp.Enum("see_also", "depends_on", "supersedes", "contradicts"),
+		),
+		mcp.WithString("direction",
+			mcp.Description("遍历方向（可选，默认 both）：outgoing 沿 source → target；incoming 反向"),
+			mcp.Enum("outgoing", "incoming", "both"),
+		),
+	)
+	s.server.AddTool(graphTool, s.handleGraph)
+
+	// 工具 11: cangjie_mem_diagnose
+	diagnoseTool := mcp.NewTool("cangjie_mem_diagnose",
+		mcp.WithDescription("解析 cjc/cjpm 编译输出，为每条错误查找已记录的修复方法。\n\n"+
+			"✅ 使用场景：\n"+
+			"- cjpm build 失败后，直接把完整输出传入 output，先看看是否有已知的修复方法\n\n"+
+			"💡 提示：按诊断签名精确匹配 → 错误码匹配 → 全文检索依次查找（结果中的 match 字段说明匹配方式）。"+
+			"解决了新的编译错误后，用 cangjie_mem_store 存储修复方法并传入 diagnostic_signature"),
+		mcp.WithString("output",
+			mcp.Required(),
+			mcp.Description("编译输出（必需，cjc 或 cjpm build 的完整输出）"),
+		),
+		mcp.WithString("language_tag",
+			mcp.Description("语言标签（默认 cangjie）"),
+		),
+		mcp.WithString("project_context",
+			mcp.Description("当前项目路径（可选，用于匹配项目级记忆并按 cjpm.toml 依赖过滤库级记忆）"),
+		),
+		mcp.WithNumber("max_fixes",
+			mcp.Description("每条诊断最多返回的修复记忆数（默认 3，最大 10）"),
+		),
+		mcp.WithBoolean("include_warnings",
+			mcp.Description("是否同时查找警告的修复方法（默认只处理错误）"),
+		),
+	)
+	s.server.AddTool(diagnoseTool, s.handleDiagnose)
+
+	// 工具 12: cangjie_mem_symbol
+	symbolTool := mcp.NewTool("cangjie_mem_symbol",
+		mcp.WithDescription("按名称精确查询仓颉 API 符号（包、类型、成员的声明和文档注释）。\n\n"+
+			"✅ 使用场景：\n"+
+			"- 确认函数签名：name=std.collection.ArrayList.append 或 ArrayList.append\n"+
+			"- 列出类型的所有公开成员：name=std.collection.ArrayList, prefix=true\n\n"+
+			"💡 提示：符号由源码导入（POST /api/symbols/import），查不到时再用 cangjie_mem_recall 全文检索。"+
+			"结果中的 knowledge_id 为关联的 API 记忆"),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.Description("符号名称（必需）：完整名称或按 . 分隔的后缀，如 ArrayList.append"),
+		),
+		mcp.WithBoolean("prefix",
+			mcp.Description("按前缀匹配（默认 false）"),
+		),
+		mcp.WithString("kind",
+			mcp.Description("只返回该类型的符号（可选）"),
+			mcp.Enum("class", "struct", "interface", "enum", "type", "func", "init", "prop", "var", "let"),
+		),
+		mcp.WithString("library_name",
+			mcp.Description("只返回该库的符号（可选）"),
+		),
+		mcp.WithString("language_tag",
+			mcp.Description("语言标签（默认 cangjie）"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("返回数量（默认 20，最大 100）"),
+		),
+	)
+	s.server.AddTool(symbolTool, s.handleSymbol)
 }
 
 // handleStoreMemory 处理存储记忆请求
@@ -225,6 +552,7 @@ func (s *Server) handleStoreMemory(ctx context.Context, request mcp.CallToolRequ
 	if err := s.parseRequest(request, &req); err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("invalid parameters: %v", err)), nil
 	}
+	req.Editor = types.EditorMCP
 
 	// 存储记忆
 	resp, err := s.store.StoreMemory(req)
@@ -297,6 +625,7 @@ func (s *Server) handleDeleteMemory(ctx context.Context, request mcp.CallToolReq
 	if err := s.parseRequest(request, &req); err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("invalid parameters: %v", err)), nil
 	}
+	req.Editor = types.EditorMCP
 
 	// 删除记忆
 	resp, err := s.store.DeleteMemory(req)
@@ -308,6 +637,160 @@ func (s *Server) handleDeleteMemory(ctx context.Context, request mcp.CallToolReq
 	return s.toolResult(resp)
 }
 
+// handleUpdateMemory 处理更新记忆请求
+func (s *Server) handleUpdateMemory(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// 解析参数
+	var req types.UpdateRequest
+	if err := s.parseRequest(request, &req); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameters: %v", err)), nil
+	}
+	req.Editor = types.EditorMCP
+
+	// 更新记忆
+	memory, err := s.store.UpdateMemory(req)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to update memory: %v", err)), nil
+	}
+
+	// 返回结果
+	return s.toolResult(memory)
+}
+
+// handleSuggestMemory 处理建议补充请求
+func (s *Server) handleSuggestMemory(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// 解析参数
+	var req types.SuggestRequest
+	if err := s.parseRequest(request, &req); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameters: %v", err)), nil
+	}
+
+	// 记录建议
+	resp, err := s.store.SuggestMemory(req)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to record suggestion: %v", err)), nil
+	}
+
+	// 返回结果
+	return s.toolResult(resp)
+}
+
+// handleHistory 处理修订历史请求
+func (s *Server) handleHistory(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// 解析参数
+	var req types.HistoryRequest
+	if err := s.parseRequest(request, &req); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameters: %v", err)), nil
+	}
+
+	if req.Action == "" {
+		req.Action = "list"
+	}
+
+	var (
+		result interface{}
+		err    error
+	)
+	switch req.Action {
+	case "list":
+		result, err = s.store.ListRevisions(req.ID)
+	case "diff":
+		result, err = s.store.DiffRevisions(req.ID, req.From, req.To)
+	case "restore":
+		result, err = s.store.RestoreRevision(req.ID, req.Revision, types.EditorMCP)
+	default:
+		return mcp.NewToolResultError(fmt.Sprintf("invalid action: %s", req.Action)), nil
+	}
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to %s history: %v", req.Action, err)), nil
+	}
+
+	// 返回结果
+	return s.toolResult(result)
+}
+
+// handleLink 处理记忆关系请求
+func (s *Server) handleLink(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// 解析参数
+	var req types.LinkToolRequest
+	if err := s.parseRequest(request, &req); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameters: %v", err)), nil
+	}
+
+	if req.Action == "" {
+		req.Action = "link"
+	}
+
+	var (
+		result interface{}
+		err    error
+	)
+	switch req.Action {
+	case "link":
+		result, err = s.store.LinkMemories(req.LinkRequest)
+	case "unlink":
+		result, err = s.store.UnlinkMemories(req.LinkRequest)
+	default:
+		return mcp.NewToolResultError(fmt.Sprintf("invalid action: %s", req.Action)), nil
+	}
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to %s memories: %v", req.Action, err)), nil
+	}
+
+	// 返回结果
+	return s.toolResult(result)
+}
+
+// handleGraph 处理关系图遍历请求
+func (s *Server) handleGraph(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// 解析参数
+	var req types.GraphRequest
+	if err := s.parseRequest(request, &req); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameters: %v", err)), nil
+	}
+
+	resp, err := s.store.MemoryGraph(req)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to load graph: %v", err)), nil
+	}
+
+	// 返回结果
+	return s.toolResult(resp)
+}
+
+// handleDiagnose 处理编译诊断请求
+func (s *Server) handleDiagnose(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// 解析参数
+	var req types.DiagnoseRequest
+	if err := s.parseRequest(request, &req); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameters: %v", err)), nil
+	}
+
+	resp, err := s.store.Diagnose(req)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to diagnose: %v", err)), nil
+	}
+
+	// 返回结果
+	return s.toolResult(resp)
+}
+
+// handleSymbol 处理符号查询请求
+func (s *Server) handleSymbol(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// 解析参数
+	var req types.SymbolRequest
+	if err := s.parseRequest(request, &req); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid parameters: %v", err)), nil
+	}
+
+	resp, err := s.store.LookupSymbols(req)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to lookup symbols: %v", err)), nil
+	}
+
+	// 返回结果
+	return s.toolResult(resp)
+}
+
 // parseRequest 解析请求参数
 func (s *Server) parseRequest(request mcp.CallToolRequest, dest interface{}) error {
 	data, err := json.Marshal(request.Params.Arguments)
@@ -397,11 +880,48 @@ func (s *Server) startServerWithHandler(addr string, handler http.Handler) error
 	return httpServer.ListenAndServe()
 }
 
-// Close 关闭服务器
+// Close 关闭服务器（通知后台任务退出并等待其结束后再关闭数据库）
 func (s *Server) Close() error {
+	close(s.stop)
+	s.wg.Wait()
 	return s.store.Close()
 }
 
+// backfillEmbeddings 为已有记忆回填向量，服务器关闭时在当前批次结束后退出
+func (s *Server) backfillEmbeddings() {
+	defer s.wg.Done()
+
+	n, err := s.store.BackfillEmbeddings(s.stop, 0)
+	if err != nil {
+		log.Printf("Warning: failed to backfill embeddings: %v", err)
+	} else if n > 0 {
+		log.Printf("✓ 已为 %d 条记忆生成向量", n)
+	}
+}
+
+// purgeTrashLoop 启动时及之后每小时清理一次回收站中过期的记忆
+func (s *Server) purgeTrashLoop(retention time.Duration) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		n, err := s.store.PurgeExpiredTrash(retention)
+		if err != nil {
+			log.Printf("Warning: failed to purge trash: %v", err)
+		} else if n > 0 {
+			log.Printf("✓ 已彻底删除 %d 条回收站中过期的记忆", n)
+		}
+
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 // GetStore 获取 Store 实例
 func (s *Server) GetStore() *store.Store {
 	return s.store
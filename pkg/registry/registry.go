@@ -0,0 +1,242 @@
+// Package registry 知识包仓库：本地目录或静态 HTTP 服务
+//
+// 仓库根目录下的 index.json 列出每个包的每个版本及其知识包文件的相对路径，
+// 本地目录和 HTTP 服务使用相同的结构，因此任何静态文件服务器都可以作为仓库。
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ystyle/cangjie-mem/pkg/semver"
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// IndexFile 仓库索引文件名
+const IndexFile = "index.json"
+
+// 下载知识包的最大大小和超时
+const (
+	maxFileSize  = 64 << 20
+	fetchTimeout = 30 * time.Second
+)
+
+// Registry 知识包仓库
+type Registry struct {
+	source string   // 仓库目录（绝对路径）或 URL，记录为安装来源
+	dir    string   // 本地仓库目录
+	base   *url.URL // HTTP 仓库根地址
+	client *http.Client
+}
+
+// Open 打开仓库：http:// 或 https:// 开头的为 HTTP 仓库，否则为本地目录
+func Open(source string) (*Registry, error) {
+	source = strings.TrimSpace(source)
+	if source == "" {
+		return nil, fmt.Errorf("invalid registry: registry cannot be empty")
+	}
+
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		base, err := url.Parse(source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid registry: %w", err)
+		}
+		if !strings.HasSuffix(base.Path, "/") {
+			base.Path += "/"
+		}
+		return &Registry{source: source, base: base, client: &http.Client{Timeout: fetchTimeout}}, nil
+	}
+
+	// 本地目录记录为绝对路径，以便在其他工作目录下升级
+	dir, err := filepath.Abs(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid registry: %w", err)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid registry: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("invalid registry: %s is not a directory", source)
+	}
+	return &Registry{source: dir, dir: dir}, nil
+}
+
+// Source 仓库目录或 URL
+func (r *Registry) Source() string {
+	return r.source
+}
+
+// Index 读取仓库索引
+func (r *Registry) Index() (*types.RegistryIndex, error) {
+	data, err := r.read(IndexFile)
+	if err != nil {
+		return nil, err
+	}
+	var index types.RegistryIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("invalid registry index: %w", err)
+	}
+	if index.Packages == nil {
+		index.Packages = []types.RegistryEntry{}
+	}
+	return &index, nil
+}
+
+// Fetch 下载知识包：version 为空时取最新版本
+func (r *Registry) Fetch(name, version string) (*types.KnowledgePackage, error) {
+	index, err := r.Index()
+	if err != nil {
+		return nil, err
+	}
+	entry, err := Resolve(index, name, version)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := r.read(entry.Path)
+	if err != nil {
+		return nil, err
+	}
+	var pkg types.KnowledgePackage
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return nil, fmt.Errorf("invalid package %s: %w", entry.Path, err)
+	}
+	if pkg.Package.Name != entry.Name || pkg.Package.Version != entry.Version {
+		return nil, fmt.Errorf("invalid package %s: contains %s@%s, index lists %s@%s",
+			entry.Path, pkg.Package.Name, pkg.Package.Version, entry.Name, entry.Version)
+	}
+	return &pkg, nil
+}
+
+// Resolve 在索引中查找包的指定版本，version 为空时返回最新版本
+func Resolve(index *types.RegistryIndex, name, version string) (*types.RegistryEntry, error) {
+	var latest *types.RegistryEntry
+	for i := range index.Packages {
+		entry := &index.Packages[i]
+		if entry.Name != name {
+			continue
+		}
+		if version != "" && entry.Version == version {
+			return entry, nil
+		}
+		if version == "" && (latest == nil || compareVersions(entry.Version, latest.Version) > 0) {
+			latest = entry
+		}
+	}
+	if latest == nil {
+		if version != "" {
+			return nil, fmt.Errorf("package not found in registry: %s@%s", name, version)
+		}
+		return nil, fmt.Errorf("package not found in registry: %s", name)
+	}
+	return latest, nil
+}
+
+// compareVersions 比较两个包版本：都是语义化版本时按语义化版本比较，否则按字符串比较
+// （导出的包版本为 2006.01.02.150405 格式的时间戳，按字符串比较即为时间顺序）
+func compareVersions(a, b string) int {
+	va, errA := semver.Parse(a)
+	vb, errB := semver.Parse(b)
+	if errA == nil && errB == nil {
+		if c := semver.Compare(va, vb); c != 0 {
+			return c
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+// read 读取仓库中的文件（路径相对仓库根目录，不允许跳出仓库）
+func (r *Registry) read(rel string) ([]byte, error) {
+	if rel == "" || path.IsAbs(rel) || !filepath.IsLocal(filepath.FromSlash(rel)) {
+		return nil, fmt.Errorf("invalid registry path: %q", rel)
+	}
+
+	if r.base == nil {
+		data, err := os.ReadFile(filepath.Join(r.dir, filepath.FromSlash(rel)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read registry file %s: %w", rel, err)
+		}
+		return data, nil
+	}
+
+	ref, err := url.Parse(rel)
+	if err != nil {
+		return nil, fmt.Errorf("invalid registry path: %q", rel)
+	}
+	u := r.base.ResolveReference(ref).String()
+	resp, err := r.client.Get(u)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: %s", u, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", u, err)
+	}
+	if len(data) > maxFileSize {
+		return nil, fmt.Errorf("failed to fetch %s: file exceeds %d bytes", u, maxFileSize)
+	}
+	return data, nil
+}
+
+// BuildIndex 扫描目录下的知识包文件（*.json，不含子目录和 index.json）生成仓库索引并写入 index.json
+func BuildIndex(dir string) (*types.RegistryIndex, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read registry: %w", err)
+	}
+
+	index := &types.RegistryIndex{Packages: []types.RegistryEntry{}}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || name == IndexFile || filepath.Ext(name) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		var pkg types.KnowledgePackage
+		if err := json.Unmarshal(data, &pkg); err != nil {
+			return nil, fmt.Errorf("invalid package %s: %w", name, err)
+		}
+		if pkg.Package.Name == "" || pkg.Package.Version == "" {
+			return nil, fmt.Errorf("invalid package %s: package name and version are required", name)
+		}
+		index.Packages = append(index.Packages, types.RegistryEntry{
+			Name:        pkg.Package.Name,
+			Version:     pkg.Package.Version,
+			Description: pkg.Package.Description,
+			Path:        name,
+		})
+	}
+	sort.Slice(index.Packages, func(i, j int) bool {
+		a, b := index.Packages[i], index.Packages[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return compareVersions(a.Version, b.Version) < 0
+	})
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode registry index: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, IndexFile), append(data, '\n'), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write registry index: %w", err)
+	}
+	return index, nil
+}
@@ -0,0 +1,64 @@
+package registry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+func writePackage(t *testing.T, dir, file, name, version string) {
+	t.Helper()
+	pkg := types.NewKnowledgePackage(types.PackageInfo{Name: name, Version: version}, nil)
+	data, _ := json.Marshal(pkg)
+	if err := os.WriteFile(filepath.Join(dir, file), data, 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	dir := t.TempDir()
+	writePackage(t, dir, "tang-1.9.0.json", "tang", "1.9.0")
+	writePackage(t, dir, "tang-1.10.0.json", "tang", "1.10.0")
+	writePackage(t, dir, "json-0.1.0.json", "json", "0.1.0")
+
+	index, err := BuildIndex(dir)
+	if err != nil || len(index.Packages) != 3 || index.Packages[0].Name != "json" || index.Packages[2].Version != "1.10.0" {
+		t.Fatalf("BuildIndex() = %+v, %v", index, err)
+	}
+
+	// 本地目录和静态 HTTP 服务使用相同的结构
+	server := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer server.Close()
+
+	for _, source := range []string{dir, server.URL, server.URL + "/"} {
+		reg, err := Open(source)
+		if err != nil {
+			t.Fatalf("Open(%s) error = %v", source, err)
+		}
+		// 未指定版本时取最新版本（按语义化版本比较）
+		pkg, err := reg.Fetch("tang", "")
+		if err != nil || pkg.Package.Version != "1.10.0" {
+			t.Errorf("Fetch(%s, latest) = %+v, %v", source, pkg, err)
+		}
+		if pkg, err := reg.Fetch("tang", "1.9.0"); err != nil || pkg.Package.Version != "1.9.0" {
+			t.Errorf("Fetch(%s, 1.9.0) = %+v, %v", source, pkg, err)
+		}
+		if _, err := reg.Fetch("tang", "2.0.0"); err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Errorf("Fetch(%s, missing) error = %v", source, err)
+		}
+	}
+
+	// 索引中的路径不能跳出仓库
+	if _, err := (&Registry{dir: dir}).read("../secret.json"); err == nil {
+		t.Error("read(../secret.json) error = nil")
+	}
+	if _, err := Open(filepath.Join(dir, "tang-1.9.0.json")); err == nil {
+		t.Error("Open(file) error = nil")
+	}
+}
@@ -0,0 +1,56 @@
+// Package segment 提供面向 FTS5 的中日韩（CJK）文本切分
+//
+// SQLite 默认的 unicode61 分词器会把一串连续的汉字当作一个词，
+// 导致「配置」无法匹配「日志配置位置」。这里在写入索引前把每个 CJK 字符
+// 用空格隔开（单字切分），查询时再把包含 CJK 的词转换为短语查询，
+// 从而实现任意子串匹配。
+package segment
+
+import (
+	"strings"
+	"unicode"
+)
+
+// IsCJK 判断字符是否为中日韩文字
+func IsCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r)
+}
+
+// ContainsCJK 判断文本是否包含中日韩文字
+func ContainsCJK(s string) bool {
+	for _, r := range s {
+		if IsCJK(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// Text 将文本切分为适合写入 FTS5 索引的形式（每个 CJK 字符独立成词）
+func Text(s string) string {
+	if !ContainsCJK(s) {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s) * 2)
+
+	prevCJK := false
+	prevSpace := true
+	for _, r := range s {
+		cjk := IsCJK(r)
+		space := unicode.IsSpace(r)
+		// CJK 字符与相邻的非空白字符之间插入空格
+		if (cjk || prevCJK) && !prevSpace && !space {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(r)
+		prevCJK = cjk
+		prevSpace = space
+	}
+
+	return b.String()
+}
@@ -0,0 +1,26 @@
+package segment
+
+import "testing"
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"纯英文", "hello world", "hello world"},
+		{"纯中文", "日志配置", "日 志 配 置"},
+		{"中英混合", "tang路由配置", "tang 路 由 配 置"},
+		{"保留已有空白", "使用 var 声明", "使 用 var 声 明"},
+		{"中文标点", "配置，位置", "配 置 ， 位 置"},
+		{"空字符串", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Text(tt.in); got != tt.want {
+				t.Errorf("Text(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,360 @@
+// Package semver 提供语义化版本解析与版本范围匹配
+//
+// 版本范围语法（与 npm 基本一致）：
+//
+//	1.2.3            精确版本
+//	1.2 / 1.2.x      1.2 系列（>=1.2.0 <1.3.0）
+//	* / x / 空       任意版本
+//	>=1.0 <2.0       空格分隔的条件需同时满足
+//	^1.2.3           兼容版本（>=1.2.3 <2.0.0；0.x 时锁定次版本）
+//	~1.2.3           补丁版本（>=1.2.3 <1.3.0）
+//	1.0 - 2.0        闭区间
+//	^1.0 || ^2.0     满足任意一组即可
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version 语义化版本
+type Version struct {
+	Major, Minor, Patch int
+	Pre                 []string // 预发布标识（如 beta.1），有预发布标识的版本低于对应正式版本
+}
+
+// partial 解析中的版本（允许省略或使用通配符的部分）
+type partial struct {
+	Version
+	parts int // 明确给出的部分数（0~3），通配符之后的部分不计入
+}
+
+// Parse 解析版本号（允许 v 前缀，省略的部分视为 0）
+func Parse(s string) (Version, error) {
+	p, err := parsePartial(s)
+	if err != nil {
+		return Version{}, err
+	}
+	if p.parts == 0 {
+		return Version{}, fmt.Errorf("invalid version: %q", s)
+	}
+	return p.Version, nil
+}
+
+// parsePartial 解析可能不完整的版本号（如 1、1.2、1.x、*）
+func parsePartial(s string) (partial, error) {
+	raw := s
+	s = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(s), "v"), "V")
+	if s == "" {
+		return partial{}, fmt.Errorf("invalid version: %q", raw)
+	}
+
+	// 构建元数据不参与比较
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		s = s[:i]
+	}
+
+	var p partial
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		p.Pre = strings.Split(s[i+1:], ".")
+		s = s[:i]
+		for _, id := range p.Pre {
+			if id == "" {
+				return partial{}, fmt.Errorf("invalid version: %q", raw)
+			}
+		}
+	}
+
+	fields := strings.Split(s, ".")
+	if len(fields) > 3 {
+		return partial{}, fmt.Errorf("invalid version: %q", raw)
+	}
+	nums := []*int{&p.Major, &p.Minor, &p.Patch}
+	wildcard := false
+	for i, f := range fields {
+		if f == "x" || f == "X" || f == "*" {
+			wildcard = true
+			continue
+		}
+		if wildcard {
+			return partial{}, fmt.Errorf("invalid version: %q", raw)
+		}
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 {
+			return partial{}, fmt.Errorf("invalid version: %q", raw)
+		}
+		*nums[i] = n
+		p.parts = i + 1
+	}
+	if p.Pre != nil && p.parts < 3 {
+		return partial{}, fmt.Errorf("invalid version: %q", raw)
+	}
+	return p, nil
+}
+
+// Compare 比较两个版本，返回 -1、0 或 1
+func Compare(a, b Version) int {
+	for _, d := range []int{a.Major - b.Major, a.Minor - b.Minor, a.Patch - b.Patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+	return comparePre(a.Pre, b.Pre)
+}
+
+// comparePre 比较预发布标识（没有预发布标识的版本更高）
+func comparePre(a, b []string) int {
+	switch {
+	case len(a) == 0 && len(b) == 0:
+		return 0
+	case len(a) == 0:
+		return 1
+	case len(b) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(a) && i < len(b); i++ {
+		an, aErr := strconv.Atoi(a[i])
+		bn, bErr := strconv.Atoi(b[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				return sign(an - bn)
+			}
+		case aErr == nil:
+			return -1 // 数字标识低于字母标识
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(a[i], b[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return sign(len(a) - len(b))
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// String 返回版本号字符串
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.Pre) > 0 {
+		s += "-" + strings.Join(v.Pre, ".")
+	}
+	return s
+}
+
+// comparator 单个比较条件
+type comparator struct {
+	op string // =, >, >=, <, <=
+	v  Version
+}
+
+func (c comparator) matches(v Version) bool {
+	cmp := Compare(v, c.v)
+	switch c.op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	default:
+		return cmp == 0
+	}
+}
+
+// Range 版本范围（多组条件之间为 OR，组内条件为 AND）
+type Range struct {
+	sets [][]comparator
+}
+
+// ParseRange 解析版本范围
+func ParseRange(s string) (Range, error) {
+	var r Range
+	for _, group := range strings.Split(s, "||") {
+		set, err := parseComparatorSet(group)
+		if err != nil {
+			return Range{}, fmt.Errorf("invalid version range %q: %w", s, err)
+		}
+		r.sets = append(r.sets, set)
+	}
+	return r, nil
+}
+
+// Contains 判断版本是否在范围内
+func (r Range) Contains(v Version) bool {
+	for _, set := range r.sets {
+		ok := true
+		for _, c := range set {
+			if !c.matches(v) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Satisfies 判断版本是否满足版本范围
+func Satisfies(version, constraint string) (bool, error) {
+	v, err := Parse(version)
+	if err != nil {
+		return false, err
+	}
+	r, err := ParseRange(constraint)
+	if err != nil {
+		return false, err
+	}
+	return r.Contains(v), nil
+}
+
+// parseComparatorSet 解析一组 AND 条件
+func parseComparatorSet(s string) ([]comparator, error) {
+	fields := strings.Fields(s)
+
+	// 闭区间：1.0 - 2.0
+	if len(fields) == 3 && fields[1] == "-" {
+		lo, err := parsePartial(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		hi, err := parsePartial(fields[2])
+		if err != nil {
+			return nil, err
+		}
+		set := []comparator{{">=", lo.Version}}
+		return append(set, upperBound(hi, true)...), nil
+	}
+
+	// 运算符与版本之间允许空格（如 ">= 1.0"）
+	var tokens []string
+	for i := 0; i < len(fields); i++ {
+		f := fields[i]
+		if strings.Trim(f, "<>=^~") == "" && i+1 < len(fields) {
+			f += fields[i+1]
+			i++
+		}
+		tokens = append(tokens, f)
+	}
+
+	var set []comparator
+	for _, tok := range tokens {
+		cs, err := parseComparator(tok)
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, cs...)
+	}
+	return set, nil
+}
+
+// parseComparator 解析单个条件（可能展开为多个比较）
+func parseComparator(tok string) ([]comparator, error) {
+	op := ""
+	for _, prefix := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(tok, prefix) {
+			op = prefix
+			tok = tok[len(prefix):]
+			break
+		}
+	}
+
+	p, err := parsePartial(tok)
+	if err != nil {
+		return nil, err
+	}
+	lo := p.Version
+
+	switch op {
+	case "^":
+		if p.parts == 0 {
+			return nil, nil
+		}
+		var hi Version
+		switch {
+		case lo.Major > 0 || p.parts == 1:
+			hi = Version{Major: lo.Major + 1}
+		case lo.Minor > 0 || p.parts == 2:
+			hi = Version{Minor: lo.Minor + 1}
+		default:
+			hi = Version{Patch: lo.Patch + 1}
+		}
+		return []comparator{{">=", lo}, {"<", hi}}, nil
+	case "~":
+		if p.parts == 0 {
+			return nil, nil
+		}
+		hi := Version{Major: lo.Major, Minor: lo.Minor + 1}
+		if p.parts == 1 {
+			hi = Version{Major: lo.Major + 1}
+		}
+		return []comparator{{">=", lo}, {"<", hi}}, nil
+	case ">", "<=":
+		if p.parts == 0 {
+			if op == ">" {
+				return []comparator{{"<", Version{}}}, nil // 永不满足
+			}
+			return nil, nil
+		}
+		if p.parts < 3 {
+			// >1.2 即 >=1.3.0；<=1.2 即 <1.3.0
+			next := upperBound(p, false)[0].v
+			if op == ">" {
+				return []comparator{{">=", next}}, nil
+			}
+			return []comparator{{"<", next}}, nil
+		}
+		return []comparator{{op, lo}}, nil
+	case ">=", "<":
+		if p.parts == 0 {
+			if op == "<" {
+				return []comparator{{"<", Version{}}}, nil
+			}
+			return nil, nil
+		}
+		return []comparator{{op, lo}}, nil
+	default:
+		// 精确版本或通配（1.2 / 1.2.x / *）
+		if p.parts == 3 {
+			return []comparator{{"=", lo}}, nil
+		}
+		if p.parts == 0 {
+			return nil, nil
+		}
+		return append([]comparator{{">=", lo}}, upperBound(p, false)...), nil
+	}
+}
+
+// upperBound 返回不完整版本的上界（1.2 → <1.3.0）；inclusive 且版本完整时返回 <=
+func upperBound(p partial, inclusive bool) []comparator {
+	switch p.parts {
+	case 0:
+		return nil
+	case 1:
+		return []comparator{{"<", Version{Major: p.Major + 1}}}
+	case 2:
+		return []comparator{{"<", Version{Major: p.Major, Minor: p.Minor + 1}}}
+	default:
+		if inclusive {
+			return []comparator{{"<=", p.Version}}
+		}
+		return []comparator{{"<", p.Version}}
+	}
+}
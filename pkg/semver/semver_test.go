@@ -0,0 +1,69 @@
+package semver
+
+import "testing"
+
+func TestSatisfies(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{"", "1.2.3", true},
+		{"*", "0.0.1", true},
+		{"1.2.3", "1.2.3", true},
+		{"1.2.3", "1.2.4", false},
+		{"v1.2.3", "1.2.3", true},
+		{"1.2", "1.2.9", true},
+		{"1.2.x", "1.3.0", false},
+		{"1", "1.9.0", true},
+		{"1.x", "2.0.0", false},
+		{">=1.0 <2.0", "1.5.0", true},
+		{">=1.0 <2.0", "2.0.0", false},
+		{">= 1.0 < 2.0", "1.0.0", true},
+		{"^1.2.3", "1.9.0", true},
+		{"^1.2.3", "2.0.0", false},
+		{"^1.2.3", "1.2.2", false},
+		{"^0.2.3", "0.2.9", true},
+		{"^0.2.3", "0.3.0", false},
+		{"^0.0.3", "0.0.4", false},
+		{"~1.2.3", "1.2.9", true},
+		{"~1.2.3", "1.3.0", false},
+		{"~1", "1.9.9", true},
+		{">1.2", "1.2.9", false},
+		{">1.2", "1.3.0", true},
+		{"<=1.2", "1.2.9", true},
+		{"1.0 - 2.0", "2.0.5", true},
+		{"1.0 - 2.0.0", "2.0.1", false},
+		{"^1.0 || ^3.0", "3.1.0", true},
+		{"^1.0 || ^3.0", "2.1.0", false},
+		{">=1.0.0", "1.0.0-beta", false},
+		{"1.0.0-beta.2", "1.0.0-beta.2", true},
+		{">=1.0.0-beta.2", "1.0.0-beta.10", true},
+		{">=1.0.0-beta", "1.0.0-alpha", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.constraint+"@"+tt.version, func(t *testing.T) {
+			got, err := Satisfies(tt.version, tt.constraint)
+			if err != nil {
+				t.Fatalf("Satisfies(%q, %q) error = %v", tt.version, tt.constraint, err)
+			}
+			if got != tt.want {
+				t.Errorf("Satisfies(%q, %q) = %v, want %v", tt.version, tt.constraint, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRangeErrors(t *testing.T) {
+	for _, s := range []string{"abc", "1.2.3.4", ">=", "1.x.3", "^1.2 <", "1.0-beta", ">= 1.0, < 2.0"} {
+		if _, err := ParseRange(s); err == nil {
+			t.Errorf("ParseRange(%q) expected error", s)
+		}
+	}
+	for _, s := range []string{"", "*", "x", "1.2"} {
+		if _, err := Parse(s); (err != nil) != (s == "" || s == "*" || s == "x") {
+			t.Errorf("Parse(%q) error = %v", s, err)
+		}
+	}
+}
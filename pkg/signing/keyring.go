@@ -0,0 +1,71 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// Keyring 受信任的公钥（按指纹索引，名称为 .pub 文件名）
+type Keyring struct {
+	names map[string]string // 公钥指纹 → 名称
+}
+
+// LoadKeyring 加载目录下的所有 .pub 公钥
+func LoadKeyring(dir string) (*Keyring, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyring: %w", err)
+	}
+
+	k := &Keyring{names: make(map[string]string)}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != PublicKeyExt {
+			continue
+		}
+		pub, err := ReadPublicKey(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		k.Add(strings.TrimSuffix(entry.Name(), PublicKeyExt), pub)
+	}
+	return k, nil
+}
+
+// Add 添加受信任的公钥
+func (k *Keyring) Add(name string, pub ed25519.PublicKey) {
+	if k.names == nil {
+		k.names = make(map[string]string)
+	}
+	k.names[KeyID(pub)] = name
+}
+
+// Names 密钥环中的公钥名称（按名称排序）
+func (k *Keyring) Names() []string {
+	names := make([]string, 0, len(k.names))
+	for _, name := range k.names {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Verify 验证知识包签名，并标记签名公钥是否受信任（k 为 nil 时只验证签名，签名者都不受信任）
+func (k *Keyring) Verify(p *types.KnowledgePackage) (*types.PackageSigner, error) {
+	signer, err := Verify(p)
+	if err != nil {
+		return nil, err
+	}
+	if k != nil {
+		if name, ok := k.names[signer.KeyID]; ok {
+			signer.Trusted = true
+			signer.Name = name
+		}
+	}
+	return signer, nil
+}
@@ -0,0 +1,202 @@
+// Package signing 知识包的 Ed25519 签名与验证
+//
+// 签名内容为知识包校验和（覆盖格式版本和每条记忆的 UUID、内容哈希）、包信息、签名者和签名时间，
+// 因此记忆内容或包信息的任何修改都会使签名失效。受信任的公钥放在密钥环目录中，每个 .pub 文件一个。
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// Algorithm 签名算法
+const Algorithm = "ed25519"
+
+// 密钥文件扩展名
+const (
+	PrivateKeyExt = ".key"
+	PublicKeyExt  = ".pub"
+)
+
+// signatureDomain 签名内容的前缀，避免与其他用途的签名混用
+const signatureDomain = "cangjie-mem package signature v1"
+
+// KeyID 公钥指纹（SHA256:base64）
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// Sign 用私钥签名知识包：先协商格式版本（1.0 升级为当前格式），再对校验和、包信息和签名者签名
+func Sign(p *types.KnowledgePackage, key ed25519.PrivateKey, signer string, now time.Time) error {
+	signer = strings.TrimSpace(signer)
+	if signer == "" {
+		return fmt.Errorf("invalid signer: signer cannot be empty")
+	}
+	if err := types.NegotiatePackage(p); err != nil {
+		return err
+	}
+
+	pub := key.Public().(ed25519.PublicKey)
+	sig := &types.PackageSignature{
+		Algorithm: Algorithm,
+		Signer:    signer,
+		KeyID:     KeyID(pub),
+		PublicKey: base64.StdEncoding.EncodeToString(pub),
+		SignedAt:  now.UTC().Truncate(time.Second),
+	}
+	message, err := signedMessage(p, sig)
+	if err != nil {
+		return err
+	}
+	sig.Value = base64.StdEncoding.EncodeToString(ed25519.Sign(key, message))
+	p.Signature = sig
+	return nil
+}
+
+// Verify 验证知识包的签名（只验证签名本身，不检查公钥是否受信任，见 Keyring.Verify）
+func Verify(p *types.KnowledgePackage) (*types.PackageSigner, error) {
+	if err := types.NegotiatePackage(p); err != nil {
+		return nil, err
+	}
+	sig := p.Signature
+	if sig == nil {
+		return nil, fmt.Errorf("invalid signature: package is not signed")
+	}
+	if sig.Algorithm != Algorithm {
+		return nil, fmt.Errorf("invalid signature: unsupported algorithm %q", sig.Algorithm)
+	}
+
+	pub, err := base64.StdEncoding.DecodeString(sig.PublicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid signature: malformed public key")
+	}
+	if KeyID(pub) != sig.KeyID {
+		return nil, fmt.Errorf("invalid signature: key id does not match public key")
+	}
+	value, err := base64.StdEncoding.DecodeString(sig.Value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature: malformed signature value")
+	}
+	message, err := signedMessage(p, sig)
+	if err != nil {
+		return nil, err
+	}
+	if !ed25519.Verify(pub, message, value) {
+		return nil, fmt.Errorf("invalid signature: signature mismatch")
+	}
+
+	return &types.PackageSigner{Signer: sig.Signer, KeyID: sig.KeyID, SignedAt: sig.SignedAt}, nil
+}
+
+// signedMessage 生成签名内容（每个字段前写入长度，避免字段边界不同而拼接结果相同）
+func signedMessage(p *types.KnowledgePackage, sig *types.PackageSignature) ([]byte, error) {
+	info, err := json.Marshal(p.Package)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode package info: %w", err)
+	}
+
+	var b strings.Builder
+	b.WriteString(signatureDomain + "\n")
+	for _, field := range []string{
+		p.Checksum, string(info), sig.Algorithm, sig.Signer, sig.KeyID, sig.SignedAt.UTC().Format(time.RFC3339),
+	} {
+		fmt.Fprintf(&b, "%d:%s\n", len(field), field)
+	}
+	return []byte(b.String()), nil
+}
+
+// GenerateKey 生成密钥对，写入 dir 下的 name.key（私钥，仅当前用户可读）和 name.pub（公钥，分发给导入方放入密钥环）
+func GenerateKey(dir, name string) (ed25519.PublicKey, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return nil, fmt.Errorf("invalid key name: %q", name)
+	}
+	keyPath := filepath.Join(dir, name+PrivateKeyExt)
+	if _, err := os.Stat(keyPath); err == nil {
+		return nil, fmt.Errorf("key already exists: %s", keyPath)
+	}
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode private key: %w", err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode public key: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create key directory: %w", err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write private key: %w", err)
+	}
+	pubPath := filepath.Join(dir, name+PublicKeyExt)
+	if err := os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write public key: %w", err)
+	}
+	return pub, nil
+}
+
+// ReadPrivateKey 读取 PEM 格式的 Ed25519 私钥
+func ReadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	der, err := readPEM(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key %s: %w", path, err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("invalid private key %s: not an ed25519 key", path)
+	}
+	return priv, nil
+}
+
+// ReadPublicKey 读取 PEM 格式的 Ed25519 公钥
+func ReadPublicKey(path string) (ed25519.PublicKey, error) {
+	der, err := readPEM(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key %s: %w", path, err)
+	}
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("invalid public key %s: not an ed25519 key", path)
+	}
+	return pub, nil
+}
+
+// readPEM 读取 PEM 文件中指定类型的块
+func readPEM(path, blockType string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != blockType {
+		return nil, fmt.Errorf("invalid key %s: expected PEM %s", path, blockType)
+	}
+	return block.Bytes, nil
+}
@@ -0,0 +1,88 @@
+package signing
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+func testPackage() types.KnowledgePackage {
+	return types.KnowledgePackage{
+		Version: types.PackageFormatV1,
+		Package: types.PackageInfo{Name: "tang", Version: "1.0.0"},
+		Memories: types.NewPackageMemories([]types.StoreRequest{
+			{Level: types.LevelLibrary, LibraryName: "tang", Title: "路由注册", Content: "使用 router.get 注册路由"},
+		}),
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	dir := t.TempDir()
+	pub, err := GenerateKey(dir, "alice")
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	if _, err := GenerateKey(dir, "alice"); err == nil {
+		t.Error("GenerateKey() overwrote an existing key")
+	}
+	key, err := ReadPrivateKey(dir + "/alice" + PrivateKeyExt)
+	if err != nil {
+		t.Fatalf("ReadPrivateKey() error = %v", err)
+	}
+
+	// 签名时 1.0 包升级为当前格式
+	pkg := testPackage()
+	if err := Sign(&pkg, key, "Alice", time.Now()); err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if pkg.Version != types.PackageFormatVersion || pkg.Signature == nil || pkg.Signature.KeyID != KeyID(pub) {
+		t.Fatalf("Sign() = %+v", pkg)
+	}
+
+	// 不在密钥环中时签名有效但不受信任
+	signer, err := (*Keyring)(nil).Verify(&pkg)
+	if err != nil || signer.Signer != "Alice" || signer.Trusted {
+		t.Errorf("Verify(no keyring) = %+v, %v", signer, err)
+	}
+	keyring, err := LoadKeyring(dir)
+	if err != nil || len(keyring.Names()) != 1 {
+		t.Fatalf("LoadKeyring() = %v, %v", keyring, err)
+	}
+	signer, err = keyring.Verify(&pkg)
+	if err != nil || !signer.Trusted || signer.Name != "alice" {
+		t.Errorf("Verify(keyring) = %+v, %v", signer, err)
+	}
+
+	// 修改包信息、记忆内容或签名者后验证失败
+	tampered := pkg
+	tampered.Package.Name = "evil"
+	if _, err := Verify(&tampered); err == nil || !strings.Contains(err.Error(), "signature mismatch") {
+		t.Errorf("Verify(tampered package info) error = %v", err)
+	}
+	tampered = pkg
+	tampered.Memories = append([]types.PackageMemory(nil), pkg.Memories...)
+	tampered.Memories[0].Content = "被篡改的内容"
+	if _, err := Verify(&tampered); err == nil {
+		t.Error("Verify(tampered memory) error = nil")
+	}
+	tampered = pkg
+	tampered.Memories = append([]types.PackageMemory(nil), pkg.Memories...)
+	tampered.Memories[0].AccessCount = 1000000
+	if _, err := Verify(&tampered); err == nil {
+		t.Error("Verify(tampered access_count) error = nil")
+	}
+	sig := *pkg.Signature
+	sig.Signer = "Bob"
+	tampered = pkg
+	tampered.Signature = &sig
+	if _, err := Verify(&tampered); err == nil {
+		t.Error("Verify(tampered signer) error = nil")
+	}
+
+	unsigned := testPackage()
+	if _, err := Verify(&unsigned); err == nil || !strings.Contains(err.Error(), "not signed") {
+		t.Errorf("Verify(unsigned) error = %v", err)
+	}
+}
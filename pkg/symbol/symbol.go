@@ -0,0 +1,482 @@
+// Package symbol 从仓颉源码中提取公开 API 声明（包、类型、成员、签名和文档注释）
+//
+// 解析是基于行和花括号深度的轻量实现，不做完整语法分析：
+// 只收集顶层的 public 声明，以及 public 类型（含 extend）中的 public 成员和接口的全部成员
+package symbol
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+// Ext 仓颉源文件扩展名
+const Ext = ".cj"
+
+var (
+	packagePattern = regexp.MustCompile(`^(?:macro\s+)?package\s+([\w.]+)`)
+	declPattern    = regexp.MustCompile(`^((?:(?:public|protected|private|internal|open|abstract|sealed|static|override|redef|mut|unsafe|foreign|const|operator)\s+)*)(class|struct|interface|enum|type|func|init|prop|var|let|extend)\b\s*(.*)$`)
+	identPattern   = regexp.MustCompile(`^[A-Za-z_]\w*`)
+	spacePattern   = regexp.MustCompile(`\s+`)
+)
+
+// typeKinds 会开启类型作用域的声明
+var typeKinds = map[string]bool{
+	types.SymbolClass:     true,
+	types.SymbolStruct:    true,
+	types.SymbolInterface: true,
+	types.SymbolEnum:      true,
+	"extend":              true,
+}
+
+// scope 花括号作用域：类型体或普通代码块（name 为空）
+type scope struct {
+	name   string
+	kind   string
+	public bool
+}
+
+// decl 正在解析的声明（签名可能跨多行）
+type decl struct {
+	kind      string
+	typeName  string
+	member    string
+	signature string
+	line      int
+	doc       string
+	depth     int // 未闭合的圆括号/方括号数
+}
+
+// parser 单个文件的解析状态
+type parser struct {
+	file    string
+	pkg     string
+	stack   []scope
+	pending *scope // 已读到类型声明、尚未读到 { 的类型
+	current *decl
+	doc     []string // 待归属的文档注释
+	lineDoc bool     // doc 来自连续的 // 行注释
+	symbols []types.Symbol
+
+	inBlock      bool // 块注释中
+	blockIsDoc   bool // 当前块注释是 /** */ 文档注释
+	block        []string
+	inMultiQuote bool // """ 多行字符串中
+}
+
+// Parse 解析单个源文件的内容，file 为记录在符号中的文件路径
+func Parse(r io.Reader, file string) ([]types.Symbol, error) {
+	p := &parser{file: file}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		p.parseLine(scanner.Text(), lineNo)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	p.finishDecl()
+	return p.symbols, nil
+}
+
+// ParseFile 解析单个源文件
+func ParseFile(path, name string) ([]types.Symbol, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f, name)
+}
+
+// ParseDir 递归解析目录下的 .cj 文件（跳过隐藏目录、target 目录和 _test.cj 测试文件），
+// 返回符号和解析的文件数；root 为单个文件时只解析该文件
+func ParseDir(root string) ([]types.Symbol, int, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !info.IsDir() {
+		symbols, err := ParseFile(root, filepath.Base(root))
+		if err != nil {
+			return nil, 0, err
+		}
+		return symbols, 1, nil
+	}
+
+	var symbols []types.Symbol
+	files := 0
+	err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := entry.Name()
+		if entry.IsDir() {
+			if path != root && (strings.HasPrefix(name, ".") || name == "target") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !IsSource(name) {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		parsed, err := ParseFile(path, filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+		symbols = append(symbols, parsed...)
+		files++
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return symbols, files, nil
+}
+
+// IsSource 判断文件名是否为需要解析的仓颉源文件（不含测试文件）
+func IsSource(name string) bool {
+	return strings.HasSuffix(name, Ext) && !strings.HasSuffix(name, "_test"+Ext)
+}
+
+// parseLine 处理一行源码
+func (p *parser) parseLine(line string, lineNo int) {
+	code := strings.TrimSpace(p.stripComments(line))
+	if code == "" {
+		return
+	}
+
+	if p.current != nil {
+		p.continueDecl(code)
+	} else if m := packagePattern.FindStringSubmatch(code); m != nil && len(p.stack) == 0 {
+		p.pkg = m[1]
+		p.doc, p.lineDoc = nil, false
+	} else if strings.HasPrefix(code, "@") && !declPattern.MatchString(afterAnnotations(code)) {
+		// 注解行：保留前面的文档注释
+	} else if p.inDeclScope() {
+		p.startDecl(afterAnnotations(code), lineNo)
+	} else {
+		p.doc, p.lineDoc = nil, false
+	}
+
+	p.countBraces(code)
+}
+
+// stripComments 去掉注释和字符串内容，收集文档注释
+func (p *parser) stripComments(line string) string {
+	var sb strings.Builder
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		next := rune(0)
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+
+		switch {
+		case p.inBlock:
+			end := strings.Index(string(runes[i:]), "*/")
+			if end < 0 {
+				p.block = append(p.block, string(runes[i:]))
+				return sb.String()
+			}
+			text := string(runes[i:])[:end]
+			p.block = append(p.block, text)
+			i += len([]rune(text)) + 1
+			p.inBlock = false
+			if p.blockIsDoc {
+				p.doc = cleanBlockDoc(p.block)
+				p.lineDoc = false
+			}
+		case p.inMultiQuote:
+			if strings.HasPrefix(string(runes[i:]), `"""`) {
+				p.inMultiQuote = false
+				sb.WriteString(`""`)
+				i += 2
+			}
+		case r == '/' && next == '/':
+			// 独占一行的行注释也作为文档注释
+			if strings.TrimSpace(sb.String()) == "" && p.current == nil {
+				if !p.lineDoc {
+					p.doc = nil
+				}
+				p.lineDoc = true
+				p.doc = append(p.doc, strings.TrimPrefix(strings.TrimPrefix(string(runes[i+2:]), "/"), " "))
+			}
+			return sb.String()
+		case r == '/' && next == '*':
+			p.inBlock = true
+			p.block = nil
+			p.blockIsDoc = i+2 < len(runes) && runes[i+2] == '*' && !(i+3 < len(runes) && runes[i+3] == '/')
+			i++
+			if p.blockIsDoc {
+				i++
+			}
+		case strings.HasPrefix(string(runes[i:]), `"""`):
+			p.inMultiQuote = true
+			i += 2
+		case r == '"' || r == '\'':
+			// 单行字符串：跳到闭合引号
+			j := i + 1
+			for ; j < len(runes); j++ {
+				if runes[j] == '\\' {
+					j++
+					continue
+				}
+				if runes[j] == r {
+					break
+				}
+			}
+			sb.WriteRune(r)
+			sb.WriteRune(r)
+			i = j
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// inDeclScope 当前位置是否可能出现需要收集的声明（顶层或类型体内）
+func (p *parser) inDeclScope() bool {
+	if len(p.stack) == 0 {
+		return true
+	}
+	return p.stack[len(p.stack)-1].name != ""
+}
+
+// startDecl 识别声明行
+func (p *parser) startDecl(code string, lineNo int) {
+	doc := strings.TrimSpace(strings.Join(p.doc, "\n"))
+	p.doc, p.lineDoc = nil, false
+
+	m := declPattern.FindStringSubmatch(code)
+	if m == nil {
+		return
+	}
+	modifiers := strings.Fields(m[1])
+	kind, rest := m[2], m[3]
+	public := hasModifier(modifiers, "public")
+
+	if typeKinds[kind] {
+		name := identPattern.FindString(strings.TrimSpace(skipGenerics(rest)))
+		if name == "" {
+			return
+		}
+		if kind == "extend" {
+			// extend 本身没有可见性修饰符，是否导出由成员决定
+			p.pending = &scope{name: name, kind: kind, public: true}
+			return
+		}
+		p.pending = &scope{name: name, kind: kind, public: public && len(p.stack) == 0}
+		if !p.pending.public {
+			return
+		}
+		p.current = &decl{kind: kind, typeName: name, line: lineNo, doc: doc}
+		p.continueDecl(code)
+		return
+	}
+
+	var parent *scope
+	if len(p.stack) > 0 {
+		parent = &p.stack[len(p.stack)-1]
+		if !parent.public {
+			return
+		}
+		if !public && parent.kind != types.SymbolInterface {
+			return
+		}
+	} else if !public {
+		return
+	}
+
+	name := memberName(kind, rest)
+	if name == "" {
+		return
+	}
+	d := &decl{kind: kind, line: lineNo, doc: doc}
+	if parent != nil {
+		d.typeName, d.member = parent.name, name
+	} else {
+		d.member = name
+	}
+	p.current = d
+	p.continueDecl(code)
+}
+
+// continueDecl 追加签名文本，声明头结束时生成符号
+func (p *parser) continueDecl(code string) {
+	d := p.current
+	for i, r := range code {
+		switch r {
+		case '(', '[':
+			d.depth++
+		case ')', ']':
+			d.depth--
+		case '{':
+			if d.depth <= 0 {
+				d.appendSignature(code[:i])
+				p.finishDecl()
+				return
+			}
+		case '=':
+			if d.depth <= 0 && (d.kind == types.SymbolVar || d.kind == types.SymbolLet) && !strings.HasPrefix(code[i:], "==") {
+				d.appendSignature(code[:i])
+				p.finishDecl()
+				return
+			}
+		}
+	}
+	d.appendSignature(code)
+	if d.depth <= 0 {
+		p.finishDecl()
+	}
+}
+
+// appendSignature 追加一段签名（多行签名合并空白）
+func (d *decl) appendSignature(text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	if d.signature != "" {
+		d.signature += " "
+	}
+	d.signature += text
+}
+
+// finishDecl 结束当前声明并记录符号
+func (p *parser) finishDecl() {
+	d := p.current
+	if d == nil {
+		return
+	}
+	p.current = nil
+
+	signature := strings.ReplaceAll(spacePattern.ReplaceAllString(d.signature, " "), "( ", "(")
+	signature = strings.ReplaceAll(signature, " )", ")")
+	p.symbols = append(p.symbols, types.Symbol{
+		Package:       p.pkg,
+		Type:          d.typeName,
+		Member:        d.member,
+		QualifiedName: types.QualifyName(p.pkg, d.typeName, d.member),
+		Kind:          d.kind,
+		Signature:     strings.TrimSpace(signature),
+		Doc:           d.doc,
+		File:          p.file,
+		Line:          d.line,
+	})
+}
+
+// countBraces 更新花括号作用域
+func (p *parser) countBraces(code string) {
+	for _, r := range code {
+		switch r {
+		case '{':
+			if p.pending != nil {
+				p.stack = append(p.stack, *p.pending)
+				p.pending = nil
+			} else {
+				p.stack = append(p.stack, scope{})
+			}
+		case '}':
+			if len(p.stack) > 0 {
+				p.stack = p.stack[:len(p.stack)-1]
+			}
+		}
+	}
+}
+
+// memberName 提取函数、属性和变量的名称
+func memberName(kind, rest string) string {
+	rest = strings.TrimSpace(rest)
+	if kind == types.SymbolInit {
+		return "init"
+	}
+	if name := identPattern.FindString(rest); name != "" {
+		return name
+	}
+	// 操作符函数（如 operator func +(rhs: T)）
+	if kind == types.SymbolFunc {
+		if i := strings.Index(rest, "("); i > 0 {
+			return strings.TrimSpace(rest[:i])
+		}
+	}
+	return ""
+}
+
+// skipGenerics 跳过开头的泛型参数（如 extend<T> ArrayList<T> 中的 <T>）
+func skipGenerics(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "<") {
+		return s
+	}
+	depth := 0
+	for i, r := range s {
+		switch r {
+		case '<':
+			depth++
+		case '>':
+			depth--
+			if depth == 0 {
+				return s[i+1:]
+			}
+		}
+	}
+	return s
+}
+
+// afterAnnotations 去掉声明前同一行的注解（如 @Deprecated public func f()）
+func afterAnnotations(code string) string {
+	for strings.HasPrefix(code, "@") {
+		end := strings.IndexAny(code, " \t")
+		if end < 0 {
+			return ""
+		}
+		// 带参数的注解（如 @Deprecated[message: "x"]）
+		if i := strings.Index(code, "["); i >= 0 && i < end {
+			close := strings.Index(code, "]")
+			if close < 0 {
+				return ""
+			}
+			end = close + 1
+		}
+		code = strings.TrimSpace(code[end:])
+	}
+	return code
+}
+
+// hasModifier 判断修饰符列表是否包含指定修饰符
+func hasModifier(modifiers []string, modifier string) bool {
+	for _, m := range modifiers {
+		if m == modifier {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanBlockDoc 整理 /** */ 文档注释：去掉每行开头的 * 和公共缩进
+func cleanBlockDoc(lines []string) []string {
+	var doc []string
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		line = strings.TrimPrefix(line, "*")
+		line = strings.TrimPrefix(line, " ")
+		doc = append(doc, line)
+	}
+	return doc
+}
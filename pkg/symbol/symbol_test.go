@@ -0,0 +1,151 @@
+package symbol
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ystyle/cangjie-mem/pkg/types"
+)
+
+const sampleSource = `// Copyright (c) demo
+package std.collection
+
+import std.math.*
+
+/**
+ * 动态数组
+ *
+ * 支持按下标访问
+ */
+public class ArrayList<T> <: Collection<T> {
+    private var items: Array<T> = Array<T>()
+
+    /** 创建空数组 */
+    public init() {
+        let s = "public func fake() {"
+    }
+
+    /**
+     * 在末尾追加元素
+     */
+    public func append(element: T): Unit {
+        if (true) {
+            func inner() {}
+        }
+    }
+
+    // 插入到指定位置
+    public func insert(
+        index: Int64,
+        element: T
+    ): Unit {}
+
+    @Deprecated
+    public prop size: Int64 {
+        get() { 0 }
+    }
+
+    func hidden(): Unit {}
+
+    public operator func [](index: Int64): T {
+        items[index]
+    }
+}
+
+public interface Collection<T> {
+    func isEmpty(): Bool
+}
+
+class Internal {
+    public func skipped(): Unit {}
+}
+
+extend<T> ArrayList<T> {
+    public func first(): ?T { None }
+}
+
+public let DEFAULT_CAPACITY: Int64 = 16
+
+/* 普通块注释不作为文档 */
+public func max(a: Int64, b: Int64): Int64 {
+    if (a > b) { a } else { b }
+}
+`
+
+func TestParse(t *testing.T) {
+	symbols, err := Parse(strings.NewReader(sampleSource), "src/array_list.cj")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	want := []struct {
+		name, kind, signature, doc string
+	}{
+		{"std.collection.ArrayList", types.SymbolClass, "public class ArrayList<T> <: Collection<T>", "动态数组\n\n支持按下标访问"},
+		{"std.collection.ArrayList.init", types.SymbolInit, "public init()", "创建空数组"},
+		{"std.collection.ArrayList.append", types.SymbolFunc, "public func append(element: T): Unit", "在末尾追加元素"},
+		{"std.collection.ArrayList.insert", types.SymbolFunc, "public func insert(index: Int64, element: T): Unit", "插入到指定位置"},
+		{"std.collection.ArrayList.size", types.SymbolProp, "public prop size: Int64", ""},
+		{"std.collection.ArrayList.[]", types.SymbolFunc, "public operator func [](index: Int64): T", ""},
+		{"std.collection.Collection", types.SymbolInterface, "public interface Collection<T>", ""},
+		{"std.collection.Collection.isEmpty", types.SymbolFunc, "func isEmpty(): Bool", ""},
+		{"std.collection.ArrayList.first", types.SymbolFunc, "public func first(): ?T", ""},
+		{"std.collection.DEFAULT_CAPACITY", types.SymbolLet, "public let DEFAULT_CAPACITY: Int64", ""},
+		{"std.collection.max", types.SymbolFunc, "public func max(a: Int64, b: Int64): Int64", ""},
+	}
+	if len(symbols) != len(want) {
+		for _, s := range symbols {
+			t.Logf("%s %s %q", s.QualifiedName, s.Kind, s.Signature)
+		}
+		t.Fatalf("Parse() returned %d symbols, want %d", len(symbols), len(want))
+	}
+	for i, w := range want {
+		s := symbols[i]
+		if s.QualifiedName != w.name || s.Kind != w.kind || s.Signature != w.signature || s.Doc != w.doc {
+			t.Errorf("symbol %d = {%s %s %q %q}, want {%s %s %q %q}", i, s.QualifiedName, s.Kind, s.Signature, s.Doc, w.name, w.kind, w.signature, w.doc)
+		}
+	}
+
+	appendSymbol := symbols[2]
+	if appendSymbol.Package != "std.collection" || appendSymbol.Type != "ArrayList" || appendSymbol.Member != "append" || appendSymbol.File != "src/array_list.cj" || appendSymbol.Line != 22 {
+		t.Errorf("append symbol = %+v", appendSymbol)
+	}
+}
+
+func TestParseDir(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"src/a.cj":      "package demo\npublic func a(): Unit {}\n",
+		"src/sub/b.cj":  "package demo.sub\npublic struct B {}\n",
+		"src/a_test.cj": "package demo\npublic func testA(): Unit {}\n",
+		".git/c.cj":     "package hidden\npublic func c(): Unit {}\n",
+		"target/d.cj":   "package built\npublic func d(): Unit {}\n",
+		"README.md":     "# demo\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+
+	symbols, count, err := ParseDir(root)
+	if err != nil {
+		t.Fatalf("ParseDir() error = %v", err)
+	}
+	if count != 2 || len(symbols) != 2 {
+		t.Fatalf("ParseDir() = %d symbols from %d files, want 2 from 2", len(symbols), count)
+	}
+	if symbols[0].QualifiedName != "demo.a" || symbols[1].QualifiedName != "demo.sub.B" || symbols[1].File != "src/sub/b.cj" {
+		t.Errorf("ParseDir() symbols = %+v", symbols)
+	}
+
+	if _, _, err := ParseDir(filepath.Join(root, "missing")); err == nil {
+		t.Error("ParseDir(missing) expected error")
+	}
+}
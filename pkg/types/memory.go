@@ -1,7 +1,7 @@
 package types
 
 import (
-	"fmt"
+	"strings"
 	"time"
 )
 
@@ -24,20 +24,33 @@ const (
 
 // Memory 记忆条目
 type Memory struct {
-	ID                 int64            `json:"id"`
-	Level              KnowledgeLevel   `json:"level"`
-	LanguageTag        string           `json:"language_tag"`
-	LibraryName        string           `json:"library_name,omitempty"`
-	ProjectPathPattern string           `json:"project_path_pattern,omitempty"`
-	Title              string           `json:"title"`
-	Content            string           `json:"content"`
-	Summary            string           `json:"summary,omitempty"`
-	Source             KnowledgeSource  `json:"source"`
-	AccessCount        int              `json:"access_count"`
-	Confidence         float64          `json:"confidence"`
-	CreatedAt          time.Time        `json:"created_at"`
-	UpdatedAt          time.Time        `json:"updated_at"`
-	LastAccessedAt     *time.Time       `json:"last_accessed_at,omitempty"`
+	ID                 int64           `json:"id"`
+	Level              KnowledgeLevel  `json:"level"`
+	LanguageTag        string          `json:"language_tag"`
+	LibraryName        string          `json:"library_name,omitempty"`
+	LibraryVersion     string          `json:"library_version,omitempty"` // 适用的库版本范围（语义化版本范围，如 ^1.2、>=1.0 <2.0），为空表示适用于所有版本
+	ProjectPathPattern string          `json:"project_path_pattern,omitempty"`
+	Title              string          `json:"title"`
+	Content            string          `json:"content"`
+	Summary            string          `json:"summary,omitempty"`
+	Source             KnowledgeSource `json:"source"`
+	Tags               []string        `json:"tags,omitempty"`
+	AccessCount        int             `json:"access_count"`
+	Confidence         float64         `json:"confidence"`
+	CreatedAt          time.Time       `json:"created_at"`
+	UpdatedAt          time.Time       `json:"updated_at"`
+	LastAccessedAt     *time.Time      `json:"last_accessed_at,omitempty"`
+	DeletedAt          *time.Time      `json:"deleted_at,omitempty"` // 移入回收站的时间，为空表示未删除
+
+	DiagnosticSignature string      `json:"diagnostic_signature,omitempty"` // 编译诊断签名（规范化后的错误信息）
+	Provenance          *Provenance `json:"provenance,omitempty"`           // 从库源码导入时的出处
+	UUID                string      `json:"uuid,omitempty"`                 // 跨服务器稳定的记忆 ID（知识包导入导出时保留）
+}
+
+// Provenance 记忆的出处（由 ingest-library 或符号导入生成，重复导入时据此找到原记忆）
+type Provenance struct {
+	File    string `json:"file"`              // 相对库根目录的文件路径
+	Section string `json:"section,omitempty"` // 章节标题路径或声明的完整名称
 }
 
 // StoreRequest 存储请求
@@ -45,11 +58,120 @@ type StoreRequest struct {
 	Level              KnowledgeLevel  `json:"level" mcp:"required"`
 	LanguageTag        string          `json:"language_tag"`
 	LibraryName        string          `json:"library_name,omitempty"`
+	LibraryVersion     string          `json:"library_version,omitempty"` // 适用的库版本范围（仅对 library 层级有效）
 	ProjectPathPattern string          `json:"project_path_pattern,omitempty"`
 	Title              string          `json:"title" mcp:"required"`
 	Content            string          `json:"content" mcp:"required"`
 	Summary            string          `json:"summary,omitempty"`
 	Source             KnowledgeSource `json:"source"`
+	Tags               []string        `json:"tags,omitempty"`
+	Editor             string          `json:"-"` // 操作者（mcp/api/import 等），记录到修订历史
+
+	// 编译诊断签名（可选）：可直接粘贴 cjc 报错（如 error: undeclared identifier 'foo'），存储时规范化，
+	// 并自动添加 diagnostic 标签，供 cangjie_mem_diagnose 匹配
+	DiagnosticSignature string `json:"diagnostic_signature,omitempty"`
+}
+
+// UpdateRequest 更新请求（部分更新：仅修改非 nil 的字段）
+type UpdateRequest struct {
+	ID                 int64            `json:"id" mcp:"required"`
+	Level              *KnowledgeLevel  `json:"level,omitempty"`
+	LanguageTag        *string          `json:"language_tag,omitempty"`
+	LibraryName        *string          `json:"library_name,omitempty"`
+	LibraryVersion     *string          `json:"library_version,omitempty"`
+	ProjectPathPattern *string          `json:"project_path_pattern,omitempty"`
+	Title              *string          `json:"title,omitempty"`
+	Content            *string          `json:"content,omitempty"`
+	Summary            *string          `json:"summary,omitempty"`
+	Source             *KnowledgeSource `json:"source,omitempty"`
+	Tags               *[]string        `json:"tags,omitempty"` // 传入时整体替换标签，空数组表示清空
+	Editor             string           `json:"-"`              // 操作者，记录到修订历史
+
+	DiagnosticSignature *string `json:"diagnostic_signature,omitempty"` // 编译诊断签名，空字符串表示移除
+}
+
+// NewUpdateRequest 由完整的 StoreRequest 构造更新请求（覆盖全部字段）
+func NewUpdateRequest(id int64, req StoreRequest) UpdateRequest {
+	u := UpdateRequest{
+		ID:                 id,
+		Level:              &req.Level,
+		LanguageTag:        &req.LanguageTag,
+		LibraryName:        &req.LibraryName,
+		LibraryVersion:     &req.LibraryVersion,
+		ProjectPathPattern: &req.ProjectPathPattern,
+		Title:              &req.Title,
+		Editor:             req.Editor,
+		Content:            &req.Content,
+		Summary:            &req.Summary,
+		Source:             &req.Source,
+	}
+	// 未提供标签时保持原有标签不变
+	if req.Tags != nil {
+		u.Tags = &req.Tags
+	}
+	if req.DiagnosticSignature != "" {
+		u.DiagnosticSignature = &req.DiagnosticSignature
+	}
+	return u
+}
+
+// IsEmpty 是否没有任何需要更新的字段
+func (r UpdateRequest) IsEmpty() bool {
+	return r.Level == nil && r.LanguageTag == nil && r.LibraryName == nil &&
+		r.LibraryVersion == nil && r.ProjectPathPattern == nil && r.Title == nil && r.Content == nil &&
+		r.Summary == nil && r.Source == nil && r.Tags == nil && r.DiagnosticSignature == nil
+}
+
+// ApplyTo 将更新字段合并到已有记忆上
+func (r UpdateRequest) ApplyTo(m *Memory) {
+	if r.Level != nil {
+		m.Level = *r.Level
+	}
+	if r.LanguageTag != nil {
+		m.LanguageTag = *r.LanguageTag
+	}
+	if r.LibraryName != nil {
+		m.LibraryName = *r.LibraryName
+	}
+	if r.LibraryVersion != nil {
+		m.LibraryVersion = *r.LibraryVersion
+	}
+	if r.ProjectPathPattern != nil {
+		m.ProjectPathPattern = *r.ProjectPathPattern
+	}
+	if r.Title != nil {
+		m.Title = *r.Title
+	}
+	if r.Content != nil {
+		m.Content = *r.Content
+	}
+	if r.Summary != nil {
+		m.Summary = *r.Summary
+	}
+	if r.Source != nil {
+		m.Source = *r.Source
+	}
+	if r.Tags != nil {
+		m.Tags = NormalizeTags(*r.Tags)
+	}
+	if r.DiagnosticSignature != nil {
+		m.DiagnosticSignature = *r.DiagnosticSignature
+	}
+}
+
+// NormalizeTags 规范化标签：去除首尾空白、统一小写、去重并去掉空标签
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	return result
 }
 
 // StoreResponse 存储响应
@@ -61,30 +183,93 @@ type StoreResponse struct {
 
 // RecallRequest 回忆请求
 type RecallRequest struct {
-	Query          string  `json:"query" mcp:"required"`
-	Level          string  `json:"level,omitempty"` // 空字符串表示自动判断
-	LanguageTag    string  `json:"language_tag"`
-	LibraryName    string  `json:"library_name,omitempty"` // 库名筛选（仅对 library 层级有效）
-	ProjectContext string  `json:"project_context,omitempty"`
-	MaxResults     int     `json:"max_results"`
-	MinConfidence  float64 `json:"min_confidence"`
+	Query            string   `json:"query" mcp:"required"`
+	Level            string   `json:"level,omitempty"` // 空字符串表示自动判断
+	LanguageTag      string   `json:"language_tag"`
+	LibraryName      string   `json:"library_name,omitempty"`    // 库名筛选（仅对 library 层级有效）
+	LibraryVersion   string   `json:"library_version,omitempty"` // 当前使用的库版本（如 1.2.3），库级记忆只保留版本范围包含该版本的
+	Tags             []string `json:"tags,omitempty"`            // 标签筛选（需同时包含所有标签）
+	ProjectContext   string   `json:"project_context,omitempty"`
+	MaxResults       int      `json:"max_results"`
+	MinConfidence    float64  `json:"min_confidence"`
+	Mode             string   `json:"mode,omitempty"`              // 检索模式：keyword（默认）或 hybrid
+	Explain          bool     `json:"explain,omitempty"`           // 是否返回置信度各因子明细
+	Highlight        string   `json:"highlight,omitempty"`         // 片段高亮标记：markdown（默认，**词**）、html（<mark>词</mark>）或 none
+	SnippetFragments int      `json:"snippet_fragments,omitempty"` // 每条结果最多返回的片段数（默认 3）
+	IncludeRelated   bool     `json:"include_related,omitempty"`   // 是否附带一跳关联记忆
 }
 
+// 片段高亮标记
+const (
+	HighlightMarkdown = "markdown" // **词**
+	HighlightHTML     = "html"     // <mark>词</mark>（片段中的其他文本会做 HTML 转义）
+	HighlightNone     = "none"     // 不加标记
+)
+
+// 检索模式
+const (
+	RecallModeKeyword = "keyword" // 仅关键词全文检索
+	RecallModeHybrid  = "hybrid"  // 关键词 + 向量语义混合检索
+)
+
 // RecallResult 回忆结果
 type RecallResult struct {
-	ID                  int64          `json:"id"`
-	Level               KnowledgeLevel `json:"level"`
-	Title               string         `json:"title"`
-	Content             string         `json:"content"`
-	Summary             string         `json:"summary,omitempty"`
-	LibraryName         string         `json:"library_name,omitempty"`
-	ProjectPathPattern  string         `json:"project_path_pattern,omitempty"`
-	Source              KnowledgeSource `json:"source"`
-	Confidence          float64        `json:"confidence"`
-	AccessCount         int            `json:"access_count"`
-	MatchedText         string         `json:"matched_text,omitempty"` // 匹配的文本片段
-	CreatedAt           string         `json:"created_at,omitempty"`   // 创建时间
-	UpdatedAt           string         `json:"updated_at,omitempty"`   // 更新时间
+	ID                 int64             `json:"id"`
+	Level              KnowledgeLevel    `json:"level"`
+	Title              string            `json:"title"`
+	Content            string            `json:"content"`
+	Summary            string            `json:"summary,omitempty"`
+	LibraryName        string            `json:"library_name,omitempty"`
+	LibraryVersion     string            `json:"library_version,omitempty"`
+	ProjectPathPattern string            `json:"project_path_pattern,omitempty"`
+	Source             KnowledgeSource   `json:"source"`
+	Tags               []string          `json:"tags,omitempty"`
+	Confidence         float64           `json:"confidence"`
+	AccessCount        int               `json:"access_count"`
+	Score              float64           `json:"score,omitempty"`        // 检索相关度（混合检索时为 BM25 与向量相似度的融合分数）
+	MatchedText        string            `json:"matched_text,omitempty"` // 匹配的文本片段（带高亮标记，多个片段以空格连接）
+	Snippets           []Snippet         `json:"snippets,omitempty"`     // 匹配片段及检索词位置
+	Related            []RelatedMemory   `json:"related,omitempty"`      // 关联记忆（include_related=true 时返回）
+	CreatedAt          string            `json:"created_at,omitempty"`   // 创建时间
+	UpdatedAt          string            `json:"updated_at,omitempty"`   // 更新时间
+	Explanation        *ScoreExplanation `json:"explanation,omitempty"`  // 置信度明细（explain=true 时返回）
+
+	BM25       float64 `json:"-"` // FTS5 原始 bm25 分数（越小越相关，仅内部排序使用）
+	Similarity float64 `json:"-"` // 向量余弦相似度（仅内部排序使用）
+}
+
+// Snippet 匹配片段
+// 位置均为 Unicode 字符（code point）在 content 中的偏移，[Start, End)
+type Snippet struct {
+	Text    string       `json:"text"`  // 带高亮标记的片段文本
+	Start   int          `json:"start"` // 片段在 content 中的起始位置
+	End     int          `json:"end"`   // 片段在 content 中的结束位置
+	Matches []TermOffset `json:"matches,omitempty"`
+}
+
+// TermOffset 检索词在 content 中的位置
+type TermOffset struct {
+	Term  string `json:"term"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// ScoreExplanation 置信度明细
+type ScoreExplanation struct {
+	Confidence float64       `json:"confidence"`
+	BM25       float64       `json:"bm25"`                 // FTS5 原始 bm25 分数（越小越相关）
+	Similarity float64       `json:"similarity,omitempty"` // 向量余弦相似度（混合检索）
+	Factors    []ScoreFactor `json:"factors"`
+}
+
+// ScoreFactor 置信度中单个因子的得分
+// Contribution = Weight × Value / 权重总和，所有因子的 Contribution 之和即为置信度
+type ScoreFactor struct {
+	Name         string  `json:"name"`   // relevance / level / source / access / project
+	Value        float64 `json:"value"`  // 因子取值 [0, 1]
+	Weight       float64 `json:"weight"` // 配置的权重
+	Contribution float64 `json:"contribution"`
+	Detail       string  `json:"detail,omitempty"`
 }
 
 // RecallResponse 回忆响应
@@ -96,19 +281,74 @@ type RecallResponse struct {
 
 // SuggestRequest 建议补充请求
 type SuggestRequest struct {
-	Query           string         `json:"query" mcp:"required"`
-	SuggestedTitle  string         `json:"suggested_title" mcp:"required"`
-	SuggestedContent string        `json:"suggested_content" mcp:"required"`
-	SuggestedLevel  KnowledgeLevel `json:"suggested_level"`
-	Reason          string         `json:"reason"`
+	Query              string         `json:"query" mcp:"required"`
+	SuggestedTitle     string         `json:"suggested_title" mcp:"required"`
+	SuggestedContent   string         `json:"suggested_content" mcp:"required"`
+	SuggestedLevel     KnowledgeLevel `json:"suggested_level"`
+	LanguageTag        string         `json:"language_tag,omitempty"`
+	LibraryName        string         `json:"library_name,omitempty"`         // 建议的库名（library 层级）
+	ProjectPathPattern string         `json:"project_path_pattern,omitempty"` // 建议的项目路径（project 层级）
+	Reason             string         `json:"reason"`
 }
 
 // SuggestResponse 建议补充响应
 type SuggestResponse struct {
-	Success       bool   `json:"success"`
-	SuggestionID  int64  `json:"suggestion_id"`
-	Status        string `json:"status"` // pending_review, approved, rejected
-	Message       string `json:"message"`
+	Success      bool   `json:"success"`
+	SuggestionID int64  `json:"suggestion_id"`
+	Status       string `json:"status"` // pending_review, approved, rejected
+	Message      string `json:"message"`
+}
+
+// 建议审核状态
+const (
+	SuggestionPending  = "pending_review" // 待审核
+	SuggestionApproved = "approved"       // 已批准（已转为记忆）
+	SuggestionRejected = "rejected"       // 已拒绝
+)
+
+// Suggestion 知识补充建议
+type Suggestion struct {
+	ID                 int64          `json:"id"`
+	Query              string         `json:"query"`
+	SuggestedTitle     string         `json:"suggested_title"`
+	SuggestedContent   string         `json:"suggested_content"`
+	SuggestedLevel     KnowledgeLevel `json:"suggested_level"`
+	LanguageTag        string         `json:"language_tag"`
+	LibraryName        string         `json:"library_name,omitempty"`
+	ProjectPathPattern string         `json:"project_path_pattern,omitempty"`
+	Reason             string         `json:"reason,omitempty"`
+	Status             string         `json:"status"`
+	MemoryID           int64          `json:"memory_id,omitempty"`   // 批准后生成的记忆 ID
+	ReviewNote         string         `json:"review_note,omitempty"` // 审核备注
+	CreatedAt          time.Time      `json:"created_at"`
+	ReviewedAt         *time.Time     `json:"reviewed_at,omitempty"`
+}
+
+// SuggestionListRequest 建议列表请求
+type SuggestionListRequest struct {
+	Status string `json:"status,omitempty"` // 可选：pending_review/approved/rejected
+	Limit  int    `json:"limit,omitempty"`  // 可选：返回数量，默认20
+	Offset int    `json:"offset,omitempty"` // 可选：分页偏移
+}
+
+// SuggestionListResponse 建议列表响应
+type SuggestionListResponse struct {
+	Total   int          `json:"total"`
+	Results []Suggestion `json:"results"`
+}
+
+// SuggestionReviewRequest 建议审核请求
+// 批准时非空字段会覆盖建议中的对应内容
+type SuggestionReviewRequest struct {
+	ID                 int64          `json:"id"`
+	Note               string         `json:"note,omitempty"`
+	Level              KnowledgeLevel `json:"level,omitempty"`
+	LibraryName        string         `json:"library_name,omitempty"`
+	ProjectPathPattern string         `json:"project_path_pattern,omitempty"`
+	Title              string         `json:"title,omitempty"`
+	Content            string         `json:"content,omitempty"`
+	Summary            string         `json:"summary,omitempty"`
+	Editor             string         `json:"-"` // 审核者，记录到修订历史
 }
 
 // IsValid 验证记忆层级是否有效
@@ -133,14 +373,16 @@ func (s KnowledgeSource) IsValid() bool {
 
 // ListRequest 列出请求
 type ListRequest struct {
-	Level              string `json:"level,omitempty"`                // 可选：language/project/library
-	LibraryName        string `json:"library_name,omitempty"`         // 可选：库名筛选
-	ProjectPathPattern string `json:"project_path_pattern,omitempty"` // 可选：项目路径筛选
-	LanguageTag        string `json:"language_tag,omitempty"`         // 可选：语言标签
-	Limit              int    `json:"limit,omitempty"`                // 可选：返回数量，默认20
-	Offset             int    `json:"offset,omitempty"`               // 可选：分页偏移
-	OrderBy            string `json:"order_by,omitempty"`             // 可选：排序字段
-	Brief              bool   `json:"brief,omitempty"`                // 可选：简洁模式，默认false。true时仅返回标题和摘要，不返回完整内容
+	Level              string   `json:"level,omitempty"`                // 可选：language/project/library
+	LibraryName        string   `json:"library_name,omitempty"`         // 可选：库名筛选
+	LibraryVersion     string   `json:"library_version,omitempty"`      // 可选：库版本（如 1.2.3），库级记忆只保留版本范围包含该版本的
+	ProjectPathPattern string   `json:"project_path_pattern,omitempty"` // 可选：项目路径筛选
+	LanguageTag        string   `json:"language_tag,omitempty"`         // 可选：语言标签
+	Tags               []string `json:"tags,omitempty"`                 // 可选：标签筛选（需同时包含所有标签）
+	Limit              int      `json:"limit,omitempty"`                // 可选：返回数量，默认20
+	Offset             int      `json:"offset,omitempty"`               // 可选：分页偏移
+	OrderBy            string   `json:"order_by,omitempty"`             // 可选：排序字段
+	Brief              bool     `json:"brief,omitempty"`                // 可选：简洁模式，默认false。true时仅返回标题和摘要，不返回完整内容
 }
 
 // ListResponse 列出响应
@@ -151,8 +393,9 @@ type ListResponse struct {
 
 // CategoryInfo 分类信息
 type CategoryInfo struct {
-	Name  string `json:"name"`
-	Count int    `json:"count"`
+	Name     string         `json:"name"`
+	Count    int            `json:"count"`
+	Versions []CategoryInfo `json:"versions,omitempty"` // 库分类：按版本范围细分的记忆数（未标注版本的记为 *）
 }
 
 // ListCategoriesRequest 分类列表请求
@@ -164,11 +407,25 @@ type ListCategoriesRequest struct {
 type ListCategoriesResponse struct {
 	Libraries []CategoryInfo `json:"libraries"` // 所有库及其记忆数
 	Projects  []CategoryInfo `json:"projects"`  // 所有项目及其记忆数
+	Tags      []CategoryInfo `json:"tags"`      // 所有标签及其记忆数
 }
 
 // DeleteRequest 删除请求
 type DeleteRequest struct {
-	ID int64 `json:"id" mcp:"required"`
+	ID     int64  `json:"id" mcp:"required"`
+	Editor string `json:"-"` // 操作者，记录到修订历史
+}
+
+// TrashListRequest 回收站列表请求
+type TrashListRequest struct {
+	LanguageTag string `json:"language_tag,omitempty"` // 可选：语言标签
+	Limit       int    `json:"limit,omitempty"`        // 可选：返回数量，默认20
+	Offset      int    `json:"offset,omitempty"`       // 可选：分页偏移
+}
+
+// PurgeResponse 彻底删除响应
+type PurgeResponse struct {
+	Purged int `json:"purged"` // 彻底删除的记忆数
 }
 
 // DeleteResponse 删除响应
@@ -178,34 +435,307 @@ type DeleteResponse struct {
 	Message string `json:"message"`
 }
 
-// 知识包格式版本
-const PackageFormatVersion = "1.0"
+// 修订操作类型
+const (
+	RevisionCreate   = "create"   // 新建
+	RevisionUpdate   = "update"   // 更新
+	RevisionImport   = "import"   // 导入覆盖或新增
+	RevisionRestore  = "restore"  // 回滚到历史版本
+	RevisionDelete   = "delete"   // 移入回收站（快照为删除前的内容）
+	RevisionUndelete = "undelete" // 从回收站恢复
+)
+
+// 操作者
+const (
+	EditorMCP    = "mcp"    // MCP 工具调用
+	EditorAPI    = "api"    // REST API / Web 界面
+	EditorImport = "import" // 知识包导入
+)
+
+// Revision 记忆修订版本（每次写入后的完整快照）
+type Revision struct {
+	ID                 int64           `json:"id"`
+	MemoryID           int64           `json:"memory_id"`
+	Revision           int             `json:"revision"` // 记忆内的版本号，从 1 开始递增
+	Action             string          `json:"action"`
+	Editor             string          `json:"editor,omitempty"`
+	Level              KnowledgeLevel  `json:"level"`
+	LanguageTag        string          `json:"language_tag"`
+	LibraryName        string          `json:"library_name,omitempty"`
+	LibraryVersion     string          `json:"library_version,omitempty"`
+	ProjectPathPattern string          `json:"project_path_pattern,omitempty"`
+	Title              string          `json:"title"`
+	Content            string          `json:"content"`
+	Summary            string          `json:"summary,omitempty"`
+	Source             KnowledgeSource `json:"source"`
+	Tags               []string        `json:"tags,omitempty"`
+	CreatedAt          time.Time       `json:"created_at"`
+}
 
-// PackageInfo 知识包信息
-type PackageInfo struct {
-	Name        string   `json:"name"`
-	Description string   `json:"description"`
-	Author      string   `json:"author,omitempty"`
-	Tags        []string `json:"tags,omitempty"`
-	Version     string   `json:"version"`
+// RevisionListResponse 修订历史列表响应（按版本号降序）
+type RevisionListResponse struct {
+	MemoryID  int64      `json:"memory_id"`
+	Total     int        `json:"total"`
+	Revisions []Revision `json:"revisions"`
 }
 
-// KnowledgePackage 知识包（导入/导出格式）
-type KnowledgePackage struct {
-	Version  string            `json:"version"`  // 包格式版本（如 "1.0"）
-	Package  PackageInfo       `json:"package"`  // 包信息
-	Memories []StoreRequest    `json:"memories"` // 记忆列表
+// RevisionDiffResponse 修订版本差异响应
+type RevisionDiffResponse struct {
+	MemoryID int64  `json:"memory_id"`
+	From     int    `json:"from"`
+	To       int    `json:"to"`
+	Diff     string `json:"diff"` // 统一格式差异，无变化时为空
 }
 
-// CheckPackageVersion 校验包格式版本兼容性
-func CheckPackageVersion(version string) error {
-	if version == "" {
-		return fmt.Errorf("package version is required")
+// HistoryRequest 修订历史请求（MCP 工具）
+type HistoryRequest struct {
+	ID       int64  `json:"id" mcp:"required"`
+	Action   string `json:"action,omitempty"`   // list（默认）/diff/restore
+	From     int    `json:"from,omitempty"`     // diff：起始版本，默认 to 的上一版本
+	To       int    `json:"to,omitempty"`       // diff：目标版本，默认最新版本
+	Revision int    `json:"revision,omitempty"` // restore：要恢复的版本
+}
+
+// LinkRelation 记忆关系类型（有方向：source → target）
+type LinkRelation string
+
+const (
+	RelationSeeAlso     LinkRelation = "see_also"    // 参见
+	RelationDependsOn   LinkRelation = "depends_on"  // source 依赖 target
+	RelationSupersedes  LinkRelation = "supersedes"  // source 取代 target（target 已过时）
+	RelationContradicts LinkRelation = "contradicts" // source 与 target 相互矛盾
+)
+
+// IsValid 验证关系类型是否有效
+func (r LinkRelation) IsValid() bool {
+	switch r {
+	case RelationSeeAlso, RelationDependsOn, RelationSupersedes, RelationContradicts:
+		return true
+	default:
+		return false
 	}
-	if version != PackageFormatVersion {
-		return fmt.Errorf("unsupported package format version: %s (expected %s)", version, PackageFormatVersion)
+}
+
+// 关系遍历方向
+const (
+	LinkDirectionOutgoing = "outgoing" // 当前记忆指向其他记忆
+	LinkDirectionIncoming = "incoming" // 其他记忆指向当前记忆
+	LinkDirectionBoth     = "both"
+)
+
+// Link 记忆关系
+type Link struct {
+	ID        int64        `json:"id"`
+	SourceID  int64        `json:"source_id"`
+	TargetID  int64        `json:"target_id"`
+	Relation  LinkRelation `json:"relation"`
+	Note      string       `json:"note,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// LinkRequest 建立/删除关系请求
+type LinkRequest struct {
+	SourceID int64        `json:"source_id" mcp:"required"`
+	TargetID int64        `json:"target_id" mcp:"required"`
+	Relation LinkRelation `json:"relation"`       // 建立关系时必需；删除时为空表示删除两者之间的所有关系
+	Note     string       `json:"note,omitempty"` // 关系说明
+}
+
+// LinkToolRequest 关系管理请求（MCP 工具）
+type LinkToolRequest struct {
+	LinkRequest
+	Action string `json:"action,omitempty"` // link（默认）/unlink
+}
+
+// UnlinkResponse 删除关系响应
+type UnlinkResponse struct {
+	Removed int `json:"removed"` // 删除的关系数
+}
+
+// GraphRequest 关系图遍历请求
+type GraphRequest struct {
+	ID        int64        `json:"id" mcp:"required"`
+	Depth     int          `json:"depth,omitempty"`     // 遍历深度（默认 1，最大 3）
+	Relation  LinkRelation `json:"relation,omitempty"`  // 只遍历该类型的关系
+	Direction string       `json:"direction,omitempty"` // outgoing / incoming / both（默认）
+}
+
+// GraphNode 关系图节点
+type GraphNode struct {
+	ID          int64          `json:"id"`
+	Level       KnowledgeLevel `json:"level"`
+	Title       string         `json:"title"`
+	Summary     string         `json:"summary,omitempty"`
+	LibraryName string         `json:"library_name,omitempty"`
+	Depth       int            `json:"depth"` // 与起点的距离（起点为 0）
+}
+
+// GraphResponse 关系图
+type GraphResponse struct {
+	Root  int64       `json:"root"`
+	Nodes []GraphNode `json:"nodes"`
+	Links []Link      `json:"links"`
+}
+
+// RelatedMemory 检索结果的关联记忆（一跳）
+type RelatedMemory struct {
+	ID        int64          `json:"id"`
+	Level     KnowledgeLevel `json:"level"`
+	Title     string         `json:"title"`
+	Summary   string         `json:"summary,omitempty"`
+	Relation  LinkRelation   `json:"relation"`
+	Direction string         `json:"direction"` // outgoing：结果 → 关联记忆；incoming：关联记忆 → 结果
+}
+
+// TagDiagnostic 编译诊断记忆的标签（存储时带诊断签名会自动添加）
+const TagDiagnostic = "diagnostic"
+
+// DiagnoseRequest 编译诊断查询请求
+type DiagnoseRequest struct {
+	Output          string `json:"output" mcp:"required"` // cjc/cjpm 原始编译输出
+	LanguageTag     string `json:"language_tag,omitempty"`
+	ProjectContext  string `json:"project_context,omitempty"`  // 当前项目路径（用于项目级记忆和依赖库）
+	MaxFixes        int    `json:"max_fixes,omitempty"`        // 每条诊断最多返回的修复记忆数（默认 3）
+	IncludeWarnings bool   `json:"include_warnings,omitempty"` // 是否同时查询 warning（默认只查询 error）
+}
+
+// 诊断匹配方式
+const (
+	DiagnosticMatchSignature = "signature" // 签名完全一致
+	DiagnosticMatchCode      = "code"      // 错误码一致
+	DiagnosticMatchText      = "text"      // 全文检索诊断记忆
+)
+
+// DiagnosticFix 诊断对应的修复记忆
+type DiagnosticFix struct {
+	ID                  int64          `json:"id"`
+	Level               KnowledgeLevel `json:"level"`
+	Title               string         `json:"title"`
+	Content             string         `json:"content"`
+	Summary             string         `json:"summary,omitempty"`
+	LibraryName         string         `json:"library_name,omitempty"`
+	DiagnosticSignature string         `json:"diagnostic_signature,omitempty"`
+	Match               string         `json:"match"` // signature / code / text
+}
+
+// DiagnosticResult 单条诊断及其修复
+type DiagnosticResult struct {
+	Severity  string          `json:"severity"`
+	Code      string          `json:"code,omitempty"`
+	Message   string          `json:"message"`
+	Signature string          `json:"signature"`
+	File      string          `json:"file,omitempty"`
+	Line      int             `json:"line,omitempty"`
+	Column    int             `json:"column,omitempty"`
+	Fixes     []DiagnosticFix `json:"fixes"`
+}
+
+// DiagnoseResponse 编译诊断查询响应
+type DiagnoseResponse struct {
+	Total   int                `json:"total"`   // 解析出的诊断数
+	Matched int                `json:"matched"` // 找到修复记忆的诊断数
+	Results []DiagnosticResult `json:"results"`
+}
+
+// 符号类型
+const (
+	SymbolClass     = "class"
+	SymbolStruct    = "struct"
+	SymbolInterface = "interface"
+	SymbolEnum      = "enum"
+	SymbolTypeAlias = "type"
+	SymbolFunc      = "func"
+	SymbolInit      = "init"
+	SymbolProp      = "prop"
+	SymbolVar       = "var"
+	SymbolLet       = "let"
+)
+
+// TagAPI 符号导入时生成的 API 记忆的标签
+const TagAPI = "api"
+
+// Symbol 仓颉 API 符号（公开声明）
+// 类型本身 Type 为类型名、Member 为空；类型成员两者都有；顶层函数/变量 Type 为空
+type Symbol struct {
+	ID            int64  `json:"id,omitempty"`
+	LanguageTag   string `json:"language_tag"`
+	LibraryName   string `json:"library_name,omitempty"` // 所属库（标准库为空）
+	Package       string `json:"package,omitempty"`      // 包名（如 std.collection）
+	Type          string `json:"type,omitempty"`         // 所属类型（如 ArrayList）
+	Member        string `json:"member,omitempty"`       // 成员名（如 append）
+	QualifiedName string `json:"qualified_name"`         // 完整名称（如 std.collection.ArrayList.append）
+	Kind          string `json:"kind"`                   // class / struct / interface / enum / type / func / init / prop / var / let
+	Signature     string `json:"signature"`              // 声明（不含函数体）
+	Doc           string `json:"doc,omitempty"`          // 文档注释
+	File          string `json:"file,omitempty"`         // 源文件（相对导入目录）
+	Line          int    `json:"line,omitempty"`
+	KnowledgeID   int64  `json:"knowledge_id,omitempty"` // 关联的记忆 ID
+	MemoryTitle   string `json:"memory_title,omitempty"` // 关联记忆的标题（查询时填充）
+}
+
+// QualifyName 拼接符号的完整名称
+func QualifyName(parts ...string) string {
+	var nonEmpty []string
+	for _, p := range parts {
+		if p != "" {
+			nonEmpty = append(nonEmpty, p)
+		}
 	}
-	return nil
+	return strings.Join(nonEmpty, ".")
+}
+
+// SymbolRequest 符号查询请求
+type SymbolRequest struct {
+	Name        string `json:"name" mcp:"required"`    // 完整名称或其后缀（如 ArrayList.append）
+	Prefix      bool   `json:"prefix,omitempty"`       // 前缀匹配（如 std.collection.ArrayList 列出所有成员）
+	Kind        string `json:"kind,omitempty"`         // 只返回该类型的符号
+	LibraryName string `json:"library_name,omitempty"` // 只返回该库的符号
+	LanguageTag string `json:"language_tag,omitempty"`
+	Limit       int    `json:"limit,omitempty"` // 默认 20，最大 100
+}
+
+// SymbolResponse 符号查询响应
+type SymbolResponse struct {
+	Total   int      `json:"total"`
+	Symbols []Symbol `json:"symbols"`
+}
+
+// SymbolImportRequest 符号导入请求（从 .cj 源码目录提取公开声明）
+type SymbolImportRequest struct {
+	Path           string `json:"path"`                      // 源码目录或单个 .cj 文件
+	LibraryName    string `json:"library_name,omitempty"`    // 库名（标准库留空）
+	LibraryVersion string `json:"library_version,omitempty"` // 生成的 API 记忆适用的库版本范围（可选）
+	LanguageTag    string `json:"language_tag,omitempty"`
+	CreateMemories bool   `json:"create_memories,omitempty"` // 为每个顶层声明生成一条 API 记忆并关联符号
+}
+
+// SymbolImportResult 符号导入结果（同一库重复导入会整体替换该库的符号）
+type SymbolImportResult struct {
+	Files           int `json:"files"`            // 解析的源文件数
+	Symbols         int `json:"symbols"`          // 导入的符号数
+	MemoriesAdded   int `json:"memories_added"`   // 新建的 API 记忆数
+	MemoriesUpdated int `json:"memories_updated"` // 更新的 API 记忆数
+}
+
+// IngestRequest 导入库源码请求（README、docs/*.md 和 .cj 源文件）
+type IngestRequest struct {
+	Name           string `json:"name"` // 库名（必需）
+	Path           string `json:"path"` // 库根目录（必需，服务器本地路径）
+	LanguageTag    string `json:"language_tag,omitempty"`
+	LibraryVersion string `json:"library_version,omitempty"` // 适用的库版本范围（可选）
+	HeadingDepth   int    `json:"heading_depth,omitempty"`   // Markdown 切分深度（默认 3）
+}
+
+// IngestResult 导入库源码结果
+type IngestResult struct {
+	Library   string `json:"library"`
+	Files     int    `json:"files"`     // 读取的文件数
+	Sections  int    `json:"sections"`  // Markdown 章节数
+	Symbols   int    `json:"symbols"`   // 导入的符号数
+	Added     int    `json:"added"`     // 新建的记忆数
+	Updated   int    `json:"updated"`   // 内容有变化而更新的记忆数
+	Unchanged int    `json:"unchanged"` // 内容没有变化的记忆数
+	Removed   int    `json:"removed"`   // 上游已删除或改名的章节、声明对应的记忆数（移入回收站）
 }
 
 // ExportRequest 导出请求
@@ -214,36 +744,179 @@ type ExportRequest struct {
 	LibraryName        string   `json:"library_name,omitempty"`
 	ProjectPathPattern string   `json:"project_path_pattern,omitempty"`
 	LanguageTag        string   `json:"language_tag,omitempty"`
-	Description        string   `json:"description,omitempty"`  // 包描述
-	Author             string   `json:"author,omitempty"`        // 包作者
-	Tags               []string `json:"tags,omitempty"`          // 包标签
+	MemoryTags         []string `json:"memory_tags,omitempty"` // 仅导出包含这些标签的记忆
+	Description        string   `json:"description,omitempty"` // 包描述
+	Author             string   `json:"author,omitempty"`      // 包作者
+	Tags               []string `json:"tags,omitempty"`        // 包标签
 }
 
 // ImportPreview 导入预览
 type ImportPreview struct {
-	ImportID  string         `json:"import_id"`  // 预览 ID
-	Total     int            `json:"total"`      // 总记忆数
-	ToAdd     int            `json:"to_add"`     // 将新增
-	ToUpdate  int            `json:"to_update"`  // 将更新
-	Conflicts []ConflictInfo `json:"conflicts"`  // 冲突列表
+	ImportID  string         `json:"import_id"`        // 预览 ID
+	Total     int            `json:"total"`            // 总记忆数
+	ToAdd     int            `json:"to_add"`           // 将新增
+	ToUpdate  int            `json:"to_update"`        // 将更新（按默认的覆盖策略）
+	ToSkip    int            `json:"to_skip"`          // 与已有记忆一致，将跳过
+	Conflicts []ConflictInfo `json:"conflicts"`        // 冲突列表（包含内容一致的）
+	Signer    *PackageSigner `json:"signer,omitempty"` // 知识包签名者（已签名时）
 }
 
 // ConflictInfo 冲突信息
 type ConflictInfo struct {
-	ExistingID  int64          `json:"existing_id"`  // 已存在记录的 ID
-	Title       string         `json:"title"`        // 标题
-	LibraryName string         `json:"library_name"` // 库名
-	Level       KnowledgeLevel `json:"level"`        // 层级
+	Index          int            `json:"index"`                     // 在知识包中的序号（确认导入时按序号指定处理策略）
+	ExistingID     int64          `json:"existing_id"`               // 已存在记录的 ID
+	Title          string         `json:"title"`                     // 标题
+	LibraryName    string         `json:"library_name"`              // 库名
+	LibraryVersion string         `json:"library_version,omitempty"` // 库版本范围
+	Level          KnowledgeLevel `json:"level"`                     // 层级
+	Diffs          []FieldDiff    `json:"diffs"`                     // 已有记忆与导入记忆不同的字段（为空表示内容一致）
+}
+
+// FieldDiff 冲突记忆的字段差异
+type FieldDiff struct {
+	Field    string `json:"field"`          // 字段名（content、summary、tags 等）
+	Existing string `json:"existing"`       // 已有记忆的值
+	Incoming string `json:"incoming"`       // 导入记忆的值
+	Diff     string `json:"diff,omitempty"` // 统一格式差异（只有 content 字段提供）
+}
+
+// ConflictStrategy 导入冲突（与已有记忆同层级、同库、同版本范围、同标题）的处理策略
+type ConflictStrategy string
+
+const (
+	ConflictOverwrite ConflictStrategy = "overwrite" // 覆盖已有记忆（默认）
+	ConflictSkip      ConflictStrategy = "skip"      // 保留已有记忆，跳过导入的
+	ConflictKeepBoth  ConflictStrategy = "keep_both" // 都保留，导入的记忆标题加序号后新增
+	ConflictMerge     ConflictStrategy = "merge"     // 合并：正文追加到已有记忆后，标签取并集
+)
+
+// IsValid 验证冲突处理策略是否有效（为空表示默认策略）
+func (c ConflictStrategy) IsValid() bool {
+	switch c {
+	case "", ConflictOverwrite, ConflictSkip, ConflictKeepBoth, ConflictMerge:
+		return true
+	default:
+		return false
+	}
+}
+
+// ConflictResolution 单条冲突记忆的处理策略
+type ConflictResolution struct {
+	Index    int              `json:"index"`    // 在知识包中的序号（见 ConflictInfo.Index）
+	Strategy ConflictStrategy `json:"strategy"` // 处理策略
+}
+
+// 导入会话状态
+const (
+	ImportSessionPending   = "pending"   // 已预览，等待确认
+	ImportSessionCompleted = "completed" // 已导入
+	ImportSessionFailed    = "failed"    // 有记忆导入失败，已回滚
+	ImportSessionExpired   = "expired"   // 超时未确认
+)
+
+// 导入来源
+const (
+	ImportSourcePackage  = "package"  // 知识包
+	ImportSourceMarkdown = "markdown" // Markdown 文档
+)
+
+// ImportSession 导入会话：上传预览后等待确认，确认后作为导入历史保留
+type ImportSession struct {
+	ID            string        `json:"id"`                       // 会话 ID（即预览的 import_id）
+	Status        string        `json:"status"`                   // pending / completed / failed / expired
+	Source        string        `json:"source"`                   // package / markdown
+	Uploader      string        `json:"uploader,omitempty"`       // 上传者（认证用户名或客户端地址）
+	FormatVersion string        `json:"format_version,omitempty"` // 知识包格式版本
+	Package       PackageInfo   `json:"package"`                  // 知识包信息
+	Total         int           `json:"total"`                    // 记忆数量
+	Result        *ImportResult `json:"result,omitempty"`         // 导入结果（确认后才有，列表中不含逐条结果）
+	MemoryIDs     []int64       `json:"memory_ids"`               // 本次导入新增或修改的记忆 ID
+	CreatedAt     time.Time     `json:"created_at"`
+	ExpiresAt     time.Time     `json:"expires_at"`
+	CompletedAt   *time.Time    `json:"completed_at,omitempty"`
+}
+
+// ImportHistoryRequest 导入历史查询请求
+type ImportHistoryRequest struct {
+	Status string `json:"status,omitempty"` // 按状态过滤（为空时返回全部）
+	Limit  int    `json:"limit,omitempty"`
+	Offset int    `json:"offset,omitempty"`
+}
+
+// ImportHistoryResponse 导入历史（按上传时间倒序）
+type ImportHistoryResponse struct {
+	Total    int             `json:"total"`
+	Sessions []ImportSession `json:"sessions"`
+}
+
+// MarkdownImportRequest Markdown 文档导入请求：按标题切分为记忆，经预览确认后导入
+type MarkdownImportRequest struct {
+	Content            string         `json:"content"`         // Markdown 文档内容（必需）
+	Title              string         `json:"title,omitempty"` // 文档标题（可选，作为每条记忆标题的前缀，也用作第一个标题之前内容的标题）
+	Level              KnowledgeLevel `json:"level,omitempty"` // 记忆层级（默认 language）
+	LanguageTag        string         `json:"language_tag,omitempty"`
+	LibraryName        string         `json:"library_name,omitempty"`         // library 层级必需
+	LibraryVersion     string         `json:"library_version,omitempty"`      // 适用的库版本范围（仅 library 层级）
+	ProjectPathPattern string         `json:"project_path_pattern,omitempty"` // project 层级必需
+	HeadingDepth       int            `json:"heading_depth,omitempty"`        // 按几级以内的标题切分（1~6，默认 3）
+	Tags               []string       `json:"tags,omitempty"`                 // 每条记忆的标签
 }
 
 // ImportConfirmRequest 导入确认请求
 type ImportConfirmRequest struct {
 	ImportID string `json:"import_id"` // 预览 ID
+	ImportOptions
 }
 
-// ImportResult 导入结果
+// ImportOptions 导入选项
+type ImportOptions struct {
+	DryRun      bool                 `json:"dry_run,omitempty"`     // 试运行：只返回导入结果，不写入
+	Strategy    ConflictStrategy     `json:"strategy,omitempty"`    // 冲突的默认处理策略（默认 overwrite）
+	Resolutions []ConflictResolution `json:"resolutions,omitempty"` // 逐条指定冲突的处理策略（优先于默认策略）
+}
+
+// StrategyFor 第 index 条记忆冲突时的处理策略
+func (o ImportOptions) StrategyFor(index int) ConflictStrategy {
+	for _, r := range o.Resolutions {
+		if r.Index == index && r.Strategy != "" {
+			return r.Strategy
+		}
+	}
+	if o.Strategy != "" {
+		return o.Strategy
+	}
+	return ConflictOverwrite
+}
+
+// 单条记忆的导入动作
+const (
+	ImportAdded   = "added"   // 新增
+	ImportUpdated = "updated" // 覆盖已有记忆
+	ImportSkipped = "skipped" // 跳过（内容未变、包内重复或冲突时选择跳过）
+	ImportFailed  = "failed"  // 失败（任意一条失败时整个导入回滚）
+)
+
+// ImportResult 导入结果（整个导入在一个事务中执行，有失败时全部回滚）
 type ImportResult struct {
-	Added   int `json:"added"`   // 新增数量
-	Updated int `json:"updated"` // 更新数量
-	Total   int `json:"total"`   // 总数
+	Added      int                `json:"added"`       // 新增数量
+	Updated    int                `json:"updated"`     // 更新数量
+	Skipped    int                `json:"skipped"`     // 跳过数量
+	Failed     int                `json:"failed"`      // 失败数量
+	Total      int                `json:"total"`       // 总数
+	DryRun     bool               `json:"dry_run"`     // 是否为试运行
+	RolledBack bool               `json:"rolled_back"` // 是否已回滚（有失败或试运行时为 true，没有写入任何记忆）
+	Items      []ImportItemResult `json:"items"`       // 每条记忆的导入结果（与导入顺序一致）
+}
+
+// ImportItemResult 单条记忆的导入结果
+type ImportItemResult struct {
+	Index          int            `json:"index"`                     // 在知识包中的序号（从 0 开始）
+	UUID           string         `json:"uuid,omitempty"`            // 记忆的稳定 UUID
+	Title          string         `json:"title"`                     // 标题
+	Level          KnowledgeLevel `json:"level"`                     // 层级
+	LibraryName    string         `json:"library_name,omitempty"`    // 库名
+	LibraryVersion string         `json:"library_version,omitempty"` // 库版本范围
+	Action         string         `json:"action"`                    // added / updated / skipped / failed
+	ID             int64          `json:"id,omitempty"`              // 记忆 ID（已有记忆的 ID，或新增记忆的 ID；回滚后新增的不返回）
+	Reason         string         `json:"reason,omitempty"`          // 跳过或失败的原因
 }
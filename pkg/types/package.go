@@ -0,0 +1,309 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 知识包格式版本
+const (
+	PackageFormatVersion = "2.0" // 当前格式：每条记忆带 UUID、时间戳和内容哈希，包带校验和
+	PackageFormatV1      = "1.0" // 旧格式：只有记忆内容，导入时自动升级
+)
+
+// checksumPrefix 内容哈希和校验和的算法前缀
+const checksumPrefix = "sha256:"
+
+// packageNamespace 为 1.0 知识包中的记忆生成确定性 UUID 的命名空间
+var packageNamespace = uuid.MustParse("6f1d4c2e-8b3a-5e7f-9a10-2c4b6d8e0f12")
+
+// PackageInfo 知识包信息
+type PackageInfo struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Author      string   `json:"author,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Version     string   `json:"version"`
+}
+
+// KnowledgePackage 知识包（导入/导出格式）
+type KnowledgePackage struct {
+	Version  string          `json:"version"`            // 包格式版本（如 "2.0"）
+	Package  PackageInfo     `json:"package"`            // 包信息
+	Memories []PackageMemory `json:"memories"`           // 记忆列表
+	Checksum string          `json:"checksum,omitempty"` // 包校验和（2.0 起，由格式版本和每条记忆的 UUID、内容哈希、元数据计算）
+
+	Signature *PackageSignature `json:"signature,omitempty"` // 签名（可选，签名内容为校验和、包信息和签名者）
+}
+
+// PackageSignature 知识包签名
+type PackageSignature struct {
+	Algorithm string    `json:"algorithm"`  // 签名算法（ed25519）
+	Signer    string    `json:"signer"`     // 签名者（签名时声明的名称）
+	KeyID     string    `json:"key_id"`     // 公钥指纹（SHA256:...）
+	PublicKey string    `json:"public_key"` // 公钥（base64）
+	SignedAt  time.Time `json:"signed_at"`  // 签名时间
+	Value     string    `json:"value"`      // 签名值（base64）
+}
+
+// PackageSigner 验证通过的签名者
+type PackageSigner struct {
+	Signer   string    `json:"signer"`         // 签名时声明的名称
+	KeyID    string    `json:"key_id"`         // 公钥指纹
+	SignedAt time.Time `json:"signed_at"`      // 签名时间
+	Trusted  bool      `json:"trusted"`        // 公钥是否在受信任的密钥环中
+	Name     string    `json:"name,omitempty"` // 密钥环中该公钥的名称（受信任时）
+}
+
+// PackageMemory 知识包中的记忆：记忆内容加上跨服务器稳定的身份和元数据
+// 1.0 知识包的记忆只有内容字段，升级时补齐 UUID 和内容哈希
+type PackageMemory struct {
+	StoreRequest
+	UUID        string    `json:"uuid,omitempty"`         // 稳定 ID（导入时优先按 UUID 匹配已有记忆）
+	CreatedAt   time.Time `json:"created_at,omitzero"`    // 创建时间
+	UpdatedAt   time.Time `json:"updated_at,omitzero"`    // 更新时间
+	AccessCount int       `json:"access_count,omitempty"` // 访问次数
+	Confidence  float64   `json:"confidence,omitempty"`   // 置信度
+	ContentHash string    `json:"content_hash,omitempty"` // 内容哈希（见 ComputeHash）
+}
+
+// NewPackageMemories 把记忆内容包装为知识包记忆（没有 UUID，导入时按层级、库名、版本范围和标题匹配）
+func NewPackageMemories(memories []StoreRequest) []PackageMemory {
+	result := make([]PackageMemory, len(memories))
+	for i, m := range memories {
+		result[i] = PackageMemory{StoreRequest: m}
+	}
+	return result
+}
+
+// ComputeHash 计算记忆内容的哈希（层级、库、标题、正文、摘要、标签等导入时写入的字段，不含 UUID 和时间戳）
+func (m PackageMemory) ComputeHash() string {
+	languageTag := m.LanguageTag
+	if languageTag == "" {
+		languageTag = "cangjie"
+	}
+	source := m.Source
+	if source == "" {
+		source = SourceManual
+	}
+
+	h := sha256.New()
+	for _, field := range []string{
+		string(m.Level), languageTag, m.LibraryName, m.LibraryVersion, m.ProjectPathPattern,
+		m.Title, m.Content, m.Summary, string(source),
+		strings.Join(NormalizeTags(m.Tags), ","), m.DiagnosticSignature,
+	} {
+		// 每个字段前写入长度，避免字段边界不同而拼接结果相同
+		fmt.Fprintf(h, "%d:%s\n", len(field), field)
+	}
+	return checksumPrefix + hex.EncodeToString(h.Sum(nil))
+}
+
+// NewKnowledgePackage 生成当前格式的知识包：补齐每条记忆的内容哈希并计算包校验和
+func NewKnowledgePackage(info PackageInfo, memories []PackageMemory) KnowledgePackage {
+	pkg := KnowledgePackage{
+		Version:  PackageFormatVersion,
+		Package:  info,
+		Memories: memories,
+	}
+	for i := range pkg.Memories {
+		pkg.Memories[i].ContentHash = pkg.Memories[i].ComputeHash()
+	}
+	pkg.Checksum = pkg.computeChecksum()
+	return pkg
+}
+
+// computeChecksum 由格式版本和每条记忆的 UUID、内容哈希、元数据（时间戳、访问次数、置信度）计算包校验和，
+// 导入时写入的字段都受校验和保护，签名覆盖校验和，因此同样覆盖这些字段
+func (p KnowledgePackage) computeChecksum() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n", p.Version)
+	for _, m := range p.Memories {
+		fmt.Fprintf(h, "%s %s %s %s %d %s\n", m.UUID, m.ContentHash,
+			checksumTime(m.CreatedAt), checksumTime(m.UpdatedAt), m.AccessCount,
+			strconv.FormatFloat(m.Confidence, 'g', -1, 64))
+	}
+	return checksumPrefix + hex.EncodeToString(h.Sum(nil))
+}
+
+// checksumTime 时间戳在校验和中的规范形式（UTC，零值为空）
+func checksumTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// packageUpgrades 旧格式（按主版本）升级到当前格式的函数
+var packageUpgrades = map[int]func(p *KnowledgePackage) error{
+	1: upgradePackageV1,
+}
+
+// NegotiatePackage 协商知识包格式版本：当前主版本（2.x）的包校验内容哈希和校验和，
+// 旧版本（1.x）的包升级为当前格式（旧包没有签名），其他版本返回错误
+func NegotiatePackage(p *KnowledgePackage) error {
+	if p.Version == "" {
+		return fmt.Errorf("package version is required")
+	}
+	major, err := majorVersion(p.Version)
+	if err != nil {
+		return err
+	}
+
+	current, _ := majorVersion(PackageFormatVersion)
+	switch {
+	case major == current:
+		return p.verify()
+	case packageUpgrades[major] != nil:
+		return packageUpgrades[major](p)
+	default:
+		return fmt.Errorf("unsupported package format version: %s (supported: %s, %s)", p.Version, PackageFormatV1, PackageFormatVersion)
+	}
+}
+
+// majorVersion 解析格式版本号（如 "2.0"）的主版本
+func majorVersion(version string) (int, error) {
+	majorStr, _, _ := strings.Cut(version, ".")
+	major, err := strconv.Atoi(majorStr)
+	if err != nil || major < 0 {
+		return 0, fmt.Errorf("invalid package format version: %s", version)
+	}
+	return major, nil
+}
+
+// verify 校验 2.0 知识包：UUID 格式和唯一性、每条记忆的内容哈希、包校验和
+func (p *KnowledgePackage) verify() error {
+	seen := make(map[string]int, len(p.Memories))
+	for i, m := range p.Memories {
+		if m.UUID == "" {
+			return fmt.Errorf("invalid package: memory %d (%s) has no uuid", i, m.Title)
+		}
+		if _, err := uuid.Parse(m.UUID); err != nil {
+			return fmt.Errorf("invalid package: memory %d (%s) has invalid uuid %q", i, m.Title, m.UUID)
+		}
+		if first, ok := seen[m.UUID]; ok {
+			return fmt.Errorf("invalid package: memory %d has the same uuid as memory %d", i, first)
+		}
+		seen[m.UUID] = i
+		if m.ContentHash != m.ComputeHash() {
+			return fmt.Errorf("invalid package: content hash mismatch for memory %d (%s)", i, m.Title)
+		}
+	}
+	if p.Checksum == "" {
+		return fmt.Errorf("invalid package: checksum is required")
+	}
+	if p.Checksum != p.computeChecksum() {
+		return fmt.Errorf("invalid package: checksum mismatch")
+	}
+	return nil
+}
+
+// upgradePackageV1 把 1.0 知识包升级为当前格式：按语言、层级、库名、版本范围、项目路径和标题
+// （与导入时匹配已有记忆的键一致）生成确定性 UUID（同一条记忆每次导入得到相同的 UUID），
+// 并补齐内容哈希和校验和；升级后的包同样经过 2.0 校验，键重复的记忆会因 UUID 相同而报错
+func upgradePackageV1(p *KnowledgePackage) error {
+	upgraded := NewKnowledgePackage(p.Package, p.Memories)
+	for i := range upgraded.Memories {
+		m := &upgraded.Memories[i]
+		languageTag := m.LanguageTag
+		if languageTag == "" {
+			languageTag = "cangjie"
+		}
+		key := strings.Join([]string{languageTag, string(m.Level), m.LibraryName, m.LibraryVersion, m.ProjectPathPattern, m.Title}, "\x00")
+		m.UUID = uuid.NewSHA1(packageNamespace, []byte(key)).String()
+	}
+	upgraded.Checksum = upgraded.computeChecksum()
+	if err := upgraded.verify(); err != nil {
+		return err
+	}
+	*p = upgraded
+	return nil
+}
+
+// RegistryIndex 知识包仓库索引（仓库根目录下的 index.json，本地目录和静态 HTTP 服务使用相同结构）
+type RegistryIndex struct {
+	Packages []RegistryEntry `json:"packages"`
+}
+
+// RegistryEntry 仓库中的一个知识包版本
+type RegistryEntry struct {
+	Name        string `json:"name"`                  // 包名
+	Version     string `json:"version"`               // 包版本
+	Description string `json:"description,omitempty"` // 描述
+	Path        string `json:"path"`                  // 知识包文件相对仓库根目录的路径
+}
+
+// ConfiguredRegistry 服务端配置的一个仓库及其中可安装的包
+type ConfiguredRegistry struct {
+	Source   string          `json:"source"`   // 仓库目录或 URL
+	Packages []RegistryEntry `json:"packages"` // 仓库索引中的包版本
+}
+
+// InstalledPackage 已安装的知识包
+type InstalledPackage struct {
+	Name          string    `json:"name"`                  // 包名
+	Version       string    `json:"version"`               // 包版本
+	Description   string    `json:"description,omitempty"` // 描述
+	Author        string    `json:"author,omitempty"`      // 作者
+	FormatVersion string    `json:"format_version"`        // 知识包格式版本
+	Registry      string    `json:"registry"`              // 安装来源仓库（目录或 URL）
+	Checksum      string    `json:"checksum"`              // 包校验和
+	Signer        string    `json:"signer,omitempty"`      // 签名者（已签名时）
+	MemoryIDs     []int64   `json:"memory_ids"`            // 包拥有的记忆 ID（卸载时移入回收站或恢复为安装前版本）
+	InstalledAt   time.Time `json:"installed_at"`          // 首次安装时间
+	UpdatedAt     time.Time `json:"updated_at"`            // 最近安装或升级时间
+}
+
+// PackageInstallRequest 安装或升级知识包请求
+type PackageInstallRequest struct {
+	Registry string `json:"-"`                 // 仓库目录或 URL（仅命令行指定；为空时从服务端配置的仓库中查找）
+	Name     string `json:"name"`              // 包名
+	Version  string `json:"version,omitempty"` // 版本（默认为仓库中的最新版本）
+	ImportOptions
+}
+
+// PackageDiff 升级前后的差异（按记忆 UUID 对比已安装的记忆和新版本）
+type PackageDiff struct {
+	FromVersion string            `json:"from_version,omitempty"` // 已安装版本（首次安装时为空）
+	ToVersion   string            `json:"to_version"`             // 新版本
+	Added       []PackageDiffItem `json:"added"`                  // 新版本新增的记忆
+	Changed     []PackageDiffItem `json:"changed"`                // 内容有变化的记忆（含字段差异）
+	Removed     []PackageDiffItem `json:"removed"`                // 新版本中已删除的记忆（升级时移入回收站或恢复为安装前版本）
+	Conflicts   []PackageDiffItem `json:"conflicts"`              // 与不属于该包的已有记忆冲突（按导入策略处理，默认都保留）
+	Unchanged   int               `json:"unchanged"`              // 未变化的记忆数
+}
+
+// PackageDiffItem 差异中的一条记忆
+type PackageDiffItem struct {
+	Index   int         `json:"index"`             // 在新版本中的序号（removed 为 -1）
+	UUID    string      `json:"uuid"`              // 记忆 UUID
+	Title   string      `json:"title"`             // 标题
+	ID      int64       `json:"id,omitempty"`      // 已有记忆的 ID
+	Package string      `json:"package,omitempty"` // 冲突记忆所属的其他包（仅 conflicts）
+	Diffs   []FieldDiff `json:"diffs,omitempty"`   // 字段差异（changed、conflicts）
+}
+
+// PackageInstallResult 安装、升级结果
+type PackageInstallResult struct {
+	Package     InstalledPackage `json:"package"`          // 安装后的包（试运行或回滚时未写入，不含记忆 ID）
+	Diff        PackageDiff      `json:"diff"`             // 与已安装版本的差异
+	Import      *ImportResult    `json:"import"`           // 逐条导入结果
+	Removed     int              `json:"removed"`          // 移入回收站的记忆数
+	Restored    int              `json:"restored"`         // 恢复为安装前版本的记忆数（包覆盖过的已有记忆）
+	RestoredIDs []int64          `json:"restored_ids"`     // 恢复为安装前版本的记忆 ID
+	Signer      *PackageSigner   `json:"signer,omitempty"` // 知识包签名者（已签名时）
+}
+
+// PackageUninstallResult 卸载结果
+type PackageUninstallResult struct {
+	Name        string  `json:"name"`         // 包名
+	Removed     int     `json:"removed"`      // 移入回收站的记忆数
+	Restored    int     `json:"restored"`     // 恢复为安装前版本的记忆数（包覆盖过的已有记忆）
+	RestoredIDs []int64 `json:"restored_ids"` // 恢复为安装前版本的记忆 ID
+}
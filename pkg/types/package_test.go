@@ -0,0 +1,99 @@
+package types
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNegotiatePackage(t *testing.T) {
+	memories := []StoreRequest{
+		{Level: LevelLibrary, LibraryName: "tang", Title: "路由注册", Content: "使用 router.get 注册路由", Tags: []string{"web"}},
+		{Level: LevelLanguage, Title: "match 表达式", Content: "case 后不写花括号"},
+	}
+
+	// 1.0 知识包升级为当前格式，同一条记忆每次得到相同的 UUID
+	v1 := KnowledgePackage{Version: PackageFormatV1, Memories: NewPackageMemories(memories)}
+	if err := NegotiatePackage(&v1); err != nil {
+		t.Fatalf("NegotiatePackage(1.0) error = %v", err)
+	}
+	if v1.Version != PackageFormatVersion || v1.Checksum == "" || v1.Memories[0].UUID == "" || v1.Memories[0].ContentHash == "" {
+		t.Fatalf("NegotiatePackage(1.0) = %+v", v1)
+	}
+	again := KnowledgePackage{Version: "1.2", Memories: NewPackageMemories(memories)}
+	if err := NegotiatePackage(&again); err != nil || again.Memories[0].UUID != v1.Memories[0].UUID || again.Memories[0].UUID == again.Memories[1].UUID {
+		t.Errorf("NegotiatePackage(1.x) uuids = %q, %q, want %q", again.Memories[0].UUID, again.Memories[1].UUID, v1.Memories[0].UUID)
+	}
+
+	// 同名记忆按语言区分，得到不同的 UUID；完全相同的键在升级时报错
+	languages := KnowledgePackage{Version: PackageFormatV1, Memories: NewPackageMemories([]StoreRequest{
+		{Level: LevelLanguage, LanguageTag: "cangjie", Title: "泛型", Content: "仓颉的泛型"},
+		{Level: LevelLanguage, LanguageTag: "rust", Title: "泛型", Content: "Rust 的泛型"},
+		{Level: LevelLanguage, Title: "match 表达式", Content: "case 后不写花括号"},
+	})}
+	if err := NegotiatePackage(&languages); err != nil {
+		t.Fatalf("NegotiatePackage(1.0 with language tags) error = %v", err)
+	}
+	if languages.Memories[0].UUID == languages.Memories[1].UUID {
+		t.Errorf("NegotiatePackage(1.0) gave cangjie and rust memories the same uuid %q", languages.Memories[0].UUID)
+	}
+	if languages.Memories[2].UUID != v1.Memories[1].UUID {
+		t.Errorf("NegotiatePackage(1.0) default language uuid = %q, want %q", languages.Memories[2].UUID, v1.Memories[1].UUID)
+	}
+	duplicated := KnowledgePackage{Version: PackageFormatV1, Memories: NewPackageMemories([]StoreRequest{
+		{Level: LevelLanguage, Title: "泛型", Content: "第一条"},
+		{Level: LevelLanguage, LanguageTag: "cangjie", Title: "泛型", Content: "第二条"},
+	})}
+	if err := NegotiatePackage(&duplicated); err == nil || !strings.Contains(err.Error(), "same uuid") {
+		t.Errorf("NegotiatePackage(1.0 duplicate key) error = %v", err)
+	}
+
+	// 2.0 知识包校验通过
+	v2 := NewKnowledgePackage(PackageInfo{Name: "tang"}, v1.Memories)
+	if err := NegotiatePackage(&v2); err != nil {
+		t.Errorf("NegotiatePackage(2.0) error = %v", err)
+	}
+
+	// 篡改内容、校验和或 UUID 时校验失败
+	tampered := v2
+	tampered.Memories = append([]PackageMemory(nil), v2.Memories...)
+	tampered.Memories[0].Content = "被篡改的内容"
+	if err := NegotiatePackage(&tampered); err == nil || !strings.Contains(err.Error(), "content hash mismatch") {
+		t.Errorf("NegotiatePackage(tampered content) error = %v", err)
+	}
+	tampered.Memories[0].ContentHash = tampered.Memories[0].ComputeHash()
+	if err := NegotiatePackage(&tampered); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("NegotiatePackage(tampered checksum) error = %v", err)
+	}
+	// 元数据（访问次数、置信度、时间戳）同样受校验和保护
+	inflated := NewKnowledgePackage(PackageInfo{Name: "tang"}, append([]PackageMemory(nil), v1.Memories...))
+	inflated.Memories[0].AccessCount = 1000000
+	if err := NegotiatePackage(&inflated); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("NegotiatePackage(tampered access_count) error = %v", err)
+	}
+	tampered.Memories[1].UUID = tampered.Memories[0].UUID
+	if err := NegotiatePackage(&tampered); err == nil || !strings.Contains(err.Error(), "same uuid") {
+		t.Errorf("NegotiatePackage(duplicate uuid) error = %v", err)
+	}
+	unsigned := NewKnowledgePackage(PackageInfo{}, v1.Memories)
+	unsigned.Checksum = ""
+	if err := NegotiatePackage(&unsigned); err == nil {
+		t.Error("NegotiatePackage(no checksum) error = nil")
+	}
+
+	tests := []struct {
+		name    string
+		version string
+	}{
+		{"empty version", ""},
+		{"future version", "3.0"},
+		{"invalid version", "abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := KnowledgePackage{Version: tt.version}
+			if err := NegotiatePackage(&p); err == nil {
+				t.Errorf("NegotiatePackage(%q) error = nil", tt.version)
+			}
+		})
+	}
+}
@@ -10,6 +10,14 @@ import type {
   KnowledgePackage,
   ImportPreview,
   ImportResult,
+  ImportConfirmRequest,
+  ImportHistoryResponse,
+  ImportSession,
+  InstalledPackage,
+  PackageInstallRequest,
+  PackageInstallResult,
+  PackageUninstallResult,
+  ConfiguredRegistry,
 } from '../types'
 
 // API 基础 URL
@@ -106,6 +114,7 @@ async function request<T>(endpoint: string, options: RequestOptions = {}): Promi
 export async function getMemories(params: {
   level?: string
   library_name?: string
+  library_version?: string
   project_path_pattern?: string
   language_tag?: string
   limit?: number
@@ -116,6 +125,7 @@ export async function getMemories(params: {
   const query = new URLSearchParams()
   if (params.level) query.set('level', params.level)
   if (params.library_name) query.set('library_name', params.library_name)
+  if (params.library_version) query.set('library_version', params.library_version)
   if (params.project_path_pattern) query.set('project_path_pattern', params.project_path_pattern)
   if (params.language_tag) query.set('language_tag', params.language_tag)
   if (params.limit) query.set('limit', params.limit.toString())
@@ -153,6 +163,7 @@ interface RecallResult {
   content: string
   summary?: string
   library_name?: string
+  library_version?: string
   project_path_pattern?: string
   source: string
   confidence: number
@@ -169,6 +180,7 @@ function recallResultToMemory(result: RecallResult): Memory {
     level: result.level as any,
     language_tag: 'cangjie',
     library_name: result.library_name,
+    library_version: result.library_version,
     project_path_pattern: result.project_path_pattern,
     title: result.title,
     content: result.content,
@@ -240,6 +252,9 @@ export async function searchMemories(data: {
   project_context?: string
   max_results?: number
   min_confidence?: number
+  explain?: boolean
+  highlight?: 'markdown' | 'html' | 'none'
+  snippet_fragments?: number
 }) {
   return request<RecallResponse>('/search', { method: 'POST', body: data })
 }
@@ -299,9 +314,55 @@ export async function previewImport(pkg: KnowledgePackage) {
   return request<ImportPreview>('/import', { method: 'POST', body: pkg })
 }
 
-// 确认导入
-export async function confirmImport(importId: string) {
-  return request<ImportResult>('/import/confirm', { method: 'POST', body: { import_id: importId } })
+// 确认导入（dry_run 为 true 时试运行，只返回导入结果不写入；resolutions 逐条指定冲突处理策略）
+export async function confirmImport(req: ImportConfirmRequest) {
+  return request<ImportResult>('/import/confirm', { method: 'POST', body: req })
+}
+
+// 导入历史
+export async function getImportHistory(params: { status?: string; limit?: number; offset?: number } = {}) {
+  const query = new URLSearchParams()
+  if (params.status) query.set('status', params.status)
+  if (params.limit) query.set('limit', String(params.limit))
+  if (params.offset) query.set('offset', String(params.offset))
+  const qs = query.toString()
+  return request<ImportHistoryResponse>(`/import/history${qs ? `?${qs}` : ''}`)
+}
+
+// 导入会话详情（包含逐条导入结果）
+export async function getImportSession(id: string) {
+  return request<ImportSession>(`/import/history/${encodeURIComponent(id)}`)
+}
+
+// ========== 知识包仓库 API ==========
+
+// 已安装的知识包
+export async function listPackages() {
+  return request<InstalledPackage[]>('/packages')
+}
+
+export async function getPackage(name: string) {
+  return request<InstalledPackage>(`/packages/${encodeURIComponent(name)}`)
+}
+
+// 从仓库安装知识包（dry_run 为 true 时只返回差异和导入结果）
+export async function installPackage(req: PackageInstallRequest) {
+  return request<PackageInstallResult>('/packages/install', { method: 'POST', body: req })
+}
+
+// 升级已安装的知识包，结果中包含与已安装版本的差异
+export async function upgradePackage(req: PackageInstallRequest) {
+  return request<PackageInstallResult>('/packages/upgrade', { method: 'POST', body: req })
+}
+
+// 卸载知识包，包拥有的记忆移入回收站
+export async function uninstallPackage(name: string) {
+  return request<PackageUninstallResult>(`/packages/${encodeURIComponent(name)}`, { method: 'DELETE' })
+}
+
+// 服务端配置的仓库中可安装的包
+export async function getRegistryIndex() {
+  return request<ConfiguredRegistry[]>('/registry')
 }
 
 // ========== 健康检查 ==========
@@ -7,25 +7,38 @@ export type KnowledgeSource = 'manual' | 'auto_captured'
 // 记忆条目
 export interface Memory {
   id: number
+  uuid?: string
   level: KnowledgeLevel
   language_tag: string
   library_name?: string
+  library_version?: string
   project_path_pattern?: string
   title: string
   content: string
   summary?: string
   source: KnowledgeSource
+  tags?: string[]
+  diagnostic_signature?: string
+  provenance?: Provenance
   access_count: number
   confidence: number
   created_at: string
   updated_at: string
   last_accessed_at?: string
+  deleted_at?: string
+}
+
+// 记忆出处（从库源码导入）
+export interface Provenance {
+  file: string
+  section?: string
 }
 
 // 列表请求
 export interface ListRequest {
   level?: string
   library_name?: string
+  library_version?: string
   project_path_pattern?: string
   language_tag?: string
   limit?: number
@@ -48,11 +61,40 @@ export interface RecallResult {
   content: string
   summary?: string
   library_name?: string
+  library_version?: string
   project_path_pattern?: string
   source: KnowledgeSource
   confidence: number
   access_count: number
+  score?: number
   matched_text?: string
+  snippets?: Snippet[]
+  related?: RelatedMemory[]
+}
+
+// 关联记忆（一跳）
+export interface RelatedMemory {
+  id: number
+  level: KnowledgeLevel
+  title: string
+  summary?: string
+  relation: 'see_also' | 'depends_on' | 'supersedes' | 'contradicts'
+  direction: 'outgoing' | 'incoming'
+}
+
+// 匹配片段（位置为 Unicode 字符在 content 中的偏移，可用 Array.from(content) 定位）
+export interface Snippet {
+  text: string
+  start: number
+  end: number
+  matches?: TermOffset[]
+}
+
+// 检索词位置
+export interface TermOffset {
+  term: string
+  start: number
+  end: number
 }
 
 // 存储请求
@@ -60,11 +102,13 @@ export interface StoreRequest {
   level: KnowledgeLevel
   language_tag?: string
   library_name?: string
+  library_version?: string
   project_path_pattern?: string
   title: string
   content: string
   summary?: string
   source?: KnowledgeSource
+  tags?: string[]
 }
 
 // 存储响应
@@ -78,12 +122,14 @@ export interface StoreResponse {
 export interface CategoryInfo {
   name: string
   count: number
+  versions?: CategoryInfo[] // 库分类按版本范围细分，* 表示未标注版本
 }
 
 // 分类列表响应
 export interface ListCategoriesResponse {
   libraries: CategoryInfo[]
   projects: CategoryInfo[]
+  tags?: CategoryInfo[]
 }
 
 // 导入请求
@@ -94,25 +140,52 @@ export interface ImportRequest {
 
 // 导入预览
 export interface ImportPreview {
+  import_id: string
   total: number
   to_add: number
   to_update: number
+  to_skip: number
   conflicts: ConflictInfo[]
+  signer?: PackageSigner
+}
+
+// 知识包签名者
+export interface PackageSigner {
+  signer: string
+  key_id: string
+  signed_at: string
+  trusted: boolean
+  name?: string
 }
 
 // 冲突信息
 export interface ConflictInfo {
+  index: number
   title: string
   level: KnowledgeLevel
   library_name?: string
+  library_version?: string
   existing_id: number
-  action: 'update' | 'add'
+  diffs: FieldDiff[]
+}
+
+// 冲突记忆的字段差异
+export interface FieldDiff {
+  field: string
+  existing: string
+  incoming: string
+  diff?: string
 }
 
+// 冲突处理策略
+export type ConflictStrategy = 'overwrite' | 'skip' | 'keep_both' | 'merge'
+
 // 导入确认请求
 export interface ImportConfirmRequest {
-  import_id?: string
-  strategy: 'skip' | 'overwrite' | 'merge'
+  import_id: string
+  dry_run?: boolean
+  strategy?: ConflictStrategy
+  resolutions?: { index: number; strategy: ConflictStrategy }[]
 }
 
 // 导入响应
@@ -133,7 +206,26 @@ export interface KnowledgePackage {
     tags?: string[]
     version: string
   }
-  memories: StoreRequest[]
+  memories: PackageMemory[]
+  checksum?: string
+  signature?: {
+    algorithm: string
+    signer: string
+    key_id: string
+    public_key: string
+    signed_at: string
+    value: string
+  }
+}
+
+// 知识包中的记忆（2.0 格式带稳定 UUID、时间戳和内容哈希）
+export interface PackageMemory extends StoreRequest {
+  uuid?: string
+  created_at?: string
+  updated_at?: string
+  access_count?: number
+  confidence?: number
+  content_hash?: string
 }
 
 // 回忆响应
@@ -150,5 +242,129 @@ export type CategoriesResponse = ListCategoriesResponse
 export interface ImportResult {
   added: number
   updated: number
+  skipped: number
+  failed: number
+  total: number
+  dry_run: boolean
+  rolled_back: boolean
+  items: ImportItemResult[]
+}
+
+// 导入会话（导入历史）
+export interface ImportSession {
+  id: string
+  status: 'pending' | 'completed' | 'failed' | 'expired'
+  source: 'package' | 'markdown'
+  uploader?: string
+  format_version?: string
+  package: { name: string; description: string; author?: string; tags?: string[]; version: string }
   total: number
+  result?: ImportResult
+  memory_ids: number[]
+  created_at: string
+  expires_at: string
+  completed_at?: string
+}
+
+// 导入历史
+export interface ImportHistoryResponse {
+  total: number
+  sessions: ImportSession[]
+}
+
+// 单条记忆的导入结果
+export interface ImportItemResult {
+  index: number
+  uuid?: string
+  title: string
+  level: KnowledgeLevel
+  library_name?: string
+  library_version?: string
+  action: 'added' | 'updated' | 'skipped' | 'failed'
+  id?: number
+  reason?: string
+}
+
+// ========== 知识包仓库 ==========
+
+// 仓库索引
+export interface RegistryIndex {
+  packages: RegistryEntry[]
+}
+
+// 仓库中的一个知识包版本
+export interface RegistryEntry {
+  name: string
+  version: string
+  description?: string
+  path: string
+}
+
+// 服务端配置的仓库及其中可安装的包
+export interface ConfiguredRegistry {
+  source: string
+  packages: RegistryEntry[]
+}
+
+// 已安装的知识包
+export interface InstalledPackage {
+  name: string
+  version: string
+  description?: string
+  author?: string
+  format_version: string
+  registry: string
+  checksum: string
+  signer?: string
+  memory_ids: number[]
+  installed_at: string
+  updated_at: string
+}
+
+// 安装或升级知识包请求（从服务端配置的仓库中查找，version 默认为最新版本）
+export interface PackageInstallRequest {
+  name: string
+  version?: string
+  dry_run?: boolean
+  strategy?: ConflictStrategy
+}
+
+// 差异中的一条记忆
+export interface PackageDiffItem {
+  index: number
+  uuid: string
+  title: string
+  id?: number
+  package?: string
+  diffs?: FieldDiff[]
+}
+
+// 与已安装版本的差异
+export interface PackageDiff {
+  from_version?: string
+  to_version: string
+  added: PackageDiffItem[]
+  changed: PackageDiffItem[]
+  removed: PackageDiffItem[]
+  conflicts: PackageDiffItem[]
+  unchanged: number
+}
+
+// 安装、升级结果
+export interface PackageInstallResult {
+  package: InstalledPackage
+  diff: PackageDiff
+  import: ImportResult
+  removed: number
+  restored: number
+  restored_ids: number[]
+  signer?: PackageSigner
+}
+
+// 卸载结果
+export interface PackageUninstallResult {
+  name: string
+  removed: number
+  restored: number
+  restored_ids: number[]
 }